@@ -0,0 +1,213 @@
+package backtest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"trading/exchange"
+	"trading/names"
+	"trading/user"
+	"trading/utils"
+)
+
+// Fill is an order executed by the simulated account
+type Fill struct {
+	OrderID  int64
+	Time     int64
+	Symbol   string
+	Side     names.TradeSide
+	Price    float64
+	Quantity float64
+	// fee paid in the quote asset
+	Fee float64
+}
+
+// account is a user.AccountInterface that fills every market order at the
+// replayed price and charges a fee on each fill
+type account struct {
+	balances map[string]user.Balance
+	feeRate  float64
+	fills    []Fill
+	orderId  int64
+	lock     sync.Mutex
+}
+
+func newAccount(balances map[string]float64, feeRate float64) *account {
+	b := make(map[string]user.Balance)
+	for asset, free := range balances {
+		b[asset] = user.Balance{Free: free, Asset: asset}
+	}
+	return &account{balances: b, feeRate: feeRate}
+}
+
+var knownQuotes = []string{"USDT", "BUSD", "USDC", "FDUSD", "TUSD", "BTC", "ETH", "BNB"}
+
+// resolves the base and quote of a symbol from the stored exchange info,
+// falling back to well known quote assets when the symbol is not listed
+func tradingPair(symbol names.Symbol) names.TradingPair {
+	pair := symbol.ParseTradingPair()
+	if pair.Base != "" && pair.Quote != "" {
+		return pair
+	}
+	for _, quote := range knownQuotes {
+		if strings.HasSuffix(symbol.String(), quote) && len(symbol) > len(quote) {
+			return names.TradingPair{
+				Base:  strings.TrimSuffix(symbol.String(), quote),
+				Quote: quote,
+			}
+		}
+	}
+	return names.TradingPair{}
+}
+
+// rounds quantity down to the lot size of the symbol when it is known
+func lotQuantity(symbol names.Symbol, quantity float64) float64 {
+	if symbol.Filter().StepSize() == "" {
+		return quantity
+	}
+	return symbol.Quantity(quantity)
+}
+
+func (acc *account) GetBalance(asset string) user.Balance {
+	acc.lock.Lock()
+	defer acc.lock.Unlock()
+	return acc.balances[asset]
+}
+
+func (acc *account) UpdateLockBalance(asset string, quantity float64) {
+	acc.lock.Lock()
+	defer acc.lock.Unlock()
+	b := acc.balances[asset]
+	b.Asset = asset
+	b.Locked = quantity
+	acc.balances[asset] = b
+}
+
+func (acc *account) UpdateFreeBalance(asset string, quantity float64) {
+	acc.lock.Lock()
+	defer acc.lock.Unlock()
+	b := acc.balances[asset]
+	b.Asset = asset
+	b.Free = quantity
+	acc.balances[asset] = b
+}
+
+func (acc *account) Trade(quantity, spot float64, symbol names.Symbol, side names.TradeSide) (error, bool) {
+	_, err := acc.fill(quantity, spot, symbol, side)
+	return err, err == nil
+}
+
+// fill moves the balances of a market order and records it
+func (acc *account) fill(quantity, spot float64, symbol names.Symbol, side names.TradeSide) (Fill, error) {
+	acc.lock.Lock()
+	defer acc.lock.Unlock()
+
+	pair := tradingPair(symbol)
+	if pair.Base == "" {
+		return Fill{}, fmt.Errorf("unknown trading pair for %s", symbol)
+	}
+	if quantity <= 0 || spot <= 0 {
+		return Fill{}, fmt.Errorf("invalid %s order quantity %f at %f", symbol, quantity, spot)
+	}
+
+	base, quote := acc.balances[pair.Base], acc.balances[pair.Quote]
+	value := quantity * spot
+	fee := value * acc.feeRate
+
+	if side.IsBuy() {
+		if value > quote.Free && value-quote.Free < value*1e-9 {
+			// spending the whole balance may overshoot it by a rounding error
+			value = quote.Free
+		}
+		if quote.Free < value {
+			return Fill{}, fmt.Errorf("%s cost %f, or balance %f, error", pair.Quote, value, quote.Free)
+		}
+		quote.Free -= value
+		// buy fee is deducted from the asset received
+		base.Free += quantity - (fee / spot)
+	} else if side.IsSell() {
+		if base.Free < quantity {
+			return Fill{}, fmt.Errorf("%s cost %f, or balance %f, error", pair.Base, quantity, base.Free)
+		}
+		base.Free -= quantity
+		quote.Free += value - fee
+	} else {
+		return Fill{}, fmt.Errorf("invalid trade side, must be BUY or SELL")
+	}
+	base.Asset, quote.Asset = pair.Base, pair.Quote
+	acc.balances[pair.Base], acc.balances[pair.Quote] = base, quote
+
+	acc.orderId++
+	f := Fill{
+		OrderID:  acc.orderId,
+		Time:     utils.Now().UnixMilli(),
+		Symbol:   symbol.String(),
+		Side:     side,
+		Price:    spot,
+		Quantity: quantity,
+		Fee:      fee,
+	}
+	acc.fills = append(acc.fills, f)
+	return f, nil
+}
+
+func (acc *account) order(f Fill) *exchange.Order {
+	return &exchange.Order{
+		Symbol:           f.Symbol,
+		OrderId:          f.OrderID,
+		Time:             f.Time,
+		Price:            f.Price,
+		OrigQuantity:     f.Quantity,
+		ExecutedQuantity: f.Quantity,
+		QuoteQuantity:    f.Price * f.Quantity,
+		Type:             string(names.OrderTypeMarket),
+		Side:             f.Side.String(),
+		Status:           exchange.OrderStatusFilled,
+	}
+}
+
+func (acc *account) TradeBuyConfig(config names.TradeConfig, spot float64) (*exchange.Order, error) {
+	symbol := config.Symbol
+	quantity := config.Buy.Quantity
+	if quantity <= 0 {
+		quoteBalance := acc.GetBalance(tradingPair(symbol).Quote)
+		quantity = lotQuantity(symbol, quoteBalance.Free/spot)
+	}
+	f, err := acc.fill(quantity, spot, symbol, names.TradeSideBuy)
+	if err != nil {
+		utils.LogError(err, fmt.Sprintf("<Backtest>: Error Buying %s, Qty=%f", symbol, quantity))
+		return &exchange.Order{}, err
+	}
+	return acc.order(f), nil
+}
+
+func (acc *account) TradeSellConfig(config names.TradeConfig, spot float64) (*exchange.Order, error) {
+	symbol := config.Symbol
+	quantity := config.Sell.Quantity
+	if quantity <= 0 {
+		baseBalance := acc.GetBalance(tradingPair(symbol).Base)
+		quantity = lotQuantity(symbol, baseBalance.Free)
+	}
+	f, err := acc.fill(quantity, spot, symbol, names.TradeSideSell)
+	if err != nil {
+		utils.LogError(err, fmt.Sprintf("<Backtest>: Error Selling %s, Qty=%f", symbol, quantity))
+		return &exchange.Order{}, err
+	}
+	return acc.order(f), nil
+}
+
+func (acc *account) Fills() []Fill {
+	acc.lock.Lock()
+	defer acc.lock.Unlock()
+	return append([]Fill{}, acc.fills...)
+}
+
+func (acc *account) balanceSnapshot() map[string]float64 {
+	acc.lock.Lock()
+	defer acc.lock.Unlock()
+	snapshot := make(map[string]float64)
+	for asset, b := range acc.balances {
+		snapshot[asset] = b.Free + b.Locked
+	}
+	return snapshot
+}
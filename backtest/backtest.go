@@ -0,0 +1,258 @@
+// Backtest replays recorded prices through a trade manager so a strategy can be
+// evaluated before it trades real funds. Prices are published in the order they
+// were recorded through a stream.ReplayStream, every order is filled by a
+// simulated account and the clock seen by the traders is the time of the
+// replayed price, not the wall clock. A price is only published once the
+// traders processed the last one and the trades and restarts it started are
+// done, so a replay of the same prices always trades the same way.
+//
+// Stable traders screen tickers of the last 24 hours of replayed prices. Graph
+// based traders (auto, bestside) and the graph filters of screeners still
+// fetch their candles from the exchange.
+package backtest
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+	"trading/exchange"
+	"trading/journal"
+	"trading/ledger"
+	"trading/kline"
+	"trading/names"
+	"trading/stream"
+	"trading/trade/manager"
+	"trading/user"
+	"trading/utils"
+)
+
+type Backtest struct {
+	ticks      []Tick
+	balances   map[string]float64
+	quoteAsset string
+	feeRate    float64
+
+	account *account
+	replay  *stream.ReplayStream
+	prices  map[string]float64
+	now     time.Time
+	// number of ticks published
+	played int
+	lock   sync.RWMutex
+}
+
+func NewBacktest(ticks []Tick, balances map[string]float64) *Backtest {
+	return &Backtest{
+		ticks:      MergeTicks(ticks),
+		balances:   balances,
+		quoteAsset: "USDT",
+		feeRate:    0.001,
+		prices:     make(map[string]float64),
+	}
+}
+
+// set the asset in which the report is valued, default USDT
+func (b *Backtest) UseQuoteAsset(quoteAsset string) *Backtest {
+	b.quoteAsset = quoteAsset
+	return b
+}
+
+// set the fee charged on every fill as a fraction of its value, default 0.001
+func (b *Backtest) UseFeeRate(feeRate float64) *Backtest {
+	b.feeRate = feeRate
+	return b
+}
+
+func (b *Backtest) price(symbol string) (float64, bool) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	price, ok := b.prices[symbol]
+	return price, ok
+}
+
+func (b *Backtest) clock() time.Time {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	return b.now
+}
+
+func (b *Backtest) copyPrices() map[string]float64 {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	prices := make(map[string]float64)
+	for symbol, price := range b.prices {
+		prices[symbol] = price
+	}
+	return prices
+}
+
+// tickers are the 24 hour tickers of the replayed symbols at the replay
+// clock, a symbol without ticks in the last 24 hours only has its price
+func (b *Backtest) tickers() []exchange.Ticker {
+	b.lock.RLock()
+	played, now := b.played, b.now
+	prices := make(map[string]float64)
+	for symbol, price := range b.prices {
+		prices[symbol] = price
+	}
+	b.lock.RUnlock()
+
+	ticks := b.ticks[:played]
+	from := sort.Search(len(ticks), func(i int) bool {
+		return !ticks[i].Time.Before(now.Add(-24 * time.Hour))
+	})
+	tickers := map[string]*exchange.Ticker{}
+	opens := map[string]float64{}
+	for _, t := range ticks[from:] {
+		ticker, exist := tickers[t.Symbol]
+		if !exist {
+			ticker = &exchange.Ticker{Symbol: t.Symbol, HighPrice: t.Price, LowPrice: t.Price}
+			tickers[t.Symbol] = ticker
+			opens[t.Symbol] = t.Price
+		}
+		ticker.LastPrice = t.Price
+		ticker.HighPrice = math.Max(ticker.HighPrice, t.Price)
+		ticker.LowPrice = math.Min(ticker.LowPrice, t.Price)
+		ticker.Volume += t.Volume
+		ticker.QuoteVolume += t.QuoteVolume
+		ticker.Count += t.Trades
+	}
+
+	list := []exchange.Ticker{}
+	for _, symbol := range b.symbols() {
+		price, known := prices[symbol]
+		if !known {
+			continue
+		}
+		ticker, exist := tickers[symbol]
+		if !exist {
+			ticker = &exchange.Ticker{Symbol: symbol, LastPrice: price, HighPrice: price, LowPrice: price}
+		} else if open := opens[symbol]; open > 0 {
+			ticker.PriceChangePercent = (ticker.LastPrice - open) / open * 100
+		}
+		// the replay has no order book, the spread is zero
+		ticker.BidPrice, ticker.AskPrice = ticker.LastPrice, ticker.LastPrice
+		list = append(list, *ticker)
+	}
+	return list
+}
+
+func (b *Backtest) symbols() []string {
+	seen := map[string]bool{}
+	symbols := []string{}
+	for _, t := range b.ticks {
+		if !seen[t.Symbol] {
+			seen[t.Symbol] = true
+			symbols = append(symbols, t.Symbol)
+		}
+	}
+	return symbols
+}
+
+// replaces the exchange, account, stream and clock used by the traders
+// and returns a function that restores them
+func (b *Backtest) install() func() {
+	previousAccount := user.MockAccount
+	previousMockAccount, previousMockFees := os.Getenv("MOCK_ACCOUNT"), os.Getenv("MOCK_FEES")
+	previousStreamer := stream.Streamer
+
+	utils.Env().SetMockAccount()
+	utils.Env().SetMockFees()
+	user.MockAccount = b.account
+	previousExchange := exchange.Use(&replayExchange{Exchange: exchange.Get(), backtest: b})
+	utils.UseClock(b.clock)
+	stream.UseStreamer(b.replay)
+	previousJournal := journal.Use(journal.NewMemoryJournal())
+	previousLedger := ledger.Use(ledger.New(ledger.FIFO))
+
+	return func() {
+		journal.Use(previousJournal)
+		ledger.Use(previousLedger)
+		user.MockAccount = previousAccount
+		os.Setenv("MOCK_ACCOUNT", previousMockAccount)
+		os.Setenv("MOCK_FEES", previousMockFees)
+		exchange.Use(previousExchange)
+		utils.UseClock(nil)
+		stream.UseStreamer(previousStreamer)
+	}
+}
+
+// Run starts the trade manager returned by trade and replays every tick to it.
+// trade is called after the simulated environment is in place so traders
+// created inside it receive the replayed prices, the pool of the manager is
+// stopped and drained before the environment is restored
+func (b *Backtest) Run(trade func() *manager.TradeManager) Report {
+	if len(b.ticks) == 0 {
+		utils.LogWarn("<Backtest>: no ticks to replay")
+		return Report{QuoteAsset: b.quoteAsset}
+	}
+
+	b.account = newAccount(b.balances, b.feeRate)
+	b.replay = stream.NewReplayStream(b.symbols())
+
+	// every symbol needs a price before traders ask for their pretrade price
+	b.now = b.ticks[0].Time
+	for _, t := range b.ticks {
+		if _, exist := b.prices[t.Symbol]; !exist {
+			b.prices[t.Symbol] = t.Price
+		}
+	}
+	openingPrices := b.copyPrices()
+	openingBalances := b.account.balanceSnapshot()
+
+	restore := b.install()
+	defer restore()
+
+	utils.LogInfo(fmt.Sprintf("<Backtest>: replaying %d ticks of %d symbols", len(b.ticks), len(openingPrices)))
+	pool := trade().DoTrade().Pool()
+	if pool != nil {
+		defer pool.Wait()
+		defer pool.Stop()
+	}
+
+	curve := []EquityPoint{}
+	for i, t := range b.ticks {
+		b.lock.Lock()
+		b.now = t.Time
+		b.prices[t.Symbol] = t.Price
+		b.played = i + 1
+		b.lock.Unlock()
+
+		// returns once every subscriber processed the price, the pool
+		// then waits for the traders restarted by the trades it made
+		b.replay.Publish(stream.SymbolPriceData{Price: t.Price, Symbol: t.Symbol})
+		if pool != nil {
+			pool.Wait()
+		}
+
+		curve = append(curve, EquityPoint{
+			Time:   t.Time.UnixMilli(),
+			Equity: equity(b.account.balanceSnapshot(), b.copyPrices(), b.quoteAsset),
+		})
+	}
+	b.replay.Close()
+
+	report := newReport(
+		b.quoteAsset,
+		openingBalances,
+		b.account.balanceSnapshot(),
+		openingPrices,
+		b.copyPrices(),
+		b.account.Fills(),
+		curve,
+	)
+	utils.LogInfo(report.String())
+	return report
+}
+
+// RunKlines backtests the trade manager against recorded candles of each symbol
+func RunKlines(klines map[names.Symbol][]kline.KlineData, balances map[string]float64, trade func() *manager.TradeManager) Report {
+	tickLists := [][]Tick{}
+	for symbol, data := range klines {
+		tickLists = append(tickLists, TicksFromKlines(symbol.String(), data))
+	}
+	return NewBacktest(MergeTicks(tickLists...), balances).Run(trade)
+}
@@ -0,0 +1,141 @@
+package backtest
+
+import (
+	"testing"
+	"time"
+	"trading/exchange"
+	"trading/kline"
+	"trading/names"
+	"trading/trade/manager"
+	"trading/trade/traders"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ticksOf(symbol string, prices ...float64) []Tick {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	ticks := []Tick{}
+	for i, price := range prices {
+		ticks = append(ticks, Tick{Time: start.Add(time.Duration(i) * time.Minute), Symbol: symbol, Price: price})
+	}
+	return ticks
+}
+
+func TestTicksFromKlines(t *testing.T) {
+	klines := []kline.KlineData{
+		{Open: 10, High: 12, Low: 9, Close: 11, OpenTime: 0, CloseTime: 60000, QuoteAssetVolume: 500, TradeNum: 7},
+		{Open: 11, High: 13, Low: 8, Close: 9, OpenTime: 60000, CloseTime: 120000},
+	}
+	ticks := TicksFromKlines("BTCUSDT", klines)
+	prices := []float64{}
+	for _, tick := range ticks {
+		prices = append(prices, tick.Price)
+	}
+	assert.Equal(t, []float64{10, 9, 12, 11, 11, 13, 8, 9}, prices, "rising candle visits low first, falling candle visits high first")
+	assert.Equal(t, int64(20000), ticks[1].Time.UnixMilli())
+	assert.Equal(t, 0.0, ticks[2].QuoteVolume)
+	assert.Equal(t, 500.0, ticks[3].QuoteVolume, "the volume of the candle is counted on its close")
+	assert.Equal(t, int64(7), ticks[3].Trades)
+}
+
+func TestReplayTickers(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	ticks := MergeTicks([]Tick{
+		{Time: start, Symbol: "BTCUSDT", Price: 50, QuoteVolume: 1000},
+		{Time: start.Add(2 * time.Hour), Symbol: "BTCUSDT", Price: 100, QuoteVolume: 10, Trades: 1},
+		{Time: start.Add(20 * time.Hour), Symbol: "BTCUSDT", Price: 120, QuoteVolume: 20, Trades: 2},
+		{Time: start.Add(26 * time.Hour), Symbol: "BTCUSDT", Price: 110, QuoteVolume: 30, Trades: 3},
+	}, ticksOf("ETHUSDT", 5))
+	b := NewBacktest(ticks, nil)
+	b.played, b.now = len(ticks), start.Add(26*time.Hour)
+	b.prices = map[string]float64{"BTCUSDT": 110, "ETHUSDT": 5}
+
+	tickers := b.tickers()
+	assert.Len(t, tickers, 2)
+	btc := tickers[0]
+	assert.Equal(t, "BTCUSDT", btc.Symbol)
+	assert.Equal(t, 110.0, btc.LastPrice)
+	assert.InDelta(t, 10, btc.PriceChangePercent, 1e-9, "from the first price of the last 24 hours")
+	assert.Equal(t, 120.0, btc.HighPrice)
+	assert.Equal(t, 100.0, btc.LowPrice)
+	assert.Equal(t, 60.0, btc.QuoteVolume)
+	assert.Equal(t, int64(6), btc.Count)
+	assert.Equal(t, exchange.Ticker{Symbol: "ETHUSDT", LastPrice: 5, HighPrice: 5, LowPrice: 5, BidPrice: 5, AskPrice: 5}, tickers[1],
+		"a symbol without ticks in the last 24 hours only has its price")
+
+	b.played, b.now = 0, start
+	assert.Equal(t, 5.0, b.tickers()[1].LastPrice, "the opening prices before the first tick")
+}
+
+func TestMergeTicks(t *testing.T) {
+	merged := MergeTicks(ticksOf("BTCUSDT", 1, 2), ticksOf("ETHUSDT", 3, 4))
+	assert.Equal(t, "BTCUSDT", merged[0].Symbol)
+	assert.Equal(t, "ETHUSDT", merged[1].Symbol)
+	assert.EqualValues(t, 4, merged[3].Price)
+}
+
+func TestMaxDrawdown(t *testing.T) {
+	curve := []EquityPoint{{Equity: 100}, {Equity: 120}, {Equity: 90}, {Equity: 130}, {Equity: 110}}
+	drawdown, percent := maxDrawdown(curve)
+	assert.EqualValues(t, 30, drawdown)
+	assert.EqualValues(t, 25, percent)
+}
+
+func TestAccountFees(t *testing.T) {
+	acc := newAccount(map[string]float64{"USDT": 100}, 0.001)
+	_, err := acc.fill(1, 50, "BTCUSDT", names.TradeSideBuy)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 50, acc.GetBalance("USDT").Free)
+	assert.InDelta(t, 0.999, acc.GetBalance("BTC").Free, 1e-9, "buy fee is taken from the asset received")
+
+	_, err = acc.fill(0.999, 60, "BTCUSDT", names.TradeSideSell)
+	assert.Nil(t, err)
+	assert.InDelta(t, 50+59.94*0.999, acc.GetBalance("USDT").Free, 1e-9)
+
+	_, err = acc.fill(1, 60, "BTCUSDT", names.TradeSideSell)
+	assert.NotNil(t, err, "can not sell more than the balance")
+
+	closed, wins := closedTrades(acc.Fills(), map[string]float64{}, map[string]float64{})
+	assert.Equal(t, 1, closed)
+	assert.Equal(t, 1, wins)
+}
+
+func TestBacktestLimitTrader(t *testing.T) {
+	config := names.TradeConfig{
+		Symbol:    "BTCUSDT",
+		Side:      names.TradeSideBuy,
+		IsCyclick: true,
+		Buy: names.SideConfig{
+			LimitType: names.RatePercent,
+			StopLimit: 10,
+			LockDelta: 1,
+			Quantity:  names.MAX_QUANTITY,
+		},
+		Sell: names.SideConfig{
+			LimitType: names.RatePercent,
+			StopLimit: 5,
+			LockDelta: 1,
+			Quantity:  names.MAX_QUANTITY,
+		},
+	}
+	ticks := ticksOf("BTCUSDT", 100, 95, 89, 85, 86, 86, 92, 95, 93, 93)
+
+	run := func() Report {
+		return NewBacktest(ticks, map[string]float64{"USDT": 1000}).
+			UseFeeRate(0).
+			Run(func() *manager.TradeManager {
+				return traders.NewLimitTrade([]names.TradeConfig{config})
+			})
+	}
+	report := run()
+
+	assert.Equal(t, 2, report.TradeCount)
+	assert.Equal(t, names.TradeSideBuy, report.Fills[0].Side)
+	assert.EqualValues(t, 86, report.Fills[0].Price)
+	assert.EqualValues(t, 93, report.Fills[1].Price)
+	assert.EqualValues(t, 100, report.WinRate)
+	assert.InDelta(t, 1000*93.0/86.0-1000, report.PnL, 1e-6)
+	assert.EqualValues(t, 0, report.FeesPaid)
+
+	assert.Equal(t, report.Fills, run().Fills, "a replay of the same prices trades the same way")
+}
@@ -0,0 +1,48 @@
+package backtest
+
+import (
+	"fmt"
+	"trading/exchange"
+	"trading/names"
+)
+
+// replayExchange answers the prices and tickers of the replayed symbols and
+// the stored exchange info, everything else is asked to the exchange it replaces
+type replayExchange struct {
+	exchange.Exchange
+	backtest *Backtest
+}
+
+func (e *replayExchange) Name() string {
+	return "backtest"
+}
+
+func (e *replayExchange) PriceLatest(symbol string) (float64, error) {
+	if price, ok := e.backtest.price(symbol); ok {
+		return price, nil
+	}
+	return 0, fmt.Errorf("%s is not replayed", symbol)
+}
+
+func (e *replayExchange) Prices(symbols []string) (map[string]float64, error) {
+	prices := make(map[string]float64)
+	for _, symbol := range symbols {
+		price, err := e.PriceLatest(symbol)
+		if err != nil {
+			return nil, err
+		}
+		prices[symbol] = price
+	}
+	return prices, nil
+}
+
+// Tickers are the tickers of the replayed prices so screeners pick from the
+// replayed symbols
+func (e *replayExchange) Tickers() ([]exchange.Ticker, error) {
+	return e.backtest.tickers(), nil
+}
+
+func (e *replayExchange) ExchangeInfo() (*exchange.ExchangeInfo, error) {
+	info := names.LoadStoredExchangeInfo()
+	return &info, nil
+}
@@ -0,0 +1,165 @@
+package backtest
+
+import (
+	"fmt"
+	"math"
+	"trading/names"
+)
+
+// EquityPoint is the value of the account in the quote asset at a point in time
+type EquityPoint struct {
+	Time   int64
+	Equity float64
+}
+
+type Report struct {
+	QuoteAsset     string
+	StartEquity    float64
+	EndEquity      float64
+	PnL            float64
+	PnLPercent     float64
+	TradeCount     int
+	ClosedTrades   int
+	WinningTrades  int
+	WinRate        float64
+	MaxDrawdown    float64
+	MaxDrawdownPct float64
+	FeesPaid       float64
+	Fills          []Fill
+	EquityCurve    []EquityPoint
+	FinalBalances  map[string]float64
+}
+
+// values balances in the quote asset using the last known price of each asset
+func equity(balances map[string]float64, prices map[string]float64, quoteAsset string) float64 {
+	total := 0.0
+	for asset, quantity := range balances {
+		if asset == quoteAsset {
+			total += quantity
+			continue
+		}
+		if price, ok := prices[asset+quoteAsset]; ok {
+			total += quantity * price
+		}
+	}
+	return total
+}
+
+// the largest fall of the equity curve from a previous peak
+func maxDrawdown(curve []EquityPoint) (float64, float64) {
+	var peak, drawdown, drawdownPercent float64
+	for i, point := range curve {
+		if i == 0 || point.Equity > peak {
+			peak = point.Equity
+		}
+		if fall := peak - point.Equity; fall > drawdown {
+			drawdown = fall
+			if peak > 0 {
+				drawdownPercent = (fall / peak) * 100
+			}
+		}
+	}
+	return drawdown, drawdownPercent
+}
+
+// closedTrades matches every sell against the average cost of the asset held,
+// assets held before the first buy are costed at the first replayed price
+func closedTrades(fills []Fill, openingPrices map[string]float64, openingBalances map[string]float64) (closed, wins int) {
+	type position struct{ quantity, cost float64 }
+	positions := make(map[string]position)
+
+	for _, f := range fills {
+		p, exist := positions[f.Symbol]
+		if !exist {
+			base := tradingPair(names.Symbol(f.Symbol)).Base
+			quantity := openingBalances[base]
+			p = position{quantity: quantity, cost: quantity * openingPrices[f.Symbol]}
+		}
+
+		if f.Side.IsBuy() {
+			p.quantity += f.Quantity - (f.Fee / f.Price)
+			p.cost += f.Quantity * f.Price
+		} else {
+			averageCost := 0.0
+			if p.quantity > 0 {
+				averageCost = p.cost / p.quantity
+			}
+			profit := (f.Price-averageCost)*f.Quantity - f.Fee
+			closed++
+			if profit > 0 {
+				wins++
+			}
+			sold := math.Min(f.Quantity, p.quantity)
+			p.cost -= sold * averageCost
+			p.quantity -= sold
+		}
+		positions[f.Symbol] = p
+	}
+	return closed, wins
+}
+
+func newReport(quoteAsset string, start, end map[string]float64, openingPrices, closingPrices map[string]float64, fills []Fill, curve []EquityPoint) Report {
+	startEquity := equity(start, openingPrices, quoteAsset)
+	endEquity := equity(end, closingPrices, quoteAsset)
+
+	fees := 0.0
+	for _, f := range fills {
+		fees += f.Fee
+	}
+
+	closed, wins := closedTrades(fills, openingPrices, start)
+	winRate := 0.0
+	if closed > 0 {
+		winRate = float64(wins) / float64(closed) * 100
+	}
+
+	pnlPercent := 0.0
+	if startEquity > 0 {
+		pnlPercent = ((endEquity - startEquity) / startEquity) * 100
+	}
+
+	drawdown, drawdownPercent := maxDrawdown(curve)
+
+	return Report{
+		QuoteAsset:     quoteAsset,
+		StartEquity:    startEquity,
+		EndEquity:      endEquity,
+		PnL:            endEquity - startEquity,
+		PnLPercent:     pnlPercent,
+		TradeCount:     len(fills),
+		ClosedTrades:   closed,
+		WinningTrades:  wins,
+		WinRate:        winRate,
+		MaxDrawdown:    drawdown,
+		MaxDrawdownPct: drawdownPercent,
+		FeesPaid:       fees,
+		Fills:          fills,
+		EquityCurve:    curve,
+		FinalBalances:  end,
+	}
+}
+
+func (r Report) String() string {
+	return fmt.Sprintf(
+		"\n===== BACKTEST REPORT %s =====\n"+
+			"Start Equity      : %f\n"+
+			"End Equity        : %f\n"+
+			"PnL               : %f (%.2f%%)\n"+
+			"Trades            : %d\n"+
+			"Closed Trades     : %d\n"+
+			"Win Rate          : %.2f%%\n"+
+			"Max Drawdown      : %f (%.2f%%)\n"+
+			"Fees Paid         : %f\n",
+		r.QuoteAsset,
+		r.StartEquity,
+		r.EndEquity,
+		r.PnL,
+		r.PnLPercent,
+		r.TradeCount,
+		r.ClosedTrades,
+		r.WinRate,
+		r.MaxDrawdown,
+		r.MaxDrawdownPct,
+		r.FeesPaid,
+	)
+}
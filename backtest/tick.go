@@ -0,0 +1,126 @@
+package backtest
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+	"trading/kline"
+)
+
+// Tick is a single recorded price of a symbol
+type Tick struct {
+	Time   time.Time
+	Symbol string
+	Price  float64
+	// traded since the previous tick of the symbol, zero when unknown
+	Volume      float64
+	QuoteVolume float64
+	Trades      int64
+}
+
+// TicksFromKlines expands every candle into the four prices it is known to
+// have traded at. A rising candle is assumed to have visited its low before
+// its high and a falling candle its high before its low. The volume of the
+// candle is counted on its close
+func TicksFromKlines(symbol string, klines []kline.KlineData) []Tick {
+	ticks := []Tick{}
+	for _, k := range klines {
+		open := time.UnixMilli(k.OpenTime)
+		duration := time.Duration(k.CloseTime-k.OpenTime) * time.Millisecond
+		step := duration / 3
+
+		path := []float64{k.Open, k.High, k.Low, k.Close}
+		if k.Close >= k.Open {
+			path = []float64{k.Open, k.Low, k.High, k.Close}
+		}
+		for i, price := range path {
+			ticks = append(ticks, Tick{
+				Time:   open.Add(step * time.Duration(i)),
+				Symbol: symbol,
+				Price:  price,
+			})
+		}
+		close := &ticks[len(ticks)-1]
+		close.Volume, close.QuoteVolume, close.Trades = k.Volume, k.QuoteAssetVolume, k.TradeNum
+	}
+	return ticks
+}
+
+// MergeTicks combines the ticks of many symbols into a single timeline
+func MergeTicks(tickLists ...[]Tick) []Tick {
+	merged := []Tick{}
+	for _, ticks := range tickLists {
+		merged = append(merged, ticks...)
+	}
+	sort.SliceStable(merged, func(a, b int) bool {
+		return merged[a].Time.Before(merged[b].Time)
+	})
+	return merged
+}
+
+// LoadKlines reads candles saved as a json array of kline.KlineData
+func LoadKlines(filename string) ([]kline.KlineData, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var klines []kline.KlineData
+	if err := json.Unmarshal(content, &klines); err != nil {
+		return nil, fmt.Errorf("could not read klines from %s: %w", filename, err)
+	}
+	return klines, nil
+}
+
+// SaveKlines writes candles as a json array that can be read by LoadKlines
+func SaveKlines(filename string, klines []kline.KlineData) error {
+	content, err := json.Marshal(klines)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, content, 0644)
+}
+
+// LoadTicks reads a csv tick file with the columns time,symbol,price
+// where time is a unix timestamp in milliseconds
+func LoadTicks(filename string) ([]Tick, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	ticks := []Tick{}
+	for line, record := range records {
+		if len(record) != 3 {
+			return nil, fmt.Errorf("%s line %d: expected time,symbol,price", filename, line+1)
+		}
+		timestamp, err := strconv.ParseInt(record[0], 10, 64)
+		if err != nil {
+			if line == 0 {
+				// header row
+				continue
+			}
+			return nil, fmt.Errorf("%s line %d: invalid time %s", filename, line+1, record[0])
+		}
+		price, err := strconv.ParseFloat(record[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: invalid price %s", filename, line+1, record[2])
+		}
+		ticks = append(ticks, Tick{
+			Time:   time.UnixMilli(timestamp),
+			Symbol: record[1],
+			Price:  price,
+		})
+	}
+	return MergeTicks(ticks), nil
+}
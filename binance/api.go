@@ -107,6 +107,10 @@ func (binance *binanceApi[R]) GetPayload() interface{} {
 	return binance.payload
 }
 
+func (binance *binanceApi[R]) GetClient() *http.Client {
+	return HTTPClient()
+}
+
 func (binance *binanceApi[R]) Request() api.RequestResponse[R] {
 	return binance.RequestWithQuery(nil)
 }
@@ -0,0 +1,420 @@
+package binance
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"trading/utils"
+)
+
+// Priority of a request to the exchange, lower goes first
+type Priority int
+
+const (
+	PriorityOrder Priority = iota
+	PriorityPrice
+	PriorityNormal
+	PriorityLow
+)
+
+func (p Priority) String() string {
+	return [...]string{"order", "price", "normal", "low"}[p]
+}
+
+// Limits of the exchange, Reserve is the share of the weight only
+// orders may use so that a busy bot can still exit its positions
+type Limits struct {
+	WeightPerMinute  int
+	OrdersPerTenSecs int
+	OrdersPerDay     int
+	Reserve          float64
+}
+
+var DefaultLimits = Limits{
+	WeightPerMinute:  6000,
+	OrdersPerTenSecs: 50,
+	OrdersPerDay:     160000,
+	Reserve:          0.1,
+}
+
+// the weight of the endpoints, the exchange reports the real weight used
+// after every request so these only need to be close
+var endpointWeights = map[string]int{
+	"/api/v3/klines":           2,
+	"/api/v3/avgPrice":         2,
+	"/api/v3/exchangeInfo":     20,
+	"/api/v3/account":          20,
+	"/api/v3/allOrders":        20,
+	"/api/v3/historicalTrades": 25,
+}
+
+func requestWeight(r *http.Request) int {
+	query := r.URL.Query()
+	single := query.Get("symbol") != ""
+	switch r.URL.Path {
+	case "/api/v3/ticker/price":
+		if single {
+			return 2
+		}
+		return 4
+	case "/api/v3/ticker/24hr", "/api/v3/openOrders":
+		if single {
+			return 2
+		}
+		return 80
+	case "/api/v3/order":
+		if r.Method == http.MethodGet {
+			return 4
+		}
+		return 1
+	case "/api/v3/depth":
+		limit, _ := strconv.Atoi(query.Get("limit"))
+		switch {
+		case limit > 1000:
+			return 250
+		case limit > 500:
+			return 50
+		case limit > 100:
+			return 25
+		}
+		return 5
+	}
+	if weight, exist := endpointWeights[r.URL.Path]; exist {
+		return weight
+	}
+	return 1
+}
+
+// places or cancels an order and counts against the order limits
+func isOrder(r *http.Request) bool {
+	return r.Method != http.MethodGet && strings.HasPrefix(r.URL.Path, "/api/v3/order")
+}
+
+func requestPriority(r *http.Request) Priority {
+	if p, ok := r.Context().Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/api/v3/order"):
+		return PriorityOrder
+	case r.URL.Path == "/api/v3/ticker/price" || r.URL.Path == "/api/v3/avgPrice" || r.URL.Path == "/api/v3/depth":
+		return PriorityPrice
+	case r.URL.Path == "/api/v3/exchangeInfo" || r.URL.Path == "/api/v3/ticker/24hr" ||
+		strings.HasPrefix(r.URL.Path, "/sapi/"):
+		return PriorityLow
+	}
+	return PriorityNormal
+}
+
+type priorityKey struct{}
+
+// WithPriority sends the requests made with ctx at priority
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+type waiter struct {
+	priority Priority
+	seq      int64
+	weight   int
+	order    bool
+	index    int
+}
+
+type waiters []*waiter
+
+func (w waiters) Len() int { return len(w) }
+func (w waiters) Less(a, b int) bool {
+	if w[a].priority != w[b].priority {
+		return w[a].priority < w[b].priority
+	}
+	return w[a].seq < w[b].seq
+}
+func (w waiters) Swap(a, b int) {
+	w[a], w[b] = w[b], w[a]
+	w[a].index, w[b].index = a, b
+}
+func (w *waiters) Push(x any) {
+	item := x.(*waiter)
+	item.index = len(*w)
+	*w = append(*w, item)
+}
+func (w *waiters) Pop() any {
+	old := *w
+	item := old[len(old)-1]
+	*w = old[:len(old)-1]
+	return item
+}
+
+// RateLimiter queues the requests to the exchange so that they stay within
+// its weight and order limits. It follows the usage the exchange reports in
+// the response headers and stops every request after a 429 or 418 until
+// the exchange allows them again
+type RateLimiter struct {
+	limits    Limits
+	transport http.RoundTripper
+	lock      sync.Mutex
+	cond      *sync.Cond
+	queue     waiters
+	seq       int64
+	wake      *time.Timer
+	wakeAt    time.Time
+
+	minute    time.Time
+	weight    int
+	tenSecs   time.Time
+	orders    int
+	day       time.Time
+	ordersDay int
+	retryAt   time.Time
+	backoff   time.Duration
+	warned    bool
+
+	requests  int64
+	throttled int64
+	banned    int64
+}
+
+func NewRateLimiter(limits Limits) *RateLimiter {
+	l := &RateLimiter{limits: limits, transport: http.DefaultTransport}
+	l.cond = sync.NewCond(&l.lock)
+	return l
+}
+
+// UseTransport sends the requests with transport instead of the default one
+func (l *RateLimiter) UseTransport(transport http.RoundTripper) *RateLimiter {
+	l.transport = transport
+	return l
+}
+
+// roll starts new windows once their time is over
+func (l *RateLimiter) roll(now time.Time) {
+	if minute := now.Truncate(time.Minute); minute.After(l.minute) {
+		l.minute, l.weight, l.warned = minute, 0, false
+	}
+	if tenSecs := now.Truncate(10 * time.Second); tenSecs.After(l.tenSecs) {
+		l.tenSecs, l.orders = tenSecs, 0
+	}
+	if day := now.UTC().Truncate(24 * time.Hour); day.After(l.day) {
+		l.day, l.ordersDay = day, 0
+	}
+}
+
+// when reports when w may be sent, the zero time when it may be sent now
+func (l *RateLimiter) when(w *waiter, now time.Time) time.Time {
+	if now.Before(l.retryAt) {
+		return l.retryAt
+	}
+	l.roll(now)
+	limit := l.limits.WeightPerMinute
+	if w.priority != PriorityOrder {
+		limit = int(float64(limit) * (1 - l.limits.Reserve))
+	}
+	// a request heavier than the budget still goes once the window is empty
+	if l.weight > 0 && l.weight+w.weight > limit {
+		return l.minute.Add(time.Minute)
+	}
+	if w.order {
+		if l.orders >= l.limits.OrdersPerTenSecs {
+			return l.tenSecs.Add(10 * time.Second)
+		}
+		if l.ordersDay >= l.limits.OrdersPerDay {
+			return l.day.Add(24 * time.Hour)
+		}
+	}
+	return time.Time{}
+}
+
+// wakeUp wakes the waiting requests at the time given
+func (l *RateLimiter) wakeUp(at time.Time) {
+	if l.wake != nil && !l.wakeAt.After(at) {
+		return
+	}
+	if l.wake != nil {
+		l.wake.Stop()
+	}
+	l.wakeAt = at
+	l.wake = time.AfterFunc(at.Sub(utils.Now()), func() {
+		l.lock.Lock()
+		l.wake = nil
+		l.cond.Broadcast()
+		l.lock.Unlock()
+	})
+}
+
+// acquire waits until the request may be sent and reserves its weight
+func (l *RateLimiter) acquire(ctx context.Context, w *waiter) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.seq++
+	w.seq = l.seq
+	heap.Push(&l.queue, w)
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			l.lock.Lock()
+			l.cond.Broadcast()
+			l.lock.Unlock()
+		case <-done:
+		}
+	}()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			heap.Remove(&l.queue, w.index)
+			l.cond.Broadcast()
+			return err
+		}
+		if l.queue[0] == w {
+			at := l.when(w, utils.Now())
+			if at.IsZero() {
+				break
+			}
+			l.wakeUp(at)
+		}
+		l.cond.Wait()
+	}
+
+	heap.Pop(&l.queue)
+	l.weight += w.weight
+	if w.order {
+		l.orders++
+		l.ordersDay++
+	}
+	l.requests++
+	if !l.warned && l.weight >= l.limits.WeightPerMinute*8/10 {
+		l.warned = true
+		utils.LogWarn(fmt.Sprintf("<Rate Limit>: used %d of %d request weight this minute", l.weight, l.limits.WeightPerMinute))
+	}
+	l.cond.Broadcast()
+	return nil
+}
+
+func headerInt(response *http.Response, name string) (int, bool) {
+	value, err := strconv.Atoi(response.Header.Get(name))
+	return value, err == nil
+}
+
+// record follows the usage reported by the exchange and backs off when it refuses requests
+func (l *RateLimiter) record(response *http.Response) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.roll(utils.Now())
+
+	if weight, ok := headerInt(response, "X-Mbx-Used-Weight-1m"); ok && weight > l.weight {
+		l.weight = weight
+	}
+	if orders, ok := headerInt(response, "X-Mbx-Order-Count-10s"); ok && orders > l.orders {
+		l.orders = orders
+	}
+	if orders, ok := headerInt(response, "X-Mbx-Order-Count-1d"); ok && orders > l.ordersDay {
+		l.ordersDay = orders
+	}
+
+	switch response.StatusCode {
+	case http.StatusTooManyRequests, http.StatusTeapot:
+		if response.StatusCode == http.StatusTeapot {
+			l.banned++
+		} else {
+			l.throttled++
+		}
+		// back off twice as long every time the exchange refuses in a row
+		if l.backoff == 0 {
+			l.backoff = time.Second
+		} else if l.backoff < 2*time.Minute {
+			l.backoff *= 2
+		}
+		wait := l.backoff
+		if seconds, ok := headerInt(response, "Retry-After"); ok {
+			wait = time.Duration(seconds) * time.Second
+		}
+		l.retryAt = utils.Now().Add(wait)
+		utils.LogWarn(fmt.Sprintf("<Rate Limit>: exchange answered %d, every request waits %s", response.StatusCode, wait))
+	default:
+		l.backoff = 0
+	}
+	l.cond.Broadcast()
+}
+
+func (l *RateLimiter) RoundTrip(request *http.Request) (*http.Response, error) {
+	w := &waiter{priority: requestPriority(request), weight: requestWeight(request), order: isOrder(request)}
+	if !strings.HasPrefix(request.URL.Path, "/api/") {
+		// sapi endpoints have their own limits
+		w.weight = 0
+	}
+	if err := l.acquire(request.Context(), w); err != nil {
+		return nil, err
+	}
+	response, err := l.transport.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+	l.record(response)
+	return response, nil
+}
+
+type LimiterMetrics struct {
+	UsedWeight       int       `json:"usedWeight"`
+	WeightLimit      int       `json:"weightLimit"`
+	WeightPercent    float64   `json:"weightPercent"`
+	OrdersTenSecs    int       `json:"ordersTenSecs"`
+	OrderLimitTenSec int       `json:"orderLimitTenSecs"`
+	OrdersDay        int       `json:"ordersDay"`
+	OrderLimitDay    int       `json:"orderLimitDay"`
+	Queued           int       `json:"queued"`
+	QueuedOrders     int       `json:"queuedOrders"`
+	Requests         int64     `json:"requests"`
+	Throttled        int64     `json:"throttled"`
+	Banned           int64     `json:"banned"`
+	RetryAt          time.Time `json:"retryAt,omitempty"`
+}
+
+// Metrics shows how close the requests are to the limits of the exchange
+func (l *RateLimiter) Metrics() LimiterMetrics {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.roll(utils.Now())
+	m := LimiterMetrics{
+		UsedWeight:       l.weight,
+		WeightLimit:      l.limits.WeightPerMinute,
+		OrdersTenSecs:    l.orders,
+		OrderLimitTenSec: l.limits.OrdersPerTenSecs,
+		OrdersDay:        l.ordersDay,
+		OrderLimitDay:    l.limits.OrdersPerDay,
+		Queued:           len(l.queue),
+		Requests:         l.requests,
+		Throttled:        l.throttled,
+		Banned:           l.banned,
+	}
+	if l.limits.WeightPerMinute > 0 {
+		m.WeightPercent = float64(l.weight) / float64(l.limits.WeightPerMinute) * 100
+	}
+	for _, w := range l.queue {
+		if w.priority == PriorityOrder {
+			m.QueuedOrders++
+		}
+	}
+	if utils.Now().Before(l.retryAt) {
+		m.RetryAt = l.retryAt
+	}
+	return m
+}
+
+// Limiter is shared by every request to the exchange
+var Limiter = NewRateLimiter(DefaultLimits)
+
+var httpClient = &http.Client{Transport: Limiter}
+
+// HTTPClient sends requests through Limiter
+func HTTPClient() *http.Client {
+	return httpClient
+}
@@ -0,0 +1,164 @@
+package binance
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+	"trading/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTransport answers every request with status and headers and remembers the paths
+type fakeTransport struct {
+	lock    sync.Mutex
+	status  int
+	headers map[string]string
+	paths   []string
+}
+
+func (t *fakeTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.paths = append(t.paths, request.URL.Path)
+	response := &http.Response{StatusCode: t.status, Header: http.Header{}, Body: http.NoBody, Request: request}
+	for name, value := range t.headers {
+		response.Header.Set(name, value)
+	}
+	return response, nil
+}
+
+func send(t *testing.T, client *http.Client, method, url string) {
+	request, _ := http.NewRequest(method, url, nil)
+	response, err := client.Do(request)
+	assert.Nil(t, err)
+	if response != nil {
+		response.Body.Close()
+	}
+}
+
+func useFixedClock(now time.Time) func() {
+	utils.UseClock(func() time.Time { return now })
+	return func() { utils.UseClock(nil) }
+}
+
+func TestRequestWeight(t *testing.T) {
+	request, _ := http.NewRequest(http.MethodGet, "https://api.binance.com/api/v3/ticker/24hr", nil)
+	assert.Equal(t, 80, requestWeight(request))
+	assert.Equal(t, PriorityLow, requestPriority(request))
+
+	request, _ = http.NewRequest(http.MethodPost, "https://api.binance.com/api/v3/order/oco", nil)
+	assert.True(t, isOrder(request))
+	assert.Equal(t, PriorityOrder, requestPriority(request))
+
+	request, _ = http.NewRequest(http.MethodGet, "https://api.binance.com/api/v3/order?symbol=BTCUSDT", nil)
+	assert.False(t, isOrder(request))
+	assert.Equal(t, 4, requestWeight(request))
+
+	request, _ = http.NewRequest(http.MethodGet, "https://api.binance.com/api/v3/depth?symbol=BTCUSDT&limit=1000", nil)
+	assert.Equal(t, 50, requestWeight(request))
+
+	request = request.WithContext(WithPriority(context.Background(), PriorityLow))
+	assert.Equal(t, PriorityLow, requestPriority(request))
+}
+
+func TestLimiterHeaders(t *testing.T) {
+	defer useFixedClock(time.Date(2022, 1, 1, 10, 0, 5, 0, time.UTC))()
+	transport := &fakeTransport{status: http.StatusOK, headers: map[string]string{
+		"X-Mbx-Used-Weight-1m":  "4800",
+		"X-Mbx-Order-Count-10s": "3",
+		"X-Mbx-Order-Count-1d":  "120",
+	}}
+	limiter := NewRateLimiter(DefaultLimits).UseTransport(transport)
+	send(t, &http.Client{Transport: limiter}, http.MethodPost, "https://api.binance.com/api/v3/order")
+
+	metrics := limiter.Metrics()
+	assert.Equal(t, 4800, metrics.UsedWeight)
+	assert.Equal(t, 80.0, metrics.WeightPercent)
+	assert.Equal(t, 3, metrics.OrdersTenSecs)
+	assert.Equal(t, 120, metrics.OrdersDay)
+	assert.Equal(t, int64(1), metrics.Requests)
+
+	utils.UseClock(func() time.Time { return time.Date(2022, 1, 1, 10, 1, 0, 0, time.UTC) })
+	metrics = limiter.Metrics()
+	assert.Equal(t, 0, metrics.UsedWeight, "a new minute starts a new window")
+	assert.Equal(t, 0, metrics.OrdersTenSecs)
+	assert.Equal(t, 120, metrics.OrdersDay)
+}
+
+func TestLimiterBackoff(t *testing.T) {
+	now := time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC)
+	defer useFixedClock(now)()
+	transport := &fakeTransport{status: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "30"}}
+	limiter := NewRateLimiter(DefaultLimits).UseTransport(transport)
+	client := &http.Client{Transport: limiter}
+
+	send(t, client, http.MethodGet, "https://api.binance.com/api/v3/klines")
+	metrics := limiter.Metrics()
+	assert.Equal(t, int64(1), metrics.Throttled)
+	assert.Equal(t, now.Add(30*time.Second), metrics.RetryAt, "the exchange tells how long to wait")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	request, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.binance.com/api/v3/klines", nil)
+	_, err := client.Do(request)
+	assert.NotNil(t, err, "every request waits for the exchange")
+	assert.Equal(t, 0, limiter.Metrics().Queued)
+
+	// a ban without Retry-After doubles the last backoff
+	now = now.Add(time.Minute)
+	utils.UseClock(func() time.Time { return now })
+	transport.status, transport.headers = http.StatusTeapot, nil
+	send(t, client, http.MethodGet, "https://api.binance.com/api/v3/klines")
+	metrics = limiter.Metrics()
+	assert.Equal(t, int64(1), metrics.Banned)
+	assert.Equal(t, now.Add(2*time.Second), metrics.RetryAt)
+
+	now = now.Add(time.Minute)
+	transport.status = http.StatusOK
+	send(t, client, http.MethodGet, "https://api.binance.com/api/v3/klines")
+	assert.True(t, limiter.Metrics().RetryAt.IsZero())
+	assert.Equal(t, time.Duration(0), limiter.backoff, "a successful request resets the backoff")
+}
+
+func TestLimiterPriority(t *testing.T) {
+	now := time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC)
+	var clockLock sync.Mutex
+	utils.UseClock(func() time.Time {
+		clockLock.Lock()
+		defer clockLock.Unlock()
+		return now
+	})
+	defer utils.UseClock(nil)
+
+	transport := &fakeTransport{status: http.StatusOK}
+	limiter := NewRateLimiter(DefaultLimits).UseTransport(transport)
+	client := &http.Client{Transport: limiter}
+	limiter.retryAt = now.Add(time.Hour)
+
+	var wg sync.WaitGroup
+	paths := []string{"/api/v3/exchangeInfo", "/api/v3/klines", "/api/v3/ticker/price", "/api/v3/order"}
+	for i, path := range paths {
+		wg.Add(1)
+		go func(path string) {
+			defer wg.Done()
+			send(t, client, http.MethodPost, "https://api.binance.com"+path)
+		}(path)
+		// queue them in order so that only their priority reorders them
+		assert.Eventually(t, func() bool { return limiter.Metrics().Queued == i+1 }, time.Second, time.Millisecond)
+	}
+	assert.Equal(t, 1, limiter.Metrics().QueuedOrders)
+
+	clockLock.Lock()
+	now = now.Add(2 * time.Hour)
+	clockLock.Unlock()
+	limiter.lock.Lock()
+	limiter.cond.Broadcast()
+	limiter.lock.Unlock()
+	wg.Wait()
+
+	assert.Equal(t, []string{"/api/v3/order", "/api/v3/ticker/price", "/api/v3/klines", "/api/v3/exchangeInfo"}, transport.paths)
+	assert.Equal(t, 1+4+2+20, limiter.Metrics().UsedWeight)
+}
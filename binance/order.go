@@ -71,3 +71,61 @@ func CreateBuyMarketOrder(symbol string, quantity float64) (*binance.CreateOrder
 func CreateSellMarketOrder(symbol string, quantity float64) (*binance.CreateOrderResponse, error) {
 	return CreateOrder(symbol, quantity, "SELL", binance.OrderTypeMarket)
 }
+
+// OrderTypeOCO places a limit order and a stop loss limit order where
+// the fill of one cancels the other
+const OrderTypeOCO binance.OrderType = "OCO"
+
+// OrderRequest is an order that is not filled at the market price
+type OrderRequest struct {
+	Symbol         string
+	Side           string
+	Type           binance.OrderType
+	TimeInForce    binance.TimeInForceType
+	Quantity       float64
+	Price          float64
+	StopPrice      float64
+	StopLimitPrice float64
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// CreateLimitOrder places a LIMIT, STOP_LOSS_LIMIT or TAKE_PROFIT_LIMIT order
+func CreateLimitOrder(request OrderRequest) (*binance.CreateOrderResponse, error) {
+	service := GetClient().
+		NewCreateOrderService().
+		Side(binance.SideType(request.Side)).
+		Symbol(request.Symbol).
+		Quantity(formatFloat(request.Quantity)).
+		Type(request.Type).
+		TimeInForce(request.TimeInForce).
+		Price(formatFloat(request.Price))
+	if request.StopPrice > 0 {
+		service.StopPrice(formatFloat(request.StopPrice))
+	}
+	return service.Do(context.Background())
+}
+
+func CreateOCOOrder(request OrderRequest) (*binance.CreateOCOResponse, error) {
+	return GetClient().
+		NewCreateOCOService().
+		Side(binance.SideType(request.Side)).
+		Symbol(request.Symbol).
+		Quantity(formatFloat(request.Quantity)).
+		Price(formatFloat(request.Price)).
+		StopPrice(formatFloat(request.StopPrice)).
+		StopLimitPrice(formatFloat(request.StopLimitPrice)).
+		StopLimitTimeInForce(request.TimeInForce).
+		Do(context.Background())
+}
+
+func GetOrder(symbol string, orderId int64) (Order, error) {
+	return GetClient().NewGetOrderService().Symbol(symbol).OrderID(orderId).Do(context.Background())
+}
+
+func CancelOrder(symbol string, orderId int64) error {
+	_, err := GetClient().NewCancelOrderService().Symbol(symbol).OrderID(orderId).Do(context.Background())
+	return err
+}
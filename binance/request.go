@@ -2,9 +2,9 @@ package binance
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 
-	// "fmt"
 	"os"
 
 	// "strconv"
@@ -33,20 +33,34 @@ func GetPriceLatest(symbol string) float64 {
 	// if utils.Env().IsMock() {
 	// 	return utils.Env().RandomNumber()
 	// }
-	price, error := GetClient().NewListPricesService().Symbol(symbol).Do(context.Background())
+	price, error := PriceLatest(symbol)
 	if error != nil {
 		utils.LogError(error, "Get Price Latest %s")
 		return 0
 	}
+	return price
+}
+
+// PriceLatest is GetPriceLatest returning the error instead of logging it
+func PriceLatest(symbol string) (float64, error) {
+	price, err := GetClient().NewListPricesService().Symbol(symbol).Do(context.Background())
+	if err != nil {
+		return 0, err
+	}
+	if len(price) == 0 {
+		return 0, fmt.Errorf("no price for %s", symbol)
+	}
 	f, _ := strconv.ParseFloat(price[0].Price, 64)
-	return f
+	return f, nil
 }
 
 func GetClient() *binance.Client {
 	var secret = os.Getenv("API_SECRET")
 	var key = os.Getenv("API_KEY")
 	binance.UseTestnet = !utils.Env().IsProd()
-	return binance.NewClient(key, secret)
+	client := binance.NewClient(key, secret)
+	client.HTTPClient = HTTPClient()
+	return client
 }
 
 func RequestChannel(chan int) {
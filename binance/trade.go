@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"trading/utils"
-	"net/http"
 	"sort"
 	"github.com/adshao/go-binance/v2"
 )
@@ -39,7 +38,7 @@ type PriceChangeStats struct {
 }
 
 func GetSymbolStats() []PriceChangeStats {
-	req, err := http.Get("https://api.binance.com/api/v3/ticker/24hr")
+	req, err := HTTPClient().Get("https://api.binance.com/api/v3/ticker/24hr")
 
 	if err != nil {
 		utils.LogError(err, "<GetSymbolStats> request error")
@@ -0,0 +1,638 @@
+// Package config describes a whole trading setup in a JSON or YAML file so a
+// different setup can be started without editing main.go
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"trading/names"
+	"trading/notify"
+	"trading/trade/allocator"
+	"trading/trade/arbitrage"
+	"trading/trade/graph"
+	"trading/trade/locker"
+	"trading/trade/screener"
+	"trading/trade/traders"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	TraderLimit           = "limit"
+	TraderAuto            = "auto"
+	TraderBestSide        = "bestside"
+	TraderStableBestSide  = "stablebestside"
+	TraderAutoStable      = "autostable"
+	TraderAutoStableSplit = "autostablesplit"
+	TraderAutoStableHigh  = "autostablehigh"
+	TraderGrid            = "grid"
+	TraderDCA             = "dca"
+	TraderPairs           = "pairs"
+
+	LockPeakHigh     = "peakhigh"
+	LockImmediateDue = "immediatedue"
+	LockTrailing     = "trailing"
+
+	AllocationEqual      = "equal"
+	AllocationVolatility = "volatility"
+	AllocationFixed      = "fixed"
+	AllocationKelly      = "kelly"
+)
+
+type DeviationConfig struct {
+	Delta    float64 `json:"delta" yaml:"delta"`
+	FlipSide bool    `json:"flipSide" yaml:"flipSide"`
+}
+
+type SideConfig struct {
+	StopLimit  float64         `json:"stopLimit" yaml:"stopLimit"`
+	LimitType  string          `json:"limitType" yaml:"limitType"`
+	Quantity   float64         `json:"quantity" yaml:"quantity"`
+	MustProfit bool            `json:"mustProfit" yaml:"mustProfit"`
+	LockDelta  float64         `json:"lockDelta" yaml:"lockDelta"`
+	Deviation  DeviationConfig `json:"deviation" yaml:"deviation"`
+	// market order when not set
+	Order    names.OrderConfig `json:"order" yaml:"order"`
+	StopLoss names.StopLoss    `json:"stopLoss" yaml:"stopLoss"`
+	// percent a market order may fill away from the price, not limited when zero
+	MaxSlippage float64 `json:"maxSlippage" yaml:"maxSlippage"`
+}
+
+type TradeConfig struct {
+	Id        string     `json:"id" yaml:"id"`
+	Symbol    string     `json:"symbol" yaml:"symbol"`
+	Side      string     `json:"side" yaml:"side"`
+	IsCyclick bool       `json:"cyclic" yaml:"cyclic"`
+	Buy       SideConfig `json:"buy" yaml:"buy"`
+	Sell      SideConfig `json:"sell" yaml:"sell"`
+}
+
+// Strategy is a single trade manager, the trader decides which of
+// configs, stable, grid, dca or pairs is used
+type Strategy struct {
+	Name         string                    `json:"name" yaml:"name"`
+	Trader       string                    `json:"trader" yaml:"trader"`
+	LockCreator  string                    `json:"lockCreator" yaml:"lockCreator"`
+	PrioritySide string                    `json:"prioritySide" yaml:"prioritySide"`
+	Configs      []TradeConfig             `json:"configs" yaml:"configs"`
+	Stable       *traders.StableTradeParam `json:"stable" yaml:"stable"`
+	// ladder of the grid trader
+	Grid *traders.GridTradeParam `json:"grid" yaml:"grid"`
+	// orders of the dca trader
+	DCA *traders.DCATradeParam `json:"dca" yaml:"dca"`
+	// symbols and thresholds of the pairs trader
+	Pairs *traders.PairsTradeParam `json:"pairs" yaml:"pairs"`
+	// graph settings of the auto and bestside traders
+	Interval   string `json:"interval" yaml:"interval"`
+	Datapoints int    `json:"datapoints" yaml:"datapoints"`
+	// side bestside and stablebestside traders start in
+	BestSide string `json:"bestSide" yaml:"bestSide"`
+	// file the strategy state is saved to, when it exists the strategy is
+	// resumed from it instead of starting from configs or stable
+	Snapshot string `json:"snapshot" yaml:"snapshot"`
+	// percent of what the strategy invested its profit may fall from its
+	// peak before it stops buying, with drawdownExit every position is also sold
+	MaxDrawdown  float64 `json:"maxDrawdown" yaml:"maxDrawdown"`
+	DrawdownExit bool    `json:"drawdownExit" yaml:"drawdownExit"`
+	// options of the trailing lock creator, its defaults when not set
+	Trailing *TrailingConfig `json:"trailing" yaml:"trailing"`
+}
+
+// TrailingConfig sets how far trailing locks follow the best price, values
+// that are not set keep their default
+type TrailingConfig struct {
+	// atr or stddev
+	Source string `json:"source" yaml:"source"`
+	// candles of the atr or prices of the standard deviation
+	Period   int    `json:"period" yaml:"period"`
+	Interval string `json:"interval" yaml:"interval"`
+	// distance of the trail in volatilities, it tightens to minMultiplier as
+	// the gains grow to tightenPercent
+	Multiplier     float64 `json:"multiplier" yaml:"multiplier"`
+	MinMultiplier  float64 `json:"minMultiplier" yaml:"minMultiplier"`
+	TightenPercent float64 `json:"tightenPercent" yaml:"tightenPercent"`
+	// percent gain after which the trail is kept at or beyond the pretrade price
+	BreakevenPercent float64 `json:"breakevenPercent" yaml:"breakevenPercent"`
+}
+
+// ScreenerConfig is a named screener stable strategies pick their assets
+// with, filters that are not set do not filter
+type ScreenerConfig struct {
+	Name           string   `json:"name" yaml:"name"`
+	QuoteAsset     string   `json:"quoteAsset" yaml:"quoteAsset"`
+	MinPriceChange float64  `json:"minPriceChange" yaml:"minPriceChange"`
+	MaxPriceChange float64  `json:"maxPriceChange" yaml:"maxPriceChange"`
+	MinQuoteVolume float64  `json:"minQuoteVolume" yaml:"minQuoteVolume"`
+	MinTrades      int64    `json:"minTrades" yaml:"minTrades"`
+	MaxSpread      float64  `json:"maxSpread" yaml:"maxSpread"`
+	MinVolatility  float64  `json:"minVolatility" yaml:"minVolatility"`
+	MaxVolatility  float64  `json:"maxVolatility" yaml:"maxVolatility"`
+	MinRSI         float64  `json:"minRsi" yaml:"minRsi"`
+	MaxRSI         float64  `json:"maxRsi" yaml:"maxRsi"`
+	Trends         []string `json:"trends" yaml:"trends"`
+	Blacklist      []string `json:"blacklist" yaml:"blacklist"`
+	Whitelist      []string `json:"whitelist" yaml:"whitelist"`
+	// change, volume, trades, volatility, oversold or liquidity
+	Score string `json:"score" yaml:"score"`
+	// number of symbols kept, every symbol when zero
+	Limit int `json:"limit" yaml:"limit"`
+	// candles of the rsi and trend filters
+	Interval   string `json:"interval" yaml:"interval"`
+	Datapoints int    `json:"datapoints" yaml:"datapoints"`
+}
+
+// AllocationConfig shares the quote assets between the configs of every
+// strategy, buys without a fixed quantity spend the part the rule gives them
+type AllocationConfig struct {
+	// equal, volatility, fixed or kelly
+	Rule string `json:"rule" yaml:"rule"`
+	// percent of the capital every config gets with the fixed rule
+	Fraction float64 `json:"fraction" yaml:"fraction"`
+	// scales the kelly fraction down, 0.5 for half kelly
+	KellyMultiplier float64 `json:"kellyMultiplier" yaml:"kellyMultiplier"`
+	// percent of the capital a single config may get with the kelly rule
+	MaxFraction float64 `json:"maxFraction" yaml:"maxFraction"`
+	// smallest amount of the quote asset worth reserving
+	Minimum float64 `json:"minimum" yaml:"minimum"`
+}
+
+// NotifierConfig is a notifier and the events it is sent, every event when
+// none is set. Values like ${TELEGRAM_TOKEN} are read from the env
+type NotifierConfig struct {
+	// webhook, telegram, email or desktop
+	Type   string   `json:"type" yaml:"type"`
+	Events []string `json:"events" yaml:"events"`
+	// the webhook url or the bot api of telegram, api.telegram.org when empty
+	URL    string `json:"url" yaml:"url"`
+	Token  string `json:"token" yaml:"token"`
+	ChatId string `json:"chatId" yaml:"chatId"`
+	// host:port of the smtp server
+	SMTP     string   `json:"smtp" yaml:"smtp"`
+	Username string   `json:"username" yaml:"username"`
+	Password string   `json:"password" yaml:"password"`
+	From     string   `json:"from" yaml:"from"`
+	To       []string `json:"to" yaml:"to"`
+}
+
+type NotificationConfig struct {
+	Notifiers []NotifierConfig `json:"notifiers" yaml:"notifiers"`
+	// a stall is notified when no price ticks for this many minutes, zero does not watch
+	StallMinutes float64 `json:"stallMinutes" yaml:"stallMinutes"`
+	// minutes an event with the same title is not sent again
+	CooldownMinutes float64 `json:"cooldownMinutes" yaml:"cooldownMinutes"`
+}
+
+// ArbitrageConfig watches the triangular cycles of the spot pairs, the
+// opportunities are only logged unless execute is set
+type ArbitrageConfig struct {
+	// assets the cycles start and end in, USDT when none is set
+	Assets []string `json:"assets" yaml:"assets"`
+	// amount of the start asset a cycle trades
+	Amount float64 `json:"amount" yaml:"amount"`
+	// percent a cycle must gain after the fees of its three legs
+	MinProfit float64 `json:"minProfit" yaml:"minProfit"`
+	Execute   bool    `json:"execute" yaml:"execute"`
+	// percent a leg may fill away from its quote, not limited when zero
+	MaxSlippage float64 `json:"maxSlippage" yaml:"maxSlippage"`
+	// seconds the same cycle is not reported again, a minute when zero
+	CooldownSeconds float64 `json:"cooldownSeconds" yaml:"cooldownSeconds"`
+}
+
+type Config struct {
+	Allocation    *AllocationConfig   `json:"allocation" yaml:"allocation"`
+	Notifications *NotificationConfig `json:"notifications" yaml:"notifications"`
+	Arbitrage     *ArbitrageConfig    `json:"arbitrage" yaml:"arbitrage"`
+	Screeners     []ScreenerConfig    `json:"screeners" yaml:"screeners"`
+	Strategies    []Strategy          `json:"strategies" yaml:"strategies"`
+}
+
+// Load reads a config file, the format is picked from the extension
+// (.json, .yaml or .yml) and the config is validated before it is returned
+func Load(filename string) (Config, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return Config{}, err
+	}
+	return Parse(content, filepath.Ext(filename))
+}
+
+// Decode decodes content of the format given by extension into v, a key
+// that is not a field of v is an error so a misspelled key is not ignored
+func Decode(content []byte, extension string, v any) error {
+	switch strings.ToLower(extension) {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+		return decoder.Decode(v)
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(v); err != nil && err != io.EOF {
+			return err
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported format %q, use .json, .yaml or .yml", extension)
+}
+
+// Parse decodes the content of a config file of the format given by extension
+func Parse(content []byte, extension string) (Config, error) {
+	var config Config
+	switch strings.ToLower(extension) {
+	case ".json", ".yaml", ".yml":
+	default:
+		return Config{}, fmt.Errorf("unsupported config format %q, use .json, .yaml or .yml", extension)
+	}
+	if err := Decode(content, extension, &config); err != nil {
+		return Config{}, fmt.Errorf("could not read config: %w", err)
+	}
+
+	config.applyDefaults()
+	if err := config.Validate(); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// Save writes the config to a file Load reads back, the format is picked
+// from the extension like Load
+func (c Config) Save(filename string) error {
+	var content []byte
+	var err error
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		content, err = json.MarshalIndent(c, "", "  ")
+	case ".yaml", ".yml":
+		var buffer bytes.Buffer
+		encoder := yaml.NewEncoder(&buffer)
+		encoder.SetIndent(2)
+		err = encoder.Encode(c)
+		content = buffer.Bytes()
+	default:
+		return fmt.Errorf("unsupported config format %q, use .json, .yaml or .yml", filepath.Ext(filename))
+	}
+	if err != nil {
+		return fmt.Errorf("could not write config: %w", err)
+	}
+	return ioutil.WriteFile(filename, content, 0644)
+}
+
+func (c *Config) applyDefaults() {
+	if c.Arbitrage != nil {
+		c.Arbitrage.Assets = upper(c.Arbitrage.Assets)
+		if len(c.Arbitrage.Assets) == 0 {
+			c.Arbitrage.Assets = []string{"USDT"}
+		}
+	}
+	for i := range c.Screeners {
+		sc := &c.Screeners[i]
+		sc.QuoteAsset = strings.ToUpper(sc.QuoteAsset)
+		sc.Score = strings.ToLower(sc.Score)
+		sc.Blacklist = upper(sc.Blacklist)
+		sc.Whitelist = upper(sc.Whitelist)
+		if sc.Score == "" {
+			sc.Score = "change"
+		}
+		if sc.Interval == "" {
+			sc.Interval = "15m"
+		}
+		if sc.Datapoints == 0 {
+			sc.Datapoints = 30
+		}
+	}
+	for i := range c.Strategies {
+		s := &c.Strategies[i]
+		s.Trader = strings.ToLower(s.Trader)
+		s.LockCreator = strings.ToLower(s.LockCreator)
+		s.PrioritySide = strings.ToUpper(s.PrioritySide)
+		s.BestSide = strings.ToUpper(s.BestSide)
+
+		if s.Name == "" {
+			s.Name = fmt.Sprintf("%s-%d", s.Trader, i)
+		}
+		if s.LockCreator == "" {
+			s.LockCreator = LockPeakHigh
+		}
+		if s.PrioritySide == "" {
+			s.PrioritySide = names.TradeSideSell.String()
+		}
+		if s.BestSide == "" {
+			s.BestSide = names.TradeSideSell.String()
+		}
+		if s.Interval == "" {
+			s.Interval = "15m"
+		}
+		if s.Datapoints == 0 {
+			s.Datapoints = 12
+		}
+		if s.Stable != nil {
+			s.Stable.BestSide = names.TradeSide(strings.ToUpper(s.Stable.BestSide.String()))
+			s.Stable.Side = names.TradeSide(strings.ToUpper(s.Stable.Side.String()))
+			s.Stable.BuyOrder = normalizeOrder(s.Stable.BuyOrder)
+			s.Stable.SellOrder = normalizeOrder(s.Stable.SellOrder)
+			s.Stable.BuyStopLoss = normalizeStopLoss(s.Stable.BuyStopLoss)
+			s.Stable.SellStopLoss = normalizeStopLoss(s.Stable.SellStopLoss)
+			if s.Stable.Status == "" {
+				s.Stable.Status = traders.StatusContention
+			}
+		}
+
+		if s.Grid != nil {
+			s.Grid.Symbol = names.Symbol(strings.ToUpper(s.Grid.Symbol.String()))
+			s.Grid.Spacing = traders.GridSpacing(strings.ToLower(string(s.Grid.Spacing)))
+			if s.Grid.Spacing == "" {
+				s.Grid.Spacing = traders.GridArithmetic
+			}
+			s.Grid.BuyOrder = normalizeOrder(s.Grid.BuyOrder)
+			s.Grid.SellOrder = normalizeOrder(s.Grid.SellOrder)
+		}
+		if s.DCA != nil {
+			for j := range s.DCA.Symbols {
+				s.DCA.Symbols[j] = names.Symbol(strings.ToUpper(s.DCA.Symbols[j].String()))
+			}
+			caps := map[names.Symbol]float64{}
+			for symbol, cap := range s.DCA.MaxInvestments {
+				caps[names.Symbol(strings.ToUpper(symbol.String()))] = cap
+			}
+			s.DCA.MaxInvestments = caps
+			if s.DCA.VolumeScale == 0 {
+				s.DCA.VolumeScale = 1
+			}
+			if s.DCA.StepScale == 0 {
+				s.DCA.StepScale = 1
+			}
+			s.DCA.BuyOrder = normalizeOrder(s.DCA.BuyOrder)
+			s.DCA.SellOrder = normalizeOrder(s.DCA.SellOrder)
+		}
+		if s.Pairs != nil {
+			s.Pairs.First = names.Symbol(strings.ToUpper(s.Pairs.First.String()))
+			s.Pairs.Second = names.Symbol(strings.ToUpper(s.Pairs.Second.String()))
+			if s.Pairs.Interval == "" {
+				s.Pairs.Interval = "1h"
+			}
+			if s.Pairs.Lookback == 0 {
+				s.Pairs.Lookback = 100
+			}
+			if s.Pairs.EntryZ == 0 {
+				s.Pairs.EntryZ = 2
+			}
+			s.Pairs.BuyOrder = normalizeOrder(s.Pairs.BuyOrder)
+			s.Pairs.SellOrder = normalizeOrder(s.Pairs.SellOrder)
+		}
+
+		for j := range s.Configs {
+			s.Configs[j].normalize()
+		}
+	}
+}
+
+func (tc *TradeConfig) normalize() {
+	tc.Symbol = strings.ToUpper(tc.Symbol)
+	tc.Side = strings.ToUpper(tc.Side)
+	tc.Buy.LimitType = strings.ToUpper(tc.Buy.LimitType)
+	tc.Sell.LimitType = strings.ToUpper(tc.Sell.LimitType)
+	tc.Buy.Order = normalizeOrder(tc.Buy.Order)
+	tc.Sell.Order = normalizeOrder(tc.Sell.Order)
+	tc.Buy.StopLoss = normalizeStopLoss(tc.Buy.StopLoss)
+	tc.Sell.StopLoss = normalizeStopLoss(tc.Sell.StopLoss)
+}
+
+func upper(values []string) []string {
+	for i := range values {
+		values[i] = strings.ToUpper(values[i])
+	}
+	return values
+}
+
+func symbols(values []string) []names.Symbol {
+	list := []names.Symbol{}
+	for _, v := range values {
+		list = append(list, names.Symbol(v))
+	}
+	return list
+}
+
+// Screener builds the screener, filters on the ticker run before the
+// ones that need the graph of the symbol
+func (sc ScreenerConfig) Screener() *screener.Screener {
+	s := screener.New(sc.Name).
+		UseScore(screener.Scores[sc.Score]).
+		UseLimit(sc.Limit).
+		UseGraph(sc.Interval, sc.Datapoints)
+
+	if len(sc.Whitelist) > 0 {
+		s.UseFilters(screener.Whitelist(symbols(sc.Whitelist)...))
+	}
+	if len(sc.Blacklist) > 0 {
+		s.UseFilters(screener.Blacklist(symbols(sc.Blacklist)...))
+	}
+	if sc.QuoteAsset != "" {
+		s.UseFilters(screener.QuoteAsset(sc.QuoteAsset))
+	}
+	if sc.MinPriceChange != 0 || sc.MaxPriceChange != 0 {
+		max := sc.MaxPriceChange
+		if max == 0 {
+			max = math.Inf(1)
+		}
+		s.UseFilters(screener.PriceChange(sc.MinPriceChange, max))
+	}
+	if sc.MinQuoteVolume > 0 {
+		s.UseFilters(screener.MinQuoteVolume(sc.MinQuoteVolume))
+	}
+	if sc.MinTrades > 0 {
+		s.UseFilters(screener.MinTrades(sc.MinTrades))
+	}
+	if sc.MaxSpread > 0 {
+		s.UseFilters(screener.MaxSpread(sc.MaxSpread))
+	}
+	if sc.MinVolatility > 0 || sc.MaxVolatility > 0 {
+		s.UseFilters(screener.Volatility(sc.MinVolatility, sc.MaxVolatility))
+	}
+	if sc.MinRSI > 0 || sc.MaxRSI > 0 {
+		s.UseFilters(screener.RSI(sc.MinRSI, sc.MaxRSI))
+	}
+	if len(sc.Trends) > 0 {
+		trends := []graph.TrendType{}
+		for _, t := range sc.Trends {
+			trends = append(trends, graph.TrendType(t))
+		}
+		s.UseFilters(screener.Trend(trends...))
+	}
+	return s
+}
+
+// Allocator builds the allocator of the rule, equal weight when no rule is set
+func (ac AllocationConfig) Allocator() *allocator.Allocator {
+	var rule allocator.Rule
+	switch strings.ToLower(ac.Rule) {
+	case AllocationVolatility:
+		rule = allocator.VolatilityWeighted(nil)
+	case AllocationFixed:
+		rule = allocator.FixedFraction(ac.Fraction)
+	case AllocationKelly:
+		rule = allocator.Kelly(ac.KellyMultiplier, ac.MaxFraction, nil)
+	default:
+		rule = allocator.EqualWeight()
+	}
+	return allocator.New(rule).UseMinimum(ac.Minimum)
+}
+
+// Detector builds the arbitrage detector over every spot pair of info
+func (ac ArbitrageConfig) Detector(info names.SymbolInfo) *arbitrage.Detector {
+	d := arbitrage.NewDetector(arbitrage.NewGraph(info, ac.Assets...)).
+		UseAmount(ac.Amount).
+		UseMinProfit(ac.MinProfit).
+		UseExecute(ac.Execute).
+		UseMaxSlippage(ac.MaxSlippage)
+	if ac.CooldownSeconds > 0 {
+		d.UseCooldown(time.Duration(ac.CooldownSeconds * float64(time.Second)))
+	}
+	return d
+}
+
+func (tc TrailingConfig) Options() locker.TrailingOptions {
+	options := locker.DefaultTrailing
+	if tc.Source != "" {
+		options.Source = locker.VolatilitySource(strings.ToLower(tc.Source))
+	}
+	if tc.Period > 0 {
+		options.Period = tc.Period
+	}
+	if tc.Interval != "" {
+		options.Interval = tc.Interval
+	}
+	if tc.Multiplier > 0 {
+		options.Multiplier = tc.Multiplier
+	}
+	if tc.MinMultiplier > 0 {
+		options.MinMultiplier = tc.MinMultiplier
+	}
+	if tc.TightenPercent > 0 {
+		options.TightenPercent = tc.TightenPercent
+	}
+	if tc.BreakevenPercent > 0 {
+		options.BreakevenPercent = tc.BreakevenPercent
+	}
+	return options
+}
+
+const (
+	NotifierWebhook  = "webhook"
+	NotifierTelegram = "telegram"
+	NotifierEmail    = "email"
+	NotifierDesktop  = "desktop"
+)
+
+func (nc NotifierConfig) Notifier() notify.Notifier {
+	switch strings.ToLower(nc.Type) {
+	case NotifierWebhook:
+		return notify.Webhook(os.ExpandEnv(nc.URL))
+	case NotifierTelegram:
+		return notify.Telegram(os.ExpandEnv(nc.URL), os.ExpandEnv(nc.Token), os.ExpandEnv(nc.ChatId))
+	case NotifierEmail:
+		return notify.Email(os.ExpandEnv(nc.SMTP), os.ExpandEnv(nc.Username), os.ExpandEnv(nc.Password), nc.From, nc.To)
+	}
+	return notify.Desktop()
+}
+
+// Dispatcher sends the events of every notifier
+func (nc NotificationConfig) Dispatcher() *notify.Dispatcher {
+	d := notify.New().UseCooldown(time.Duration(nc.CooldownMinutes * float64(time.Minute)))
+	for _, n := range nc.Notifiers {
+		events := []notify.EventType{}
+		for _, e := range n.Events {
+			events = append(events, notify.EventType(e))
+		}
+		d.Add(n.Notifier(), events...)
+	}
+	return d
+}
+
+// stop losses are a percent of the entry price unless they are fixed
+func normalizeStopLoss(stopLoss names.StopLoss) names.StopLoss {
+	stopLoss.Type = names.StopLimit(strings.ToUpper(string(stopLoss.Type)))
+	if stopLoss.Type == "" {
+		stopLoss.Type = names.RatePercent
+	}
+	return stopLoss
+}
+
+func normalizeOrder(order names.OrderConfig) names.OrderConfig {
+	order.Type = names.OrderType(strings.ToUpper(string(order.Type)))
+	order.TimeInForce = names.TimeInForce(strings.ToUpper(string(order.TimeInForce)))
+	return order
+}
+
+// ParseTradeConfig decodes and validates a single json trade config
+func ParseTradeConfig(content []byte) (TradeConfig, error) {
+	var tc TradeConfig
+	if err := Decode(content, ".json", &tc); err != nil {
+		return TradeConfig{}, fmt.Errorf("could not read trade config: %w", err)
+	}
+	tc.normalize()
+	if err := tc.Validate(); err != nil {
+		return TradeConfig{}, err
+	}
+	return tc, nil
+}
+
+// TradeConfig converts a configured trade into the names.TradeConfig used by the traders
+func (tc TradeConfig) TradeConfig() names.TradeConfig {
+	return names.TradeConfig{
+		Id:        tc.Id,
+		Symbol:    names.Symbol(tc.Symbol),
+		Side:      names.TradeSide(tc.Side),
+		IsCyclick: tc.IsCyclick,
+		Buy:       tc.Buy.SideConfig(),
+		Sell:      tc.Sell.SideConfig(),
+	}
+}
+
+func (sc SideConfig) SideConfig() names.SideConfig {
+	return names.SideConfig{
+		StopLimit:  sc.StopLimit,
+		LimitType:  names.StopLimit(sc.LimitType),
+		Quantity:   sc.Quantity,
+		MustProfit: sc.MustProfit,
+		LockDelta:  sc.LockDelta,
+		DeviationSync: names.DeviationSync{
+			Delta:    sc.Deviation.Delta,
+			FlipSide: sc.Deviation.FlipSide,
+		},
+		Order:       sc.Order,
+		StopLoss:    sc.StopLoss,
+		MaxSlippage: sc.MaxSlippage,
+	}
+}
+
+func (s Strategy) TradeConfigs() []names.TradeConfig {
+	configs := []names.TradeConfig{}
+	for _, tc := range s.Configs {
+		configs = append(configs, tc.TradeConfig())
+	}
+	return names.NewIdTradeConfigs(configs...)
+}
+
+// the traders that can be resumed from a snapshot
+func (s Strategy) resumable() bool {
+	return s.Trader == TraderLimit || s.Trader == TraderAutoStable
+}
+
+// the traders built from a list of trade configs, the rest use a
+// StableTradeParam, a GridTradeParam, a DCATradeParam or a PairsTradeParam
+func (s Strategy) usesConfigs() bool {
+	switch s.Trader {
+	case TraderLimit, TraderAuto, TraderBestSide, TraderStableBestSide:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,508 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+	"trading/names"
+	"trading/trade/locker"
+	"trading/trade/traders"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseYaml(t *testing.T) {
+	content := `
+strategies:
+  - trader: Limit
+    lockCreator: immediateDue
+    configs:
+      - symbol: bnbusdt
+        side: buy
+        cyclic: true
+        buy: {limitType: percent, stopLimit: 2, lockDelta: 0.5, quantity: -1}
+        sell: {limitType: FIXED, stopLimit: 10, quantity: 1, deviation: {delta: 1, flipSide: true}}
+  - name: stable
+    trader: autostable
+    stable: {quoteAsset: USDT, buyStopLimit: 8, sellStopLimit: 4, bestSide: sell}
+`
+	config, err := Parse([]byte(content), ".yaml")
+	assert.Nil(t, err)
+	assert.Len(t, config.Strategies, 2)
+
+	limit := config.Strategies[0]
+	assert.Equal(t, "limit-0", limit.Name)
+	assert.Equal(t, LockImmediateDue, limit.LockCreator)
+	assert.Equal(t, "SELL", limit.PrioritySide)
+
+	tc := limit.TradeConfigs()[0]
+	assert.NotEmpty(t, tc.Id)
+	assert.Equal(t, names.Symbol("BNBUSDT"), tc.Symbol)
+	assert.Equal(t, names.TradeSideBuy, tc.Side)
+	assert.True(t, tc.IsCyclick)
+	assert.Equal(t, names.RatePercent, tc.Buy.LimitType)
+	assert.Equal(t, names.MAX_QUANTITY, tc.Buy.Quantity)
+	assert.Equal(t, names.RateFixed, tc.Sell.LimitType)
+	assert.Equal(t, names.DeviationSync{Delta: 1, FlipSide: true}, tc.Sell.DeviationSync)
+
+	stable := config.Strategies[1].Stable
+	assert.Equal(t, "USDT", stable.QuoteAsset)
+	assert.Equal(t, names.TradeSideSell, stable.BestSide)
+	assert.Equal(t, traders.StatusContention, stable.Status)
+}
+
+func TestParseJson(t *testing.T) {
+	content := `{"strategies": [{"trader": "auto", "interval": "1h", "datapoints": 8, "configs": [
+		{"symbol": "BTCUSDT", "buy": {"limitType": "PERCENT", "stopLimit": 1, "quantity": 10},
+		"sell": {"limitType": "PERCENT", "stopLimit": 1, "quantity": -1}}]}]}`
+	config, err := Parse([]byte(content), ".json")
+	assert.Nil(t, err)
+	assert.Equal(t, "1h", config.Strategies[0].Interval)
+	assert.Equal(t, 8, config.Strategies[0].Datapoints)
+}
+
+func TestUnknownKeys(t *testing.T) {
+	_, err := Parse([]byte(`
+strategies:
+  - trader: limit
+    configs:
+      - symbol: BTCUSDT
+        side: BUY
+        buy: {limitType: PERCENT, stopLimit: 1, lockdelta: 0.5, quantity: -1}
+        sell: {limitType: PERCENT, stopLimit: 1, quantity: -1}
+`), ".yaml")
+	assert.ErrorContains(t, err, "field lockdelta not found", "a misspelled key is not loaded as a zero value")
+
+	_, err = ParseTradeConfig([]byte(`{"symbol": "BTCUSDT", "side": "SELL", "sell": {"limitType": "PERCENT", "stopLimit": 1, "quantity": -1, "stopLos": {"value": 2}}}`))
+	assert.ErrorContains(t, err, `unknown field "stopLos"`)
+}
+
+func TestValidation(t *testing.T) {
+	content := `
+strategies:
+  - trader: limit
+    lockCreator: sometimes
+    configs:
+      - symbol: BTC-USDT
+        buy: {limitType: PERCENT, stopLimit: 120, lockDelta: 150, quantity: 1}
+        sell: {limitType: RANDOM, quantity: 0}
+  - trader: autostablehigh
+  - trader: unknown
+`
+	_, err := Parse([]byte(content), ".yml")
+	assert.NotNil(t, err)
+
+	validation, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Equal(t, []string{
+		`strategies[0].lockCreator: unknown lock creator "sometimes", expected peakhigh, immediatedue or trailing`,
+		`strategies[0].configs[0].symbol: invalid symbol "BTC-USDT"`,
+		`strategies[0].configs[0].side: is required`,
+		`strategies[0].configs[0].buy.stopLimit: buy percent must be below 100, got 120`,
+		`strategies[0].configs[0].buy.lockDelta: 150 is greater than stopLimit 120`,
+		`strategies[0].configs[0].sell.limitType: must be PERCENT or FIXED, got "RANDOM"`,
+		`strategies[0].configs[0].sell.stopLimit: must be greater than 0, got 0`,
+		`strategies[0].configs[0].sell.quantity: is required, use -1 for the whole balance`,
+		`strategies[1].stable: trader autostablehigh requires stable trade params`,
+		`strategies[2].trader: unknown trader "unknown", expected one of limit, auto, bestside, stablebestside, autostable, autostablesplit, autostablehigh, grid, dca, pairs`,
+	}, validation.Problems)
+}
+
+func TestUnsupportedFormat(t *testing.T) {
+	_, err := Parse([]byte(""), ".toml")
+	assert.NotNil(t, err)
+}
+
+func TestOrderConfig(t *testing.T) {
+	content := `
+strategies:
+  - trader: limit
+    configs:
+      - symbol: DIAUSDT
+        side: sell
+        buy: {limitType: PERCENT, stopLimit: 1, quantity: -1, order: {type: limit, timeInForce: ioc, limitOffset: 0.1}}
+        sell: {limitType: PERCENT, stopLimit: 1, quantity: -1, order: {type: oco, limitOffset: 2, stopOffset: 1, stopLimitOffset: 0.1, timeout: 60}}
+`
+	config, err := Parse([]byte(content), ".yaml")
+	assert.Nil(t, err)
+	tc := config.Strategies[0].TradeConfigs()[0]
+	assert.Equal(t, names.OrderConfig{Type: names.OrderTypeLimit, TimeInForce: names.TimeInForceIOC, LimitOffset: 0.1}, tc.Buy.Order)
+	assert.Equal(t, names.OrderTypeOCO, tc.Sell.Order.Type)
+	assert.Equal(t, 60, tc.Sell.Order.Timeout)
+
+	content = `
+strategies:
+  - trader: limit
+    configs:
+      - symbol: DIAUSDT
+        side: sell
+        buy: {limitType: PERCENT, stopLimit: 1, quantity: -1, order: {type: stop, timeInForce: day}}
+        sell: {limitType: PERCENT, stopLimit: 1, quantity: -1, order: {type: OCO, limitOffset: -1}}
+`
+	_, err = Parse([]byte(content), ".yaml")
+	validation, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Equal(t, []string{
+		`strategies[0].configs[0].buy.order.type: must be one of MARKET, LIMIT, STOP_LOSS_LIMIT, TAKE_PROFIT_LIMIT or OCO, got "STOP"`,
+		`strategies[0].configs[0].buy.order.timeInForce: must be GTC, IOC or FOK, got "DAY"`,
+		`strategies[0].configs[0].sell.order.limitOffset: must be a percent from 0 up to 100, got -1`,
+		`strategies[0].configs[0].sell.order.limitOffset: OCO orders need a limit price away from the market`,
+		`strategies[0].configs[0].sell.order.stopOffset: OCO orders need a stop price away from the market`,
+	}, validation.Problems)
+}
+
+func TestStopLossConfig(t *testing.T) {
+	content := `
+strategies:
+  - trader: limit
+    maxDrawdown: 8
+    drawdownExit: true
+    configs:
+      - symbol: DIAUSDT
+        side: sell
+        buy: {limitType: PERCENT, stopLimit: 1, quantity: -1, stopLoss: {value: 0.5, type: fixed}}
+        sell: {limitType: PERCENT, stopLimit: 1, quantity: -1, stopLoss: {value: 3}, maxSlippage: 0.4}
+`
+	config, err := Parse([]byte(content), ".yaml")
+	assert.Nil(t, err)
+	strategy := config.Strategies[0]
+	assert.EqualValues(t, 8, strategy.MaxDrawdown)
+	tc := strategy.TradeConfigs()[0]
+	assert.Equal(t, names.StopLoss{Value: 3, Type: names.RatePercent}, tc.Sell.StopLoss)
+	assert.Equal(t, names.StopLoss{Value: 0.5, Type: names.RateFixed}, tc.Buy.StopLoss)
+	assert.Equal(t, 0.4, tc.Sell.MaxSlippage)
+
+	content = `
+strategies:
+  - trader: limit
+    drawdownExit: true
+    configs:
+      - symbol: DIAUSDT
+        side: sell
+        buy: {limitType: PERCENT, stopLimit: 1, quantity: -1}
+        sell: {limitType: PERCENT, stopLimit: 1, quantity: -1, stopLoss: {value: 100}}
+`
+	_, err = Parse([]byte(content), ".yaml")
+	validation, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Equal(t, []string{
+		`strategies[0].drawdownExit: requires maxDrawdown`,
+		`strategies[0].configs[0].sell.stopLoss.value: sell percent must be below 100, got 100`,
+	}, validation.Problems)
+}
+
+func TestScreenerConfig(t *testing.T) {
+	content := `
+screeners:
+  - name: liquid
+    quoteAsset: usdt
+    minQuoteVolume: 1000000
+    trends: [Uptrend]
+    blacklist: [dogeusdt]
+strategies:
+  - trader: autostable
+    stable: {quoteAsset: USDT, buyStopLimit: 8, sellStopLimit: 4, screener: liquid}
+`
+	config, err := Parse([]byte(content), ".yaml")
+	assert.Nil(t, err)
+	sc := config.Screeners[0]
+	assert.Equal(t, "USDT", sc.QuoteAsset)
+	assert.Equal(t, []string{"DOGEUSDT"}, sc.Blacklist)
+	assert.Equal(t, "change", sc.Score, "ranked by the price change by default")
+	assert.Equal(t, "15m", sc.Interval)
+	assert.Equal(t, "liquid", sc.Screener().Name())
+
+	invalid := `
+screeners:
+  - name: broken
+    minRsi: 80
+    maxRsi: 20
+    trends: [Sideways]
+    score: luck
+strategies:
+  - trader: autostable
+    stable: {quoteAsset: USDT, buyStopLimit: 8, sellStopLimit: 4, screener: missing}
+`
+	_, err = Parse([]byte(invalid), ".yaml")
+	validation, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Equal(t, []string{
+		`screeners[0].minRsi: 80 is greater than maxRsi 20`,
+		`screeners[0].trends: unknown trend "Sideways", expected Uptrend, DownTrend, Dumping, Range or BreakOut`,
+		`screeners[0].score: unknown score "luck"`,
+		`strategies[0].stable.screener: unknown screener "missing"`,
+	}, validation.Problems)
+}
+
+func TestAllocationConfig(t *testing.T) {
+	content := `
+allocation: {rule: Kelly, kellyMultiplier: 0.5, maxFraction: 25, minimum: 15}
+strategies:
+  - trader: autostable
+    stable: {quoteAsset: USDT, buyStopLimit: 8, sellStopLimit: 4}
+`
+	config, err := Parse([]byte(content), ".yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, "kelly", config.Allocation.Allocator().Rule().Name())
+
+	invalid := `
+allocation: {rule: fixed, fraction: 120, minimum: -1}
+strategies:
+  - trader: autostable
+    stable: {quoteAsset: USDT, buyStopLimit: 8, sellStopLimit: 4}
+`
+	_, err = Parse([]byte(invalid), ".yaml")
+	validation, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Equal(t, []string{
+		`allocation.fraction: must be within 0 and 100 for the fixed rule, got 120`,
+		`allocation.minimum: can not be negative, got -1`,
+	}, validation.Problems)
+}
+
+func TestNotificationConfig(t *testing.T) {
+	content := `
+notifications:
+  stallMinutes: 5
+  notifiers:
+    - {type: webhook, url: "http://localhost/hook", events: [trade, stall]}
+    - {type: desktop}
+strategies:
+  - trader: autostable
+    stable: {quoteAsset: USDT, buyStopLimit: 8, sellStopLimit: 4}
+`
+	config, err := Parse([]byte(content), ".yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, 5.0, config.Notifications.StallMinutes)
+	assert.Equal(t, "webhook", config.Notifications.Notifiers[0].Notifier().Name())
+	assert.NotNil(t, config.Notifications.Dispatcher())
+
+	invalid := `
+notifications:
+  stallMinutes: -1
+  notifiers:
+    - {type: pager}
+    - {type: telegram, token: abc, events: [trades]}
+strategies:
+  - trader: autostable
+    stable: {quoteAsset: USDT, buyStopLimit: 8, sellStopLimit: 4}
+`
+	_, err = Parse([]byte(invalid), ".yaml")
+	validation, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Equal(t, []string{
+		`notifications.stallMinutes: can not be negative, got -1`,
+		`notifications.notifiers[0].type: unknown notifier "pager", expected webhook, telegram, email or desktop`,
+		`notifications.notifiers[1].token: token and chatId are required for telegram`,
+		`notifications.notifiers[1].events: unknown event "trades", expected one of [trade orderError deviation failover drawdown stall]`,
+	}, validation.Problems)
+}
+
+func TestTrailingConfig(t *testing.T) {
+	content := `
+strategies:
+  - trader: autostable
+    lockCreator: trailing
+    trailing: {source: stddev, period: 30, multiplier: 2}
+    stable: {quoteAsset: USDT, buyStopLimit: 8, sellStopLimit: 4}
+`
+	config, err := Parse([]byte(content), ".yaml")
+	assert.Nil(t, err)
+	options := config.Strategies[0].Trailing.Options()
+	assert.Equal(t, locker.VolatilityStdDev, options.Source)
+	assert.Equal(t, 30, options.Period)
+	assert.Equal(t, 2.0, options.Multiplier)
+	assert.Equal(t, locker.DefaultTrailing.BreakevenPercent, options.BreakevenPercent, "unset values keep their default")
+	assert.NotNil(t, config.Strategies[0].lockCreator())
+
+	invalid := `
+strategies:
+  - trader: autostable
+    trailing: {source: range, interval: 7m, multiplier: 1, minMultiplier: 2}
+    stable: {quoteAsset: USDT, buyStopLimit: 8, sellStopLimit: 4}
+`
+	_, err = Parse([]byte(invalid), ".yaml")
+	validation, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Equal(t, []string{
+		`strategies[0].trailing: requires lockCreator trailing, got "peakhigh"`,
+		`strategies[0].trailing.source: unknown source "range", expected atr or stddev`,
+		`strategies[0].trailing.interval: unknown kline interval "7m"`,
+		`strategies[0].trailing.minMultiplier: can not be more than multiplier 1, got 2`,
+	}, validation.Problems)
+}
+
+func TestGridConfig(t *testing.T) {
+	content := `
+strategies:
+  - trader: grid
+    grid: {symbol: ethusdt, lower: 1500, upper: 2100, levels: 13, quantity: 0.01}
+`
+	config, err := Parse([]byte(content), ".yaml")
+	assert.Nil(t, err)
+	grid := config.Strategies[0].Grid
+	assert.Equal(t, names.Symbol("ETHUSDT"), grid.Symbol)
+	assert.Equal(t, traders.GridArithmetic, grid.Spacing, "arithmetic by default")
+	assert.NotNil(t, config.Strategies[0].TradeManager())
+
+	invalid := `
+strategies:
+  - trader: grid
+    grid: {symbol: ETHUSDT, lower: 2100, upper: 1500, levels: 1, spacing: fibonacci}
+  - trader: grid
+  - trader: autostable
+    stable: {quoteAsset: USDT, buyStopLimit: 8, sellStopLimit: 4}
+    grid: {symbol: ETHUSDT, lower: 1500, upper: 2100, levels: 13, quantity: 0.01}
+`
+	_, err = Parse([]byte(invalid), ".yaml")
+	validation, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Equal(t, []string{
+		`strategies[0].grid.upper: must be above lower 2100, got 1500`,
+		`strategies[0].grid.levels: must be at least 2, got 1`,
+		`strategies[0].grid.spacing: must be arithmetic or geometric, got "fibonacci"`,
+		`strategies[0].grid.quantity: must be above 0, got 0`,
+		`strategies[1].grid: trader grid requires grid trade params`,
+		`strategies[2].grid: is only used by trader grid`,
+	}, validation.Problems)
+}
+
+func TestDCAConfig(t *testing.T) {
+	content := `
+strategies:
+  - trader: dca
+    dca:
+      symbols: [btcusdt]
+      baseOrder: 20
+      safetyOrder: 20
+      safetyOrders: 4
+      stepPercent: 2
+      takeProfit: 1.5
+      maxInvestments: {btcusdt: 300}
+`
+	config, err := Parse([]byte(content), ".yaml")
+	assert.Nil(t, err)
+	dca := config.Strategies[0].DCA
+	assert.Equal(t, []names.Symbol{"BTCUSDT"}, dca.Symbols)
+	assert.Equal(t, 300.0, dca.Cap("BTCUSDT"))
+	assert.Equal(t, 1.0, dca.VolumeScale, "safety orders of the same size by default")
+	assert.NotNil(t, config.Strategies[0].TradeManager())
+
+	invalid := `
+strategies:
+  - trader: dca
+    dca:
+      symbols: [BTC-USDT]
+      safetyOrders: 3
+      safetyOrder: 10
+      stepPercent: 40
+      maxInvestment: 5
+  - trader: dca
+`
+	_, err = Parse([]byte(invalid), ".yaml")
+	validation, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Equal(t, []string{
+		`strategies[0].dca.symbols[0]: invalid symbol "BTC-USDT"`,
+		`strategies[0].dca.baseOrder: must be above 0, got 0`,
+		`strategies[0].dca.stepScale: safety order 3 would buy 120% below the base order`,
+		`strategies[0].dca.takeProfit: must be above 0, got 0`,
+		`strategies[1].dca: trader dca requires dca trade params`,
+	}, validation.Problems)
+}
+
+func TestPairsConfig(t *testing.T) {
+	content := `
+strategies:
+  - trader: pairs
+    pairs: {first: ethusdt, second: btcusdt, exitZ: 0.5, amount: 50}
+`
+	config, err := Parse([]byte(content), ".yaml")
+	assert.Nil(t, err)
+	pairs := config.Strategies[0].Pairs
+	assert.Equal(t, names.Symbol("ETHUSDT"), pairs.First)
+	assert.Equal(t, "1h", pairs.Interval)
+	assert.Equal(t, 100, pairs.Lookback)
+	assert.Equal(t, 2.0, pairs.EntryZ, "two standard deviations by default")
+	assert.NotNil(t, config.Strategies[0].TradeManager())
+
+	invalid := `
+strategies:
+  - trader: pairs
+    pairs: {first: ETHUSDT, second: ETHUSDT, interval: 7m, entryZ: 1, exitZ: 1.5}
+  - trader: pairs
+  - trader: autostable
+    stable: {quoteAsset: USDT, buyStopLimit: 8, sellStopLimit: 4}
+    pairs: {first: ETHUSDT, second: BTCUSDT}
+`
+	_, err = Parse([]byte(invalid), ".yaml")
+	validation, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Equal(t, []string{
+		`strategies[0].pairs.second: must differ from first ETHUSDT`,
+		`strategies[0].pairs.interval: unknown kline interval "7m"`,
+		`strategies[0].pairs.entryZ: must be above exitZ 1.5, got 1`,
+		`strategies[0].pairs.amount: must be above 0, got 0`,
+		`strategies[1].pairs: trader pairs requires pairs trade params`,
+		`strategies[2].pairs: is only used by trader pairs`,
+	}, validation.Problems)
+}
+
+func TestArbitrageConfig(t *testing.T) {
+	content := `
+arbitrage: {assets: [usdt, btc], amount: 100, minProfit: 0.2, execute: true, cooldownSeconds: 30}
+strategies:
+  - trader: autostable
+    stable: {quoteAsset: USDT, buyStopLimit: 8, sellStopLimit: 4}
+`
+	config, err := Parse([]byte(content), ".yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"USDT", "BTC"}, config.Arbitrage.Assets)
+
+	dryRun := `
+arbitrage: {}
+strategies:
+  - trader: autostable
+    stable: {quoteAsset: USDT, buyStopLimit: 8, sellStopLimit: 4}
+`
+	config, err = Parse([]byte(dryRun), ".yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"USDT"}, config.Arbitrage.Assets, "cycles start in USDT when no asset is set")
+	assert.False(t, config.Arbitrage.Execute)
+
+	invalid := `
+arbitrage: {execute: true, minProfit: -1, cooldownSeconds: -5}
+strategies:
+  - trader: autostable
+    stable: {quoteAsset: USDT, buyStopLimit: 8, sellStopLimit: 4}
+`
+	_, err = Parse([]byte(invalid), ".yaml")
+	validation, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Equal(t, []string{
+		`arbitrage.amount: must be positive to execute the cycles, got 0`,
+		`arbitrage.minProfit: can not be negative, got -1`,
+		`arbitrage.cooldownSeconds: can not be negative, got -5`,
+	}, validation.Problems)
+}
+
+func TestSaveConfig(t *testing.T) {
+	content := `
+allocation: {rule: fixed, fraction: 20}
+strategies:
+  - name: stable
+    trader: autostable
+    stable: {quoteAsset: usdt, buyStopLimit: 8, sellStopLimit: 4, sellLockDelta: 0.02}
+`
+	config, err := Parse([]byte(content), ".yaml")
+	assert.Nil(t, err)
+	for _, extension := range []string{".yaml", ".json"} {
+		filename := filepath.Join(t.TempDir(), "config"+extension)
+		assert.Nil(t, config.Save(filename))
+		loaded, err := Load(filename)
+		assert.Nil(t, err)
+		assert.Equal(t, config.Allocation, loaded.Allocation, "a saved config loads back the same")
+		assert.Equal(t, config.Strategies[0].LockCreator, loaded.Strategies[0].LockCreator)
+		assert.Equal(t, config.Strategies[0].Stable, loaded.Strategies[0].Stable)
+	}
+	assert.NotNil(t, config.Save(filepath.Join(t.TempDir(), "config.toml")))
+}
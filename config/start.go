@@ -0,0 +1,142 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+	"trading/names"
+	"trading/notify"
+	"trading/stream"
+	"trading/trade/allocator"
+	"trading/trade/locker"
+	"trading/trade/manager"
+	"trading/trade/screener"
+	"trading/trade/traders"
+	"trading/utils"
+)
+
+var lockCreators = map[string]names.LockCreatorFunc{
+	LockPeakHigh:     locker.PeakHighLockCreator,
+	LockImmediateDue: locker.ImmediateDueLockCreator,
+	LockTrailing:     locker.TrailingLockCreator,
+}
+
+// lockCreator creates the locks of the strategy, trailing locks with the
+// options of the strategy when it has any
+func (s Strategy) lockCreator() names.LockCreatorFunc {
+	if s.LockCreator == LockTrailing && s.Trailing != nil {
+		return locker.NewTrailingLockCreator(s.Trailing.Options())
+	}
+	return lockCreators[s.LockCreator]
+}
+
+// TradeManager creates the trade manager described by the strategy
+// without starting it
+func (s Strategy) TradeManager() *manager.TradeManager {
+	var tm *manager.TradeManager
+	bestSide := names.TradeSide(s.BestSide)
+
+	switch s.Trader {
+	case TraderLimit:
+		tm = traders.NewLimitTrade(s.TradeConfigs())
+	case TraderAuto:
+		tm = traders.NewAutoTrade(s.TradeConfigs(), s.Datapoints, s.Interval)
+	case TraderBestSide:
+		configs := s.TradeConfigs()
+		tm = traders.NewBestSideTrade(configs, s.Datapoints, s.Interval, bestSide, traders.StatusContention, configs[0])
+	case TraderStableBestSide:
+		configs := s.TradeConfigs()
+		tm = traders.NewStableBestSide(configs, bestSide, traders.StatusContention, configs[0])
+	case TraderAutoStable:
+		tm = traders.NewAutoStableTrader(*s.Stable)
+	case TraderAutoStableSplit:
+		tm = traders.NewAutoStableSplitTrader(*s.Stable)
+	case TraderAutoStableHigh:
+		tm = traders.NewAutoStableBuyHighTrader(*s.Stable)
+	case TraderGrid:
+		tm = traders.NewGridTrade(*s.Grid)
+	case TraderDCA:
+		tm = traders.NewDCATrade(*s.DCA)
+	case TraderPairs:
+		tm = traders.NewPairsTrade(*s.Pairs)
+	default:
+		return &manager.TradeManager{}
+	}
+
+	return tm.
+		UseLockCreator(s.lockCreator()).
+		UsePriority(names.TradeSide(s.PrioritySide)).
+		UseMaxDrawdown(s.MaxDrawdown, s.DrawdownExit)
+}
+
+// resume creates the trade manager from the snapshot of the strategy,
+// false is returned when there is no snapshot to resume from
+func (s Strategy) resume() (*manager.TradeManager, bool) {
+	if s.Snapshot == "" {
+		return nil, false
+	}
+	if _, err := os.Stat(s.Snapshot); err != nil {
+		return nil, false
+	}
+
+	snapshot, err := manager.LoadSnapshot(s.Snapshot)
+	if err != nil {
+		utils.LogError(err, fmt.Sprintf("<Config>: strategy %s will start over", s.Name))
+		return nil, false
+	}
+	var tm *manager.TradeManager
+	switch s.Trader {
+	case TraderLimit:
+		tm, err = traders.ResumeLimitTrade(snapshot)
+	case TraderAutoStable:
+		tm, err = traders.ResumeAutoStableTrader(snapshot)
+	default:
+		// only these traders save their state in the snapshot
+		err = fmt.Errorf("resume unsupported for the %s trader", s.Trader)
+	}
+	if err != nil {
+		utils.LogError(err, fmt.Sprintf("<Config>: strategy %s will start over", s.Name))
+		return nil, false
+	}
+	utils.LogInfo(fmt.Sprintf("<Config>: resuming strategy %s from %s saved at %s", s.Name, s.Snapshot, snapshot.Saved))
+	return tm.
+		UseLockCreator(s.lockCreator()).
+		UseMaxDrawdown(s.MaxDrawdown, s.DrawdownExit), true
+}
+
+// Start sends the notifications, shares the capital and watches the
+// arbitrage cycles when they are set, registers the screeners and runs every
+// strategy of the config, strategies with a snapshot are resumed from it
+func (c Config) Start() []*manager.TradeManager {
+	if c.Notifications != nil {
+		notify.Use(c.Notifications.Dispatcher())
+		if c.Notifications.StallMinutes > 0 {
+			stream.WatchStalls(time.Duration(c.Notifications.StallMinutes * float64(time.Minute)))
+		}
+		utils.LogInfo(fmt.Sprintf("<Config>: sending notifications to %d notifiers", len(c.Notifications.Notifiers)))
+	}
+	if c.Allocation != nil {
+		a := c.Allocation.Allocator()
+		allocator.Use(a)
+		utils.LogInfo(fmt.Sprintf("<Config>: allocating capital by %s rule", a.Rule().Name()))
+	}
+	if c.Arbitrage != nil {
+		c.Arbitrage.Detector(names.GetStoredInfo()).Start()
+	}
+	for _, sc := range c.Screeners {
+		screener.Register(sc.Screener())
+	}
+	managers := []*manager.TradeManager{}
+	for _, s := range c.Strategies {
+		tm, resumed := s.resume()
+		if !resumed {
+			utils.LogInfo(fmt.Sprintf("<Config>: starting strategy %s with %s trader", s.Name, s.Trader))
+			tm = s.TradeManager()
+		}
+		if s.Snapshot != "" {
+			tm.UseSnapshotFile(s.Snapshot)
+		}
+		managers = append(managers, tm.DoTrade())
+	}
+	return managers
+}
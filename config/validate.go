@@ -0,0 +1,686 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"trading/names"
+	"trading/notify"
+	"trading/trade/graph"
+	"trading/trade/locker"
+	"trading/trade/screener"
+	"trading/trade/traders"
+)
+
+var symbolPattern = regexp.MustCompile(`^[A-Z0-9]+$`)
+
+var intervals = map[string]bool{
+	"1m": true, "3m": true, "5m": true, "15m": true, "30m": true,
+	"1h": true, "2h": true, "4h": true, "6h": true, "8h": true, "12h": true,
+	"1d": true, "3d": true, "1w": true, "1M": true,
+}
+
+// ValidationError lists every problem found in a config
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid config:\n  %s", strings.Join(e.Problems, "\n  "))
+}
+
+func (e *ValidationError) add(field string, format string, args ...any) {
+	e.Problems = append(e.Problems, fmt.Sprintf("%s: %s", field, fmt.Sprintf(format, args...)))
+}
+
+func isSide(side string) bool {
+	return side == names.TradeSideBuy.String() || side == names.TradeSideSell.String()
+}
+
+// Validate checks the whole config and reports all problems at once
+func (c Config) Validate() error {
+	errs := &ValidationError{}
+	if len(c.Strategies) == 0 {
+		errs.add("strategies", "at least one strategy is required")
+	}
+
+	if c.Allocation != nil {
+		c.Allocation.validate("allocation", errs)
+	}
+	if c.Notifications != nil {
+		c.Notifications.validate("notifications", errs)
+	}
+	if c.Arbitrage != nil {
+		c.Arbitrage.validate("arbitrage", errs)
+	}
+
+	screeners := map[string]bool{}
+	for i, sc := range c.Screeners {
+		field := fmt.Sprintf("screeners[%d]", i)
+		if screeners[sc.Name] {
+			errs.add(field+".name", "duplicate screener name %q", sc.Name)
+		}
+		screeners[sc.Name] = true
+		sc.validate(field, errs)
+	}
+
+	seen := map[string]bool{}
+	for i, s := range c.Strategies {
+		field := fmt.Sprintf("strategies[%d]", i)
+		if seen[s.Name] {
+			errs.add(field+".name", "duplicate strategy name %q", s.Name)
+		}
+		seen[s.Name] = true
+		s.validate(field, errs)
+		if s.Stable != nil && s.Stable.Screener != "" && !screeners[s.Stable.Screener] {
+			if _, registered := screener.Get(s.Stable.Screener); !registered {
+				errs.add(field+".stable.screener", "unknown screener %q", s.Stable.Screener)
+			}
+		}
+	}
+
+	if len(errs.Problems) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (s Strategy) validate(field string, errs *ValidationError) {
+	switch s.Trader {
+	case TraderLimit, TraderAuto, TraderBestSide, TraderStableBestSide,
+		TraderAutoStable, TraderAutoStableSplit, TraderAutoStableHigh, TraderGrid, TraderDCA, TraderPairs:
+	case "":
+		errs.add(field+".trader", "is required")
+		return
+	default:
+		errs.add(field+".trader", "unknown trader %q, expected one of %s", s.Trader, strings.Join([]string{
+			TraderLimit, TraderAuto, TraderBestSide, TraderStableBestSide,
+			TraderAutoStable, TraderAutoStableSplit, TraderAutoStableHigh, TraderGrid, TraderDCA, TraderPairs,
+		}, ", "))
+		return
+	}
+
+	if _, ok := lockCreators[s.LockCreator]; !ok {
+		errs.add(field+".lockCreator", "unknown lock creator %q, expected %s, %s or %s", s.LockCreator, LockPeakHigh, LockImmediateDue, LockTrailing)
+	}
+	if s.Trailing != nil {
+		if s.LockCreator != LockTrailing {
+			errs.add(field+".trailing", "requires lockCreator %s, got %q", LockTrailing, s.LockCreator)
+		}
+		s.Trailing.validate(field+".trailing", errs)
+	}
+	if !isSide(s.PrioritySide) {
+		errs.add(field+".prioritySide", "must be BUY or SELL, got %q", s.PrioritySide)
+	}
+	if !isSide(s.BestSide) {
+		errs.add(field+".bestSide", "must be BUY or SELL, got %q", s.BestSide)
+	}
+	if s.MaxDrawdown < 0 {
+		errs.add(field+".maxDrawdown", "can not be negative")
+	}
+	if s.DrawdownExit && s.MaxDrawdown == 0 {
+		errs.add(field+".drawdownExit", "requires maxDrawdown")
+	}
+	if s.Snapshot != "" && !s.resumable() {
+		errs.add(field+".snapshot", "trader %s can not be resumed, only %s and %s", s.Trader, TraderLimit, TraderAutoStable)
+	}
+
+	if s.usesConfigs() {
+		if len(s.Configs) == 0 {
+			errs.add(field+".configs", "trader %s requires at least one trade config", s.Trader)
+		}
+		if s.Stable != nil {
+			errs.add(field+".stable", "is not used by trader %s, use configs", s.Trader)
+		}
+		if s.Trader == TraderAuto || s.Trader == TraderBestSide {
+			if !intervals[s.Interval] {
+				errs.add(field+".interval", "unknown kline interval %q", s.Interval)
+			}
+			if s.Datapoints < 2 {
+				errs.add(field+".datapoints", "must be at least 2, got %d", s.Datapoints)
+			}
+		}
+		ids := map[string]bool{}
+		for j, tc := range s.Configs {
+			configField := fmt.Sprintf("%s.configs[%d]", field, j)
+			if tc.Id != "" {
+				if ids[tc.Id] {
+					errs.add(configField+".id", "duplicate trade config id %q", tc.Id)
+				}
+				ids[tc.Id] = true
+			}
+			tc.validate(configField, s.Trader == TraderLimit, errs)
+		}
+		return
+	}
+
+	if s.Trader == TraderGrid {
+		if s.Grid == nil {
+			errs.add(field+".grid", "trader %s requires grid trade params", s.Trader)
+			return
+		}
+		if len(s.Configs) > 0 {
+			errs.add(field+".configs", "are not used by trader %s, use grid", s.Trader)
+		}
+		if s.Stable != nil {
+			errs.add(field+".stable", "is not used by trader %s, use grid", s.Trader)
+		}
+		validateGrid(field+".grid", *s.Grid, errs)
+		return
+	}
+	if s.Grid != nil {
+		errs.add(field+".grid", "is only used by trader %s", TraderGrid)
+	}
+
+	if s.Trader == TraderDCA {
+		if s.DCA == nil {
+			errs.add(field+".dca", "trader %s requires dca trade params", s.Trader)
+			return
+		}
+		if len(s.Configs) > 0 {
+			errs.add(field+".configs", "are not used by trader %s, use dca", s.Trader)
+		}
+		if s.Stable != nil {
+			errs.add(field+".stable", "is not used by trader %s, use dca", s.Trader)
+		}
+		validateDCA(field+".dca", *s.DCA, errs)
+		return
+	}
+	if s.DCA != nil {
+		errs.add(field+".dca", "is only used by trader %s", TraderDCA)
+	}
+
+	if s.Trader == TraderPairs {
+		if s.Pairs == nil {
+			errs.add(field+".pairs", "trader %s requires pairs trade params", s.Trader)
+			return
+		}
+		if len(s.Configs) > 0 {
+			errs.add(field+".configs", "are not used by trader %s, use pairs", s.Trader)
+		}
+		if s.Stable != nil {
+			errs.add(field+".stable", "is not used by trader %s, use pairs", s.Trader)
+		}
+		validatePairs(field+".pairs", *s.Pairs, errs)
+		return
+	}
+	if s.Pairs != nil {
+		errs.add(field+".pairs", "is only used by trader %s", TraderPairs)
+	}
+
+	if s.Stable == nil {
+		errs.add(field+".stable", "trader %s requires stable trade params", s.Trader)
+		return
+	}
+	if len(s.Configs) > 0 {
+		errs.add(field+".configs", "are not used by trader %s, use stable", s.Trader)
+	}
+	validateStable(field+".stable", *s.Stable, errs)
+}
+
+func validateGrid(field string, grid traders.GridTradeParam, errs *ValidationError) {
+	if grid.Symbol == "" {
+		errs.add(field+".symbol", "is required")
+	} else if !symbolPattern.MatchString(grid.Symbol.String()) {
+		errs.add(field+".symbol", "invalid symbol %q", grid.Symbol)
+	}
+	if grid.Lower <= 0 {
+		errs.add(field+".lower", "must be above 0, got %v", grid.Lower)
+	}
+	if grid.Upper <= grid.Lower {
+		errs.add(field+".upper", "must be above lower %v, got %v", grid.Lower, grid.Upper)
+	}
+	if grid.Levels < 2 {
+		errs.add(field+".levels", "must be at least 2, got %d", grid.Levels)
+	}
+	if grid.Spacing != traders.GridArithmetic && grid.Spacing != traders.GridGeometric {
+		errs.add(field+".spacing", "must be %s or %s, got %q", traders.GridArithmetic, traders.GridGeometric, grid.Spacing)
+	}
+	if grid.Quantity <= 0 {
+		errs.add(field+".quantity", "must be above 0, got %v", grid.Quantity)
+	}
+	validateOrder(field+".buyOrder", grid.BuyOrder, errs)
+	validateOrder(field+".sellOrder", grid.SellOrder, errs)
+}
+
+func validateDCA(field string, dca traders.DCATradeParam, errs *ValidationError) {
+	if len(dca.Symbols) == 0 {
+		errs.add(field+".symbols", "requires at least one symbol")
+	}
+	for i, symbol := range dca.Symbols {
+		if !symbolPattern.MatchString(symbol.String()) {
+			errs.add(fmt.Sprintf("%s.symbols[%d]", field, i), "invalid symbol %q", symbol)
+		}
+	}
+	if dca.BaseOrder <= 0 {
+		errs.add(field+".baseOrder", "must be above 0, got %v", dca.BaseOrder)
+	}
+	if dca.SafetyOrders < 0 {
+		errs.add(field+".safetyOrders", "can not be negative")
+	}
+	if dca.SafetyOrders > 0 {
+		if dca.SafetyOrder <= 0 {
+			errs.add(field+".safetyOrder", "must be above 0, got %v", dca.SafetyOrder)
+		}
+		if dca.StepPercent <= 0 || dca.StepPercent >= 100 {
+			errs.add(field+".stepPercent", "must be a percent above 0 and below 100, got %v", dca.StepPercent)
+		} else if deviation, _ := dca.SafetyOrderAt(dca.SafetyOrders); deviation >= 100 {
+			errs.add(field+".stepScale", "safety order %d would buy %v%% below the base order", dca.SafetyOrders, deviation)
+		}
+	}
+	if dca.VolumeScale < 0 {
+		errs.add(field+".volumeScale", "can not be negative")
+	}
+	if dca.StepScale < 0 {
+		errs.add(field+".stepScale", "can not be negative")
+	}
+	if dca.TakeProfit <= 0 {
+		errs.add(field+".takeProfit", "must be above 0, got %v", dca.TakeProfit)
+	}
+	if dca.LockDelta < 0 {
+		errs.add(field+".lockDelta", "can not be negative")
+	}
+	if dca.MaxInvestment < 0 {
+		errs.add(field+".maxInvestment", "can not be negative")
+	}
+	capped := []string{}
+	for symbol := range dca.MaxInvestments {
+		capped = append(capped, symbol.String())
+	}
+	sort.Strings(capped)
+	for _, symbol := range capped {
+		if cap := dca.MaxInvestments[names.Symbol(symbol)]; cap < dca.BaseOrder {
+			errs.add(fmt.Sprintf("%s.maxInvestments.%s", field, symbol), "must be at least the base order %v, got %v", dca.BaseOrder, cap)
+		}
+	}
+	if dca.MaxInvestment > 0 && dca.MaxInvestment < dca.BaseOrder {
+		errs.add(field+".maxInvestment", "must be at least the base order %v, got %v", dca.BaseOrder, dca.MaxInvestment)
+	}
+	validateOrder(field+".buyOrder", dca.BuyOrder, errs)
+	validateOrder(field+".sellOrder", dca.SellOrder, errs)
+}
+
+func validatePairs(field string, pairs traders.PairsTradeParam, errs *ValidationError) {
+	for _, leg := range []struct {
+		name   string
+		symbol names.Symbol
+	}{{"first", pairs.First}, {"second", pairs.Second}} {
+		if leg.symbol == "" {
+			errs.add(field+"."+leg.name, "is required")
+		} else if !symbolPattern.MatchString(leg.symbol.String()) {
+			errs.add(field+"."+leg.name, "invalid symbol %q", leg.symbol)
+		}
+	}
+	if pairs.First != "" && pairs.First == pairs.Second {
+		errs.add(field+".second", "must differ from first %s", pairs.First)
+	}
+	if !intervals[pairs.Interval] {
+		errs.add(field+".interval", "unknown kline interval %q", pairs.Interval)
+	}
+	if pairs.Lookback < 2 {
+		errs.add(field+".lookback", "must be at least 2, got %d", pairs.Lookback)
+	}
+	if pairs.ExitZ < 0 {
+		errs.add(field+".exitZ", "can not be negative")
+	}
+	if pairs.EntryZ <= pairs.ExitZ {
+		errs.add(field+".entryZ", "must be above exitZ %v, got %v", pairs.ExitZ, pairs.EntryZ)
+	}
+	if pairs.Amount <= 0 {
+		errs.add(field+".amount", "must be above 0, got %v", pairs.Amount)
+	}
+	validateOrder(field+".buyOrder", pairs.BuyOrder, errs)
+	validateOrder(field+".sellOrder", pairs.SellOrder, errs)
+}
+
+// Validate checks a single trade config, a side is required
+func (tc TradeConfig) Validate() error {
+	errs := &ValidationError{}
+	tc.validate("config", true, errs)
+	if len(errs.Problems) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (tc TradeConfig) validate(field string, sideRequired bool, errs *ValidationError) {
+	if tc.Symbol == "" {
+		errs.add(field+".symbol", "is required")
+	} else if !symbolPattern.MatchString(tc.Symbol) {
+		errs.add(field+".symbol", "invalid symbol %q", tc.Symbol)
+	}
+	if tc.Side == "" {
+		if sideRequired {
+			errs.add(field+".side", "is required")
+		}
+	} else if !isSide(tc.Side) {
+		errs.add(field+".side", "must be BUY or SELL, got %q", tc.Side)
+	}
+	tc.Buy.validate(field+".buy", names.TradeSideBuy, errs)
+	tc.Sell.validate(field+".sell", names.TradeSideSell, errs)
+}
+
+func (sc SideConfig) validate(field string, side names.TradeSide, errs *ValidationError) {
+	limitType := names.StopLimit(sc.LimitType)
+	if !limitType.IsPercent() && !limitType.IsFixed() {
+		errs.add(field+".limitType", "must be %s or %s, got %q", names.RatePercent, names.RateFixed, sc.LimitType)
+	}
+	if sc.StopLimit <= 0 {
+		errs.add(field+".stopLimit", "must be greater than 0, got %v", sc.StopLimit)
+	}
+	if side.IsBuy() && limitType.IsPercent() && sc.StopLimit >= 100 {
+		errs.add(field+".stopLimit", "buy percent must be below 100, got %v", sc.StopLimit)
+	}
+	if sc.Quantity == 0 {
+		errs.add(field+".quantity", "is required, use %v for the whole balance", names.MAX_QUANTITY)
+	}
+	if sc.LockDelta < 0 {
+		errs.add(field+".lockDelta", "can not be negative")
+	}
+	if limitType.IsPercent() && sc.StopLimit > 0 && sc.LockDelta > sc.StopLimit {
+		errs.add(field+".lockDelta", "%v is greater than stopLimit %v", sc.LockDelta, sc.StopLimit)
+	}
+	if sc.Deviation.Delta < 0 {
+		errs.add(field+".deviation.delta", "can not be negative")
+	}
+	if sc.MaxSlippage < 0 {
+		errs.add(field+".maxSlippage", "can not be negative")
+	}
+	validateOrder(field+".order", sc.Order, errs)
+	validateStopLoss(field+".stopLoss", side, sc.StopLoss, errs)
+}
+
+func validateStopLoss(field string, side names.TradeSide, stopLoss names.StopLoss, errs *ValidationError) {
+	if stopLoss.Value < 0 {
+		errs.add(field+".value", "can not be negative")
+	}
+	if stopLoss.Type != "" && !stopLoss.Type.IsPercent() && !stopLoss.Type.IsFixed() {
+		errs.add(field+".type", "must be %s or %s, got %q", names.RatePercent, names.RateFixed, stopLoss.Type)
+	}
+	if side.IsSell() && stopLoss.Type.IsPercent() && stopLoss.Value >= 100 {
+		errs.add(field+".value", "sell percent must be below 100, got %v", stopLoss.Value)
+	}
+}
+
+func validateOrder(field string, order names.OrderConfig, errs *ValidationError) {
+	if !order.Type.IsValid() {
+		errs.add(field+".type", "must be one of %s, %s, %s, %s or %s, got %q",
+			names.OrderTypeMarket, names.OrderTypeLimit, names.OrderTypeStopLossLimit,
+			names.OrderTypeTakeProfitLimit, names.OrderTypeOCO, order.Type)
+	}
+	if !order.TimeInForce.IsValid() {
+		errs.add(field+".timeInForce", "must be %s, %s or %s, got %q",
+			names.TimeInForceGTC, names.TimeInForceIOC, names.TimeInForceFOK, order.TimeInForce)
+	}
+	offsets := []struct {
+		name  string
+		value float64
+	}{
+		{"limitOffset", order.LimitOffset},
+		{"stopOffset", order.StopOffset},
+		{"stopLimitOffset", order.StopLimitOffset},
+	}
+	for _, offset := range offsets {
+		if offset.value < 0 || offset.value >= 100 {
+			errs.add(field+"."+offset.name, "must be a percent from 0 up to 100, got %v", offset.value)
+		}
+	}
+	if order.Timeout < 0 {
+		errs.add(field+".timeout", "can not be negative")
+	}
+	switch order.Type {
+	case names.OrderTypeStopLossLimit, names.OrderTypeTakeProfitLimit:
+		if order.StopOffset <= 0 {
+			errs.add(field+".stopOffset", "%s orders need a stop price away from the market", order.Type)
+		}
+	case names.OrderTypeOCO:
+		// the limit leg must be above and the stop below the market for a sell
+		if order.LimitOffset <= 0 {
+			errs.add(field+".limitOffset", "OCO orders need a limit price away from the market")
+		}
+		if order.StopOffset <= 0 {
+			errs.add(field+".stopOffset", "OCO orders need a stop price away from the market")
+		}
+	}
+}
+
+func validateStable(field string, p traders.StableTradeParam, errs *ValidationError) {
+	if p.QuoteAsset == "" {
+		errs.add(field+".quoteAsset", "is required")
+	}
+	if p.BuyStopLimit <= 0 {
+		errs.add(field+".buyStopLimit", "must be greater than 0, got %v", p.BuyStopLimit)
+	}
+	if p.SellStopLimit <= 0 {
+		errs.add(field+".sellStopLimit", "must be greater than 0, got %v", p.SellStopLimit)
+	}
+	notNegative := []struct {
+		name  string
+		value float64
+	}{
+		{"buyLockDelta", p.BuyLockDelta},
+		{"sellLockDelta", p.SellLockDelta},
+		{"buyDeviationDelta", p.BuyDeviationDelta},
+		{"sellDeviationDelta", p.SellDeviationDelta},
+	}
+	for _, delta := range notNegative {
+		if delta.value < 0 {
+			errs.add(field+"."+delta.name, "can not be negative, got %v", delta.value)
+		}
+	}
+	if p.BuyStopLimit > 0 && p.BuyLockDelta > p.BuyStopLimit {
+		errs.add(field+".buyLockDelta", "%v is greater than buyStopLimit %v", p.BuyLockDelta, p.BuyStopLimit)
+	}
+	if p.SellStopLimit > 0 && p.SellLockDelta > p.SellStopLimit {
+		errs.add(field+".sellLockDelta", "%v is greater than sellStopLimit %v", p.SellLockDelta, p.SellStopLimit)
+	}
+	if p.BuyStopLimit >= 100 {
+		errs.add(field+".buyStopLimit", "buy percent must be below 100, got %v", p.BuyStopLimit)
+	}
+	if p.MaxPriceChange != 0 && p.MinPriceChange > p.MaxPriceChange {
+		errs.add(field+".minPriceChange", "%v is greater than maxPriceChange %v", p.MinPriceChange, p.MaxPriceChange)
+	}
+	if p.BestSide != "" && !isSide(p.BestSide.String()) {
+		errs.add(field+".bestSide", "must be BUY or SELL, got %q", p.BestSide)
+	}
+	if p.Side != "" && !isSide(p.Side.String()) {
+		errs.add(field+".side", "must be BUY or SELL, got %q", p.Side)
+	}
+	if p.Status != traders.StatusContention && p.Status != traders.StatusFullfilment {
+		errs.add(field+".status", "must be %s or %s, got %q", traders.StatusContention, traders.StatusFullfilment, p.Status)
+	}
+	validateOrder(field+".buyOrder", p.BuyOrder, errs)
+	validateOrder(field+".sellOrder", p.SellOrder, errs)
+	validateStopLoss(field+".buyStopLoss", names.TradeSideBuy, p.BuyStopLoss, errs)
+	validateStopLoss(field+".sellStopLoss", names.TradeSideSell, p.SellStopLoss, errs)
+}
+
+var trends = map[graph.TrendType]bool{
+	graph.Uptrend: true, graph.DownTrend: true, graph.Dumping: true, graph.Range: true, graph.Breakout: true,
+}
+
+func (ac AllocationConfig) validate(field string, errs *ValidationError) {
+	switch strings.ToLower(ac.Rule) {
+	case AllocationEqual, AllocationVolatility, AllocationKelly, "":
+	case AllocationFixed:
+		if ac.Fraction <= 0 || ac.Fraction > 100 {
+			errs.add(field+".fraction", "must be within 0 and 100 for the fixed rule, got %v", ac.Fraction)
+		}
+	default:
+		errs.add(field+".rule", "unknown rule %q, expected %s, %s, %s or %s", ac.Rule,
+			AllocationEqual, AllocationVolatility, AllocationFixed, AllocationKelly)
+	}
+	if ac.MaxFraction > 100 {
+		errs.add(field+".maxFraction", "can not be more than 100, got %v", ac.MaxFraction)
+	}
+	notNegative := []struct {
+		name  string
+		value float64
+	}{
+		{"kellyMultiplier", ac.KellyMultiplier},
+		{"maxFraction", ac.MaxFraction},
+		{"minimum", ac.Minimum},
+	}
+	for _, v := range notNegative {
+		if v.value < 0 {
+			errs.add(field+"."+v.name, "can not be negative, got %v", v.value)
+		}
+	}
+}
+
+func (ac ArbitrageConfig) validate(field string, errs *ValidationError) {
+	if ac.Execute && ac.Amount <= 0 {
+		errs.add(field+".amount", "must be positive to execute the cycles, got %v", ac.Amount)
+	} else if ac.Amount < 0 {
+		errs.add(field+".amount", "can not be negative, got %v", ac.Amount)
+	}
+	notNegative := []struct {
+		name  string
+		value float64
+	}{
+		{"minProfit", ac.MinProfit},
+		{"maxSlippage", ac.MaxSlippage},
+		{"cooldownSeconds", ac.CooldownSeconds},
+	}
+	for _, v := range notNegative {
+		if v.value < 0 {
+			errs.add(field+"."+v.name, "can not be negative, got %v", v.value)
+		}
+	}
+}
+
+func (tc TrailingConfig) validate(field string, errs *ValidationError) {
+	switch locker.VolatilitySource(strings.ToLower(tc.Source)) {
+	case locker.VolatilityATR, locker.VolatilityStdDev, "":
+	default:
+		errs.add(field+".source", "unknown source %q, expected %s or %s", tc.Source, locker.VolatilityATR, locker.VolatilityStdDev)
+	}
+	if tc.Interval != "" && !intervals[tc.Interval] {
+		errs.add(field+".interval", "unknown kline interval %q", tc.Interval)
+	}
+	if tc.Period < 0 {
+		errs.add(field+".period", "can not be negative, got %v", tc.Period)
+	}
+	notNegative := []struct {
+		name  string
+		value float64
+	}{
+		{"multiplier", tc.Multiplier},
+		{"minMultiplier", tc.MinMultiplier},
+		{"tightenPercent", tc.TightenPercent},
+		{"breakevenPercent", tc.BreakevenPercent},
+	}
+	for _, v := range notNegative {
+		if v.value < 0 {
+			errs.add(field+"."+v.name, "can not be negative, got %v", v.value)
+		}
+	}
+	if options := tc.Options(); options.MinMultiplier > options.Multiplier {
+		errs.add(field+".minMultiplier", "can not be more than multiplier %v, got %v", options.Multiplier, options.MinMultiplier)
+	}
+}
+
+func (nc NotificationConfig) validate(field string, errs *ValidationError) {
+	if nc.StallMinutes < 0 {
+		errs.add(field+".stallMinutes", "can not be negative, got %v", nc.StallMinutes)
+	}
+	if nc.CooldownMinutes < 0 {
+		errs.add(field+".cooldownMinutes", "can not be negative, got %v", nc.CooldownMinutes)
+	}
+	for i, n := range nc.Notifiers {
+		n.validate(fmt.Sprintf("%s.notifiers[%d]", field, i), errs)
+	}
+}
+
+func (nc NotifierConfig) validate(field string, errs *ValidationError) {
+	switch strings.ToLower(nc.Type) {
+	case NotifierWebhook:
+		if nc.URL == "" {
+			errs.add(field+".url", "is required for a webhook")
+		}
+	case NotifierTelegram:
+		if nc.Token == "" || nc.ChatId == "" {
+			errs.add(field+".token", "token and chatId are required for telegram")
+		}
+	case NotifierEmail:
+		if nc.SMTP == "" || nc.From == "" || len(nc.To) == 0 {
+			errs.add(field+".smtp", "smtp, from and to are required for email")
+		}
+	case NotifierDesktop:
+	default:
+		errs.add(field+".type", "unknown notifier %q, expected %s, %s, %s or %s", nc.Type,
+			NotifierWebhook, NotifierTelegram, NotifierEmail, NotifierDesktop)
+	}
+	for _, e := range nc.Events {
+		if !notify.EventType(e).IsValid() {
+			errs.add(field+".events", "unknown event %q, expected one of %v", e, notify.EventTypes)
+		}
+	}
+}
+
+func (sc ScreenerConfig) validate(field string, errs *ValidationError) {
+	if sc.Name == "" {
+		errs.add(field+".name", "is required")
+	}
+	ranges := []struct {
+		name     string
+		min, max float64
+	}{
+		{"PriceChange", sc.MinPriceChange, sc.MaxPriceChange},
+		{"Volatility", sc.MinVolatility, sc.MaxVolatility},
+		{"Rsi", sc.MinRSI, sc.MaxRSI},
+	}
+	for _, r := range ranges {
+		if r.max != 0 && r.min > r.max {
+			errs.add(field+".min"+r.name, "%v is greater than max%s %v", r.min, r.name, r.max)
+		}
+	}
+	if sc.MinRSI < 0 || sc.MaxRSI > 100 {
+		errs.add(field+".minRsi", "rsi range must be within 0 and 100")
+	}
+	notNegative := []struct {
+		name  string
+		value float64
+	}{
+		{"minQuoteVolume", sc.MinQuoteVolume},
+		{"minTrades", float64(sc.MinTrades)},
+		{"maxSpread", sc.MaxSpread},
+		{"minVolatility", sc.MinVolatility},
+		{"limit", float64(sc.Limit)},
+	}
+	for _, v := range notNegative {
+		if v.value < 0 {
+			errs.add(field+"."+v.name, "can not be negative, got %v", v.value)
+		}
+	}
+	for _, t := range sc.Trends {
+		if !trends[graph.TrendType(t)] {
+			errs.add(field+".trends", "unknown trend %q, expected %s, %s, %s, %s or %s", t,
+				graph.Uptrend, graph.DownTrend, graph.Dumping, graph.Range, graph.Breakout)
+		}
+	}
+	lists := []struct {
+		name    string
+		symbols []string
+	}{
+		{"blacklist", sc.Blacklist},
+		{"whitelist", sc.Whitelist},
+	}
+	for _, list := range lists {
+		for _, symbol := range list.symbols {
+			if !symbolPattern.MatchString(symbol) {
+				errs.add(field+"."+list.name, "invalid symbol %q", symbol)
+			}
+		}
+	}
+	if _, ok := screener.Scores[sc.Score]; !ok {
+		errs.add(field+".score", "unknown score %q", sc.Score)
+	}
+	if !intervals[sc.Interval] {
+		errs.add(field+".interval", "unknown kline interval %q", sc.Interval)
+	}
+	if sc.Datapoints < 2 {
+		errs.add(field+".datapoints", "must be at least 2, got %d", sc.Datapoints)
+	}
+}
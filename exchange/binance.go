@@ -0,0 +1,360 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"trading/binance"
+
+	binLib "github.com/adshao/go-binance/v2"
+)
+
+// Binance is the exchange the bot was written for
+type Binance struct{}
+
+func NewBinance() *Binance {
+	return &Binance{}
+}
+
+func parseFloat(value string) float64 {
+	number, _ := strconv.ParseFloat(value, 64)
+	return number
+}
+
+// the average price of what was executed, the order price until then
+func averagePrice(price, executed, quoteQuantity string) float64 {
+	if e := parseFloat(executed); e > 0 && parseFloat(quoteQuantity) > 0 {
+		return parseFloat(quoteQuantity) / e
+	}
+	return parseFloat(price)
+}
+
+func (b *Binance) Name() string {
+	return "binance"
+}
+
+func (b *Binance) PriceLatest(symbol string) (float64, error) {
+	return binance.PriceLatest(symbol)
+}
+
+func (b *Binance) Prices(symbols []string) (map[string]float64, error) {
+	return binance.GetSymbolPrices(symbols)
+}
+
+func (b *Binance) Tickers() ([]Ticker, error) {
+	stats := binance.GetSymbolStats()
+	if stats == nil {
+		return nil, fmt.Errorf("could not read the binance 24 hour tickers")
+	}
+	tickers := make([]Ticker, 0, len(stats))
+	for _, s := range stats {
+		tickers = append(tickers, Ticker{
+			Symbol:             s.Symbol,
+			LastPrice:          parseFloat(s.LastPrice),
+			PriceChangePercent: s.PriceChangePercent,
+			HighPrice:          parseFloat(s.HighPrice),
+			LowPrice:           parseFloat(s.LowPrice),
+			BidPrice:           parseFloat(s.BidPrice),
+			AskPrice:           parseFloat(s.AskPrice),
+			Volume:             parseFloat(s.Volume),
+			QuoteVolume:        parseFloat(s.QuoteVolume),
+			Count:              s.Count,
+		})
+	}
+	return tickers, nil
+}
+
+func (b *Binance) Klines(request KlineRequest) ([]Kline, error) {
+	service := binance.GetClient().NewKlinesService().Symbol(request.Symbol).Interval(request.Interval)
+	if request.StartTime > 0 {
+		service.StartTime(request.StartTime)
+	}
+	if request.EndTime > 0 {
+		service.EndTime(request.EndTime)
+	}
+	if request.Limit > 0 {
+		service.Limit(request.Limit)
+	}
+	data, err := service.Do(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	klines := make([]Kline, 0, len(data))
+	for _, k := range data {
+		klines = append(klines, Kline{
+			OpenTime:                 k.OpenTime,
+			CloseTime:                k.CloseTime,
+			Open:                     parseFloat(k.Open),
+			High:                     parseFloat(k.High),
+			Low:                      parseFloat(k.Low),
+			Close:                    parseFloat(k.Close),
+			Volume:                   parseFloat(k.Volume),
+			QuoteAssetVolume:         parseFloat(k.QuoteAssetVolume),
+			TakerBuyBaseAssetVolume:  parseFloat(k.TakerBuyBaseAssetVolume),
+			TakerBuyQuoteAssetVolume: parseFloat(k.TakerBuyQuoteAssetVolume),
+			TradeNum:                 k.TradeNum,
+		})
+	}
+	return klines, nil
+}
+
+func (b *Binance) StreamKlines(symbols []string, interval string, handler func(KlineEvent), errHandler func(error)) (done, stop chan struct{}, err error) {
+	pairs := map[string]string{}
+	for _, symbol := range symbols {
+		pairs[symbol] = interval
+	}
+	return binLib.WsCombinedKlineServe(pairs, func(event *binLib.WsKlineEvent) {
+		k := event.Kline
+		handler(KlineEvent{
+			Symbol:   event.Symbol,
+			Interval: k.Interval,
+			Final:    k.IsFinal,
+			Kline: Kline{
+				OpenTime:                 k.StartTime,
+				CloseTime:                k.EndTime,
+				Open:                     parseFloat(k.Open),
+				High:                     parseFloat(k.High),
+				Low:                      parseFloat(k.Low),
+				Close:                    parseFloat(k.Close),
+				Volume:                   parseFloat(k.Volume),
+				QuoteAssetVolume:         parseFloat(k.QuoteVolume),
+				TakerBuyBaseAssetVolume:  parseFloat(k.ActiveBuyVolume),
+				TakerBuyQuoteAssetVolume: parseFloat(k.ActiveBuyQuoteVolume),
+				TradeNum:                 k.TradeNum,
+			},
+		})
+	}, errHandler)
+}
+
+func (b *Binance) ExchangeInfo() (*ExchangeInfo, error) {
+	data, err := binance.GetClient().NewExchangeInfoService().Do(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	info := &ExchangeInfo{ServerTime: data.ServerTime}
+	for _, s := range data.Symbols {
+		info.Symbols = append(info.Symbols, SymbolInfo{
+			Symbol:               s.Symbol,
+			Status:               s.Status,
+			BaseAsset:            s.BaseAsset,
+			QuoteAsset:           s.QuoteAsset,
+			IsSpotTradingAllowed: s.IsSpotTradingAllowed,
+			Filters:              s.Filters,
+		})
+	}
+	return info, nil
+}
+
+func (b *Binance) TradeFee(symbol string) (Fee, error) {
+	data, err := binance.GetClient().NewTradeFeeService().Symbol(symbol).Do(context.Background())
+	if err != nil {
+		return Fee{}, err
+	}
+	if len(data) == 0 {
+		return Fee{}, fmt.Errorf("no trade fee for %s", symbol)
+	}
+	return Fee{
+		Symbol: data[0].Symbol,
+		Maker:  parseFloat(data[0].MakerCommission),
+		Taker:  parseFloat(data[0].TakerCommission),
+	}, nil
+}
+
+func (b *Binance) Balances() ([]Balance, error) {
+	account, err := binance.GetClient().NewGetAccountService().Do(context.Background(), binLib.WithRecvWindow(60000))
+	if err != nil {
+		return nil, err
+	}
+	balances := make([]Balance, 0, len(account.Balances))
+	for _, balance := range account.Balances {
+		balances = append(balances, Balance{
+			Asset:  balance.Asset,
+			Free:   parseFloat(balance.Free),
+			Locked: parseFloat(balance.Locked),
+		})
+	}
+	return balances, nil
+}
+
+func (b *Binance) request(request OrderRequest) binance.OrderRequest {
+	return binance.OrderRequest{
+		Symbol:         request.Symbol,
+		Side:           request.Side,
+		Type:           binLib.OrderType(request.Type),
+		TimeInForce:    binLib.TimeInForceType(request.TimeInForce),
+		Quantity:       request.Quantity,
+		Price:          request.Price,
+		StopPrice:      request.StopPrice,
+		StopLimitPrice: request.StopLimitPrice,
+	}
+}
+
+func (b *Binance) PlaceOrder(request OrderRequest) (*Order, error) {
+	var response *binLib.CreateOrderResponse
+	var err error
+	if request.Type == "" || binLib.OrderType(request.Type) == binLib.OrderTypeMarket {
+		response, err = binance.CreateOrder(request.Symbol, request.Quantity, request.Side, binLib.OrderTypeMarket)
+	} else {
+		response, err = binance.CreateLimitOrder(b.request(request))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	order := &Order{
+		Symbol:           response.Symbol,
+		OrderId:          response.OrderID,
+		ClientOrderId:    response.ClientOrderID,
+		Side:             string(response.Side),
+		Type:             string(response.Type),
+		TimeInForce:      string(response.TimeInForce),
+		Status:           OrderStatus(response.Status),
+		Price:            averagePrice(response.Price, response.ExecutedQuantity, response.CummulativeQuoteQuantity),
+		StopPrice:        request.StopPrice,
+		OrigQuantity:     parseFloat(response.OrigQuantity),
+		ExecutedQuantity: parseFloat(response.ExecutedQuantity),
+		QuoteQuantity:    parseFloat(response.CummulativeQuoteQuantity),
+		Time:             response.TransactTime,
+	}
+	for _, fill := range response.Fills {
+		order.Fills = append(order.Fills, Fill{
+			TradeId:         int64(fill.TradeID),
+			Price:           parseFloat(fill.Price),
+			Quantity:        parseFloat(fill.Quantity),
+			Commission:      parseFloat(fill.Commission),
+			CommissionAsset: fill.CommissionAsset,
+		})
+	}
+	return order, nil
+}
+
+func (b *Binance) PlaceOCOOrder(request OrderRequest) ([]*Order, error) {
+	response, err := binance.CreateOCOOrder(b.request(request))
+	if err != nil {
+		return nil, err
+	}
+	orders := []*Order{}
+	for _, report := range response.OrderReports {
+		orders = append(orders, &Order{
+			Symbol:           report.Symbol,
+			OrderId:          report.OrderID,
+			ClientOrderId:    report.ClientOrderID,
+			Side:             string(report.Side),
+			Type:             string(report.Type),
+			TimeInForce:      string(report.TimeInForce),
+			Status:           OrderStatus(report.Status),
+			Price:            averagePrice(report.Price, report.ExecutedQuantity, report.CummulativeQuoteQuantity),
+			StopPrice:        parseFloat(report.StopPrice),
+			OrigQuantity:     parseFloat(report.OrigQuantity),
+			ExecutedQuantity: parseFloat(report.ExecutedQuantity),
+			QuoteQuantity:    parseFloat(report.CummulativeQuoteQuantity),
+			Time:             report.TransactionTime,
+		})
+	}
+	if len(orders) == 0 {
+		// only the ids of the orders were returned
+		for _, o := range response.Orders {
+			orders = append(orders, &Order{Symbol: o.Symbol, OrderId: o.OrderID, ClientOrderId: o.ClientOrderID})
+		}
+	}
+	return orders, nil
+}
+
+func toOrder(o *binLib.Order) *Order {
+	updated := o.UpdateTime
+	if updated == 0 {
+		updated = o.Time
+	}
+	return &Order{
+		Symbol:           o.Symbol,
+		OrderId:          o.OrderID,
+		ClientOrderId:    o.ClientOrderID,
+		Side:             string(o.Side),
+		Type:             string(o.Type),
+		TimeInForce:      string(o.TimeInForce),
+		Status:           OrderStatus(o.Status),
+		Price:            averagePrice(o.Price, o.ExecutedQuantity, o.CummulativeQuoteQuantity),
+		StopPrice:        parseFloat(o.StopPrice),
+		OrigQuantity:     parseFloat(o.OrigQuantity),
+		ExecutedQuantity: parseFloat(o.ExecutedQuantity),
+		QuoteQuantity:    parseFloat(o.CummulativeQuoteQuantity),
+		Time:             updated,
+	}
+}
+
+func (b *Binance) GetOrder(symbol string, orderId int64) (*Order, error) {
+	o, err := binance.GetOrder(symbol, orderId)
+	if err != nil {
+		return nil, err
+	}
+	return toOrder(o), nil
+}
+
+func (b *Binance) OrderHistory(symbol string) ([]*Order, error) {
+	history, err := binance.GetClient().NewListOrdersService().Symbol(symbol).Do(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	orders := make([]*Order, 0, len(history))
+	for _, o := range history {
+		orders = append(orders, toOrder(o))
+	}
+	return orders, nil
+}
+
+func (b *Binance) CancelOrder(symbol string, orderId int64) error {
+	return binance.CancelOrder(symbol, orderId)
+}
+
+func (b *Binance) StreamPrices(symbols []string, handler func(PriceEvent), errHandler func(error)) (done, stop chan struct{}, err error) {
+	return binLib.WsCombinedMarketStatServe(symbols, func(event *binLib.WsMarketStatEvent) {
+		handler(PriceEvent{Symbol: event.Symbol, Price: parseFloat(event.LastPrice), Time: event.Time})
+	}, errHandler)
+}
+
+func priceLevels(levels []binLib.Bid) []PriceLevel {
+	parsed := make([]PriceLevel, 0, len(levels))
+	for _, level := range levels {
+		parsed = append(parsed, PriceLevel{Price: parseFloat(level.Price), Quantity: parseFloat(level.Quantity)})
+	}
+	return parsed
+}
+
+func (b *Binance) Depth(symbol string, limit int) (*Depth, error) {
+	depth, err := binance.GetClient().NewDepthService().Symbol(symbol).Limit(limit).Do(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return &Depth{
+		Symbol:       symbol,
+		LastUpdateId: depth.LastUpdateID,
+		Bids:         priceLevels(depth.Bids),
+		Asks:         priceLevels(depth.Asks),
+	}, nil
+}
+
+func (b *Binance) StreamDepth(symbols []string, handler func(DepthEvent), errHandler func(error)) (done, stop chan struct{}, err error) {
+	return binLib.WsCombinedDepthServe100Ms(symbols, func(event *binLib.WsDepthEvent) {
+		handler(DepthEvent{
+			Symbol:        event.Symbol,
+			FirstUpdateId: event.FirstUpdateID,
+			LastUpdateId:  event.LastUpdateID,
+			Bids:          priceLevels(event.Bids),
+			Asks:          priceLevels(event.Asks),
+			Time:          event.Time,
+		})
+	}, errHandler)
+}
+
+func (b *Binance) StreamBookTickers(symbols []string, handler func(BookTicker), errHandler func(error)) (done, stop chan struct{}, err error) {
+	return binLib.WsCombinedBookTickerServe(symbols, func(event *binLib.WsBookTickerEvent) {
+		handler(BookTicker{
+			Symbol:      event.Symbol,
+			UpdateId:    event.UpdateID,
+			BidPrice:    parseFloat(event.BestBidPrice),
+			BidQuantity: parseFloat(event.BestBidQty),
+			AskPrice:    parseFloat(event.BestAskPrice),
+			AskQuantity: parseFloat(event.BestAskQty),
+		})
+	}, errHandler)
+}
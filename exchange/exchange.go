@@ -0,0 +1,84 @@
+// Package exchange is the boundary between the bot and the market it trades
+// on. Traders, accounts and streams only use an Exchange so that another
+// market can replace Binance without changing them
+package exchange
+
+import (
+	"fmt"
+	"sync"
+	"trading/utils"
+)
+
+type Exchange interface {
+	Name() string
+	PriceLatest(symbol string) (float64, error)
+	// latest prices of symbols by symbol
+	Prices(symbols []string) (map[string]float64, error)
+	// 24 hour statistics of every symbol, the biggest price change first
+	Tickers() ([]Ticker, error)
+	Klines(request KlineRequest) ([]Kline, error)
+	// streams the candles of interval of symbols while they form
+	StreamKlines(symbols []string, interval string, handler func(KlineEvent), errHandler func(error)) (done, stop chan struct{}, err error)
+	ExchangeInfo() (*ExchangeInfo, error)
+	TradeFee(symbol string) (Fee, error)
+	Balances() ([]Balance, error)
+	PlaceOrder(request OrderRequest) (*Order, error)
+	// places a limit order and a stop loss limit order where the fill
+	// of one cancels the other
+	PlaceOCOOrder(request OrderRequest) ([]*Order, error)
+	GetOrder(symbol string, orderId int64) (*Order, error)
+	// orders of symbol on the account whatever their status, the oldest first
+	OrderHistory(symbol string) ([]*Order, error)
+	CancelOrder(symbol string, orderId int64) error
+	// streams the last price of symbols until stop is sent to, done is
+	// closed when the stream ends
+	StreamPrices(symbols []string, handler func(PriceEvent), errHandler func(error)) (done, stop chan struct{}, err error)
+	// the limit best bids and asks of symbol
+	Depth(symbol string, limit int) (*Depth, error)
+	// streams the changes to the order book of symbols
+	StreamDepth(symbols []string, handler func(DepthEvent), errHandler func(error)) (done, stop chan struct{}, err error)
+	// streams the best bid and ask of symbols whenever they change
+	StreamBookTickers(symbols []string, handler func(BookTicker), errHandler func(error)) (done, stop chan struct{}, err error)
+}
+
+var (
+	current Exchange
+	lock    sync.RWMutex
+)
+
+// Get returns the exchange in use, Binance when none was set
+func Get() Exchange {
+	lock.RLock()
+	e := current
+	lock.RUnlock()
+	if e != nil {
+		return e
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if current == nil {
+		current = NewBinance()
+	}
+	return current
+}
+
+// Use replaces the exchange in use and returns the previous one
+func Use(e Exchange) Exchange {
+	lock.Lock()
+	defer lock.Unlock()
+	previous := current
+	current = e
+	return previous
+}
+
+// GetPriceLatest is the latest price of symbol on the exchange in use, zero
+// when it could not be fetched
+func GetPriceLatest(symbol string) float64 {
+	price, err := Get().PriceLatest(symbol)
+	if err != nil {
+		utils.LogError(err, fmt.Sprintf("<Exchange>: could not get the latest %s price", symbol))
+		return 0
+	}
+	return price
+}
@@ -0,0 +1,51 @@
+package exchange
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeExchange struct {
+	Exchange
+	prices map[string]float64
+}
+
+func (f *fakeExchange) Name() string {
+	return "fake"
+}
+
+func (f *fakeExchange) PriceLatest(symbol string) (float64, error) {
+	price, exist := f.prices[symbol]
+	if !exist {
+		return 0, fmt.Errorf("unknown symbol %s", symbol)
+	}
+	return price, nil
+}
+
+func TestUse(t *testing.T) {
+	fake := &fakeExchange{prices: map[string]float64{"BTCUSDT": 100}}
+	previous := Use(fake)
+	defer Use(previous)
+
+	assert.Equal(t, "fake", Get().Name())
+	assert.Equal(t, 100.0, GetPriceLatest("BTCUSDT"))
+	assert.Equal(t, 0.0, GetPriceLatest("ETHUSDT"), "a failed price is zero")
+
+	assert.Equal(t, fake, Use(nil))
+	assert.Equal(t, "binance", Get().Name(), "binance is used when none is set")
+}
+
+func TestOrderStatus(t *testing.T) {
+	assert.False(t, OrderStatusNew.IsComplete())
+	assert.False(t, OrderStatusPartiallyFilled.IsComplete())
+	assert.True(t, OrderStatusFilled.IsComplete())
+	assert.True(t, OrderStatusCanceled.IsComplete())
+	assert.True(t, OrderStatusExpired.IsComplete())
+}
+
+func TestAveragePrice(t *testing.T) {
+	assert.Equal(t, 105.0, averagePrice("0", "2", "210"), "price of the fills")
+	assert.Equal(t, 99.5, averagePrice("99.5", "0", "0"), "limit price until filled")
+}
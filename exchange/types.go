@@ -0,0 +1,180 @@
+package exchange
+
+type Balance struct {
+	Asset  string
+	Free   float64
+	Locked float64
+}
+
+type Fee struct {
+	Symbol string
+	Maker  float64
+	Taker  float64
+}
+
+type Ticker struct {
+	Symbol             string
+	LastPrice          float64
+	PriceChangePercent float64
+	HighPrice          float64
+	LowPrice           float64
+	BidPrice           float64
+	AskPrice           float64
+	Volume             float64
+	QuoteVolume        float64
+	// number of trades
+	Count int64
+}
+
+type KlineRequest struct {
+	Symbol   string
+	Interval string
+	// milliseconds, ignored when zero
+	StartTime int64
+	EndTime   int64
+	Limit     int
+}
+
+type Kline struct {
+	OpenTime                 int64
+	CloseTime                int64
+	Open                     float64
+	High                     float64
+	Low                      float64
+	Close                    float64
+	Volume                   float64
+	QuoteAssetVolume         float64
+	TakerBuyBaseAssetVolume  float64
+	TakerBuyQuoteAssetVolume float64
+	TradeNum                 int64
+}
+
+// KlineEvent is an update of the current candle of a symbol, Final is set
+// once the candle closed
+type KlineEvent struct {
+	Symbol   string
+	Interval string
+	Kline    Kline
+	Final    bool
+}
+
+type PriceEvent struct {
+	Symbol string
+	Price  float64
+	// milliseconds
+	Time int64
+}
+
+// PriceLevel is a price of the order book and the quantity offered at it
+type PriceLevel struct {
+	Price    float64
+	Quantity float64
+}
+
+// Depth is a snapshot of the order book, the best bids and asks first
+type Depth struct {
+	Symbol       string
+	LastUpdateId int64
+	Bids         []PriceLevel
+	Asks         []PriceLevel
+}
+
+// DepthEvent holds the levels that changed between FirstUpdateId and
+// LastUpdateId, a level with no quantity left the book
+type DepthEvent struct {
+	Symbol        string
+	FirstUpdateId int64
+	LastUpdateId  int64
+	Bids          []PriceLevel
+	Asks          []PriceLevel
+	// milliseconds
+	Time int64
+}
+
+// BookTicker is the best bid and ask of a symbol
+type BookTicker struct {
+	Symbol      string
+	UpdateId    int64
+	BidPrice    float64
+	BidQuantity float64
+	AskPrice    float64
+	AskQuantity float64
+}
+
+// SymbolInfo keeps the names of the Binance exchange info so that stored
+// exchange info is read as it is
+type SymbolInfo struct {
+	Symbol               string                   `json:"symbol"`
+	Status               string                   `json:"status"`
+	BaseAsset            string                   `json:"baseAsset"`
+	QuoteAsset           string                   `json:"quoteAsset"`
+	IsSpotTradingAllowed bool                     `json:"isSpotTradingAllowed"`
+	Filters              []map[string]interface{} `json:"filters"`
+}
+
+type ExchangeInfo struct {
+	ServerTime int64        `json:"serverTime"`
+	Symbols    []SymbolInfo `json:"symbols"`
+}
+
+type OrderStatus string
+
+const (
+	OrderStatusNew             OrderStatus = "NEW"
+	OrderStatusPartiallyFilled OrderStatus = "PARTIALLY_FILLED"
+	OrderStatusFilled          OrderStatus = "FILLED"
+	OrderStatusCanceled        OrderStatus = "CANCELED"
+	OrderStatusRejected        OrderStatus = "REJECTED"
+	OrderStatusExpired         OrderStatus = "EXPIRED"
+)
+
+// IsComplete is true when the order can no longer be filled
+func (s OrderStatus) IsComplete() bool {
+	switch s {
+	case OrderStatusFilled, OrderStatusCanceled, OrderStatusRejected, OrderStatusExpired:
+		return true
+	}
+	return false
+}
+
+// OrderRequest is an order to place, the prices are ignored by market
+// orders and the stop prices by orders that are not stopped
+type OrderRequest struct {
+	Symbol string
+	// BUY or SELL
+	Side string
+	// MARKET, LIMIT, STOP_LOSS_LIMIT, TAKE_PROFIT_LIMIT or OCO
+	Type           string
+	TimeInForce    string
+	Quantity       float64
+	Price          float64
+	StopPrice      float64
+	StopLimitPrice float64
+}
+
+type Fill struct {
+	TradeId         int64
+	Price           float64
+	Quantity        float64
+	Commission      float64
+	CommissionAsset string
+}
+
+type Order struct {
+	Symbol        string
+	OrderId       int64
+	ClientOrderId string
+	Side          string
+	Type          string
+	TimeInForce   string
+	Status        OrderStatus
+	// the average price of the fills once the order executed
+	Price            float64
+	StopPrice        float64
+	OrigQuantity     float64
+	ExecutedQuantity float64
+	QuoteQuantity    float64
+	// milliseconds
+	Time  int64
+	Fills []Fill
+}
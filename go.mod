@@ -6,6 +6,7 @@ require (
 	github.com/adshao/go-binance/v2 v2.4.2
 	github.com/davecgh/go-spew v1.1.1
 	github.com/joho/godotenv v1.5.1
+	go.etcd.io/bbolt v1.3.7
 )
 
 require (
@@ -17,7 +18,6 @@ require (
 	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
 	github.com/modern-go/reflect2 v1.0.2 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
 require (
@@ -29,4 +29,5 @@ require (
 	github.com/stretchr/testify v1.8.4
 	github.com/tzneal/gopicotts v0.0.0-20170517233132-149cb8d03413
 	golang.org/x/sys v0.10.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,58 @@
+package indicators
+
+import "math"
+
+// ATR is the average true range of the candles with Wilder's smoothing
+type ATR struct {
+	period    int
+	count     int
+	value     float64
+	lastClose float64
+}
+
+func NewATR(period int) *ATR {
+	if period < 1 {
+		period = 1
+	}
+	return &ATR{period: period}
+}
+
+// the range of candle including the gap from the previous close
+func (a *ATR) trueRange(candle Candle) float64 {
+	if a.count == 0 {
+		return candle.High - candle.Low
+	}
+	return math.Max(candle.High-candle.Low, math.Max(math.Abs(candle.High-a.lastClose), math.Abs(candle.Low-a.lastClose)))
+}
+
+func (a *ATR) Add(candle Candle) {
+	tr := a.trueRange(candle)
+	period := float64(a.period)
+	if a.count < a.period {
+		a.value += tr / period
+	} else {
+		a.value = (a.value*(period-1) + tr) / period
+	}
+	a.count++
+	a.lastClose = candle.Close
+}
+
+func (a *ATR) Ready() bool {
+	return a.count >= a.period
+}
+
+func (a *ATR) Value() float64 {
+	if !a.Ready() && a.count > 0 {
+		// the average of the ranges seen so far
+		return a.value * float64(a.period) / float64(a.count)
+	}
+	return a.value
+}
+
+// Percent is the range in percent of the last close
+func (a *ATR) Percent() float64 {
+	if a.lastClose == 0 {
+		return 0
+	}
+	return a.Value() / a.lastClose * 100
+}
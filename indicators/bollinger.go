@@ -0,0 +1,66 @@
+package indicators
+
+import "math"
+
+// Bollinger bands are the simple average of the closes and the bands
+// stdDev standard deviations above and below it
+type Bollinger struct {
+	stdDev  float64
+	closes  *window
+	squares *window
+}
+
+func NewBollinger(period int, stdDev float64) *Bollinger {
+	if period < 1 {
+		period = 1
+	}
+	return &Bollinger{stdDev: stdDev, closes: newWindow(period), squares: newWindow(period)}
+}
+
+func (b *Bollinger) Add(candle Candle) {
+	b.Update(candle.Close)
+}
+
+func (b *Bollinger) Update(value float64) {
+	b.closes.add(value)
+	b.squares.add(value * value)
+}
+
+func (b *Bollinger) Ready() bool {
+	return b.closes.full
+}
+
+func (b *Bollinger) Middle() float64 {
+	return b.closes.mean()
+}
+
+func (b *Bollinger) deviation() float64 {
+	mean := b.closes.mean()
+	// rounding can leave a tiny negative variance for flat prices
+	return math.Sqrt(math.Max(0, b.squares.mean()-mean*mean))
+}
+
+func (b *Bollinger) Upper() float64 {
+	return b.Middle() + b.stdDev*b.deviation()
+}
+
+func (b *Bollinger) Lower() float64 {
+	return b.Middle() - b.stdDev*b.deviation()
+}
+
+// Width of the bands in percent of the middle
+func (b *Bollinger) Width() float64 {
+	if b.Middle() == 0 {
+		return 0
+	}
+	return (b.Upper() - b.Lower()) / b.Middle() * 100
+}
+
+// PercentB is where price is within the bands, 0 at the lower band and 1 at the upper band
+func (b *Bollinger) PercentB(price float64) float64 {
+	width := b.Upper() - b.Lower()
+	if width == 0 {
+		return 0.5
+	}
+	return (price - b.Lower()) / width
+}
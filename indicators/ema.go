@@ -0,0 +1,81 @@
+package indicators
+
+// EMA is the exponential moving average of the closes, it is seeded with
+// the simple average of the first period closes
+type EMA struct {
+	period int
+	alpha  float64
+	seed   *window
+	value  float64
+}
+
+func NewEMA(period int) *EMA {
+	if period < 1 {
+		period = 1
+	}
+	return &EMA{period: period, alpha: 2 / float64(period+1), seed: newWindow(period)}
+}
+
+func (e *EMA) Add(candle Candle) {
+	e.Update(candle.Close)
+}
+
+// Update adds value and returns the average
+func (e *EMA) Update(value float64) float64 {
+	if !e.seed.full {
+		e.seed.add(value)
+		e.value = e.seed.mean()
+		return e.value
+	}
+	e.value += e.alpha * (value - e.value)
+	return e.value
+}
+
+func (e *EMA) Ready() bool {
+	return e.seed.full
+}
+
+func (e *EMA) Value() float64 {
+	return e.value
+}
+
+// MACD is the difference between a fast and a slow EMA of the closes and
+// the EMA of that difference as its signal
+type MACD struct {
+	fast   *EMA
+	slow   *EMA
+	signal *EMA
+}
+
+func NewMACD(fast, slow, signal int) *MACD {
+	return &MACD{fast: NewEMA(fast), slow: NewEMA(slow), signal: NewEMA(signal)}
+}
+
+func (m *MACD) Add(candle Candle) {
+	m.Update(candle.Close)
+}
+
+func (m *MACD) Update(value float64) {
+	m.fast.Update(value)
+	m.slow.Update(value)
+	if m.slow.Ready() {
+		m.signal.Update(m.Value())
+	}
+}
+
+func (m *MACD) Ready() bool {
+	return m.signal.Ready()
+}
+
+func (m *MACD) Value() float64 {
+	return m.fast.Value() - m.slow.Value()
+}
+
+func (m *MACD) Signal() float64 {
+	return m.signal.Value()
+}
+
+// Histogram is above zero while the MACD is above its signal
+func (m *MACD) Histogram() float64 {
+	return m.Value() - m.Signal()
+}
@@ -0,0 +1,165 @@
+// Package indicators computes technical indicators one candle at a time so
+// that they can follow klines and live prices without recomputing history
+package indicators
+
+import (
+	"trading/kline"
+	"trading/stream"
+)
+
+// Candle is a point the indicators are updated with, a live tick is a
+// candle whose prices are all the tick price and that has no volume
+type Candle struct {
+	Open   float64
+	High   float64
+	Low    float64
+	Close  float64
+	Volume float64
+	// milliseconds
+	Time int64
+}
+
+func FromKline(k kline.KlineData) Candle {
+	return Candle{Open: k.Open, High: k.High, Low: k.Low, Close: k.Close, Volume: k.Volume, Time: k.CloseTime}
+}
+
+func FromKlines(klines []kline.KlineData) []Candle {
+	candles := make([]Candle, 0, len(klines))
+	for _, k := range klines {
+		candles = append(candles, FromKline(k))
+	}
+	return candles
+}
+
+func FromTick(data stream.SymbolPriceData) Candle {
+	return Candle{Open: data.Price, High: data.Price, Low: data.Price, Close: data.Price}
+}
+
+type Indicator interface {
+	Add(candle Candle)
+	// Ready is true once enough candles were added for the value to be used
+	Ready() bool
+}
+
+// window keeps the last size values added
+type window struct {
+	values []float64
+	next   int
+	full   bool
+	sum    float64
+}
+
+func newWindow(size int) *window {
+	return &window{values: make([]float64, size)}
+}
+
+// add returns the value that left the window, zero until it is full
+func (w *window) add(value float64) float64 {
+	out := w.values[w.next]
+	w.values[w.next] = value
+	w.sum += value - out
+	w.next = (w.next + 1) % len(w.values)
+	if w.next == 0 {
+		w.full = true
+	}
+	return out
+}
+
+func (w *window) count() int {
+	if w.full {
+		return len(w.values)
+	}
+	return w.next
+}
+
+func (w *window) mean() float64 {
+	if w.count() == 0 {
+		return 0
+	}
+	return w.sum / float64(w.count())
+}
+
+// Periods of the indicators in a Set
+type Periods struct {
+	EMA             int
+	RSI             int
+	MACDFast        int
+	MACDSlow        int
+	MACDSignal      int
+	Bollinger       int
+	BollingerStdDev float64
+	ATR             int
+}
+
+var DefaultPeriods = Periods{
+	EMA:             20,
+	RSI:             14,
+	MACDFast:        12,
+	MACDSlow:        26,
+	MACDSignal:      9,
+	Bollinger:       20,
+	BollingerStdDev: 2,
+	ATR:             14,
+}
+
+// Set updates every indicator with the same candles
+type Set struct {
+	EMA       *EMA
+	RSI       *RSI
+	MACD      *MACD
+	Bollinger *Bollinger
+	ATR       *ATR
+	VWAP      *VWAP
+	OBV       *OBV
+	last      Candle
+}
+
+func NewSet(periods Periods) *Set {
+	return &Set{
+		EMA:       NewEMA(periods.EMA),
+		RSI:       NewRSI(periods.RSI),
+		MACD:      NewMACD(periods.MACDFast, periods.MACDSlow, periods.MACDSignal),
+		Bollinger: NewBollinger(periods.Bollinger, periods.BollingerStdDev),
+		ATR:       NewATR(periods.ATR),
+		VWAP:      NewVWAP(),
+		OBV:       NewOBV(),
+	}
+}
+
+// NewKlineSet is a set with the default periods updated with klines
+func NewKlineSet(klines []kline.KlineData) *Set {
+	set := NewSet(DefaultPeriods)
+	for _, candle := range FromKlines(klines) {
+		set.Add(candle)
+	}
+	return set
+}
+
+func (s *Set) Add(candle Candle) {
+	for _, indicator := range s.indicators() {
+		indicator.Add(candle)
+	}
+	s.last = candle
+}
+
+func (s *Set) AddTick(data stream.SymbolPriceData) {
+	s.Add(FromTick(data))
+}
+
+func (s *Set) Ready() bool {
+	for _, indicator := range s.indicators() {
+		if !indicator.Ready() {
+			return false
+		}
+	}
+	return true
+}
+
+// Last is the last candle added
+func (s *Set) Last() Candle {
+	return s.last
+}
+
+func (s *Set) indicators() []Indicator {
+	return []Indicator{s.EMA, s.RSI, s.MACD, s.Bollinger, s.ATR, s.VWAP, s.OBV}
+}
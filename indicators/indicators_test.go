@@ -0,0 +1,109 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+	"trading/kline"
+	"trading/stream"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEMA(t *testing.T) {
+	ema := NewEMA(3)
+	ema.Update(1)
+	ema.Update(2)
+	assert.False(t, ema.Ready())
+	assert.Equal(t, 2.0, ema.Update(3), "seeded with the simple average")
+	assert.True(t, ema.Ready())
+	assert.Equal(t, 3.0, ema.Update(4))
+	assert.Equal(t, 4.0, ema.Update(5))
+}
+
+func TestRSI(t *testing.T) {
+	rsi := NewRSI(2)
+	rsi.Update(1)
+	rsi.Update(2)
+	assert.False(t, rsi.Ready())
+	assert.Equal(t, 50.0, rsi.Value(), "neutral until ready")
+	assert.Equal(t, 100.0, rsi.Update(3), "only gains")
+	assert.Equal(t, 50.0, rsi.Update(2), "gains and losses are even")
+}
+
+func TestMACD(t *testing.T) {
+	macd := NewMACD(2, 3, 2)
+	for i := 0; i < 3; i++ {
+		macd.Update(10)
+	}
+	assert.False(t, macd.Ready())
+	macd.Update(10)
+	assert.True(t, macd.Ready())
+	assert.Equal(t, 0.0, macd.Histogram(), "flat prices have no momentum")
+
+	macd.Update(20)
+	assert.Greater(t, macd.Value(), 0.0, "the fast average leads a rise")
+	assert.Greater(t, macd.Histogram(), 0.0)
+}
+
+func TestBollinger(t *testing.T) {
+	bollinger := NewBollinger(4, 2)
+	for _, price := range []float64{1, 2, 3, 4} {
+		bollinger.Update(price)
+	}
+	assert.True(t, bollinger.Ready())
+	assert.Equal(t, 2.5, bollinger.Middle())
+	assert.InDelta(t, 2.5+2*math.Sqrt(1.25), bollinger.Upper(), 1e-9)
+	assert.InDelta(t, 2.5-2*math.Sqrt(1.25), bollinger.Lower(), 1e-9)
+	assert.InDelta(t, 0.5, bollinger.PercentB(2.5), 1e-9)
+
+	bollinger.Update(5)
+	assert.Equal(t, 3.5, bollinger.Middle(), "the oldest price leaves the window")
+}
+
+func TestATR(t *testing.T) {
+	atr := NewATR(2)
+	atr.Add(Candle{High: 10, Low: 8, Close: 9})
+	assert.False(t, atr.Ready())
+	assert.Equal(t, 2.0, atr.Value())
+	atr.Add(Candle{High: 12, Low: 9, Close: 11})
+	assert.True(t, atr.Ready())
+	assert.Equal(t, 2.5, atr.Value())
+	atr.Add(Candle{High: 11, Low: 10, Close: 10})
+	assert.Equal(t, 1.75, atr.Value())
+	assert.Equal(t, 17.5, atr.Percent())
+}
+
+func TestVWAPAndOBV(t *testing.T) {
+	vwap := NewVWAP()
+	vwap.Add(Candle{High: 10, Low: 10, Close: 10, Volume: 1})
+	vwap.Add(Candle{High: 20, Low: 20, Close: 20, Volume: 3})
+	assert.Equal(t, 17.5, vwap.Value())
+
+	vwap.Reset()
+	vwap.Add(FromTick(stream.SymbolPriceData{Symbol: "BTCUSDT", Price: 10}))
+	vwap.Add(FromTick(stream.SymbolPriceData{Symbol: "BTCUSDT", Price: 20}))
+	assert.Equal(t, 15.0, vwap.Value(), "ticks weigh the same")
+
+	obv := NewOBV()
+	for _, c := range []Candle{{Close: 10, Volume: 5}, {Close: 11, Volume: 3}, {Close: 9, Volume: 2}, {Close: 9, Volume: 4}} {
+		obv.Add(c)
+	}
+	assert.Equal(t, 1.0, obv.Value())
+}
+
+func TestSet(t *testing.T) {
+	klines := []kline.KlineData{}
+	for i := 0; i < 40; i++ {
+		price := 100 + float64(i)
+		klines = append(klines, kline.KlineData{Open: price - 1, High: price + 1, Low: price - 2, Close: price, Volume: 10})
+	}
+	set := NewKlineSet(klines)
+	assert.True(t, set.Ready())
+	assert.Equal(t, 139.0, set.Last().Close)
+	assert.Equal(t, 100.0, set.RSI.Value(), "the price only rose")
+	assert.Greater(t, set.MACD.Value(), 0.0)
+	assert.InDelta(t, 3, set.ATR.Value(), 1e-9, "every candle ranges from the previous close to a point above it")
+
+	set.AddTick(stream.SymbolPriceData{Symbol: "BTCUSDT", Price: 120})
+	assert.Less(t, set.RSI.Value(), 100.0, "a live drop lowers the index")
+}
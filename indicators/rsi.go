@@ -0,0 +1,68 @@
+package indicators
+
+// RSI is the relative strength index of the closes with Wilder's smoothing
+type RSI struct {
+	period   int
+	count    int
+	previous float64
+	gain     float64
+	loss     float64
+}
+
+func NewRSI(period int) *RSI {
+	if period < 1 {
+		period = 1
+	}
+	return &RSI{period: period}
+}
+
+func (r *RSI) Add(candle Candle) {
+	r.Update(candle.Close)
+}
+
+// Update adds value and returns the index
+func (r *RSI) Update(value float64) float64 {
+	defer func() { r.previous = value }()
+	if r.count == 0 {
+		r.count++
+		return r.Value()
+	}
+
+	change := value - r.previous
+	gain, loss := 0.0, 0.0
+	if change > 0 {
+		gain = change
+	} else {
+		loss = -change
+	}
+
+	period := float64(r.period)
+	if r.count <= r.period {
+		// the first averages are simple averages
+		r.gain += gain / period
+		r.loss += loss / period
+	} else {
+		r.gain = (r.gain*(period-1) + gain) / period
+		r.loss = (r.loss*(period-1) + loss) / period
+	}
+	r.count++
+	return r.Value()
+}
+
+func (r *RSI) Ready() bool {
+	return r.count > r.period
+}
+
+// Value between 0 and 100, 50 until the index is ready
+func (r *RSI) Value() float64 {
+	if !r.Ready() {
+		return 50
+	}
+	if r.loss == 0 {
+		if r.gain == 0 {
+			return 50
+		}
+		return 100
+	}
+	return 100 - 100/(1+r.gain/r.loss)
+}
@@ -0,0 +1,71 @@
+package indicators
+
+// VWAP is the average typical price weighted by volume since it was created
+// or reset. Candles without volume, like live ticks, weigh one so that the
+// average of ticks is their simple average
+type VWAP struct {
+	value  float64
+	volume float64
+}
+
+func NewVWAP() *VWAP {
+	return &VWAP{}
+}
+
+func (v *VWAP) Add(candle Candle) {
+	weight := candle.Volume
+	if weight <= 0 {
+		weight = 1
+	}
+	typical := (candle.High + candle.Low + candle.Close) / 3
+	v.value += typical * weight
+	v.volume += weight
+}
+
+func (v *VWAP) Ready() bool {
+	return v.volume > 0
+}
+
+func (v *VWAP) Value() float64 {
+	if v.volume == 0 {
+		return 0
+	}
+	return v.value / v.volume
+}
+
+// Reset starts a new session
+func (v *VWAP) Reset() {
+	v.value, v.volume = 0, 0
+}
+
+// OBV is the on balance volume, the volume of a candle is added when it
+// closes higher and removed when it closes lower
+type OBV struct {
+	value     float64
+	lastClose float64
+	count     int
+}
+
+func NewOBV() *OBV {
+	return &OBV{}
+}
+
+func (o *OBV) Add(candle Candle) {
+	if o.count > 0 {
+		if candle.Close > o.lastClose {
+			o.value += candle.Volume
+		} else if candle.Close < o.lastClose {
+			o.value -= candle.Volume
+		}
+	}
+	o.lastClose = candle.Close
+	o.count++
+}
+
+func (o *OBV) Ready() bool {
+	return o.count > 1
+}
+
+func (o *OBV) Value() float64 {
+	return o.value
+}
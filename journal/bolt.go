@@ -0,0 +1,164 @@
+package journal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// records by their sequence
+	recordsBucket = []byte("records")
+	// sequences of the records by their time, by symbol and time and by
+	// config id and time
+	timeIndex   = []byte("time")
+	symbolIndex = []byte("symbol")
+	configIndex = []byte("config")
+)
+
+// boltStore keeps the records in a bolt database, queries read the index of
+// the narrowest field they select on within the time range they ask for
+type boltStore struct {
+	db *bolt.DB
+}
+
+func openBolt(filename string) (*boltStore, error) {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return nil, err
+	}
+	// an other process holding the database fails the open instead of waiting for it
+	db, err := bolt.Open(filename, 0644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{recordsBucket, timeIndex, symbolIndex, configIndex} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &boltStore{db: db}, nil
+}
+
+func sequenceKey(sequence int64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(sequence))
+	return key
+}
+
+// timeKey sorts by time and then by sequence, the sign bit is flipped so
+// times before 1970 sort before the ones after it
+func timeKey(t time.Time, sequence int64) []byte {
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano())^(1<<63))
+	binary.BigEndian.PutUint64(key[8:], uint64(sequence))
+	return key
+}
+
+// indexPrefix starts the keys of value in an index, the zero byte keeps a
+// value from matching the values it is the start of
+func indexPrefix(value string) []byte {
+	return append([]byte(value), 0)
+}
+
+func (b *boltStore) sequence() (int64, error) {
+	var sequence int64
+	err := b.db.View(func(tx *bolt.Tx) error {
+		if key, _ := tx.Bucket(recordsBucket).Cursor().Last(); key != nil {
+			sequence = int64(binary.BigEndian.Uint64(key))
+		}
+		return nil
+	})
+	return sequence, err
+}
+
+func (b *boltStore) put(r Record) error {
+	value, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	sequence, at := sequenceKey(r.Sequence), timeKey(r.Time, r.Sequence)
+	return b.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(recordsBucket).Put(sequence, value); err != nil {
+			return err
+		}
+		if err := tx.Bucket(timeIndex).Put(at, sequence); err != nil {
+			return err
+		}
+		if err := tx.Bucket(symbolIndex).Put(append(indexPrefix(r.Symbol.String()), at...), sequence); err != nil {
+			return err
+		}
+		return tx.Bucket(configIndex).Put(append(indexPrefix(r.ConfigId), at...), sequence)
+	})
+}
+
+func (b *boltStore) find(q Query) ([]Record, error) {
+	index, prefix := timeIndex, []byte{}
+	switch {
+	case q.ConfigId != "":
+		index, prefix = configIndex, indexPrefix(q.ConfigId)
+	case q.Symbol != "":
+		index, prefix = symbolIndex, indexPrefix(q.Symbol.String())
+	}
+	// the keys from start up to stop are read from the most recent
+	start := prefix
+	if !q.From.IsZero() {
+		start = append(append([]byte{}, prefix...), timeKey(q.From, 0)...)
+	}
+	var stop []byte
+	switch {
+	case !q.To.IsZero():
+		stop = append(append([]byte{}, prefix...), timeKey(q.To, 0)...)
+	case len(prefix) > 0:
+		// the first key after the keys of the value
+		stop = append(append([]byte{}, prefix[:len(prefix)-1]...), 1)
+	}
+
+	found := []Record{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		records := tx.Bucket(recordsBucket)
+		c := tx.Bucket(index).Cursor()
+		key, sequence := c.Last()
+		if stop != nil {
+			if key, sequence = c.Seek(stop); key == nil {
+				key, sequence = c.Last()
+			} else {
+				key, sequence = c.Prev()
+			}
+		}
+		for ; key != nil && bytes.Compare(key, start) >= 0; key, sequence = c.Prev() {
+			var r Record
+			if err := json.Unmarshal(records.Get(sequence), &r); err != nil {
+				return err
+			}
+			if !q.match(r) {
+				continue
+			}
+			found = append(found, r)
+			if q.Limit > 0 && len(found) == q.Limit {
+				break
+			}
+		}
+		return nil
+	})
+	// read from the most recent, returned from the oldest
+	for i, j := 0, len(found)-1; i < j; i, j = i+1, j-1 {
+		found[i], found[j] = found[j], found[i]
+	}
+	return found, err
+}
+
+func (b *boltStore) close() error {
+	return b.db.Close()
+}
@@ -0,0 +1,302 @@
+// Package journal records every executed order in an append only store.
+// A journal opened from a file keeps its records in an embedded bolt
+// database indexed by symbol, config id and time so queries only read the
+// records they return, jsonl is the format records are exported and
+// imported in.
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"trading/names"
+	"trading/utils"
+)
+
+// LockSnapshot is the state of the lock of a config when its order executed
+type LockSnapshot struct {
+	StopLimit             float64 `json:"stopLimit"`
+	Price                 float64 `json:"price"`
+	PretradePrice         float64 `json:"pretradePrice"`
+	AccrudGains           float64 `json:"accrudGains"`
+	IsRedemptionDue       bool    `json:"isRedemptionDue"`
+	IsRedemptionCandidate bool    `json:"isRedemptionCandidate"`
+	MinimumLockUnit       float64 `json:"minimumLockUnit"`
+	AbsoluteGrowth        float64 `json:"absoluteGrowth"`
+}
+
+func NewLockSnapshot(state names.LockState) LockSnapshot {
+	return LockSnapshot{
+		StopLimit:             state.StopLimit,
+		Price:                 state.Price,
+		PretradePrice:         state.PretradePrice,
+		AccrudGains:           state.AccrudGains,
+		IsRedemptionDue:       state.IsRedemptionIsDue,
+		IsRedemptionCandidate: state.IsRedemptionCandidate,
+		MinimumLockUnit:       state.MinimumLockUnit,
+		AbsoluteGrowth:        state.AbsoluteGrowth,
+	}
+}
+
+type Record struct {
+	Sequence      int64           `json:"sequence"`
+	Time          time.Time       `json:"time"`
+	ConfigId      string          `json:"configId"`
+	Symbol        names.Symbol    `json:"symbol"`
+	Side          names.TradeSide `json:"side"`
+	PretradePrice float64         `json:"pretradePrice"`
+	FillPrice     float64         `json:"fillPrice"`
+	Quantity      float64         `json:"quantity"`
+	Fee           float64         `json:"fee"`
+	FeeAsset      string          `json:"feeAsset"`
+	OrderId       int64           `json:"orderId"`
+	Status        string          `json:"status"`
+	Lock          LockSnapshot    `json:"lock"`
+}
+
+// Query selects records, empty fields are not used to filter
+type Query struct {
+	Symbol   names.Symbol
+	ConfigId string
+	Side     names.TradeSide
+	// inclusive start and exclusive end of the time range
+	From time.Time
+	To   time.Time
+	// the most recent records to return, 0 returns all
+	Limit int
+}
+
+func (q Query) match(r Record) bool {
+	if q.Symbol != "" && q.Symbol != r.Symbol {
+		return false
+	}
+	if q.ConfigId != "" && q.ConfigId != r.ConfigId {
+		return false
+	}
+	if q.Side != "" && q.Side != r.Side {
+		return false
+	}
+	if !q.From.IsZero() && r.Time.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !r.Time.Before(q.To) {
+		return false
+	}
+	return true
+}
+
+// store keeps the records of a journal
+type store interface {
+	// the last sequence stored
+	sequence() (int64, error)
+	put(r Record) error
+	// the records matching the query ordered by time, the most recent
+	// Limit when it is set
+	find(q Query) ([]Record, error)
+	close() error
+}
+
+// memoryStore scans every record, it is used for journals that are not
+// persisted like the journal of a backtest
+type memoryStore struct {
+	records []Record
+}
+
+func (m *memoryStore) sequence() (int64, error) {
+	if len(m.records) == 0 {
+		return 0, nil
+	}
+	return m.records[len(m.records)-1].Sequence, nil
+}
+
+func (m *memoryStore) put(r Record) error {
+	m.records = append(m.records, r)
+	return nil
+}
+
+func (m *memoryStore) find(q Query) ([]Record, error) {
+	found := []Record{}
+	for _, r := range m.records {
+		if q.match(r) {
+			found = append(found, r)
+		}
+	}
+	sort.SliceStable(found, func(a, b int) bool {
+		return found[a].Time.Before(found[b].Time)
+	})
+	if q.Limit > 0 && len(found) > q.Limit {
+		found = found[len(found)-q.Limit:]
+	}
+	return found, nil
+}
+
+func (m *memoryStore) close() error {
+	return nil
+}
+
+type Journal struct {
+	store    store
+	sequence int64
+	lock     sync.RWMutex
+}
+
+// NewMemoryJournal creates a journal that is not persisted
+func NewMemoryJournal() *Journal {
+	return &Journal{store: &memoryStore{}}
+}
+
+// Open opens the journal stored in the bolt database filename, the
+// database is created when it does not exist
+func Open(filename string) (*Journal, error) {
+	s, err := openBolt(filename)
+	if err != nil {
+		return nil, err
+	}
+	sequence, err := s.sequence()
+	if err != nil {
+		s.close()
+		return nil, err
+	}
+	return &Journal{store: s, sequence: sequence}, nil
+}
+
+func (j *Journal) Close() error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	return j.store.close()
+}
+
+// Record stores a new record and returns it with its sequence set,
+// the time of the record is set to now when it is empty
+func (j *Journal) Record(r Record) (Record, error) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	r.Sequence = j.sequence + 1
+	if r.Time.IsZero() {
+		r.Time = utils.Now()
+	}
+	if err := j.store.put(r); err != nil {
+		return r, fmt.Errorf("could not write journal record: %w", err)
+	}
+	j.sequence = r.Sequence
+	return r, nil
+}
+
+// Find returns the records matching the query ordered by time
+func (j *Journal) Find(q Query) []Record {
+	j.lock.RLock()
+	defer j.lock.RUnlock()
+	found, err := j.store.find(q)
+	if err != nil {
+		utils.LogError(err, "<Journal>: could not read records")
+		return []Record{}
+	}
+	return found
+}
+
+func (j *Journal) BySymbol(symbol names.Symbol) []Record {
+	return j.Find(Query{Symbol: symbol})
+}
+
+func (j *Journal) ByConfig(configId string) []Record {
+	return j.Find(Query{ConfigId: configId})
+}
+
+func (j *Journal) Between(from, to time.Time) []Record {
+	return j.Find(Query{From: from, To: to})
+}
+
+// Export writes the records matching the query to w as jsonl
+func (j *Journal) Export(w io.Writer, q Query) error {
+	encoder := json.NewEncoder(w)
+	for _, r := range j.Find(q) {
+		if err := encoder.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Import records the jsonl records of r as new records and returns how many
+// were recorded, a line that is not a record like a partially written last
+// line is skipped
+func (j *Journal) Import(r io.Reader) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line, imported := 0, 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			utils.LogWarn(fmt.Sprintf("<Journal>: skipping invalid record on line %d", line))
+			continue
+		}
+		if _, err := j.Record(record); err != nil {
+			return imported, err
+		}
+		imported++
+	}
+	return imported, scanner.Err()
+}
+
+var defaultJournal *Journal
+var defaultLock sync.Mutex
+
+// Default returns the journal used by the executors, it is stored in
+// logs/trades.db or the file set in the env TRADE_JOURNAL. Journals were
+// kept in jsonl before, the records of logs/trades.jsonl or of a jsonl
+// TRADE_JOURNAL are imported into a new database beside it. A journal
+// that can not be opened falls back to memory so trading is not stopped
+func Default() *Journal {
+	defaultLock.Lock()
+	defer defaultLock.Unlock()
+	if defaultJournal != nil {
+		return defaultJournal
+	}
+
+	filename := os.Getenv("TRADE_JOURNAL")
+	if filename == "" {
+		filename = "logs/trades.db"
+	}
+	legacy := strings.TrimSuffix(filename, filepath.Ext(filename)) + ".jsonl"
+	if legacy == filename {
+		filename = strings.TrimSuffix(filename, ".jsonl") + ".db"
+	}
+	j, err := Open(filename)
+	if err != nil {
+		utils.LogError(err, "<Journal>: could not open "+filename+", trades will only be kept in memory")
+		j = NewMemoryJournal()
+	}
+	if file, err := os.Open(legacy); err == nil {
+		if j.sequence == 0 {
+			imported, err := j.Import(file)
+			if err != nil {
+				utils.LogError(err, "<Journal>: could not import "+legacy)
+			}
+			utils.LogInfo(fmt.Sprintf("<Journal>: imported %d records of %s into %s", imported, legacy, filename))
+		}
+		file.Close()
+	}
+	defaultJournal = j
+	return defaultJournal
+}
+
+// Use replaces the journal used by the executors and returns the one it replaced
+func Use(j *Journal) *Journal {
+	defaultLock.Lock()
+	defer defaultLock.Unlock()
+	previous := defaultJournal
+	defaultJournal = j
+	return previous
+}
@@ -0,0 +1,89 @@
+package journal
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"trading/names"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var start = time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+
+func record(j *Journal, configId string, symbol names.Symbol, side names.TradeSide, minute int) Record {
+	r, _ := j.Record(Record{
+		Time:      start.Add(time.Duration(minute) * time.Minute),
+		ConfigId:  configId,
+		Symbol:    symbol,
+		Side:      side,
+		FillPrice: float64(minute),
+		Quantity:  1,
+		Lock:      NewLockSnapshot(names.LockState{PretradePrice: 10, AccrudGains: 2}),
+	})
+	return r
+}
+
+func TestJournalQuery(t *testing.T) {
+	persisted, err := Open(filepath.Join(t.TempDir(), "trades.db"))
+	assert.Nil(t, err)
+	defer persisted.Close()
+	for name, j := range map[string]*Journal{"memory": NewMemoryJournal(), "bolt": persisted} {
+		record(j, "a", "BTCUSDT", names.TradeSideBuy, 3)
+		record(j, "a", "BTCUSDT", names.TradeSideSell, 5)
+		record(j, "b", "ETHUSDT", names.TradeSideBuy, 1)
+		record(j, "b", "ETHUSDT", names.TradeSideSell, 9)
+		record(j, "ab", "BTCUSDT", names.TradeSideBuy, 4)
+
+		bySymbol := j.BySymbol("ETHUSDT")
+		assert.Len(t, bySymbol, 2, name)
+		assert.EqualValues(t, 1, bySymbol[0].FillPrice, "records are ordered by time")
+
+		assert.Len(t, j.ByConfig("a"), 2, "%s: a config id is not matched by the ids it starts", name)
+		assert.Len(t, j.Between(start.Add(3*time.Minute), start.Add(9*time.Minute)), 3, "%s: the end of the range is excluded", name)
+		assert.Len(t, j.Find(Query{ConfigId: "a", From: start.Add(4 * time.Minute)}), 1, name)
+		assert.Len(t, j.Find(Query{Symbol: "BTCUSDT", To: start.Add(5 * time.Minute)}), 2, name)
+
+		last := j.Find(Query{Side: names.TradeSideSell, Limit: 1})
+		assert.Len(t, last, 1, name)
+		assert.EqualValues(t, 9, last[0].FillPrice, name)
+		recent := j.Find(Query{Symbol: "BTCUSDT", Limit: 2})
+		assert.Equal(t, []float64{4, 5}, []float64{recent[0].FillPrice, recent[1].FillPrice}, "%s: the most recent ordered by time", name)
+	}
+}
+
+func TestJournalPersists(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "journal", "trades.db")
+	j, err := Open(filename)
+	assert.Nil(t, err)
+	first := record(j, "a", "BTCUSDT", names.TradeSideBuy, 1)
+	record(j, "a", "BTCUSDT", names.TradeSideSell, 2)
+	assert.EqualValues(t, 1, first.Sequence)
+	assert.Nil(t, j.Close())
+
+	reopened, err := Open(filename)
+	assert.Nil(t, err)
+	defer reopened.Close()
+	records := reopened.ByConfig("a")
+	assert.Len(t, records, 2)
+	assert.EqualValues(t, 10, records[0].Lock.PretradePrice)
+	assert.EqualValues(t, 2, records[0].Lock.AccrudGains)
+
+	next := record(reopened, "a", "BTCUSDT", names.TradeSideBuy, 3)
+	assert.EqualValues(t, 3, next.Sequence, "sequence continues after reopening")
+
+	var export bytes.Buffer
+	assert.Nil(t, reopened.Export(&export, Query{Side: names.TradeSideBuy}))
+	lines := strings.Split(strings.TrimSpace(export.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[1], `"sequence":3`)
+
+	// a partially written record is skipped
+	imported := NewMemoryJournal()
+	count, err := imported.Import(strings.NewReader(export.String() + `{"sequence": 4, "symb`))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+	assert.Len(t, imported.ByConfig("a"), 2)
+}
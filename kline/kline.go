@@ -1,11 +1,10 @@
 package kline
 
 import (
-	"context"
 	// "encoding/json"
 	// "io/ioutil"
-	"strconv"
-	"trading/binance"
+	"time"
+	"trading/exchange"
 	"trading/utils"
 )
 
@@ -51,41 +50,66 @@ func NewKline(symbol, interval string, limit int) KLineInterface {
 	return &KLine{symbol: symbol, interval: interval, pointsLimit: limit}
 }
 
+// KLineData returns the candles of the kline, they are taken from the
+// shared store once and kept until RefreshData
 func (kline *KLine) KLineData() []KlineData {
 	if len(kline.data) == 0 {
-		kline.data = GetKLineData(kline.symbol, kline.interval, kline.pointsLimit)
+		kline.data = Default().Last(kline.symbol, kline.interval, kline.pointsLimit)
 	}
 	return kline.data
 }
 
 func GetKLineData(symbol, interval string, pointLimit int) []KlineData {
-	kline, err := binance.GetClient().NewKlinesService().Interval(interval).Symbol(symbol).Limit(pointLimit).Do(context.Background())
+	kline, err := exchange.Get().Klines(exchange.KlineRequest{Symbol: symbol, Interval: interval, Limit: pointLimit})
 	if err != nil {
 		utils.LogError(err, "GetKLineData function call")
 	}
+	return toKlineData(kline)
+}
+
+// maximum number of candles the exchange returns per request
+const historyPageLimit = 1000
+
+// GetKLineHistory fetches every candle between start and end, the exchange
+// limits how many candles a request returns so the range is fetched in pages
+func GetKLineHistory(symbol, interval string, start, end time.Time) []KlineData {
+	var klines []KlineData
+	from := start.UnixMilli()
+	for from < end.UnixMilli() {
+		page, err := exchange.Get().Klines(exchange.KlineRequest{
+			Symbol:    symbol,
+			Interval:  interval,
+			StartTime: from,
+			EndTime:   end.UnixMilli(),
+			Limit:     historyPageLimit,
+		})
+		if err != nil {
+			utils.LogError(err, "GetKLineHistory function call")
+			break
+		}
+		if len(page) == 0 {
+			break
+		}
+		klines = append(klines, toKlineData(page)...)
+		from = page[len(page)-1].CloseTime + 1
+	}
+	return klines
+}
 
+func toKlineData(kline []exchange.Kline) []KlineData {
 	var klines []KlineData
 	for _, k := range kline {
-		Open, _ := strconv.ParseFloat(k.Open, 64)
-		High, _ := strconv.ParseFloat(k.High, 64)
-		Low, _ := strconv.ParseFloat(k.Low, 64)
-		Close, _ := strconv.ParseFloat(k.Close, 64)
-		OpenTime := k.OpenTime
-		Volume, _ := strconv.ParseFloat(k.Volume, 64)
-		TakerBuyBaseAssetVolume, _ := strconv.ParseFloat(k.TakerBuyBaseAssetVolume, 64)
-		TakerBuyQuoteAssetVolume, _ := strconv.ParseFloat(k.TakerBuyQuoteAssetVolume, 64)
-		QuoteAssetVolume, _ := strconv.ParseFloat(k.QuoteAssetVolume, 64)
 		klines = append(klines, KlineData{
 			TradeNum:                 k.TradeNum,
-			TakerBuyBaseAssetVolume:  TakerBuyBaseAssetVolume,
-			TakerBuyQuoteAssetVolume: TakerBuyQuoteAssetVolume,
-			Open:                     Open,
-			High:                     High,
-			Low:                      Low,
-			Close:                    Close,
-			OpenTime:                 OpenTime,
-			Volume:                   Volume,
-			QuoteAssetVolume:         QuoteAssetVolume,
+			TakerBuyBaseAssetVolume:  k.TakerBuyBaseAssetVolume,
+			TakerBuyQuoteAssetVolume: k.TakerBuyQuoteAssetVolume,
+			Open:                     k.Open,
+			High:                     k.High,
+			Low:                      k.Low,
+			Close:                    k.Close,
+			OpenTime:                 k.OpenTime,
+			Volume:                   k.Volume,
+			QuoteAssetVolume:         k.QuoteAssetVolume,
 			CloseTime:                k.CloseTime,
 		})
 	}
@@ -93,7 +117,7 @@ func GetKLineData(symbol, interval string, pointLimit int) []KlineData {
 }
 
 func (kline *KLine) RefreshData() []KlineData {
-	data := GetKLineData(kline.symbol, kline.interval, kline.pointsLimit)
+	data := Default().Refresh(kline.symbol, kline.interval, kline.pointsLimit)
 	kline.data = data
 	return data
 }
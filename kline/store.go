@@ -0,0 +1,463 @@
+package kline
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"trading/exchange"
+	"trading/utils"
+)
+
+// candles kept of every symbol and interval
+const DefaultCapacity = 1000
+
+// how long new series are collected before their stream is started, so
+// that the symbols of a trader share a connection
+const watchDelay = time.Second
+
+// how long a failed stream waits before it is started again
+const rewatchDelay = 30 * time.Second
+
+var intervalDurations = map[string]time.Duration{
+	"1m": time.Minute, "3m": 3 * time.Minute, "5m": 5 * time.Minute,
+	"15m": 15 * time.Minute, "30m": 30 * time.Minute,
+	"1h": time.Hour, "2h": 2 * time.Hour, "4h": 4 * time.Hour, "6h": 6 * time.Hour,
+	"8h": 8 * time.Hour, "12h": 12 * time.Hour,
+	"1d": 24 * time.Hour, "3d": 3 * 24 * time.Hour, "1w": 7 * 24 * time.Hour,
+	"1M": 30 * 24 * time.Hour,
+}
+
+// IntervalDuration is the time a candle of interval covers, zero when the
+// interval is unknown
+func IntervalDuration(interval string) time.Duration {
+	return intervalDurations[interval]
+}
+
+// ring keeps the last candles of a series, a full ring overwrites its oldest candle
+type ring struct {
+	data  []KlineData
+	start int
+	size  int
+}
+
+func newRing(capacity int) *ring {
+	return &ring{data: make([]KlineData, capacity)}
+}
+
+func (r *ring) len() int {
+	return r.size
+}
+
+// at is the candle i positions after the oldest
+func (r *ring) at(i int) KlineData {
+	return r.data[(r.start+i)%len(r.data)]
+}
+
+func (r *ring) last() (KlineData, bool) {
+	if r.size == 0 {
+		return KlineData{}, false
+	}
+	return r.at(r.size - 1), true
+}
+
+func (r *ring) push(k KlineData) {
+	if r.size < len(r.data) {
+		r.data[(r.start+r.size)%len(r.data)] = k
+		r.size++
+		return
+	}
+	r.data[r.start] = k
+	r.start = (r.start + 1) % len(r.data)
+}
+
+// set replaces the candle i positions after the oldest
+func (r *ring) set(i int, k KlineData) {
+	r.data[(r.start+i)%len(r.data)] = k
+}
+
+func (r *ring) reset() {
+	r.start, r.size = 0, 0
+}
+
+// lastN copies the last n candles, the oldest first
+func (r *ring) lastN(n int) []KlineData {
+	if n <= 0 || n > r.size {
+		n = r.size
+	}
+	candles := make([]KlineData, 0, n)
+	for i := r.size - n; i < r.size; i++ {
+		candles = append(candles, r.at(i))
+	}
+	return candles
+}
+
+type series struct {
+	lock     sync.Mutex
+	symbol   string
+	interval string
+	candles  *ring
+	// the most candles asked of the exchange at once
+	depth  int
+	seeded bool
+	live   bool
+}
+
+// merge adds k to the series, a candle of the same open time is replaced
+func (s *series) merge(k KlineData) {
+	last, ok := s.candles.last()
+	if !ok || k.OpenTime > last.OpenTime {
+		if duration := IntervalDuration(s.interval); ok && duration > 0 &&
+			k.OpenTime > last.OpenTime+duration.Milliseconds() {
+			// candles are missing between the two, the older ones are dropped
+			// so the series never has holes and is fetched again
+			s.candles.reset()
+			s.depth = 0
+		}
+		s.candles.push(k)
+		return
+	}
+	for i := s.candles.len() - 1; i >= 0; i-- {
+		if s.candles.at(i).OpenTime == k.OpenTime {
+			s.candles.set(i, k)
+			return
+		}
+	}
+}
+
+// mergeAll adds candles to the series, the oldest first. Candles older
+// than the series replace it and keep the newer candles it has
+func (s *series) mergeAll(candles []KlineData) {
+	if len(candles) == 0 {
+		return
+	}
+	if s.candles.len() > 0 && candles[0].OpenTime < s.candles.at(0).OpenTime {
+		kept := s.candles.lastN(0)
+		s.candles.reset()
+		for _, k := range candles {
+			s.candles.push(k)
+		}
+		for _, k := range kept {
+			if k.OpenTime > candles[len(candles)-1].OpenTime {
+				s.merge(k)
+			}
+		}
+		return
+	}
+	for _, k := range candles {
+		s.merge(k)
+	}
+}
+
+// stale reports whether the last candle closed without the stream updating it
+func (s *series) stale(now time.Time) bool {
+	last, ok := s.candles.last()
+	if !ok {
+		return true
+	}
+	closed := now.UnixMilli() > last.CloseTime
+	if !s.live {
+		return closed
+	}
+	return closed && now.UnixMilli() > last.CloseTime+IntervalDuration(s.interval).Milliseconds()
+}
+
+// Store shares the candles of every symbol and interval. Series are seeded
+// from the disk and the exchange on first use and then kept current by the
+// kline stream, so graphs of the same symbol do not fetch the same candles
+type Store struct {
+	lock      sync.Mutex
+	capacity  int
+	dir       string
+	streaming bool
+	series    map[string]*series
+	pending   map[string][]string
+	watching  *time.Timer
+	stops     []chan struct{}
+}
+
+func NewStore(capacity int) *Store {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	return &Store{capacity: capacity, series: map[string]*series{}, pending: map[string][]string{}}
+}
+
+// UseDir saves the candles in dir and seeds new series from it
+func (s *Store) UseDir(dir string) *Store {
+	s.dir = dir
+	return s
+}
+
+// UseStreaming keeps the series current from the kline stream of the exchange
+func (s *Store) UseStreaming(streaming bool) *Store {
+	s.streaming = streaming
+	return s
+}
+
+// clamp keeps n within the candles a series holds
+func (s *Store) clamp(n int) int {
+	if n > s.capacity {
+		return s.capacity
+	}
+	return n
+}
+
+func (s *Store) get(symbol, interval string) *series {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	key := symbol + "@" + interval
+	ser, exist := s.series[key]
+	if !exist {
+		ser = &series{symbol: symbol, interval: interval, candles: newRing(s.capacity)}
+		s.series[key] = ser
+	}
+	return ser
+}
+
+// Last returns the last n candles of symbol on interval, the oldest first
+func (s *Store) Last(symbol, interval string, n int) []KlineData {
+	n = s.clamp(n)
+	ser := s.get(symbol, interval)
+	ser.lock.Lock()
+	defer ser.lock.Unlock()
+	if !ser.seeded {
+		s.seed(ser, n)
+	} else if ser.stale(utils.Now()) || n > ser.depth {
+		s.refresh(ser, n)
+	}
+	return ser.candles.lastN(n)
+}
+
+// Refresh fetches the candles of symbol on interval the stream has not
+// delivered and returns the last n
+func (s *Store) Refresh(symbol, interval string, n int) []KlineData {
+	n = s.clamp(n)
+	ser := s.get(symbol, interval)
+	ser.lock.Lock()
+	defer ser.lock.Unlock()
+	if !ser.seeded {
+		s.seed(ser, n)
+	} else if !ser.live || ser.stale(utils.Now()) || n > ser.depth {
+		s.refresh(ser, n)
+	}
+	return ser.candles.lastN(n)
+}
+
+func (s *Store) seed(ser *series, n int) {
+	ser.seeded = true
+	if s.dir != "" {
+		if candles, err := s.load(ser); err == nil {
+			ser.mergeAll(candles)
+		} else if !os.IsNotExist(err) {
+			utils.LogWarn(fmt.Sprintf("<Kline Store>: could not read the saved %s %s candles, %s", ser.symbol, ser.interval, err.Error()))
+		}
+	}
+	s.refresh(ser, n)
+	if s.streaming {
+		s.watch(ser.symbol, ser.interval)
+	}
+}
+
+// refresh fetches the candles since the last one kept, or the last n when
+// the series is too short or too old to be continued
+func (s *Store) refresh(ser *series, n int) {
+	request := exchange.KlineRequest{Symbol: ser.symbol, Interval: ser.interval, Limit: n}
+	last, ok := ser.candles.last()
+	duration := IntervalDuration(ser.interval)
+	continued := ok && ser.candles.len() >= n && duration > 0 &&
+		utils.Now().Sub(time.UnixMilli(last.OpenTime)) < duration*historyPageLimit
+	if continued {
+		request.StartTime, request.Limit = last.OpenTime, historyPageLimit
+	}
+
+	candles, err := exchange.Get().Klines(request)
+	if err != nil {
+		utils.LogError(err, fmt.Sprintf("<Kline Store>: could not fetch the %s %s candles", ser.symbol, ser.interval))
+		return
+	}
+	ser.mergeAll(toKlineData(candles))
+	// the exchange has fewer candles of new symbols than asked
+	if (!continued || ser.candles.len() >= n) && n > ser.depth {
+		ser.depth = n
+	}
+}
+
+// watch streams the series once the symbols waiting for interval are collected
+func (s *Store) watch(symbol, interval string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.pending[interval] = append(s.pending[interval], symbol)
+	if s.watching == nil {
+		s.watching = time.AfterFunc(watchDelay, s.startWatching)
+	}
+}
+
+func (s *Store) startWatching() {
+	s.lock.Lock()
+	pending := s.pending
+	s.pending, s.watching = map[string][]string{}, nil
+	s.lock.Unlock()
+
+	for interval, symbols := range pending {
+		s.stream(symbols, interval)
+	}
+}
+
+func (s *Store) stream(symbols []string, interval string) {
+	var failed sync.Once
+	_, stop, err := exchange.Get().StreamKlines(symbols, interval, func(event exchange.KlineEvent) {
+		ser := s.get(event.Symbol, interval)
+		ser.lock.Lock()
+		ser.merge(toKlineData([]exchange.Kline{event.Kline})[0])
+		ser.live = true
+		ser.lock.Unlock()
+	}, func(err error) {
+		failed.Do(func() {
+			utils.LogWarn(fmt.Sprintf("<Kline Store>: %s stream of %d symbols failed, %s", interval, len(symbols), err.Error()))
+			s.unwatch(symbols, interval)
+		})
+	})
+	if err != nil {
+		failed.Do(func() {
+			utils.LogError(err, fmt.Sprintf("<Kline Store>: could not stream the %s candles", interval))
+			s.unwatch(symbols, interval)
+		})
+		return
+	}
+	s.lock.Lock()
+	s.stops = append(s.stops, stop)
+	s.lock.Unlock()
+}
+
+// unwatch refreshes the series of a failed stream from the exchange until
+// the stream is started again
+func (s *Store) unwatch(symbols []string, interval string) {
+	for _, symbol := range symbols {
+		ser := s.get(symbol, interval)
+		ser.lock.Lock()
+		ser.live = false
+		ser.lock.Unlock()
+	}
+	time.AfterFunc(rewatchDelay, func() {
+		for _, symbol := range symbols {
+			s.watch(symbol, interval)
+		}
+	})
+}
+
+// Stop ends the streams of the store
+func (s *Store) Stop() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, stop := range s.stops {
+		close(stop)
+	}
+	s.stops = nil
+	if s.watching != nil {
+		s.watching.Stop()
+		s.watching = nil
+	}
+}
+
+func (s *Store) filename(ser *series) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%s_%s.json", ser.symbol, ser.interval))
+}
+
+func (s *Store) load(ser *series) ([]KlineData, error) {
+	content, err := ioutil.ReadFile(s.filename(ser))
+	if err != nil {
+		return nil, err
+	}
+	var candles []KlineData
+	err = json.Unmarshal(content, &candles)
+	return candles, err
+}
+
+// Save writes the candles of every series to the directory of the store
+func (s *Store) Save() error {
+	if s.dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	s.lock.Lock()
+	all := make([]*series, 0, len(s.series))
+	for _, ser := range s.series {
+		all = append(all, ser)
+	}
+	s.lock.Unlock()
+
+	for _, ser := range all {
+		ser.lock.Lock()
+		candles := ser.candles.lastN(0)
+		ser.lock.Unlock()
+		if len(candles) == 0 {
+			continue
+		}
+		content, err := json.Marshal(candles)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(s.filename(ser), content, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// KeepSaved saves the store every period until stop is closed
+func (s *Store) KeepSaved(every time.Duration) (stop chan struct{}) {
+	stop = make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.Save(); err != nil {
+					utils.LogError(err, "<Kline Store>: could not save the candles")
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return stop
+}
+
+var (
+	defaultStore *Store
+	defaultLock  sync.Mutex
+)
+
+// Default returns the store shared by the graphs, it streams the candles it
+// keeps and saves them every 5 minutes in logs/klines or the directory set
+// in the env KLINE_STORE
+func Default() *Store {
+	defaultLock.Lock()
+	defer defaultLock.Unlock()
+	if defaultStore != nil {
+		return defaultStore
+	}
+	dir := os.Getenv("KLINE_STORE")
+	if dir == "" {
+		dir = "logs/klines"
+	}
+	defaultStore = NewStore(DefaultCapacity).UseDir(dir).UseStreaming(true)
+	defaultStore.KeepSaved(5 * time.Minute)
+	return defaultStore
+}
+
+// Use replaces the store shared by the graphs and returns the one it replaced
+func Use(s *Store) *Store {
+	defaultLock.Lock()
+	defer defaultLock.Unlock()
+	previous := defaultStore
+	defaultStore = s
+	return previous
+}
@@ -0,0 +1,150 @@
+package kline
+
+import (
+	"sync"
+	"testing"
+	"time"
+	"trading/exchange"
+	"trading/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var start = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// candles of a minute from start, the last one is still open at now
+type fakeKlines struct {
+	exchange.Exchange
+	lock     sync.Mutex
+	now      time.Time
+	requests []exchange.KlineRequest
+	handler  func(exchange.KlineEvent)
+	streamed []string
+}
+
+func candle(i int) exchange.Kline {
+	open := start.Add(time.Duration(i) * time.Minute)
+	return exchange.Kline{OpenTime: open.UnixMilli(), CloseTime: open.Add(time.Minute).UnixMilli() - 1, Close: float64(i)}
+}
+
+func (f *fakeKlines) Klines(request exchange.KlineRequest) ([]exchange.Kline, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.requests = append(f.requests, request)
+	current := int(f.now.Sub(start) / time.Minute)
+	first := current - request.Limit + 1
+	if request.StartTime > 0 {
+		first = int(time.UnixMilli(request.StartTime).Sub(start) / time.Minute)
+	}
+	klines := []exchange.Kline{}
+	for i := first; i <= current && len(klines) < request.Limit; i++ {
+		klines = append(klines, candle(i))
+	}
+	return klines, nil
+}
+
+func (f *fakeKlines) StreamKlines(symbols []string, interval string, handler func(exchange.KlineEvent), errHandler func(error)) (done, stop chan struct{}, err error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.handler, f.streamed = handler, symbols
+	return make(chan struct{}), make(chan struct{}), nil
+}
+
+func (f *fakeKlines) requestCount() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return len(f.requests)
+}
+
+func useFakeKlines(now time.Time) (*fakeKlines, func()) {
+	fake := &fakeKlines{now: now}
+	previous := exchange.Use(fake)
+	utils.UseClock(func() time.Time {
+		fake.lock.Lock()
+		defer fake.lock.Unlock()
+		return fake.now
+	})
+	return fake, func() {
+		exchange.Use(previous)
+		utils.UseClock(nil)
+	}
+}
+
+func closes(candles []KlineData) []float64 {
+	prices := []float64{}
+	for _, k := range candles {
+		prices = append(prices, k.Close)
+	}
+	return prices
+}
+
+func TestRing(t *testing.T) {
+	r := newRing(3)
+	for i := 0; i < 5; i++ {
+		r.push(KlineData{Close: float64(i)})
+	}
+	assert.Equal(t, 3, r.len())
+	assert.Equal(t, []float64{2, 3, 4}, closes(r.lastN(0)))
+	assert.Equal(t, []float64{3, 4}, closes(r.lastN(2)))
+}
+
+func TestStoreRefresh(t *testing.T) {
+	fake, restore := useFakeKlines(start.Add(100*time.Minute + 30*time.Second))
+	defer restore()
+	store := NewStore(50)
+
+	assert.Equal(t, []float64{96, 97, 98, 99, 100}, closes(store.Last("BTCUSDT", "1m", 5)))
+	assert.Equal(t, []float64{98, 99, 100}, closes(store.Last("BTCUSDT", "1m", 3)))
+	assert.Equal(t, 1, fake.requestCount(), "graphs of the same symbol share the candles")
+
+	fake.now = fake.now.Add(2 * time.Minute)
+	assert.Equal(t, []float64{100, 101, 102}, closes(store.Last("BTCUSDT", "1m", 3)))
+	assert.Equal(t, 2, fake.requestCount())
+	assert.Equal(t, candle(100).OpenTime, fake.requests[1].StartTime, "only the candles since the last one are fetched")
+
+	assert.Len(t, store.Last("BTCUSDT", "1m", 80), 50, "a series holds the capacity of the store")
+	assert.Len(t, store.Last("BTCUSDT", "1m", 80), 50)
+	assert.Equal(t, 3, fake.requestCount())
+}
+
+func TestStoreStream(t *testing.T) {
+	fake, restore := useFakeKlines(start.Add(10*time.Minute + 30*time.Second))
+	defer restore()
+	store := NewStore(20).UseStreaming(true)
+	defer store.Stop()
+
+	store.Last("BTCUSDT", "1m", 3)
+	store.Last("ETHUSDT", "1m", 3)
+	store.startWatching()
+	assert.ElementsMatch(t, []string{"BTCUSDT", "ETHUSDT"}, fake.streamed)
+
+	next := candle(11)
+	fake.now = fake.now.Add(time.Minute)
+	fake.handler(exchange.KlineEvent{Symbol: "BTCUSDT", Interval: "1m", Kline: next})
+	assert.Equal(t, []float64{9, 10, 11}, closes(store.Last("BTCUSDT", "1m", 3)))
+	assert.Equal(t, []float64{9, 10, 11}, closes(store.Refresh("BTCUSDT", "1m", 3)))
+	assert.Equal(t, 2, fake.requestCount(), "streamed candles need no request")
+
+	next.Close = 11.5
+	fake.handler(exchange.KlineEvent{Symbol: "BTCUSDT", Interval: "1m", Kline: next})
+	assert.Equal(t, []float64{10, 11.5}, closes(store.Last("BTCUSDT", "1m", 2)), "the open candle is updated")
+
+	fake.now = fake.now.Add(3 * time.Minute)
+	fake.handler(exchange.KlineEvent{Symbol: "BTCUSDT", Interval: "1m", Kline: candle(14)})
+	assert.Equal(t, []float64{12, 13, 14}, closes(store.Last("BTCUSDT", "1m", 3)), "candles missed by the stream are fetched")
+	assert.Equal(t, 3, fake.requestCount())
+}
+
+func TestStoreSave(t *testing.T) {
+	fake, restore := useFakeKlines(start.Add(10*time.Minute + 30*time.Second))
+	defer restore()
+	dir := t.TempDir()
+	store := NewStore(20).UseDir(dir)
+	store.Last("BTCUSDT", "1m", 5)
+	assert.Nil(t, store.Save())
+
+	fake.now = fake.now.Add(time.Minute)
+	saved := NewStore(20).UseDir(dir)
+	assert.Equal(t, []float64{7, 8, 9, 10, 11}, closes(saved.Last("BTCUSDT", "1m", 5)))
+	assert.Equal(t, candle(10).OpenTime, fake.requests[1].StartTime, "saved candles are continued")
+}
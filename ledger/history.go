@@ -0,0 +1,67 @@
+package ledger
+
+import (
+	"fmt"
+	"time"
+	"trading/exchange"
+	"trading/names"
+	"trading/utils"
+)
+
+// FromOrder converts an order of the exchange history, the fee is estimated
+// at feeRate of the quote quantity as the history does not report it.
+// Orders that did not execute are false
+func FromOrder(order *exchange.Order, feeRate float64) (Fill, bool) {
+	quantity, quote := order.ExecutedQuantity, order.QuoteQuantity
+	if quantity <= 0 || quote <= 0 {
+		return Fill{}, false
+	}
+	return Fill{
+		OrderId:  order.OrderId,
+		Symbol:   names.Symbol(order.Symbol),
+		Side:     names.TradeSide(order.Side),
+		Price:    quote / quantity,
+		Quantity: quantity,
+		Fee:      quote * feeRate,
+		Time:     time.UnixMilli(order.Time),
+	}, true
+}
+
+// ImportOrders books the executed orders no fill was booked for, they
+// belong to no config unless a config booked them already
+func (l *Ledger) ImportOrders(orders []*exchange.Order, feeRate float64) int {
+	fills := []Fill{}
+	for _, order := range orders {
+		if fill, executed := FromOrder(order, feeRate); executed {
+			fills = append(fills, fill)
+		}
+	}
+	return l.Add(fills...)
+}
+
+// ImportHistory books the order history of symbol on the exchange
+func (l *Ledger) ImportHistory(symbol names.Symbol) int {
+	orders, err := exchange.Get().OrderHistory(symbol.String())
+	if err != nil {
+		utils.LogError(err, fmt.Sprintf("<Ledger>: could not import the %s order history", symbol))
+		return 0
+	}
+	added := l.ImportOrders(orders, FeeRate)
+	utils.LogInfo(fmt.Sprintf("<Ledger>: imported %d %s orders from the exchange history", added, symbol))
+	return added
+}
+
+// ImportHistoryOnce imports the history of symbol the first time it is asked
+// for, mocked accounts have no history to import
+func (l *Ledger) ImportHistoryOnce(symbol names.Symbol) {
+	if utils.Env().IsMockAccount() {
+		return
+	}
+	l.lock.Lock()
+	imported := l.imported[symbol]
+	l.imported[symbol] = true
+	l.lock.Unlock()
+	if !imported {
+		l.ImportHistory(symbol)
+	}
+}
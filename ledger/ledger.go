@@ -0,0 +1,469 @@
+// Package ledger tracks the cost basis of every asset we hold from our own
+// fills and the order history of the exchange, and computes the realized and
+// unrealized profit net of fees per config, per symbol and per group of
+// configs. Bought quantities are held as lots that sells consume first in,
+// first out, last in, first out or at their average cost
+package ledger
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"trading/exchange"
+	"trading/journal"
+	"trading/names"
+	"trading/utils"
+)
+
+type Method string
+
+const (
+	FIFO    Method = "FIFO"
+	LIFO    Method = "LIFO"
+	Average Method = "AVERAGE"
+)
+
+func (m Method) IsValid() bool {
+	return m == FIFO || m == LIFO || m == Average
+}
+
+// FeeRate estimates the fees of orders the exchange does not report them for
+const FeeRate = 0.001
+
+// Fill is an executed order, Fee is paid in FeeAsset. Fills without a config
+// are holdings the bots did not buy, like orders placed by hand
+type Fill struct {
+	OrderId  int64           `json:"orderId"`
+	ConfigId string          `json:"configId"`
+	Symbol   names.Symbol    `json:"symbol"`
+	Side     names.TradeSide `json:"side"`
+	Price    float64         `json:"price"`
+	Quantity float64         `json:"quantity"`
+	Fee      float64         `json:"fee"`
+	FeeAsset string          `json:"feeAsset"`
+	Time     time.Time       `json:"time"`
+	// the fee in the quote asset, resolved before the fill is booked
+	feeValue float64
+}
+
+func FromRecord(r journal.Record) Fill {
+	return Fill{
+		OrderId:  r.OrderId,
+		ConfigId: r.ConfigId,
+		Symbol:   r.Symbol,
+		Side:     r.Side,
+		Price:    r.FillPrice,
+		Quantity: r.Quantity,
+		Fee:      r.Fee,
+		FeeAsset: r.FeeAsset,
+		Time:     r.Time,
+	}
+}
+
+// PnL is in the quote asset. Cost is what the open quantity was bought for
+// fees included, Unmatched is the quantity sold that no lot was held for
+type PnL struct {
+	Realized   float64 `json:"realized"`
+	Unrealized float64 `json:"unrealized"`
+	Fees       float64 `json:"fees"`
+	Quantity   float64 `json:"quantity"`
+	Cost       float64 `json:"cost"`
+	Value      float64 `json:"value"`
+	Unmatched  float64 `json:"unmatched"`
+}
+
+func (p PnL) Total() float64 {
+	return p.Realized + p.Unrealized
+}
+
+func (p *PnL) add(other PnL) {
+	p.Realized += other.Realized
+	p.Unrealized += other.Unrealized
+	p.Fees += other.Fees
+	p.Quantity += other.Quantity
+	p.Cost += other.Cost
+	p.Value += other.Value
+	p.Unmatched += other.Unmatched
+}
+
+// lot is a bought quantity not sold yet, cost is the total paid for it
+type lot struct {
+	configId string
+	quantity float64
+	cost     float64
+}
+
+type key struct {
+	configId string
+	symbol   names.Symbol
+}
+
+type Ledger struct {
+	method Method
+	prices func(symbol string) (float64, error)
+	lock   sync.RWMutex
+	fills  []Fill
+	orders map[string]bool
+	lots   map[names.Symbol][]*lot
+	stats  map[key]*PnL
+	// symbols the exchange history was imported for
+	imported map[names.Symbol]bool
+}
+
+func New(method Method) *Ledger {
+	if !method.IsValid() {
+		method = FIFO
+	}
+	return &Ledger{
+		method: method,
+		prices: func(symbol string) (float64, error) {
+			return exchange.Get().PriceLatest(symbol)
+		},
+		orders:   map[string]bool{},
+		lots:     map[names.Symbol][]*lot{},
+		stats:    map[key]*PnL{},
+		imported: map[names.Symbol]bool{},
+	}
+}
+
+func (l *Ledger) Method() Method {
+	return l.method
+}
+
+// UsePrices values open lots and fees paid in other assets with prices
+func (l *Ledger) UsePrices(prices func(symbol string) (float64, error)) *Ledger {
+	l.prices = prices
+	return l
+}
+
+func orderKey(f Fill) string {
+	return fmt.Sprintf("%s:%d", f.Symbol, f.OrderId)
+}
+
+// Add books fills and returns how many were new, an order already booked is
+// skipped. Fills older than the last one are replayed in time order
+func (l *Ledger) Add(fills ...Fill) int {
+	fills = l.feeValues(fills)
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	added, replay := 0, false
+	for _, f := range fills {
+		if f.Quantity <= 0 || f.Price <= 0 {
+			continue
+		}
+		if f.OrderId != 0 {
+			if l.orders[orderKey(f)] {
+				continue
+			}
+			l.orders[orderKey(f)] = true
+		}
+		if len(l.fills) > 0 && f.Time.Before(l.fills[len(l.fills)-1].Time) {
+			replay = true
+		}
+		l.fills = append(l.fills, f)
+		added++
+		if !replay {
+			l.book(f)
+		}
+	}
+	if replay {
+		sort.SliceStable(l.fills, func(i, j int) bool { return l.fills[i].Time.Before(l.fills[j].Time) })
+		l.lots = map[names.Symbol][]*lot{}
+		l.stats = map[key]*PnL{}
+		for _, f := range l.fills {
+			l.book(f)
+		}
+	}
+	return added
+}
+
+// Record books a fill of our own and returns the profit it realized
+func (l *Ledger) Record(f Fill) float64 {
+	before := l.realized(f.ConfigId, f.Symbol)
+	l.Add(f)
+	return l.realized(f.ConfigId, f.Symbol) - before
+}
+
+func (l *Ledger) realized(configId string, symbol names.Symbol) float64 {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	if stat, exist := l.stats[key{configId, symbol}]; exist {
+		return stat.Realized
+	}
+	return 0
+}
+
+// Load books the journal records
+func (l *Ledger) Load(records []journal.Record) int {
+	fills := make([]Fill, 0, len(records))
+	for _, r := range records {
+		fills = append(fills, FromRecord(r))
+	}
+	return l.Add(fills...)
+}
+
+func (l *Ledger) stat(configId string, symbol names.Symbol) *PnL {
+	k := key{configId, symbol}
+	if _, exist := l.stats[k]; !exist {
+		l.stats[k] = &PnL{}
+	}
+	return l.stats[k]
+}
+
+// feeValues values the fees of fills in the quote asset before the ledger is
+// locked, a fee paid in another asset asks the price of that asset
+func (l *Ledger) feeValues(fills []Fill) []Fill {
+	valued := make([]Fill, 0, len(fills))
+	prices := map[string]float64{}
+	for _, f := range fills {
+		pair := f.Symbol.ParseTradingPair()
+		switch f.FeeAsset {
+		case "", pair.Quote:
+			f.feeValue = f.Fee
+		case pair.Base:
+			f.feeValue = f.Fee * f.Price
+		default:
+			symbol := f.FeeAsset + pair.Quote
+			price, known := prices[symbol]
+			if !known {
+				var err error
+				if price, err = l.prices(symbol); err != nil {
+					utils.LogWarn(fmt.Sprintf("<Ledger>: %f %s fee of %s order %d is not counted, %s", f.Fee, f.FeeAsset, f.Symbol, f.OrderId, err.Error()))
+				}
+				prices[symbol] = price
+			}
+			f.feeValue = f.Fee * price
+		}
+		valued = append(valued, f)
+	}
+	return valued
+}
+
+func (l *Ledger) book(f Fill) {
+	pair := f.Symbol.ParseTradingPair()
+	fee := f.feeValue
+	stat := l.stat(f.ConfigId, f.Symbol)
+	stat.Fees += fee
+
+	if f.Side.IsBuy() {
+		quantity := f.Quantity
+		if f.FeeAsset != "" && f.FeeAsset == pair.Base {
+			// the fee was taken from the bought quantity
+			quantity -= f.Fee
+		}
+		l.lots[f.Symbol] = append(l.lots[f.Symbol], &lot{configId: f.ConfigId, quantity: quantity, cost: f.Price*f.Quantity + fee})
+		return
+	}
+
+	// a config sells its own lots before the ones no config bought
+	remaining, cost := f.Quantity, 0.0
+	for _, owner := range []string{f.ConfigId, ""} {
+		if remaining <= 0 {
+			break
+		}
+		sold, soldCost := l.consume(f.Symbol, owner, remaining)
+		remaining -= sold
+		cost += soldCost
+		if owner == "" {
+			break
+		}
+	}
+	matched := f.Quantity - remaining
+	if remaining > 0 {
+		stat.Unmatched += remaining
+	}
+	stat.Realized += matched*f.Price - cost - fee*matched/f.Quantity
+}
+
+// consume sells up to quantity from the lots of owner by the method of the
+// ledger and returns the quantity sold and what it cost
+func (l *Ledger) consume(symbol names.Symbol, owner string, quantity float64) (sold, cost float64) {
+	owned := []*lot{}
+	var held, heldCost float64
+	for _, lt := range l.lots[symbol] {
+		if lt.configId == owner && lt.quantity > 0 {
+			owned = append(owned, lt)
+			held += lt.quantity
+			heldCost += lt.cost
+		}
+	}
+	if held <= 0 {
+		return 0, 0
+	}
+
+	switch l.method {
+	case Average:
+		sold = quantity
+		if sold > held {
+			sold = held
+		}
+		part := sold / held
+		for _, lt := range owned {
+			lt.quantity -= lt.quantity * part
+			lt.cost -= lt.cost * part
+		}
+		cost = heldCost * part
+	default:
+		if l.method == LIFO {
+			for i, j := 0, len(owned)-1; i < j; i, j = i+1, j-1 {
+				owned[i], owned[j] = owned[j], owned[i]
+			}
+		}
+		for _, lt := range owned {
+			if sold >= quantity {
+				break
+			}
+			take := quantity - sold
+			if take > lt.quantity {
+				take = lt.quantity
+			}
+			part := lt.cost * take / lt.quantity
+			lt.quantity -= take
+			lt.cost -= part
+			sold += take
+			cost += part
+		}
+	}
+
+	// lots below dust are dropped
+	kept := l.lots[symbol][:0]
+	for _, lt := range l.lots[symbol] {
+		if lt.quantity > 1e-12 {
+			kept = append(kept, lt)
+		}
+	}
+	l.lots[symbol] = kept
+	return sold, cost
+}
+
+// EntryPrice is the cost of a unit of the open lots of the config fees
+// included, or of the lots no config bought when the config holds none.
+// It is false when nothing of symbol is held
+func (l *Ledger) EntryPrice(configId string, symbol names.Symbol) (float64, bool) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	for _, owner := range []string{configId, ""} {
+		var quantity, cost float64
+		for _, lt := range l.lots[symbol] {
+			if lt.configId == owner {
+				quantity += lt.quantity
+				cost += lt.cost
+			}
+		}
+		if quantity > 0 {
+			return cost / quantity, true
+		}
+	}
+	return 0, false
+}
+
+// lotPrices asks the latest prices of the symbols of the open lots matching
+// match without holding the lock, a symbol without a price is zero
+func (l *Ledger) lotPrices(match func(configId string, symbol names.Symbol) bool) map[names.Symbol]float64 {
+	symbols := []names.Symbol{}
+	l.lock.RLock()
+	for symbol, lots := range l.lots {
+		for _, lt := range lots {
+			if match(lt.configId, symbol) {
+				symbols = append(symbols, symbol)
+				break
+			}
+		}
+	}
+	l.lock.RUnlock()
+
+	prices := map[names.Symbol]float64{}
+	for _, symbol := range symbols {
+		price, err := l.prices(symbol.String())
+		if err != nil {
+			utils.LogWarn(fmt.Sprintf("<Ledger>: %s is valued at its cost, %s", symbol, err.Error()))
+		}
+		prices[symbol] = price
+	}
+	return prices
+}
+
+// pnl sums the stats and open lots matching match, open lots are valued at
+// the latest price of their symbol
+func (l *Ledger) pnl(match func(configId string, symbol names.Symbol) bool) PnL {
+	prices := l.lotPrices(match)
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	total := PnL{}
+	for k, stat := range l.stats {
+		if match(k.configId, k.symbol) {
+			total.add(*stat)
+		}
+	}
+
+	for symbol, lots := range l.lots {
+		for _, lt := range lots {
+			if !match(lt.configId, symbol) {
+				continue
+			}
+			// a lot opened after the prices were asked is valued at its cost
+			price := prices[symbol]
+			value := lt.cost
+			if price > 0 {
+				value = lt.quantity * price
+			}
+			total.Quantity += lt.quantity
+			total.Cost += lt.cost
+			total.Value += value
+			total.Unrealized += value - lt.cost
+		}
+	}
+	return total
+}
+
+func (l *Ledger) ConfigPnL(configId string) PnL {
+	return l.pnl(func(id string, _ names.Symbol) bool { return id == configId })
+}
+
+// SymbolPnL includes the holdings no config bought
+func (l *Ledger) SymbolPnL(symbol names.Symbol) PnL {
+	return l.pnl(func(_ string, s names.Symbol) bool { return s == symbol })
+}
+
+// PnL of the configs together, like the configs of a pool
+func (l *Ledger) PnL(configIds ...string) PnL {
+	ids := map[string]bool{}
+	for _, id := range configIds {
+		ids[id] = true
+	}
+	return l.pnl(func(id string, _ names.Symbol) bool { return ids[id] })
+}
+
+var (
+	defaultLedger *Ledger
+	defaultLock   sync.Mutex
+)
+
+// Default returns the ledger fed by the executors, it is loaded from the
+// default journal and matches lots by the env PNL_METHOD, FIFO when not set
+func Default() *Ledger {
+	defaultLock.Lock()
+	defer defaultLock.Unlock()
+	if defaultLedger != nil {
+		return defaultLedger
+	}
+	method := Method(strings.ToUpper(os.Getenv("PNL_METHOD")))
+	if method != "" && !method.IsValid() {
+		utils.LogWarn(fmt.Sprintf("<Ledger>: unknown PNL_METHOD %s, using %s", method, FIFO))
+	}
+	defaultLedger = New(method)
+	defaultLedger.Load(journal.Default().Find(journal.Query{}))
+	return defaultLedger
+}
+
+// Use replaces the ledger fed by the executors and returns the one it replaced
+func Use(l *Ledger) *Ledger {
+	defaultLock.Lock()
+	defer defaultLock.Unlock()
+	previous := defaultLedger
+	defaultLedger = l
+	return previous
+}
@@ -0,0 +1,165 @@
+package ledger
+
+import (
+	"fmt"
+	"testing"
+	"time"
+	"trading/exchange"
+	"trading/names"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var start = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func useSymbols() {
+	names.UseExchangeInfo(exchange.ExchangeInfo{Symbols: []exchange.SymbolInfo{
+		{Symbol: "BTCUSDT", BaseAsset: "BTC", QuoteAsset: "USDT"},
+		{Symbol: "ETHUSDT", BaseAsset: "ETH", QuoteAsset: "USDT"},
+	}})
+}
+
+func prices(list map[string]float64) func(string) (float64, error) {
+	return func(symbol string) (float64, error) {
+		if price, exist := list[symbol]; exist {
+			return price, nil
+		}
+		return 0, fmt.Errorf("no price for %s", symbol)
+	}
+}
+
+func fill(minute int, configId string, side names.TradeSide, price, quantity float64) Fill {
+	return Fill{
+		OrderId:  int64(minute),
+		ConfigId: configId,
+		Symbol:   "BTCUSDT",
+		Side:     side,
+		Price:    price,
+		Quantity: quantity,
+		Time:     start.Add(time.Duration(minute) * time.Minute),
+	}
+}
+
+// two buys of a config and a sell of half of them
+func booked(method Method) *Ledger {
+	l := New(method).UsePrices(prices(map[string]float64{"BTCUSDT": 130}))
+	l.Add(
+		fill(1, "a", names.TradeSideBuy, 100, 1),
+		fill(2, "a", names.TradeSideBuy, 120, 1),
+		fill(3, "a", names.TradeSideSell, 150, 1),
+	)
+	return l
+}
+
+func TestMethods(t *testing.T) {
+	useSymbols()
+	for _, c := range []struct {
+		method   Method
+		realized float64
+		entry    float64
+	}{
+		{FIFO, 50, 120},
+		{LIFO, 30, 100},
+		{Average, 40, 110},
+	} {
+		l := booked(c.method)
+		pnl := l.ConfigPnL("a")
+		assert.InDelta(t, c.realized, pnl.Realized, 1e-9, c.method)
+		assert.InDelta(t, 130-c.entry, pnl.Unrealized, 1e-9, c.method)
+		assert.InDelta(t, 1, pnl.Quantity, 1e-9, c.method)
+		entry, held := l.EntryPrice("a", "BTCUSDT")
+		assert.True(t, held)
+		assert.InDelta(t, c.entry, entry, 1e-9, c.method)
+	}
+}
+
+func TestFees(t *testing.T) {
+	useSymbols()
+	l := New(FIFO).UsePrices(prices(map[string]float64{"BTCUSDT": 100, "BNBUSDT": 300}))
+	buy := fill(1, "a", names.TradeSideBuy, 100, 1)
+	buy.Fee, buy.FeeAsset = 0.01, "BTC"
+	sell := fill(2, "a", names.TradeSideSell, 110, 0.99)
+	sell.Fee, sell.FeeAsset = 0.001, "BNB"
+	l.Add(buy, sell)
+
+	pnl := l.ConfigPnL("a")
+	assert.InDelta(t, 1.3, pnl.Fees, 1e-9, "a base fee is valued at the fill price and bnb at its price")
+	// 0.99 bought for 101 sold for 108.9 less the 0.3 fee
+	assert.InDelta(t, 108.9-101-0.3, pnl.Realized, 1e-9)
+	assert.InDelta(t, 0, pnl.Quantity, 1e-9, "the base fee was taken from the bought quantity")
+}
+
+func TestPricesOutsideLock(t *testing.T) {
+	useSymbols()
+	l := New(FIFO)
+	asked := []string{}
+	l.UsePrices(func(symbol string) (float64, error) {
+		// the ledger stays usable while a price is asked
+		if !l.lock.TryLock() {
+			t.Errorf("the price of %s is asked with the ledger locked", symbol)
+		} else {
+			l.lock.Unlock()
+		}
+		asked = append(asked, symbol)
+		return prices(map[string]float64{"BTCUSDT": 100, "BNBUSDT": 300})(symbol)
+	})
+	buy := fill(1, "a", names.TradeSideBuy, 100, 1)
+	buy.Fee, buy.FeeAsset = 0.001, "BNB"
+	l.Add(buy)
+
+	assert.InDelta(t, 0.3, l.ConfigPnL("a").Fees, 1e-9)
+	assert.Equal(t, []string{"BNBUSDT", "BTCUSDT"}, asked)
+}
+
+func TestUnmatchedAndOwners(t *testing.T) {
+	useSymbols()
+	l := New(FIFO).UsePrices(prices(map[string]float64{"BTCUSDT": 100}))
+	l.Add(
+		fill(1, "", names.TradeSideBuy, 80, 1),
+		fill(2, "b", names.TradeSideBuy, 90, 1),
+		fill(3, "a", names.TradeSideSell, 100, 2),
+	)
+
+	a := l.ConfigPnL("a")
+	assert.InDelta(t, 20, a.Realized, 1e-9, "a sells the lot no config bought, not the lot of b")
+	assert.InDelta(t, 1, a.Unmatched, 1e-9)
+	entry, held := l.EntryPrice("b", "BTCUSDT")
+	assert.True(t, held)
+	assert.Equal(t, 90.0, entry)
+	_, held = l.EntryPrice("a", "BTCUSDT")
+	assert.False(t, held)
+
+	symbol := l.SymbolPnL("BTCUSDT")
+	assert.InDelta(t, 30, symbol.Total(), 1e-9)
+	assert.InDelta(t, 10, l.PnL("a", "b").Unrealized, 1e-9)
+}
+
+func TestReplayAndDuplicates(t *testing.T) {
+	useSymbols()
+	l := New(FIFO).UsePrices(prices(map[string]float64{}))
+	assert.Equal(t, 1, l.Add(fill(3, "a", names.TradeSideSell, 150, 1)))
+	assert.InDelta(t, 1, l.ConfigPnL("a").Unmatched, 1e-9)
+
+	// the history arrives after our own sell
+	assert.Equal(t, 1, l.Add(fill(1, "", names.TradeSideBuy, 100, 1), fill(3, "a", names.TradeSideSell, 150, 1)))
+	pnl := l.ConfigPnL("a")
+	assert.InDelta(t, 50, pnl.Realized, 1e-9)
+	assert.Equal(t, 0.0, pnl.Unmatched)
+
+	assert.Equal(t, 0.0, l.Record(fill(5, "b", names.TradeSideSell, 50, 1)), "nothing held realizes nothing")
+}
+
+func TestImportOrders(t *testing.T) {
+	useSymbols()
+	l := New(FIFO).UsePrices(prices(map[string]float64{"BTCUSDT": 100}))
+	orders := []*exchange.Order{
+		{Symbol: "BTCUSDT", OrderId: 7, Side: "BUY", ExecutedQuantity: 2, QuoteQuantity: 180, Time: start.UnixMilli()},
+		{Symbol: "BTCUSDT", OrderId: 8, Side: "BUY"},
+	}
+	assert.Equal(t, 1, l.ImportOrders(orders, 0.001), "orders that did not fill are skipped")
+	assert.Equal(t, 0, l.ImportOrders(orders, 0.001))
+
+	entry, held := l.EntryPrice("a", "BTCUSDT")
+	assert.True(t, held, "configs fall back to the imported holdings")
+	assert.InDelta(t, 90.09, entry, 1e-9)
+}
@@ -1,8 +1,15 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"os"
 	"sync"
+	"trading/config"
 	"trading/names"
+	"trading/optimize"
+	"trading/server"
+	"trading/utils"
 
 	// "github.com/davecgh/go-spew/spew"
 	"github.com/joho/godotenv"
@@ -16,7 +23,65 @@ func init() {
 	names.LoadStoredExchangeInfo()
 }
 
+// runConfig starts every strategy of the config file and keeps them running,
+// the pools are served over http when address is set
+func runConfig(filename string, address string) {
+	cfg, err := config.Load(filename)
+	if err != nil {
+		utils.LogError(err, "<Config>: could not load "+filename)
+		os.Exit(1)
+	}
+	cfg.Start()
+	if address != "" {
+		go func() {
+			if err := server.NewServer(address).ListenAndServe(); err != nil {
+				utils.LogError(err, "<Server>: stopped")
+			}
+		}()
+	}
+	select {}
+}
+
+// runOptimize searches the strategy of the spec file and writes the winner
+// to the output of the spec
+func runOptimize(filename string) {
+	spec, err := optimize.LoadSpec(filename)
+	if err != nil {
+		utils.LogError(err, "<Optimize>: could not load "+filename)
+		os.Exit(1)
+	}
+	optimizer, err := spec.Optimizer()
+	if err != nil {
+		utils.LogError(err, "<Optimize>: could not load the candles of "+filename)
+		os.Exit(1)
+	}
+	result, err := optimizer.Run()
+	if err != nil {
+		utils.LogError(err, "<Optimize>: stopped")
+		os.Exit(1)
+	}
+	if spec.Output != "" {
+		if err := result.Config().Save(spec.Output); err != nil {
+			utils.LogError(err, "<Optimize>: could not write "+spec.Output)
+			os.Exit(1)
+		}
+		utils.LogInfo(fmt.Sprintf("<Optimize>: %s written to %s", result.Winner, spec.Output))
+	}
+}
+
 func main() {
+	configFile := flag.String("config", os.Getenv("TRADE_CONFIG"), "json or yaml file describing the strategies to run")
+	address := flag.String("http", os.Getenv("CONTROL_ADDR"), "address of the pool control server, e.g :8080 for 127.0.0.1:8080, changes need the env CONTROL_TOKEN")
+	optimizeFile := flag.String("optimize", "", "json or yaml spec of the strategy parameters to search, the winner is written as a config")
+	flag.Parse()
+	if *optimizeFile != "" {
+		runOptimize(*optimizeFile)
+		return
+	}
+	if *configFile != "" {
+		runConfig(*configFile, *address)
+		return
+	}
 
 	// Start the timer
 	// start := time.Now()
@@ -0,0 +1,274 @@
+// Package metrics keeps counters, gauges and summaries of the bots and
+// serves them in the Prometheus text format so they can be scraped from
+// /metrics and alerted on. Metrics are registered in the Default registry
+// when they are created, gauges that are cheaper to read when scraped than
+// to keep current are registered as functions
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"trading/utils"
+)
+
+type kind string
+
+const (
+	kindCounter kind = "counter"
+	kindGauge   kind = "gauge"
+	kindSummary kind = "summary"
+)
+
+type series struct {
+	labels []string
+	value  float64
+	sum    float64
+	count  uint64
+}
+
+type family struct {
+	name    string
+	help    string
+	kind    kind
+	labels  []string
+	lock    sync.Mutex
+	series  map[string]*series
+	collect func(emit func(value float64, labels ...string))
+}
+
+func seriesKey(labels []string) string {
+	return strings.Join(labels, "\xff")
+}
+
+// get returns the series of the label values, nil when their count is wrong
+func (f *family) get(labels []string) *series {
+	if len(labels) != len(f.labels) {
+		utils.LogWarn(fmt.Sprintf("<Metrics>: %s expects %d labels, got %d", f.name, len(f.labels), len(labels)))
+		return nil
+	}
+	key := seriesKey(labels)
+	s, exist := f.series[key]
+	if !exist {
+		s = &series{labels: append([]string{}, labels...)}
+		f.series[key] = s
+	}
+	return s
+}
+
+func (f *family) delete(labels []string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	delete(f.series, seriesKey(labels))
+}
+
+type Counter struct {
+	family *family
+}
+
+// Inc adds one to the counter of the label values
+func (c *Counter) Inc(labels ...string) {
+	c.Add(1, labels...)
+}
+
+// Add adds value to the counter of the label values, counters only go up
+func (c *Counter) Add(value float64, labels ...string) {
+	if value < 0 {
+		return
+	}
+	c.family.lock.Lock()
+	defer c.family.lock.Unlock()
+	if s := c.family.get(labels); s != nil {
+		s.value += value
+	}
+}
+
+type Gauge struct {
+	family *family
+}
+
+func (g *Gauge) Set(value float64, labels ...string) {
+	g.family.lock.Lock()
+	defer g.family.lock.Unlock()
+	if s := g.family.get(labels); s != nil {
+		s.value = value
+	}
+}
+
+func (g *Gauge) Add(value float64, labels ...string) {
+	g.family.lock.Lock()
+	defer g.family.lock.Unlock()
+	if s := g.family.get(labels); s != nil {
+		s.value += value
+	}
+}
+
+// Delete stops reporting the gauge of the label values
+func (g *Gauge) Delete(labels ...string) {
+	g.family.delete(labels)
+}
+
+// Summary reports the sum and count of the observed values, their rate
+// gives the average over any window
+type Summary struct {
+	family *family
+}
+
+func (s *Summary) Observe(value float64, labels ...string) {
+	s.family.lock.Lock()
+	defer s.family.lock.Unlock()
+	if series := s.family.get(labels); series != nil {
+		series.sum += value
+		series.count++
+	}
+}
+
+type Registry struct {
+	lock     sync.RWMutex
+	families map[string]*family
+}
+
+func NewRegistry() *Registry {
+	return &Registry{families: map[string]*family{}}
+}
+
+// Default is the registry the metrics are created in and /metrics serves
+var Default = NewRegistry()
+
+func (r *Registry) register(f *family) *family {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, exist := r.families[f.name]; exist {
+		utils.LogWarn(fmt.Sprintf("<Metrics>: %s is registered twice, the last one is kept", f.name))
+	}
+	r.families[f.name] = f
+	return f
+}
+
+func newFamily(name, help string, k kind, labels []string) *family {
+	return &family{name: name, help: help, kind: k, labels: labels, series: map[string]*series{}}
+}
+
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{family: r.register(newFamily(name, help, kindCounter, labels))}
+}
+
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{family: r.register(newFamily(name, help, kindGauge, labels))}
+}
+
+func (r *Registry) NewSummary(name, help string, labels ...string) *Summary {
+	return &Summary{family: r.register(newFamily(name, help, kindSummary, labels))}
+}
+
+// NewGaugeFunc registers a gauge that collect emits every value of when it is scraped
+func (r *Registry) NewGaugeFunc(name, help string, labels []string, collect func(emit func(value float64, labels ...string))) {
+	f := newFamily(name, help, kindGauge, labels)
+	f.collect = collect
+	r.register(f)
+}
+
+func NewCounter(name, help string, labels ...string) *Counter {
+	return Default.NewCounter(name, help, labels...)
+}
+
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return Default.NewGauge(name, help, labels...)
+}
+
+func NewSummary(name, help string, labels ...string) *Summary {
+	return Default.NewSummary(name, help, labels...)
+}
+
+func NewGaugeFunc(name, help string, labels []string, collect func(emit func(value float64, labels ...string))) {
+	Default.NewGaugeFunc(name, help, labels, collect)
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf(`%s="%s"`, name, escaper.Replace(values[i]))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// snapshot copies the series of the family, the ones of a gauge function are collected
+func (f *family) snapshot() []series {
+	list := []series{}
+	if f.collect != nil {
+		f.collect(func(value float64, labels ...string) {
+			if len(labels) != len(f.labels) {
+				utils.LogWarn(fmt.Sprintf("<Metrics>: %s expects %d labels, got %d", f.name, len(f.labels), len(labels)))
+				return
+			}
+			list = append(list, series{labels: labels, value: value})
+		})
+	} else {
+		f.lock.Lock()
+		for _, s := range f.series {
+			list = append(list, *s)
+		}
+		f.lock.Unlock()
+	}
+	sort.Slice(list, func(i, j int) bool { return seriesKey(list[i].labels) < seriesKey(list[j].labels) })
+	return list
+}
+
+// Write writes every metric of the registry in the Prometheus text format
+func (r *Registry) Write(w io.Writer) error {
+	r.lock.RLock()
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.lock.RUnlock()
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	out := bufio.NewWriter(w)
+	for _, f := range families {
+		fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s %s\n", f.name, escaper.Replace(f.help), f.name, f.kind)
+		for _, s := range f.snapshot() {
+			labels := formatLabels(f.labels, s.labels)
+			if f.kind == kindSummary {
+				fmt.Fprintf(out, "%s_sum%s %s\n", f.name, labels, formatValue(s.sum))
+				fmt.Fprintf(out, "%s_count%s %d\n", f.name, labels, s.count)
+				continue
+			}
+			fmt.Fprintf(out, "%s%s %s\n", f.name, labels, formatValue(s.value))
+		}
+	}
+	return out.Flush()
+}
+
+// Handler serves the metrics of the Default registry
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := Default.Write(w); err != nil {
+			utils.LogError(err, "<Metrics>: could not write the metrics")
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrite(t *testing.T) {
+	r := NewRegistry()
+	orders := r.NewCounter("orders_total", "orders sent", "symbol", "result")
+	orders.Inc("BTCUSDT", "placed")
+	orders.Add(2, "BTCUSDT", "placed")
+	orders.Add(-1, "BTCUSDT", "placed")
+	orders.Inc("ETHUSDT", "failed")
+	orders.Inc("ETHUSDT")
+
+	balance := r.NewGauge("balance", "free balance", "asset")
+	balance.Set(10, "USDT")
+	balance.Set(3, `B"T\C`)
+	balance.Delete(`B"T\C`)
+
+	latency := r.NewSummary("latency_seconds", "tick latency", "symbol")
+	latency.Observe(0.5, "BTCUSDT")
+	latency.Observe(0.25, "BTCUSDT")
+
+	r.NewGaugeFunc("subscriptions", "subscribers\nof a broadcast", []string{"broadcast"}, func(emit func(float64, ...string)) {
+		emit(2, "b")
+		emit(1, "a")
+		emit(5)
+	})
+
+	out := &strings.Builder{}
+	assert.Nil(t, r.Write(out))
+	assert.Equal(t, `# HELP balance free balance
+# TYPE balance gauge
+balance{asset="USDT"} 10
+# HELP latency_seconds tick latency
+# TYPE latency_seconds summary
+latency_seconds_sum{symbol="BTCUSDT"} 0.75
+latency_seconds_count{symbol="BTCUSDT"} 2
+# HELP orders_total orders sent
+# TYPE orders_total counter
+orders_total{symbol="BTCUSDT",result="placed"} 3
+orders_total{symbol="ETHUSDT",result="failed"} 1
+# HELP subscriptions subscribers\nof a broadcast
+# TYPE subscriptions gauge
+subscriptions{broadcast="a"} 1
+subscriptions{broadcast="b"} 2
+`, out.String())
+}
+
+func TestLabelEscaping(t *testing.T) {
+	assert.Equal(t, `{asset="B\"T\\C\n"}`, formatLabels([]string{"asset"}, []string{"B\"T\\C\n"}))
+	assert.Equal(t, "", formatLabels(nil, nil))
+}
+
+func TestHandler(t *testing.T) {
+	NewCounter("test_handler_total", "requests of the test").Inc()
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Header().Get("Content-Type"), "version=0.0.4")
+	assert.Contains(t, recorder.Body.String(), "test_handler_total 1\n")
+}
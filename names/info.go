@@ -1,41 +1,48 @@
 package names
 
 import (
-	"context"
 	"fmt"
-	binLib "github.com/adshao/go-binance/v2"
-	"trading/binance"
+	"trading/exchange"
+	"trading/utils"
 	"encoding/json"
 	"trading/constant"
 )
 
-func loadInfoString() binLib.ExchangeInfo {
-	var exchange binLib.ExchangeInfo
+func loadInfoString() exchange.ExchangeInfo {
+	var exchange exchange.ExchangeInfo
 	json.Unmarshal([]byte(constant.ExchangeInfo), &exchange)
 	return exchange
 }
 
-var exchangeInfo binLib.ExchangeInfo = binLib.ExchangeInfo{}
+var exchangeInfo exchange.ExchangeInfo = exchange.ExchangeInfo{}
+var exchangeInfoInUse bool
 
-func LoadStoredExchangeInfo() binLib.ExchangeInfo {
-	if exchangeInfo.ServerTime == 0 {
+func LoadStoredExchangeInfo() exchange.ExchangeInfo {
+	if exchangeInfo.ServerTime == 0 && !exchangeInfoInUse {
 		data := loadInfoString()
 		exchangeInfo = data
 	}
 	return exchangeInfo
 }
 
+// UseExchangeInfo replaces the stored exchange info used to resolve
+// symbol pairs and filters
+func UseExchangeInfo(info exchange.ExchangeInfo) {
+	exchangeInfo = info
+	exchangeInfoInUse = true
+}
+
 type infoService struct {
-	binLib.ExchangeInfo
+	exchange.ExchangeInfo
 }
 
 func GetNewInfo() infoService {
-	data, _ := binance.GetClient().NewExchangeInfoService().Do(context.Background())
-	getInfo := func() binLib.ExchangeInfo {
-		return *data
+	data, err := exchange.Get().ExchangeInfo()
+	if err != nil {
+		utils.LogError(err, "<Info>: could not get the exchange info")
+		return infoService{}
 	}
-	info := getInfo()
-	return infoService{info}
+	return infoService{*data}
 }
 
 func (info infoService) IsTrading(symbol Symbol) bool {
@@ -55,13 +62,13 @@ func (info infoService) Spotable(symbol Symbol) bool {
 	return isTrading && s.IsSpotTradingAllowed
 }
 
-func (info infoService) findSymbol(symbol Symbol) (binLib.Symbol, error) {
+func (info infoService) findSymbol(symbol Symbol) (exchange.SymbolInfo, error) {
 	for _, s := range info.Symbols {
 		if s.Symbol == symbol.String() {
 			return s, nil
 		}
 	}
-	return binLib.Symbol{}, fmt.Errorf("no info for symbol '%s'", symbol.String())
+	return exchange.SymbolInfo{}, fmt.Errorf("no info for symbol '%s'", symbol.String())
 }
 
 func (info infoService) SpotableSymbol() []Symbol {
@@ -76,7 +83,7 @@ func (info infoService) SpotableSymbol() []Symbol {
 }
 
 func (info infoService) SpotableSymbolInfo() SymbolInfo {
-	spotableSymbolInfo := []binLib.Symbol{}
+	spotableSymbolInfo := []exchange.SymbolInfo{}
 	for _, s := range info.Symbols {
 		symbol := Symbol(s.Symbol)
 		if info.Spotable(symbol) {
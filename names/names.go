@@ -67,6 +67,38 @@ type SideConfig struct {
 	//determines what percentage change in price to lock positive price movement
 	LockDelta     float64
 	DeviationSync DeviationSync
+	// the kind of order the side is traded with, market when empty
+	Order OrderConfig
+	// exits the side when the price moves against it, regardless of MustProfit
+	StopLoss StopLoss
+	// percent a market order may fill away from the market price according
+	// to the order book, zero does not limit it
+	MaxSlippage float64
+}
+
+// StopLoss is a percent of the entry price of a side or a fixed price, a sell
+// stops below it and a buy above it. A zero value disables the stop loss
+type StopLoss struct {
+	Value float64   `json:"value" yaml:"value"`
+	Type  StopLimit `json:"type" yaml:"type"`
+}
+
+func (s StopLoss) IsSet() bool {
+	return s.Value > 0
+}
+
+// Price at which a side entered at entryPrice stops, zero when not set
+func (s StopLoss) Price(side TradeSide, entryPrice float64) float64 {
+	if !s.IsSet() {
+		return 0
+	}
+	if s.Type.IsFixed() {
+		return s.Value
+	}
+	if side.IsBuy() {
+		return entryPrice * (1 + s.Value/100)
+	}
+	return entryPrice * (1 - s.Value/100)
 }
 
 type TradeConfig struct {
@@ -142,6 +174,9 @@ type LockState struct {
 	RedemptionCandidateCallback func(LockInterface)
 	MinimumLockUnit             float64
 	AbsoluteGrowth              float64
+	StopLossPrice               float64 // zero when the config has no stop loss
+	IsStopLossHit               bool
+	TrailingStop                float64 // price a trailing lock is due at, zero for other locks
 }
 
 type LockInterface interface {
@@ -153,10 +188,23 @@ type LockInterface interface {
 	SetVerbose(verbose bool)
 	TradeSide() TradeSide
 	IsRedemptionDue() bool
+	// the price has crossed the stop loss, the lock is due whatever its gains
+	IsStopLossHit() bool
 	GetLockManager() LockManagerInterface
 	RemoveFromManager() bool
 }
 
+// StopsLock is a lock whose StopLimit and LockDelta follow the market, they
+// replace the percents of the side the config is trading. The config of the
+// lock state is left as it was added
+type StopsLock interface {
+	UseStops(stopLimit, lockDelta float64)
+}
+
+// EntryPriceFunc is the cost of the position configId holds in symbol, false
+// when it holds none
+type EntryPriceFunc func(configId string, symbol Symbol) (float64, bool)
+
 // type LockCreatorFunc func(price float64, tradeConfig TradeConfig, redemptionIsMature bool, pretradePrice float64, lockManager  TradeLockManagerInterface, gainsAccrude float64) LockInterface
 type LockCreatorFunc func(price float64, tradeConfig TradeConfig, redemptionIsMature bool, pretradePrice float64, lockManager LockManagerInterface, gainsAccrude float64) LockInterface
 
@@ -169,6 +217,38 @@ type LockManagerInterface interface {
 	RemoveLock(lock LockInterface) bool
 	RemoveLocks() bool
 	RetrieveLocks() map[Symbol]LockInterface
+	// locks added after a restore start from the restored record of their config
+	RestoreLocks(records []LockRecord)
+	// set where the cost of the position of a selling config is read
+	UseEntryPrices(entryPrices EntryPriceFunc)
+	// price the config entered its current side at, it is kept when the
+	// config is watched again on the same side
+	EntryPrice(config TradeConfig) float64
+	// price at which the config stops out, zero when it has no stop loss
+	StopLossPrice(config TradeConfig) float64
+	// every sell lock stops out at its next price
+	ForceExit()
+}
+
+// LockRecord is the part of a lock state kept to restore the lock after a restart
+type LockRecord struct {
+	ConfigId      string    `json:"configId"`
+	Symbol        Symbol    `json:"symbol"`
+	Side          TradeSide `json:"side"`
+	Price         float64   `json:"price"`
+	PretradePrice float64   `json:"pretradePrice"`
+	AccrudGains   float64   `json:"accrudGains"`
+}
+
+func NewLockRecord(state LockState) LockRecord {
+	return LockRecord{
+		ConfigId:      state.TradeConfig.Id,
+		Symbol:        state.TradeConfig.Symbol,
+		Side:          state.TradeConfig.Side,
+		Price:         state.Price,
+		PretradePrice: state.PretradePrice,
+		AccrudGains:   state.AccrudGains,
+	}
 }
 
 type ExecutorFunc = func(
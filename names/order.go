@@ -0,0 +1,123 @@
+package names
+
+import (
+	"math"
+	"time"
+)
+
+type OrderType string
+
+const (
+	OrderTypeMarket          OrderType = "MARKET"
+	OrderTypeLimit           OrderType = "LIMIT"
+	OrderTypeStopLossLimit   OrderType = "STOP_LOSS_LIMIT"
+	OrderTypeTakeProfitLimit OrderType = "TAKE_PROFIT_LIMIT"
+	// a limit order and a stop loss limit order where the fill of one cancels the other
+	OrderTypeOCO OrderType = "OCO"
+)
+
+func (t OrderType) IsMarket() bool {
+	return t == "" || t == OrderTypeMarket
+}
+
+func (t OrderType) IsValid() bool {
+	switch t {
+	case "", OrderTypeMarket, OrderTypeLimit, OrderTypeStopLossLimit, OrderTypeTakeProfitLimit, OrderTypeOCO:
+		return true
+	}
+	return false
+}
+
+type TimeInForce string
+
+const (
+	TimeInForceGTC TimeInForce = "GTC"
+	TimeInForceIOC TimeInForce = "IOC"
+	TimeInForceFOK TimeInForce = "FOK"
+)
+
+func (t TimeInForce) IsValid() bool {
+	return t == "" || t == TimeInForceGTC || t == TimeInForceIOC || t == TimeInForceFOK
+}
+
+// OrderConfig decides how a side is traded, the prices of the order are
+// percentages of the market price when the order is placed. An empty
+// config trades with a market order
+type OrderConfig struct {
+	Type OrderType `json:"type" yaml:"type"`
+	// GTC when empty
+	TimeInForce TimeInForce `json:"timeInForce" yaml:"timeInForce"`
+	// percent below the market price for a buy and above it for a sell
+	// of the limit order or the limit leg of an OCO order
+	LimitOffset float64 `json:"limitOffset" yaml:"limitOffset"`
+	// percent from the market price the order is triggered at, against the
+	// trade for stop loss and OCO orders and with it for take profit orders
+	StopOffset float64 `json:"stopOffset" yaml:"stopOffset"`
+	// percent past the stop price the triggered limit order is placed at
+	// so that it fills when the price moves fast
+	StopLimitOffset float64 `json:"stopLimitOffset" yaml:"stopLimitOffset"`
+	// seconds to wait for the order before what is not filled is canceled,
+	// DefaultOrderTimeout when zero
+	Timeout int `json:"timeout" yaml:"timeout"`
+}
+
+// seconds an order waits to be filled when its config has no timeout
+var DefaultOrderTimeout = 300
+
+func (o OrderConfig) GetTimeInForce() TimeInForce {
+	if o.TimeInForce == "" {
+		return TimeInForceGTC
+	}
+	return o.TimeInForce
+}
+
+func (o OrderConfig) GetTimeout() time.Duration {
+	if o.Timeout <= 0 {
+		return time.Duration(DefaultOrderTimeout) * time.Second
+	}
+	return time.Duration(o.Timeout) * time.Second
+}
+
+type OrderPrices struct {
+	Price          float64
+	StopPrice      float64
+	StopLimitPrice float64
+}
+
+// Highest is the most a single unit of the order can cost
+func (p OrderPrices) Highest() float64 {
+	return math.Max(p.Price, p.StopLimitPrice)
+}
+
+// Prices of an order of side placed when the market is at spot
+func (o OrderConfig) Prices(side TradeSide, spot float64) OrderPrices {
+	// moves price by percent in favour of the trade, a buy favours lower prices
+	favour := func(price, percent float64) float64 {
+		if side.IsBuy() {
+			return price * (1 - percent/100)
+		}
+		return price * (1 + percent/100)
+	}
+	against := func(price, percent float64) float64 {
+		return favour(price, -percent)
+	}
+
+	switch o.Type {
+	case OrderTypeLimit:
+		return OrderPrices{Price: favour(spot, o.LimitOffset)}
+	case OrderTypeStopLossLimit:
+		stop := against(spot, o.StopOffset)
+		return OrderPrices{Price: against(stop, o.StopLimitOffset), StopPrice: stop}
+	case OrderTypeTakeProfitLimit:
+		stop := favour(spot, o.StopOffset)
+		return OrderPrices{Price: against(stop, o.StopLimitOffset), StopPrice: stop}
+	case OrderTypeOCO:
+		stop := against(spot, o.StopOffset)
+		return OrderPrices{
+			Price:          favour(spot, o.LimitOffset),
+			StopPrice:      stop,
+			StopLimitPrice: against(stop, o.StopLimitOffset),
+		}
+	}
+	return OrderPrices{Price: spot}
+}
@@ -1,20 +1,16 @@
 package names
 
 import (
-	"context"
-	"strconv"
 	"sync"
-	"trading/binance"
+	"trading/exchange"
 	"trading/utils"
-
-	binLib "github.com/adshao/go-binance/v2"
 )
 
 type SymbolInfo struct {
-	symbols []binLib.Symbol
+	symbols []exchange.SymbolInfo
 }
 
-func NewSymbolInfo(symbols []binLib.Symbol) SymbolInfo {
+func NewSymbolInfo(symbols []exchange.SymbolInfo) SymbolInfo {
 	return SymbolInfo{symbols}
 }
 
@@ -73,21 +69,19 @@ func GetTradeFees(symbols []string) map[string]tradeFeeDetails {
 				return
 			}
 
-			data, err := binance.GetClient().NewTradeFeeService().Symbol(s).Do(context.Background())
+			fee, err := exchange.Get().TradeFee(s)
 			if err != nil {
 				utils.LogError(err, "Could not get trading fees")
 				wg.Done()
 				return
 			}
-			MakerCommission, _ := strconv.ParseFloat(data[0].MakerCommission, 64)
-			TakerCommission, _ := strconv.ParseFloat(data[0].TakerCommission, 64)
 
 			rwLock.Lock()
 
 			symbolFees[s] = tradeFeeDetails{
-				Symbol:          data[0].Symbol,
-				MakerCommission: MakerCommission,
-				TakerCommission: TakerCommission,
+				Symbol:          fee.Symbol,
+				MakerCommission: fee.Maker,
+				TakerCommission: fee.Taker,
 			}
 
 			rwLock.Unlock()
@@ -2,10 +2,10 @@ package names
 
 import (
 	"fmt"
-	binLib "github.com/adshao/go-binance/v2"
 	"math"
-	// "strconv"
+	"strconv"
 	"strings"
+	"trading/exchange"
 )
 
 type Symbol string
@@ -48,13 +48,13 @@ func (s Symbol) FormatQuotePrice(price float64) string {
 	return fmt.Sprintf("%f%s", price, quoteSymbol)
 }
 
-func (s Symbol) Info() binLib.Symbol {
+func (s Symbol) Info() exchange.SymbolInfo {
 	for _, symbol := range GetStoredInfo().symbols {
 		if s.String() == symbol.Symbol {
 			return symbol
 		}
 	}
-	return binLib.Symbol{}
+	return exchange.SymbolInfo{}
 }
 
 // func (s Symbol) Quantity(quantity float64) float64 {
@@ -123,3 +123,28 @@ func (f filter) TickSize() string {
 	}
 	return ""
 }
+
+func (f filter) value(filterType, key string) float64 {
+	for _, ff := range f {
+		if ff["filterType"] == filterType {
+			if value, ok := ff[key].(string); ok {
+				number, _ := strconv.ParseFloat(value, 64)
+				return number
+			}
+		}
+	}
+	return 0
+}
+
+// LotSize returns the minimum, maximum and step of an order quantity, zero when not set
+func (f filter) LotSize() (minQty, maxQty, stepSize float64) {
+	return f.value("LOT_SIZE", "minQty"), f.value("LOT_SIZE", "maxQty"), f.value("LOT_SIZE", "stepSize")
+}
+
+// MinNotional returns the minimum value of an order in the quote asset
+func (f filter) MinNotional() float64 {
+	if notional := f.value("MIN_NOTIONAL", "minNotional"); notional != 0 {
+		return notional
+	}
+	return f.value("NOTIONAL", "minNotional")
+}
@@ -0,0 +1,148 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/smtp"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+var client = &http.Client{Timeout: 10 * time.Second}
+
+func postJson(url string, body any) error {
+	content, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	response, err := client.Post(url, "application/json", bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("%s responded %d %s", url, response.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+type webhook struct {
+	url string
+}
+
+// Webhook posts every event as json to url
+func Webhook(url string) Notifier {
+	return webhook{url: url}
+}
+
+func (webhook) Name() string {
+	return "webhook"
+}
+
+func (w webhook) Notify(e Event) error {
+	return postJson(w.url, e)
+}
+
+type telegram struct {
+	baseURL string
+	token   string
+	chatId  string
+}
+
+// TelegramAPI is the bot api the telegram notifier uses when no other is given
+const TelegramAPI = "https://api.telegram.org"
+
+// Telegram sends the text of events to a chat through the bot api at
+// baseURL, any server compatible with the telegram bot api can be used
+func Telegram(baseURL, token, chatId string) Notifier {
+	if baseURL == "" {
+		baseURL = TelegramAPI
+	}
+	return telegram{baseURL: strings.TrimRight(baseURL, "/"), token: token, chatId: chatId}
+}
+
+func (telegram) Name() string {
+	return "telegram"
+}
+
+func (t telegram) Notify(e Event) error {
+	return postJson(fmt.Sprintf("%s/bot%s/sendMessage", t.baseURL, t.token), map[string]string{
+		"chat_id": t.chatId,
+		"text":    e.Text(),
+	})
+}
+
+type email struct {
+	address  string
+	username string
+	password string
+	from     string
+	to       []string
+	send     func(address string, auth smtp.Auth, from string, to []string, message []byte) error
+}
+
+// Email sends events through the smtp server at address (host:port), the
+// server is not authenticated with when username is empty
+func Email(address, username, password, from string, to []string) Notifier {
+	return email{address: address, username: username, password: password, from: from, to: to, send: smtp.SendMail}
+}
+
+func (email) Name() string {
+	return "email"
+}
+
+func (m email) message(e Event) []byte {
+	headers := []string{
+		"From: " + m.from,
+		"To: " + strings.Join(m.to, ", "),
+		"Subject: [trading] " + e.Title,
+		"Date: " + e.Time.Format(time.RFC1123Z),
+		"Content-Type: text/plain; charset=utf-8",
+	}
+	return []byte(strings.Join(headers, "\r\n") + "\r\n\r\n" + strings.ReplaceAll(e.Text(), "\n", "\r\n") + "\r\n")
+}
+
+func (m email) Notify(e Event) error {
+	var auth smtp.Auth
+	if m.username != "" {
+		host := strings.Split(m.address, ":")[0]
+		auth = smtp.PlainAuth("", m.username, m.password, host)
+	}
+	return m.send(m.address, auth, m.from, m.to, m.message(e))
+}
+
+type desktop struct{}
+
+// Desktop shows events as notifications of the desktop with notify-send on
+// linux and osascript on mac
+func Desktop() Notifier {
+	return desktop{}
+}
+
+func (desktop) Name() string {
+	return "desktop"
+}
+
+func (desktop) Notify(e Event) error {
+	var cmd *exec.Cmd
+	body := strings.TrimPrefix(e.Text(), e.Title+"\n")
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %q with title %q", body, e.Title)
+		cmd = exec.Command("osascript", "-e", script)
+	case "linux":
+		cmd = exec.Command("notify-send", e.Title, body)
+	default:
+		return fmt.Errorf("desktop notifications are not supported on %s", runtime.GOOS)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
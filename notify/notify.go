@@ -0,0 +1,181 @@
+// Package notify sends events of the bots, like executed trades, order
+// errors or a stream that stopped ticking, to webhooks, Telegram, email or
+// the desktop. Every notifier is added to the dispatcher with the events it
+// is sent, the dispatcher delivers them in the background so a slow
+// notifier never holds up a trade
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"trading/utils"
+)
+
+type EventType string
+
+const (
+	EventTrade      EventType = "trade"
+	EventOrderError EventType = "orderError"
+	EventDeviation  EventType = "deviation"
+	EventFailover   EventType = "failover"
+	EventDrawdown   EventType = "drawdown"
+	EventStall      EventType = "stall"
+)
+
+var EventTypes = []EventType{EventTrade, EventOrderError, EventDeviation, EventFailover, EventDrawdown, EventStall}
+
+func (t EventType) IsValid() bool {
+	for _, known := range EventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Field is a named value of an event, the fields of a trade are the ones
+// of the trade summary
+type Field struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type Event struct {
+	Type   EventType `json:"type"`
+	Time   time.Time `json:"time"`
+	Title  string    `json:"title"`
+	Fields []Field   `json:"fields"`
+}
+
+// NewEvent creates an event of now, fields are pairs of names and values
+func NewEvent(t EventType, title string, fields ...string) Event {
+	e := Event{Type: t, Time: utils.Now(), Title: title}
+	for i := 0; i+1 < len(fields); i += 2 {
+		e.Fields = append(e.Fields, Field{Name: fields[i], Value: fields[i+1]})
+	}
+	return e
+}
+
+// Text is the title followed by a line for every field
+func (e Event) Text() string {
+	width := 0
+	for _, f := range e.Fields {
+		if len(f.Name) > width {
+			width = len(f.Name)
+		}
+	}
+	lines := []string{e.Title}
+	for _, f := range e.Fields {
+		lines = append(lines, fmt.Sprintf("%-*s : %s", width, f.Name, f.Value))
+	}
+	return strings.Join(lines, "\n")
+}
+
+type Notifier interface {
+	Name() string
+	Notify(event Event) error
+}
+
+type route struct {
+	notifier Notifier
+	events   map[EventType]bool
+}
+
+func (r route) accepts(t EventType) bool {
+	return len(r.events) == 0 || r.events[t]
+}
+
+type Dispatcher struct {
+	lock     sync.Mutex
+	routes   []route
+	cooldown time.Duration
+	// when an event of the same type and title was last sent
+	sent    map[string]time.Time
+	pending sync.WaitGroup
+}
+
+func New() *Dispatcher {
+	return &Dispatcher{sent: map[string]time.Time{}}
+}
+
+// Add sends the events of the types to n, every event when no type is given
+func (d *Dispatcher) Add(n Notifier, events ...EventType) *Dispatcher {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	r := route{notifier: n, events: map[EventType]bool{}}
+	for _, t := range events {
+		r.events[t] = true
+	}
+	d.routes = append(d.routes, r)
+	return d
+}
+
+// UseCooldown drops an event sent again with the same type and title within
+// duration, like an order error repeated on every tick
+func (d *Dispatcher) UseCooldown(duration time.Duration) *Dispatcher {
+	d.cooldown = duration
+	return d
+}
+
+// Send delivers the event to the notifiers of its type in the background
+func (d *Dispatcher) Send(e Event) {
+	d.lock.Lock()
+	if d.cooldown > 0 {
+		key := string(e.Type) + ":" + e.Title
+		if last, exist := d.sent[key]; exist && e.Time.Sub(last) < d.cooldown {
+			d.lock.Unlock()
+			return
+		}
+		d.sent[key] = e.Time
+	}
+	routes := append([]route{}, d.routes...)
+	d.lock.Unlock()
+
+	for _, r := range routes {
+		if !r.accepts(e.Type) {
+			continue
+		}
+		d.pending.Add(1)
+		go func(n Notifier) {
+			defer d.pending.Done()
+			if err := n.Notify(e); err != nil {
+				utils.LogError(err, fmt.Sprintf("<Notify>: %s could not send %s", n.Name(), e.Type))
+			}
+		}(r.notifier)
+	}
+}
+
+// Wait blocks until every event sent is delivered
+func (d *Dispatcher) Wait() {
+	d.pending.Wait()
+}
+
+var (
+	current *Dispatcher
+	lock    sync.RWMutex
+)
+
+// Get returns the dispatcher events are sent to, nil when nothing is notified
+func Get() *Dispatcher {
+	lock.RLock()
+	defer lock.RUnlock()
+	return current
+}
+
+// Use sends events to d and returns the dispatcher it replaced
+func Use(d *Dispatcher) *Dispatcher {
+	lock.Lock()
+	defer lock.Unlock()
+	previous := current
+	current = d
+	return previous
+}
+
+// Send sends the event to the dispatcher in use, if any
+func Send(e Event) {
+	if d := Get(); d != nil {
+		d.Send(e)
+	}
+}
@@ -0,0 +1,142 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"trading/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recorder struct {
+	lock   sync.Mutex
+	events []Event
+	err    error
+}
+
+func (r *recorder) Name() string {
+	return "recorder"
+}
+
+func (r *recorder) Notify(e Event) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.events = append(r.events, e)
+	return r.err
+}
+
+func (r *recorder) titles() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	titles := []string{}
+	for _, e := range r.events {
+		titles = append(titles, e.Title)
+	}
+	return titles
+}
+
+func TestEventText(t *testing.T) {
+	e := NewEvent(EventTrade, "BUY TRADE SUMMARY BTCUSDT", "Symbol", "BTCUSDT", "Ticker Price", "100USDT", "odd")
+	assert.Len(t, e.Fields, 2, "a name without a value is dropped")
+	assert.Equal(t, "BUY TRADE SUMMARY BTCUSDT\nSymbol       : BTCUSDT\nTicker Price : 100USDT", e.Text())
+}
+
+func TestDispatcherRoutes(t *testing.T) {
+	trades, errs, all := &recorder{}, &recorder{err: errors.New("down")}, &recorder{}
+	d := New().Add(trades, EventTrade).Add(errs, EventOrderError, EventStall).Add(all)
+
+	d.Send(NewEvent(EventTrade, "trade"))
+	d.Send(NewEvent(EventOrderError, "error"))
+	d.Send(NewEvent(EventFailover, "failover"))
+	d.Wait()
+
+	assert.Equal(t, []string{"trade"}, trades.titles())
+	assert.Equal(t, []string{"error"}, errs.titles(), "a failing notifier is logged")
+	assert.ElementsMatch(t, []string{"trade", "error", "failover"}, all.titles())
+}
+
+func TestDispatcherCooldown(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	utils.UseClock(func() time.Time { return now })
+	defer utils.UseClock(nil)
+
+	r := &recorder{}
+	d := New().Add(r).UseCooldown(time.Minute)
+	d.Send(NewEvent(EventOrderError, "BUY BTCUSDT ORDER FAILED"))
+	d.Send(NewEvent(EventOrderError, "BUY BTCUSDT ORDER FAILED"))
+	d.Send(NewEvent(EventOrderError, "BUY ETHUSDT ORDER FAILED"))
+	now = now.Add(time.Minute)
+	d.Send(NewEvent(EventOrderError, "BUY BTCUSDT ORDER FAILED"))
+	d.Wait()
+	assert.Len(t, r.titles(), 3)
+}
+
+func TestSendWithoutDispatcher(t *testing.T) {
+	previous := Use(nil)
+	defer Use(previous)
+	Send(NewEvent(EventTrade, "nobody listens"))
+
+	r := &recorder{}
+	d := New().Add(r)
+	Use(d)
+	Send(NewEvent(EventTrade, "trade"))
+	d.Wait()
+	assert.Equal(t, []string{"trade"}, r.titles())
+}
+
+func TestWebhookAndTelegram(t *testing.T) {
+	requests := map[string]map[string]any{}
+	var lock sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		decoded := map[string]any{}
+		json.Unmarshal(body, &decoded)
+		lock.Lock()
+		requests[r.URL.Path] = decoded
+		lock.Unlock()
+		if r.URL.Path == "/fail" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	e := NewEvent(EventStall, "PRICES STOPPED TICKING", "Silent For", "5m0s")
+	assert.Nil(t, Webhook(server.URL+"/hook").Notify(e))
+	assert.Equal(t, "stall", requests["/hook"]["type"])
+	assert.Equal(t, "PRICES STOPPED TICKING", requests["/hook"]["title"])
+
+	assert.Nil(t, Telegram(server.URL+"/", "TOKEN", "42").Notify(e))
+	message := requests["/botTOKEN/sendMessage"]
+	assert.Equal(t, "42", message["chat_id"])
+	assert.Equal(t, e.Text(), message["text"])
+
+	err := Webhook(server.URL + "/fail").Notify(e)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "400 bad request")
+}
+
+func TestEmail(t *testing.T) {
+	var sent []byte
+	var auth smtp.Auth
+	m := Email("smtp.example.com:587", "bot", "secret", "bot@example.com", []string{"a@example.com", "b@example.com"}).(email)
+	m.send = func(address string, a smtp.Auth, from string, to []string, message []byte) error {
+		auth, sent = a, message
+		return nil
+	}
+	e := NewEvent(EventDrawdown, "POOL p REACHED ITS MAX DRAWDOWN", "Drawdown", "10.00%")
+	assert.Nil(t, m.Notify(e))
+	assert.NotNil(t, auth)
+
+	text := string(sent)
+	assert.Contains(t, text, "To: a@example.com, b@example.com\r\n")
+	assert.Contains(t, text, "Subject: [trading] POOL p REACHED ITS MAX DRAWDOWN\r\n")
+	assert.True(t, strings.HasSuffix(text, "\r\n\r\nPOOL p REACHED ITS MAX DRAWDOWN\r\nDrawdown : 10.00%\r\n"))
+}
@@ -0,0 +1,491 @@
+// Package optimize searches the parameters of a strategy against recorded
+// prices. Every candidate is backtested on rolling walk-forward windows, it
+// is ranked by an objective on the train part of every window and the
+// winners of the windows are checked on the part that follows it, so the
+// result tells how parameters picked on the past did on unseen prices
+package optimize
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"trading/backtest"
+	"trading/config"
+	"trading/trade/manager"
+	"trading/utils"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Search string
+
+const (
+	// every combination of the values of the dimensions
+	SearchGrid Search = "grid"
+	// samples drawn within the bounds of the dimensions
+	SearchRandom Search = "random"
+)
+
+type Objective string
+
+const (
+	ObjectivePnL      Objective = "pnl"
+	ObjectiveSharpe   Objective = "sharpe"
+	ObjectiveDrawdown Objective = "drawdown"
+)
+
+// Value is the score of objective, higher is better so the drawdown is negated
+func (o Objective) Value(s Score) float64 {
+	switch o {
+	case ObjectiveSharpe:
+		return s.Sharpe
+	case ObjectiveDrawdown:
+		return -s.MaxDrawdownPct
+	}
+	return s.PnL
+}
+
+func (o Objective) IsValid() bool {
+	return o == ObjectivePnL || o == ObjectiveSharpe || o == ObjectiveDrawdown
+}
+
+// Dimension is a number of the strategy the optimizer changes. Field is the
+// path of its yaml names from the strategy like stable.buyStopLimit or
+// configs.buy.lockDelta, a path through a list changes every item of it
+type Dimension struct {
+	Field string  `json:"field" yaml:"field"`
+	Min   float64 `json:"min" yaml:"min"`
+	Max   float64 `json:"max" yaml:"max"`
+	// distance of the grid values from min, random values are rounded to it
+	Step float64 `json:"step" yaml:"step"`
+	// values tried instead of min to max
+	Values []float64 `json:"values" yaml:"values"`
+}
+
+// values are the values of a grid search
+func (d Dimension) values() []float64 {
+	if len(d.Values) > 0 {
+		return d.Values
+	}
+	values := []float64{}
+	if d.Step <= 0 {
+		return append(values, d.Min, d.Max)
+	}
+	for i := 0; ; i++ {
+		value := d.Min + float64(i)*d.Step
+		if value > d.Max+d.Step*1e-9 {
+			break
+		}
+		values = append(values, math.Round(value/d.Step)*d.Step)
+	}
+	return values
+}
+
+// sample is a random value of a random search
+func (d Dimension) sample(r *rand.Rand) float64 {
+	if len(d.Values) > 0 {
+		return d.Values[r.Intn(len(d.Values))]
+	}
+	value := d.Min + r.Float64()*(d.Max-d.Min)
+	if d.Step > 0 {
+		value = d.Min + math.Round((value-d.Min)/d.Step)*d.Step
+	}
+	return value
+}
+
+// Params are the values of the dimensions by field
+type Params map[string]float64
+
+func (p Params) String() string {
+	fields := []string{}
+	for field := range p {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	values := []string{}
+	for _, field := range fields {
+		values = append(values, fmt.Sprintf("%s=%g", field, p[field]))
+	}
+	return strings.Join(values, " ")
+}
+
+// Apply returns a copy of strategy with the values of params set
+func (p Params) Apply(strategy config.Strategy) (config.Strategy, error) {
+	// a yaml round trip copies the pointers and lists of the strategy
+	content, err := yaml.Marshal(strategy)
+	if err != nil {
+		return strategy, err
+	}
+	var copied config.Strategy
+	if err := yaml.Unmarshal(content, &copied); err != nil {
+		return strategy, err
+	}
+	for field, value := range p {
+		if err := set(reflect.ValueOf(&copied).Elem(), strings.Split(field, "."), value); err != nil {
+			return strategy, fmt.Errorf("%s: %w", field, err)
+		}
+	}
+	return copied, nil
+}
+
+// set sets the number at the yaml path of v to value
+func set(v reflect.Value, path []string, value float64) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return fmt.Errorf("%s is not set in the strategy", strings.Join(path, "."))
+		}
+		return set(v.Elem(), path, value)
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return fmt.Errorf("the list before %s is empty", strings.Join(path, "."))
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := set(v.Index(i), path, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(path) == 0 {
+		switch v.Kind() {
+		case reflect.Float32, reflect.Float64:
+			v.SetFloat(value)
+		case reflect.Int, reflect.Int32, reflect.Int64:
+			v.SetInt(int64(math.Round(value)))
+		default:
+			return fmt.Errorf("is a %s, not a number", v.Kind())
+		}
+		return nil
+	}
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("%s is not a field of a %s", path[0], v.Kind())
+	}
+	for i := 0; i < v.NumField(); i++ {
+		name := strings.Split(v.Type().Field(i).Tag.Get("yaml"), ",")[0]
+		if name == path[0] {
+			return set(v.Field(i), path[1:], value)
+		}
+	}
+	return fmt.Errorf("unknown field %s of %s", path[0], v.Type().Name())
+}
+
+// Score is how a strategy did over a window
+type Score struct {
+	PnL        float64 `json:"pnl" yaml:"pnl"`
+	PnLPercent float64 `json:"pnlPercent" yaml:"pnlPercent"`
+	// mean return of the equity points over their deviation, scaled by the
+	// square root of their number and not annualized
+	Sharpe         float64 `json:"sharpe" yaml:"sharpe"`
+	MaxDrawdownPct float64 `json:"maxDrawdownPct" yaml:"maxDrawdownPct"`
+	Trades         int     `json:"trades" yaml:"trades"`
+}
+
+func newScore(report backtest.Report) Score {
+	score := Score{
+		PnL:            report.PnL,
+		PnLPercent:     report.PnLPercent,
+		MaxDrawdownPct: report.MaxDrawdownPct,
+		Trades:         report.TradeCount,
+	}
+	returns := []float64{}
+	for i := 1; i < len(report.EquityCurve); i++ {
+		if last := report.EquityCurve[i-1].Equity; last > 0 {
+			returns = append(returns, report.EquityCurve[i].Equity/last-1)
+		}
+	}
+	if len(returns) < 2 {
+		return score
+	}
+	var mean, variance float64
+	for _, r := range returns {
+		mean += r / float64(len(returns))
+	}
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean) / float64(len(returns))
+	}
+	if variance > 0 {
+		score.Sharpe = mean / math.Sqrt(variance) * math.Sqrt(float64(len(returns)))
+	}
+	return score
+}
+
+type Candidate struct {
+	Params Params `json:"params" yaml:"params"`
+	Score  Score  `json:"score" yaml:"score"`
+	// objective value of the score
+	Value float64 `json:"value" yaml:"value"`
+}
+
+// Window is a range of the replayed prices
+type Window struct {
+	Start time.Time `json:"start" yaml:"start"`
+	End   time.Time `json:"end" yaml:"end"`
+	ticks []backtest.Tick
+}
+
+func newWindow(ticks []backtest.Tick) Window {
+	return Window{Start: ticks[0].Time, End: ticks[len(ticks)-1].Time, ticks: ticks}
+}
+
+// Fold is a walk-forward window, the candidates are ranked on its train
+// prices and its winner is scored on the test prices that follow them
+type Fold struct {
+	Train   Window      `json:"train" yaml:"train"`
+	Test    Window      `json:"test" yaml:"test"`
+	Ranking []Candidate `json:"ranking" yaml:"ranking"`
+	Winner  Params      `json:"winner" yaml:"winner"`
+	// score of the winner on the test prices
+	OutOfSample Score `json:"outOfSample" yaml:"outOfSample"`
+}
+
+type Result struct {
+	Objective Objective `json:"objective" yaml:"objective"`
+	Folds     []Fold    `json:"folds" yaml:"folds"`
+	// every candidate by its mean objective on the train prices of the folds
+	Ranking []Candidate `json:"ranking" yaml:"ranking"`
+	// the strategy with the params of the best ranked candidate
+	Winner   Params          `json:"winner" yaml:"winner"`
+	Strategy config.Strategy `json:"strategy" yaml:"strategy"`
+	// what the winners of the folds made on their test prices together
+	OutOfSample Score `json:"outOfSample" yaml:"outOfSample"`
+}
+
+// Config is a config of the winning strategy that config.Load reads back
+func (r Result) Config() config.Config {
+	return config.Config{Strategies: []config.Strategy{r.Strategy}}
+}
+
+type Optimizer struct {
+	strategy  config.Strategy
+	ticks     []backtest.Tick
+	balances  map[string]float64
+	space     []Dimension
+	search    Search
+	samples   int
+	seed      int64
+	folds     int
+	train     float64
+	objective Objective
+	feeRate   float64
+	quote     string
+}
+
+func NewOptimizer(strategy config.Strategy, ticks []backtest.Tick, balances map[string]float64) *Optimizer {
+	return &Optimizer{
+		strategy:  strategy,
+		ticks:     backtest.MergeTicks(ticks),
+		balances:  balances,
+		search:    SearchGrid,
+		samples:   20,
+		seed:      1,
+		folds:     3,
+		train:     70,
+		objective: ObjectivePnL,
+		feeRate:   0.001,
+		quote:     "USDT",
+	}
+}
+
+// UseSpace sets the dimensions searched
+func (o *Optimizer) UseSpace(space ...Dimension) *Optimizer {
+	o.space = space
+	return o
+}
+
+// UseSearch sets how candidates are picked, samples and seed are only used
+// by the random search, the same seed draws the same candidates
+func (o *Optimizer) UseSearch(search Search, samples int, seed int64) *Optimizer {
+	o.search, o.samples, o.seed = search, samples, seed
+	return o
+}
+
+// UseFolds splits the prices in folds walk-forward windows, trainPercent of
+// every window ranks the candidates and the rest tests the winner
+func (o *Optimizer) UseFolds(folds int, trainPercent float64) *Optimizer {
+	o.folds, o.train = folds, trainPercent
+	return o
+}
+
+func (o *Optimizer) UseObjective(objective Objective) *Optimizer {
+	o.objective = objective
+	return o
+}
+
+// UseFeeRate sets the fee of the backtests, default 0.001
+func (o *Optimizer) UseFeeRate(feeRate float64) *Optimizer {
+	o.feeRate = feeRate
+	return o
+}
+
+// UseQuoteAsset sets the asset the backtests are valued in, default USDT
+func (o *Optimizer) UseQuoteAsset(quoteAsset string) *Optimizer {
+	o.quote = quoteAsset
+	return o
+}
+
+// Candidates are the params searched
+func (o *Optimizer) Candidates() []Params {
+	candidates := []Params{}
+	if o.search == SearchRandom {
+		r := rand.New(rand.NewSource(o.seed))
+		for i := 0; i < o.samples; i++ {
+			params := Params{}
+			for _, d := range o.space {
+				params[d.Field] = d.sample(r)
+			}
+			candidates = append(candidates, params)
+		}
+		return candidates
+	}
+	candidates = append(candidates, Params{})
+	for _, d := range o.space {
+		next := []Params{}
+		for _, params := range candidates {
+			for _, value := range d.values() {
+				p := Params{d.Field: value}
+				for field, v := range params {
+					p[field] = v
+				}
+				next = append(next, p)
+			}
+		}
+		candidates = next
+	}
+	return candidates
+}
+
+// Folds are the walk-forward windows of the prices, the test parts follow
+// each other and every window is as long as the others
+func (o *Optimizer) Folds() []Fold {
+	n := len(o.ticks)
+	share := o.train / 100
+	length := int(float64(n) / (1 + float64(o.folds-1)*(1-share)))
+	train := int(float64(length) * share)
+	test := length - train
+	folds := []Fold{}
+	if train < 1 || test < 1 {
+		return folds
+	}
+	for i := 0; i < o.folds; i++ {
+		start := i * test
+		end := start + length
+		if i == o.folds-1 {
+			end = n
+		}
+		folds = append(folds, Fold{
+			Train: newWindow(o.ticks[start : start+train]),
+			Test:  newWindow(o.ticks[start+train : end]),
+		})
+	}
+	return folds
+}
+
+// valid drops the candidates whose strategy is not a valid config, like a
+// lockDelta above the stopLimit it was combined with
+func (o *Optimizer) valid(candidates []Params) ([]Params, error) {
+	valid := []Params{}
+	for _, params := range candidates {
+		strategy, err := params.Apply(o.strategy)
+		if err != nil {
+			return nil, err
+		}
+		if err := (config.Config{Strategies: []config.Strategy{strategy}}).Validate(); err != nil {
+			utils.LogWarn(fmt.Sprintf("<Optimize>: %s skipped, %s", params, err.Error()))
+			continue
+		}
+		valid = append(valid, params)
+	}
+	return valid, nil
+}
+
+// backtest replays window to the strategy with params
+func (o *Optimizer) backtest(params Params, window Window) (Score, error) {
+	strategy, err := params.Apply(o.strategy)
+	if err != nil {
+		return Score{}, err
+	}
+	report := backtest.NewBacktest(window.ticks, o.balances).
+		UseFeeRate(o.feeRate).
+		UseQuoteAsset(o.quote).
+		Run(func() *manager.TradeManager { return strategy.TradeManager() })
+	return newScore(report), nil
+}
+
+func (o *Optimizer) rank(candidates []Candidate) {
+	sort.SliceStable(candidates, func(a, b int) bool {
+		if candidates[a].Value != candidates[b].Value {
+			return candidates[a].Value > candidates[b].Value
+		}
+		return candidates[a].Score.PnL > candidates[b].Score.PnL
+	})
+}
+
+// Run backtests every candidate on the train prices of every fold and the
+// winner of each fold on its test prices
+func (o *Optimizer) Run() (Result, error) {
+	result := Result{Objective: o.objective}
+	candidates, err := o.valid(o.Candidates())
+	if err != nil {
+		return result, err
+	}
+	folds := o.Folds()
+	if len(candidates) == 0 || len(folds) == 0 {
+		return result, fmt.Errorf("nothing to optimize, %d candidates and %d folds of %d prices", len(candidates), len(folds), len(o.ticks))
+	}
+	utils.LogInfo(fmt.Sprintf("<Optimize>: %d candidates on %d folds by %s", len(candidates), len(folds), o.objective))
+
+	totals := make([]Candidate, len(candidates))
+	for i, params := range candidates {
+		totals[i].Params = params
+	}
+	for f := range folds {
+		fold := &folds[f]
+		for i, params := range candidates {
+			score, err := o.backtest(params, fold.Train)
+			if err != nil {
+				return result, err
+			}
+			value := o.objective.Value(score)
+			fold.Ranking = append(fold.Ranking, Candidate{Params: params, Score: score, Value: value})
+			totals[i].Value += value / float64(len(folds))
+			totals[i].Score.PnL += score.PnL / float64(len(folds))
+			totals[i].Score.PnLPercent += score.PnLPercent / float64(len(folds))
+			totals[i].Score.Sharpe += score.Sharpe / float64(len(folds))
+			totals[i].Score.MaxDrawdownPct = math.Max(totals[i].Score.MaxDrawdownPct, score.MaxDrawdownPct)
+			totals[i].Score.Trades += score.Trades
+		}
+		o.rank(fold.Ranking)
+		fold.Winner = fold.Ranking[0].Params
+
+		score, err := o.backtest(fold.Winner, fold.Test)
+		if err != nil {
+			return result, err
+		}
+		fold.OutOfSample = score
+		result.OutOfSample.PnL += score.PnL
+		result.OutOfSample.PnLPercent += score.PnLPercent
+		result.OutOfSample.Sharpe += score.Sharpe / float64(len(folds))
+		result.OutOfSample.MaxDrawdownPct = math.Max(result.OutOfSample.MaxDrawdownPct, score.MaxDrawdownPct)
+		result.OutOfSample.Trades += score.Trades
+		utils.LogInfo(fmt.Sprintf("<Optimize>: fold %d picked %s, %s %f on train and pnl %f on test",
+			f+1, fold.Winner, o.objective, fold.Ranking[0].Value, score.PnL))
+	}
+	o.rank(totals)
+
+	result.Folds = folds
+	result.Ranking = totals
+	result.Winner = totals[0].Params
+	strategy, err := result.Winner.Apply(o.strategy)
+	if err != nil {
+		return result, err
+	}
+	result.Strategy = strategy
+	utils.LogInfo(fmt.Sprintf("<Optimize>: %s won with mean %s %f, the fold winners made %f on their test prices",
+		result.Winner, o.objective, totals[0].Value, result.OutOfSample.PnL))
+	return result, nil
+}
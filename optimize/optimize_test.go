@@ -0,0 +1,182 @@
+package optimize
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+	"trading/backtest"
+	"trading/config"
+	"trading/trade/traders"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func limitStrategy() config.Strategy {
+	side := config.SideConfig{LimitType: "PERCENT", StopLimit: 5, LockDelta: 1, Quantity: -1}
+	buy := side
+	buy.StopLimit = 10
+	return config.Strategy{
+		Name:         "btc",
+		Trader:       config.TraderLimit,
+		LockCreator:  config.LockPeakHigh,
+		PrioritySide: "SELL",
+		BestSide:     "SELL",
+		Configs: []config.TradeConfig{
+			{Id: "btc", Symbol: "BTCUSDT", Side: "BUY", IsCyclick: true, Buy: buy, Sell: side},
+		},
+	}
+}
+
+func ticksOf(symbol string, prices ...float64) []backtest.Tick {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	ticks := []backtest.Tick{}
+	for i, price := range prices {
+		ticks = append(ticks, backtest.Tick{Time: start.Add(time.Duration(i) * time.Minute), Symbol: symbol, Price: price})
+	}
+	return ticks
+}
+
+func TestApply(t *testing.T) {
+	strategy := limitStrategy()
+	strategy.Configs = append(strategy.Configs, strategy.Configs[0])
+	applied, err := Params{"configs.buy.stopLimit": 7, "configs.sell.lockDelta": 0.5}.Apply(strategy)
+	assert.Nil(t, err)
+	for _, tc := range applied.Configs {
+		assert.Equal(t, 7.0, tc.Buy.StopLimit, "every config of the list")
+		assert.Equal(t, 0.5, tc.Sell.LockDelta)
+	}
+	assert.Equal(t, 10.0, strategy.Configs[0].Buy.StopLimit, "the strategy is copied")
+
+	stable := config.Strategy{Trader: config.TraderAutoStable, Stable: &traders.StableTradeParam{BuyStopLimit: 8, SellLockDelta: 0.02}}
+	applied, err = Params{"stable.buyStopLimit": 12, "stable.sellLockDelta": 0.05}.Apply(stable)
+	assert.Nil(t, err)
+	assert.Equal(t, 12.0, applied.Stable.BuyStopLimit)
+	assert.Equal(t, 0.05, applied.Stable.SellLockDelta)
+	assert.Equal(t, 8.0, stable.Stable.BuyStopLimit)
+
+	_, err = Params{"stable.buyStopLimit": 1}.Apply(strategy)
+	assert.EqualError(t, err, "stable.buyStopLimit: buyStopLimit is not set in the strategy")
+	_, err = Params{"configs.buy.limitType": 1}.Apply(strategy)
+	assert.EqualError(t, err, "configs.buy.limitType: is a string, not a number")
+	_, err = Params{"configs.buy.stop": 1}.Apply(strategy)
+	assert.EqualError(t, err, "configs.buy.stop: unknown field stop of SideConfig")
+}
+
+func TestCandidates(t *testing.T) {
+	o := NewOptimizer(limitStrategy(), nil, nil).UseSpace(
+		Dimension{Field: "configs.buy.stopLimit", Min: 1, Max: 3, Step: 1},
+		Dimension{Field: "configs.sell.lockDelta", Values: []float64{0.1, 0.2}},
+	)
+	grid := o.Candidates()
+	assert.Len(t, grid, 6)
+	assert.Equal(t, Params{"configs.buy.stopLimit": 3, "configs.sell.lockDelta": 0.2}, grid[5])
+
+	o.UseSpace(Dimension{Field: "configs.buy.stopLimit", Min: 1, Max: 3, Step: 0.5}).UseSearch(SearchRandom, 5, 7)
+	random := o.Candidates()
+	assert.Len(t, random, 5)
+	for _, params := range random {
+		value := params["configs.buy.stopLimit"]
+		assert.True(t, value >= 1 && value <= 3)
+		assert.Equal(t, 0.0, value*2-float64(int(value*2)), "rounded to the step")
+	}
+	assert.Equal(t, random, o.Candidates(), "the same seed draws the same candidates")
+}
+
+func TestFolds(t *testing.T) {
+	prices := make([]float64, 100)
+	ticks := ticksOf("BTCUSDT", prices...)
+	folds := NewOptimizer(limitStrategy(), ticks, nil).UseFolds(3, 70).Folds()
+	assert.Len(t, folds, 3)
+	// windows of 62 prices, 43 to train and 19 to test
+	assert.Equal(t, ticks[0].Time, folds[0].Train.Start)
+	assert.Equal(t, ticks[43].Time, folds[0].Test.Start)
+	assert.Equal(t, ticks[19].Time, folds[1].Train.Start)
+	assert.Equal(t, ticks[62].Time, folds[1].Test.Start, "the test prices follow each other")
+	assert.Equal(t, ticks[81].Time, folds[2].Test.Start)
+	assert.Equal(t, ticks[99].Time, folds[2].Test.End)
+
+	assert.Empty(t, NewOptimizer(limitStrategy(), ticks[:2], nil).UseFolds(3, 70).Folds())
+}
+
+func TestOptimize(t *testing.T) {
+	cycle := []float64{100, 95, 89, 85, 86, 86, 92, 95, 93, 93}
+	prices := []float64{}
+	for i := 0; i < 3; i++ {
+		prices = append(prices, cycle...)
+	}
+	result, err := NewOptimizer(limitStrategy(), ticksOf("BTCUSDT", prices...), map[string]float64{"USDT": 1000}).
+		UseSpace(Dimension{Field: "configs.buy.stopLimit", Values: []float64{30, 10, 0, 0.5}}).
+		UseFolds(2, 50).
+		UseFeeRate(0).
+		Run()
+	assert.Nil(t, err)
+	assert.Len(t, result.Folds, 2)
+	for _, fold := range result.Folds {
+		assert.Equal(t, Params{"configs.buy.stopLimit": 10}, fold.Winner, "a buy 30% down never fills")
+		assert.InDelta(t, 1000*93.0/86.0-1000, fold.OutOfSample.PnL, 1e-6)
+	}
+	assert.Equal(t, Params{"configs.buy.stopLimit": 10}, result.Winner)
+	assert.Len(t, result.Ranking, 2, "a zero stopLimit and one below the lockDelta are not valid configs")
+	assert.Equal(t, 0.0, result.Ranking[1].Score.PnL)
+	assert.Equal(t, 10.0, result.Strategy.Configs[0].Buy.StopLimit)
+	assert.InDelta(t, 2*(1000*93.0/86.0-1000), result.OutOfSample.PnL, 1e-6)
+
+	filename := filepath.Join(t.TempDir(), "optimized.yaml")
+	assert.Nil(t, result.Config().Save(filename))
+	loaded, err := config.Load(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, 10.0, loaded.Strategies[0].Configs[0].Buy.StopLimit, "the winner is a loadable config")
+}
+
+func TestLoadSpec(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "spec.yaml")
+	ioutil.WriteFile(filename, []byte(`
+strategy:
+  trader: autostable
+  stable: {quoteAsset: USDT, buyStopLimit: 8, sellStopLimit: 4, sellLockDelta: 0.02}
+klines: {BTCUSDT: btc.json}
+balances: {USDT: 1000}
+space:
+  - {field: stable.buyStopLimit, min: 4, max: 12, step: 2}
+  - {field: stable.sellLockDelta, values: [0.02, 0.05]}
+search: Random
+output: optimized.yaml
+`), 0644)
+	spec, err := LoadSpec(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, SearchRandom, spec.Search)
+	assert.Equal(t, ObjectivePnL, spec.Objective)
+	assert.Equal(t, 3, spec.Folds)
+	assert.Equal(t, config.LockPeakHigh, spec.Strategy.LockCreator, "the strategy has the defaults of a config")
+
+	ioutil.WriteFile(filename, []byte(`
+strategy:
+  trader: autostable
+  stable: {quoteAsset: USDT, buyStopLimit: 8, sellStopLimit: 4}
+space:
+  - {field: stable.buyStopLimit, min: 4, max: 2}
+  - {field: configs.buy.stopLimit, min: 1, max: 2}
+folds: -1
+objective: profit
+`), 0644)
+	_, err = LoadSpec(filename)
+	assert.EqualError(t, err, `invalid spec:
+  klines: at least one symbol is required
+  balances: are required
+  space[0].max: can not be below min 4, got 2
+  space[1].field: configs.buy.stopLimit: the list before buy.stopLimit is empty
+  folds: must be at least 1, got -1
+  objective: must be pnl, sharpe or drawdown, got "profit"`)
+
+	ioutil.WriteFile(filename, []byte(`
+strategy: {trader: autostable, stable: {quoteAsset: USDT, buyStopLimit: 8, sellStopLimit: 4}}
+klines: {BTCUSDT: btc.json}
+balances: {USDT: 1000}
+space: [{field: stable.buyStopLimit, min: 4, max: 12}]
+trainPercents: 60
+`), 0644)
+	_, err = LoadSpec(filename)
+	assert.ErrorContains(t, err, "field trainPercents not found")
+}
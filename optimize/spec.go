@@ -0,0 +1,181 @@
+package optimize
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"trading/backtest"
+	"trading/config"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec describes an optimization in a json or yaml file
+type Spec struct {
+	// strategy whose dimensions are searched, like a strategy of a config
+	Strategy config.Strategy `json:"strategy" yaml:"strategy"`
+	// files of the candles of every symbol saved by backtest.SaveKlines
+	Klines     map[string]string  `json:"klines" yaml:"klines"`
+	Balances   map[string]float64 `json:"balances" yaml:"balances"`
+	QuoteAsset string             `json:"quoteAsset" yaml:"quoteAsset"`
+	FeeRate    float64            `json:"feeRate" yaml:"feeRate"`
+	Space      []Dimension        `json:"space" yaml:"space"`
+	// grid or random
+	Search  Search `json:"search" yaml:"search"`
+	Samples int    `json:"samples" yaml:"samples"`
+	Seed    int64  `json:"seed" yaml:"seed"`
+	// walk-forward windows and the percent of each the candidates are ranked on
+	Folds        int     `json:"folds" yaml:"folds"`
+	TrainPercent float64 `json:"trainPercent" yaml:"trainPercent"`
+	// pnl, sharpe or drawdown
+	Objective Objective `json:"objective" yaml:"objective"`
+	// config file the winning strategy is written to
+	Output string `json:"output" yaml:"output"`
+}
+
+// LoadSpec reads a spec file, the format is picked from the extension and
+// the strategy gets the defaults of a config before the spec is validated
+func LoadSpec(filename string) (Spec, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return Spec{}, err
+	}
+	var spec Spec
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json", ".yaml", ".yml":
+	default:
+		return Spec{}, fmt.Errorf("unsupported spec format %q, use .json, .yaml or .yml", filepath.Ext(filename))
+	}
+	if err := config.Decode(content, filepath.Ext(filename), &spec); err != nil {
+		return Spec{}, fmt.Errorf("could not read spec: %w", err)
+	}
+
+	spec.applyDefaults()
+	// the strategy is parsed like the strategy of a config file
+	strategy, err := yaml.Marshal(config.Config{Strategies: []config.Strategy{spec.Strategy}})
+	if err != nil {
+		return Spec{}, err
+	}
+	parsed, err := config.Parse(strategy, ".yaml")
+	if err != nil {
+		return Spec{}, err
+	}
+	spec.Strategy = parsed.Strategies[0]
+	return spec, spec.Validate()
+}
+
+func (s *Spec) applyDefaults() {
+	s.Search = Search(strings.ToLower(string(s.Search)))
+	s.Objective = Objective(strings.ToLower(string(s.Objective)))
+	if s.Search == "" {
+		s.Search = SearchGrid
+	}
+	if s.Objective == "" {
+		s.Objective = ObjectivePnL
+	}
+	if s.QuoteAsset == "" {
+		s.QuoteAsset = "USDT"
+	}
+	if s.FeeRate == 0 {
+		s.FeeRate = 0.001
+	}
+	if s.Samples == 0 {
+		s.Samples = 20
+	}
+	if s.Seed == 0 {
+		s.Seed = 1
+	}
+	if s.Folds == 0 {
+		s.Folds = 3
+	}
+	if s.TrainPercent == 0 {
+		s.TrainPercent = 70
+	}
+}
+
+// Validate checks the whole spec and reports all problems at once
+func (s Spec) Validate() error {
+	problems := []string{}
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if len(s.Klines) == 0 {
+		add("klines: at least one symbol is required")
+	}
+	if len(s.Balances) == 0 {
+		add("balances: are required")
+	}
+	if len(s.Space) == 0 {
+		add("space: at least one dimension is required")
+	}
+	for i, d := range s.Space {
+		field := fmt.Sprintf("space[%d]", i)
+		if d.Field == "" {
+			add("%s.field: is required", field)
+			continue
+		}
+		if len(d.Values) == 0 && d.Max < d.Min {
+			add("%s.max: can not be below min %v, got %v", field, d.Min, d.Max)
+		}
+		if d.Step < 0 {
+			add("%s.step: can not be negative, got %v", field, d.Step)
+		}
+		if _, err := (Params{d.Field: d.Min}).Apply(s.Strategy); err != nil {
+			add("%s.field: %s", field, err.Error())
+		}
+	}
+	if s.Search != SearchGrid && s.Search != SearchRandom {
+		add("search: must be %s or %s, got %q", SearchGrid, SearchRandom, s.Search)
+	}
+	if s.Samples < 0 {
+		add("samples: can not be negative, got %d", s.Samples)
+	}
+	if s.Folds < 1 {
+		add("folds: must be at least 1, got %d", s.Folds)
+	}
+	if s.TrainPercent <= 0 || s.TrainPercent >= 100 {
+		add("trainPercent: must be above 0 and below 100, got %v", s.TrainPercent)
+	}
+	if !s.Objective.IsValid() {
+		add("objective: must be %s, %s or %s, got %q", ObjectivePnL, ObjectiveSharpe, ObjectiveDrawdown, s.Objective)
+	}
+	switch strings.ToLower(filepath.Ext(s.Output)) {
+	case ".json", ".yaml", ".yml":
+	default:
+		if s.Output != "" {
+			add("output: unsupported config format %q, use .json, .yaml or .yml", filepath.Ext(s.Output))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid spec:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// Optimizer loads the candles of the spec and builds its optimizer
+func (s Spec) Optimizer() (*Optimizer, error) {
+	symbols := []string{}
+	for symbol := range s.Klines {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	ticks := [][]backtest.Tick{}
+	for _, symbol := range symbols {
+		klines, err := backtest.LoadKlines(s.Klines[symbol])
+		if err != nil {
+			return nil, fmt.Errorf("could not load the candles of %s: %w", symbol, err)
+		}
+		ticks = append(ticks, backtest.TicksFromKlines(strings.ToUpper(symbol), klines))
+	}
+	return NewOptimizer(s.Strategy, backtest.MergeTicks(ticks...), s.Balances).
+		UseSpace(s.Space...).
+		UseSearch(s.Search, s.Samples, s.Seed).
+		UseFolds(s.Folds, s.TrainPercent).
+		UseObjective(s.Objective).
+		UseFeeRate(s.FeeRate).
+		UseQuoteAsset(s.QuoteAsset), nil
+}
@@ -18,9 +18,17 @@ type requestApi interface {
 	GetEndpoint() string
 }
 
+// clientApi is an api that sends its requests with its own client
+type clientApi interface {
+	GetClient() *http.Client
+}
+
 func Request(api requestApi, decorateFunc func(r *http.Request)) (*http.Response, error) {
 	var method, payload, endpoint = api.GetMethod(), api.GetPayload(), api.GetEndpoint()
-	client := http.Client{}
+	client := &http.Client{}
+	if c, ok := api.(clientApi); ok {
+		client = c.GetClient()
+	}
 	json.Marshal(payload)
 	req, reqError := http.NewRequest(method, endpoint, nil)
 
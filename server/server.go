@@ -0,0 +1,272 @@
+// Package server exposes the running trade pools over http so operators can
+// inspect and change running bots without restarting the process
+//
+//	GET  /pools                                 list pools
+//	GET  /pool/:id                              a single pool
+//	GET  /pool/:id/pnl                          realized and unrealized profit of the pool
+//	POST /pool/:id/stop                         stop a pool and return it
+//	PUT  /pool/:poolId/config/:configId/add     add a config to a running pool
+//	POST /pool/:poolId/config/:configId/stop    remove a config from a running pool
+//	GET  /trades?symbol=&configId=&side=&from=&to=&limit=   journal records as jsonl
+//	GET  /pnl?symbol=|configId=                 realized and unrealized profit of a symbol or config
+//	GET  /limits                                how close the requests are to the exchange limits
+//	GET  /metrics                               metrics of the bots in the Prometheus text format
+//
+// Requests other than GET change pools that trade real funds, they need the
+// header Authorization: Bearer with the token of the env CONTROL_TOKEN and
+// are refused while it is not set. An address without a host like :8080 is
+// only served on 127.0.0.1
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"trading/binance"
+	"trading/config"
+	"trading/journal"
+	"trading/ledger"
+	"trading/metrics"
+	"trading/names"
+	"trading/trade/manager"
+	"trading/utils"
+)
+
+type Server struct {
+	address string
+	token   string
+	mux     *http.ServeMux
+}
+
+func NewServer(address string) *Server {
+	s := &Server{address: bindAddress(address), token: os.Getenv("CONTROL_TOKEN"), mux: http.NewServeMux()}
+	s.mux.HandleFunc("/pools", s.handlePools)
+	s.mux.HandleFunc("/pool/", s.handlePool)
+	s.mux.HandleFunc("/trades", s.handleTrades)
+	s.mux.HandleFunc("/pnl", s.handlePnL)
+	s.mux.HandleFunc("/limits", s.handleLimits)
+	s.mux.Handle("/metrics", metrics.Handler())
+	return s
+}
+
+// bindAddress keeps the server on the loopback interface unless the address names a host
+func bindAddress(address string) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || host != "" {
+		return address
+	}
+	return net.JoinHostPort("127.0.0.1", port)
+}
+
+// set the bearer token requests other than GET must send, default the env CONTROL_TOKEN
+func (s *Server) UseToken(token string) *Server {
+	s.token = token
+	return s
+}
+
+// Handle registers an extra handler on the server
+func (s *Server) Handle(pattern string, handler http.Handler) *Server {
+	s.mux.Handle(pattern, handler)
+	return s
+}
+
+func (s *Server) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead && !s.authorize(w, r) {
+			return
+		}
+		s.mux.ServeHTTP(w, r)
+	})
+}
+
+// ListenAndServe blocks serving requests on the address of the server
+func (s *Server) ListenAndServe() error {
+	utils.LogInfo(fmt.Sprintf("<Server>: listening on %s", s.address))
+	return http.ListenAndServe(s.address, s.Handler())
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeJson(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJson(w, status, errorResponse{Error: err.Error()})
+}
+
+func allow(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s %s is not allowed, use %s", r.Method, r.URL.Path, method))
+		return false
+	}
+	return true
+}
+
+// authorize reports whether r carries the token of the server, the
+// request is answered when it does not
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if s.token == "" {
+		writeError(w, http.StatusForbidden, fmt.Errorf("%s %s is disabled, set CONTROL_TOKEN to allow it", r.Method, r.URL.Path))
+		return false
+	}
+	header := r.Header.Get("Authorization")
+	token := strings.TrimPrefix(header, "Bearer ")
+	if token == header || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, fmt.Errorf("a valid bearer token is required"))
+		return false
+	}
+	return true
+}
+
+func (s *Server) handlePools(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	statuses := []manager.PoolStatus{}
+	for _, pool := range manager.Pools() {
+		statuses = append(statuses, pool.Status())
+	}
+	writeJson(w, http.StatusOK, statuses)
+}
+
+func (s *Server) handleLimits(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	writeJson(w, http.StatusOK, binance.Limiter.Metrics())
+}
+
+func (s *Server) handlePnL(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	params := r.URL.Query()
+	switch {
+	case params.Get("configId") != "":
+		writeJson(w, http.StatusOK, ledger.Default().ConfigPnL(params.Get("configId")))
+	case params.Get("symbol") != "":
+		writeJson(w, http.StatusOK, ledger.Default().SymbolPnL(names.Symbol(strings.ToUpper(params.Get("symbol")))))
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("symbol or configId is required"))
+	}
+}
+
+// routes every path under /pool/
+func (s *Server) handlePool(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/pool/"), "/"), "/")
+
+	pool, exist := manager.GetPool(parts[0])
+	if !exist {
+		writeError(w, http.StatusNotFound, fmt.Errorf("pool %s not found", parts[0]))
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		if allow(w, r, http.MethodGet) {
+			writeJson(w, http.StatusOK, pool.Status())
+		}
+	case len(parts) == 2 && parts[1] == "pnl":
+		if allow(w, r, http.MethodGet) {
+			writeJson(w, http.StatusOK, pool.PnL())
+		}
+	case len(parts) == 2 && parts[1] == "stop":
+		if allow(w, r, http.MethodPost) {
+			writeJson(w, http.StatusOK, pool.Stop())
+		}
+	case len(parts) == 4 && parts[1] == "config" && parts[3] == "add":
+		if allow(w, r, http.MethodPut) {
+			s.addConfig(w, r, pool, parts[2])
+		}
+	case len(parts) == 4 && parts[1] == "config" && parts[3] == "stop":
+		if allow(w, r, http.MethodPost) {
+			s.removeConfig(w, pool, parts[2])
+		}
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown route %s", r.URL.Path))
+	}
+}
+
+func (s *Server) addConfig(w http.ResponseWriter, r *http.Request, pool *manager.Pool, configId string) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	tc, err := config.ParseTradeConfig(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	tc.Id = configId
+
+	if err := pool.AddConfig(tc.TradeConfig()); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJson(w, http.StatusOK, pool.Status())
+}
+
+func (s *Server) removeConfig(w http.ResponseWriter, pool *manager.Pool, configId string) {
+	if _, err := pool.RemoveConfig(configId); err != nil {
+		status := http.StatusConflict
+		if _, exist := pool.FindConfig(configId); !exist {
+			status = http.StatusNotFound
+		}
+		writeError(w, status, err)
+		return
+	}
+	writeJson(w, http.StatusOK, pool.Status())
+}
+
+// from and to are unix timestamps in milliseconds
+func (s *Server) handleTrades(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	params := r.URL.Query()
+	query := journal.Query{
+		Symbol:   names.Symbol(strings.ToUpper(params.Get("symbol"))),
+		ConfigId: params.Get("configId"),
+		Side:     names.TradeSide(strings.ToUpper(params.Get("side"))),
+	}
+
+	for name, value := range map[string]*time.Time{"from": &query.From, "to": &query.To} {
+		if params.Get(name) == "" {
+			continue
+		}
+		milli, err := strconv.ParseInt(params.Get(name), 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("%s must be a unix timestamp in milliseconds", name))
+			return
+		}
+		*value = time.UnixMilli(milli)
+	}
+	if params.Get("limit") != "" {
+		limit, err := strconv.Atoi(params.Get("limit"))
+		if err != nil || limit < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("limit must be a positive number"))
+			return
+		}
+		query.Limit = limit
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	if err := journal.Default().Export(w, query); err != nil {
+		utils.LogError(err, "<Server>: could not export trades")
+	}
+}
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"trading/journal"
+	"trading/ledger"
+	"trading/names"
+	"trading/trade/manager"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTrader watches configs by holding a lock for each of them
+type fakeTrader struct {
+	lockManager names.LockManagerInterface
+	configs     []names.TradeConfig
+}
+
+func (t *fakeTrader) Run() {
+	for _, config := range t.configs {
+		t.lockManager.AddLock(config, 100)
+	}
+}
+func (t *fakeTrader) Done(config names.TradeConfig, lock names.LockInterface) {}
+func (t *fakeTrader) SetExecutor(executor names.ExecutorFunc) names.Trader {
+	return t
+}
+func (t *fakeTrader) SetLockManager(lockManager names.LockManagerInterface) names.Trader {
+	t.lockManager = lockManager
+	return t
+}
+func (t *fakeTrader) AddConfig(config names.TradeConfig) {
+	t.lockManager.AddLock(config, 100)
+}
+func (t *fakeTrader) RemoveConfig(config names.TradeConfig) bool {
+	lock := t.lockManager.RetrieveLock(config)
+	return lock != nil && lock.RemoveFromManager()
+}
+
+const token = "secret"
+
+func request(t *testing.T, s *Server, method, path, body string) (int, map[string]any) {
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer "+token)
+	s.Handler().ServeHTTP(recorder, r)
+	response := map[string]any{}
+	if strings.HasPrefix(strings.TrimSpace(recorder.Body.String()), "{") {
+		assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	}
+	return recorder.Code, response
+}
+
+func TestPoolRoutes(t *testing.T) {
+	trader := &fakeTrader{configs: []names.TradeConfig{{Id: "btc", Symbol: "BTCUSDT", Side: names.TradeSideBuy}}}
+	pool := manager.NewTradeManager(trader).DoTrade().Pool()
+	s := NewServer(":0").UseToken(token)
+
+	recorder := httptest.NewRecorder()
+	s.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/pools", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), pool.Id())
+
+	code, body := request(t, s, http.MethodGet, "/pool/"+pool.Id(), "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, true, body["isRunning"])
+	assert.Len(t, body["configs"], 1)
+
+	code, _ = request(t, s, http.MethodGet, "/pool/unknown", "")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	defer ledger.Use(ledger.Use(ledger.New(ledger.FIFO)))
+	code, body = request(t, s, http.MethodGet, "/pool/"+pool.Id()+"/pnl", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 0.0, body["realized"])
+
+	recorder = httptest.NewRecorder()
+	s.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, recorder.Body.String(), `trading_lock_state{pool="`+pool.Id()+`",symbol="BTCUSDT",side="BUY",state="due"} 0`)
+
+	config := `{"symbol": "ethusdt", "side": "SELL",
+		"buy": {"limitType": "PERCENT", "stopLimit": 1, "quantity": -1},
+		"sell": {"limitType": "PERCENT", "stopLimit": 1, "quantity": -1}}`
+	code, body = request(t, s, http.MethodPut, "/pool/"+pool.Id()+"/config/eth/add", config)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, body["configs"], 2)
+	eth, exist := pool.FindConfig("eth")
+	assert.True(t, exist)
+	assert.Equal(t, names.Symbol("ETHUSDT"), eth.Symbol)
+
+	code, _ = request(t, s, http.MethodPut, "/pool/"+pool.Id()+"/config/eth/add", config)
+	assert.Equal(t, http.StatusConflict, code, "config ids are unique in a pool")
+
+	code, body = request(t, s, http.MethodPut, "/pool/"+pool.Id()+"/config/bad/add", `{"symbol": "ETHUSDT"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, body["error"], "config.side: is required")
+
+	code, _ = request(t, s, http.MethodGet, "/pool/"+pool.Id()+"/config/eth/stop", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+
+	code, body = request(t, s, http.MethodPost, "/pool/"+pool.Id()+"/config/eth/stop", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, body["configs"], 1)
+
+	code, _ = request(t, s, http.MethodPost, "/pool/"+pool.Id()+"/config/eth/stop", "")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, body = request(t, s, http.MethodPost, "/pool/"+pool.Id()+"/stop", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, false, body["isRunning"])
+	assert.Len(t, body["configs"], 0)
+
+	code, _ = request(t, s, http.MethodPut, "/pool/"+pool.Id()+"/config/eth/add", config)
+	assert.Equal(t, http.StatusConflict, code, "a stopped pool does not take configs")
+}
+
+func TestTrades(t *testing.T) {
+	j := journal.NewMemoryJournal()
+	defer journal.Use(journal.Use(j))
+	j.Record(journal.Record{Time: time.UnixMilli(1000), Symbol: "BTCUSDT", ConfigId: "a", Side: names.TradeSideBuy})
+	j.Record(journal.Record{Time: time.UnixMilli(2000), Symbol: "BTCUSDT", ConfigId: "a", Side: names.TradeSideSell})
+	j.Record(journal.Record{Time: time.UnixMilli(3000), Symbol: "ETHUSDT", ConfigId: "b", Side: names.TradeSideBuy})
+	s := NewServer(":0")
+
+	recorder := httptest.NewRecorder()
+	s.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/trades?symbol=btcusdt&from=1500", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	lines := strings.Split(strings.TrimSpace(recorder.Body.String()), "\n")
+	assert.Len(t, lines, 1)
+	assert.Contains(t, lines[0], `"side":"SELL"`)
+
+	code, _ := request(t, s, http.MethodGet, "/trades?from=yesterday", "")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestAuthorization(t *testing.T) {
+	trader := &fakeTrader{configs: []names.TradeConfig{{Id: "btc", Symbol: "BTCUSDT", Side: names.TradeSideBuy}}}
+	pool := manager.NewTradeManager(trader).DoTrade().Pool()
+	stop := func(s *Server, authorization string) int {
+		recorder := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/pool/"+pool.Id()+"/stop", nil)
+		if authorization != "" {
+			r.Header.Set("Authorization", authorization)
+		}
+		s.Handler().ServeHTTP(recorder, r)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, stop(NewServer(":0").UseToken(""), "Bearer "), "nothing changes without a token")
+	s := NewServer(":0").UseToken(token)
+	assert.Equal(t, http.StatusUnauthorized, stop(s, ""))
+	assert.Equal(t, http.StatusUnauthorized, stop(s, "Bearer wrong"))
+	assert.Equal(t, http.StatusUnauthorized, stop(s, token), "the token is sent as a bearer")
+	assert.True(t, pool.IsRunning())
+	code, _ := request(t, NewServer(":0"), http.MethodGet, "/pool/"+pool.Id(), "")
+	assert.Equal(t, http.StatusOK, code, "reading needs no token")
+	assert.Equal(t, http.StatusOK, stop(s, "Bearer "+token))
+	assert.False(t, pool.IsRunning())
+
+	assert.Equal(t, "127.0.0.1:8080", NewServer(":8080").address)
+	assert.Equal(t, "0.0.0.0:8080", NewServer("0.0.0.0:8080").address)
+}
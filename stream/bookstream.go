@@ -0,0 +1,113 @@
+package stream
+
+import (
+	"fmt"
+	"trading/exchange"
+	"trading/names"
+	"trading/utils"
+)
+
+// levels of the snapshot a depth watched book starts from, an estimate
+// only needs the levels close to the price
+const (
+	depthSnapshotLimit = 1000
+	depthEstimateLimit = 100
+)
+
+// StreamBookTickers keeps the best bid and ask of symbols in their order
+// books until stop is sent to
+func StreamBookTickers(symbols []string) (stop chan struct{}, err error) {
+	_, stop, err = exchange.Get().StreamBookTickers(symbols, func(ticker exchange.BookTicker) {
+		GetOrderBook(ticker.Symbol).UpdateTicker(ticker)
+	}, func(err error) {
+		utils.LogWarn(fmt.Sprintf("<Book Stream>: book ticker stream failed, %s", err.Error()))
+	})
+	return stop, err
+}
+
+// StreamBooks keeps the order books of symbols until stop is closed, the
+// best bid and ask from the book tickers and every level from the depth
+// stream. A book without its depth stream only knows its best bid and ask
+func StreamBooks(symbols []string) (stop chan struct{}, err error) {
+	tickerStop, err := StreamBookTickers(symbols)
+	if err != nil {
+		return nil, err
+	}
+	depthStop, err := WatchDepth(symbols)
+	if err != nil {
+		utils.LogWarn(fmt.Sprintf("<Book Stream>: market orders are estimated from snapshots, %s", err.Error()))
+	}
+	stop = make(chan struct{})
+	go func() {
+		<-stop
+		close(tickerStop)
+		if depthStop != nil {
+			close(depthStop)
+		}
+	}()
+	return stop, nil
+}
+
+// loadSnapshot starts the book of symbol from a snapshot of the exchange
+func loadSnapshot(book *OrderBook) {
+	if !book.startLoading() {
+		return
+	}
+	depth, err := exchange.Get().Depth(book.Symbol, depthSnapshotLimit)
+	if err != nil {
+		book.stopLoading()
+		utils.LogError(err, fmt.Sprintf("<Book Stream>: could not load the %s order book", book.Symbol))
+		return
+	}
+	book.ApplySnapshot(*depth)
+}
+
+// WatchDepth keeps every level of the order books of symbols until stop is
+// sent to, a book that misses updates loads a new snapshot
+func WatchDepth(symbols []string) (stop chan struct{}, err error) {
+	_, stop, err = exchange.Get().StreamDepth(symbols, func(event exchange.DepthEvent) {
+		book := GetOrderBook(event.Symbol)
+		if err := book.ApplyDepth(event); err != nil {
+			utils.LogWarn(fmt.Sprintf("<Book Stream>: %s, loading it again", err.Error()))
+		}
+		if !book.Synced() {
+			go loadSnapshot(book)
+		}
+	}, func(err error) {
+		utils.LogWarn(fmt.Sprintf("<Book Stream>: depth stream failed, %s", err.Error()))
+	})
+	return stop, err
+}
+
+// EstimateFill estimates the market order of quantity on symbol from its
+// local book. A book that only knows its best bid and ask is replaced by a
+// snapshot of the exchange unless the account is mocked
+func EstimateFill(symbol string, side names.TradeSide, quantity float64) (Fill, bool) {
+	book, exist := FindOrderBook(symbol)
+	if exist && book.Synced() {
+		return book.EstimateFill(side, quantity), true
+	}
+	if !utils.Env().IsMockAccount() {
+		depth, err := exchange.Get().Depth(symbol, depthEstimateLimit)
+		if err == nil {
+			snapshot := NewOrderBook(symbol)
+			snapshot.ApplySnapshot(*depth)
+			return snapshot.EstimateFill(side, quantity), true
+		}
+		utils.LogError(err, fmt.Sprintf("<Book Stream>: could not load the %s order book", symbol))
+	}
+	if exist {
+		if fill := book.EstimateFill(side, quantity); fill.Filled > 0 {
+			return fill, true
+		}
+	}
+	return Fill{Side: side, Quantity: quantity}, false
+}
+
+// withBook adds the best bid and ask of the local book to data
+func (data SymbolPriceData) withBook() SymbolPriceData {
+	if book, exist := FindOrderBook(data.Symbol); exist {
+		data.BidPrice, data.AskPrice = book.BestBid(), book.BestAsk()
+	}
+	return data
+}
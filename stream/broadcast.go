@@ -3,6 +3,7 @@ package stream
 import (
 	"fmt"
 	"sync"
+	"trading/metrics"
 	"trading/names"
 	"trading/utils"
 )
@@ -23,6 +24,12 @@ type Subscription struct {
 	channel     chan SymbolPriceData
 	tradeConfig names.TradeConfig
 	broadcast   *Broadcaster
+	// closed when the subscription is removed so a synchronous
+	// publish does not wait for a reader that has stopped reading
+	done chan struct{}
+	// acknowledges that the reader processed the last price, a
+	// synchronous publish waits for it before it returns
+	processed chan struct{}
 }
 
 func (c *Subscription) Unsubscribe() bool {
@@ -43,6 +50,24 @@ func (c *Subscription) GetChannel() chan SymbolPriceData {
 	return c.channel
 }
 
+// Each calls read with every price received until the subscription is
+// removed. read returning is the acknowledgement a synchronous stream
+// waits for, so every trade a price starts is done before the next price
+func (c *Subscription) Each(read func(data SymbolPriceData)) {
+	for {
+		select {
+		case data := <-c.channel:
+			read(data)
+			select {
+			case c.processed <- struct{}{}:
+			default:
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
 func (ps *Broadcaster) readerReport(s map[names.TradeConfig]Subscription) {
 	summary := fmt.Sprintf("Total Subscribers: %d\n", len(s))
 	fmt.Println(summary)
@@ -50,16 +75,31 @@ func (ps *Broadcaster) readerReport(s map[names.TradeConfig]Subscription) {
 
 var BROADCAST_ID = "BROADCAST_ID"
 
+// every broadcaster that has not been terminated
+var broadcasts sync.Map
+
+func init() {
+	metrics.NewGaugeFunc("trading_broadcast_subscriptions", "configs subscribed to a broadcaster", []string{"broadcast"},
+		func(emit func(value float64, labels ...string)) {
+			broadcasts.Range(func(key, value interface{}) bool {
+				emit(float64(value.(*Broadcaster).SubscriberCount()), key.(string))
+				return true
+			})
+		})
+}
+
 func NewBroadcast(broadcastId string) *Broadcaster {
+	streamer := GetStreamer()
 	p := &Broadcaster{
 		subscribers: map[names.TradeConfig]Subscription{},
 		lock:        sync.RWMutex{},
 		broadcastId: broadcastId,
-		streamer:    Streamer,
+		streamer:    streamer,
 	}
+	broadcasts.Store(broadcastId, p)
 
 	// start this stream manager
-	Streamer.RegisterBroadcast(broadcastId, func(stream StreamInterface, streamData SymbolPriceData) {
+	streamer.RegisterBroadcast(broadcastId, func(stream StreamInterface, streamData SymbolPriceData) {
 		p.publish(streamData.Symbol, streamData)
 	})
 
@@ -71,9 +111,17 @@ func NewBroadcast(broadcastId string) *Broadcaster {
 }
 
 // sends a message to the streamer to terminate Symbol broadcast for this broadcast listener
+// and removes its subscriptions so their readers return
 // returns true if this broadcast listener was succesfully terminated
 func (ps *Broadcaster) TerminateBroadCast() bool {
 	cancelled := ps.streamer.UnregisterBroadcast(ps.broadcastId)
+	broadcasts.Delete(ps.broadcastId)
+	ps.lock.Lock()
+	for config, sub := range ps.subscribers {
+		delete(ps.subscribers, config)
+		close(sub.done)
+	}
+	ps.lock.Unlock()
 	if cancelled {
 		utils.LogInfo(fmt.Sprintf("Cancelled Manager with ID %s", ps.broadcastId))
 	}
@@ -86,6 +134,8 @@ func (ps *Broadcaster) Subscribe(config names.TradeConfig) Subscription {
 		channel:     make(chan SymbolPriceData),
 		tradeConfig: config,
 		broadcast:   ps,
+		done:        make(chan struct{}),
+		processed:   make(chan struct{}, 1),
 	}
 
 	ps.lock.RLock()
@@ -110,8 +160,9 @@ func (ps *Broadcaster) Unsubscribe(config names.TradeConfig) bool {
 	var removed bool
 
 	ps.lock.Lock()
-	if _, ok := ps.subscribers[config]; ok {
+	if sub, ok := ps.subscribers[config]; ok {
 		delete(ps.subscribers, config)
+		close(sub.done)
 		removed = true
 	}
 	ps.lock.Unlock()
@@ -129,13 +180,50 @@ func (ps *Broadcaster) UnsubscribeList(list []Subscription) {
 	}
 }
 
-func (ps *Broadcaster) publish(symbol string, symbolData SymbolPriceData) {
+// SubscriberCount returns the number of configs subscribed to this broadcaster
+func (ps *Broadcaster) SubscriberCount() int {
 	ps.lock.RLock()
 	defer ps.lock.RUnlock()
+	return len(ps.subscribers)
+}
+
+// ActiveSubscriptions returns the number of subscriptions across every
+// broadcaster that has not been terminated
+func ActiveSubscriptions() int {
+	count := 0
+	broadcasts.Range(func(key, value interface{}) bool {
+		count += value.(*Broadcaster).SubscriberCount()
+		return true
+	})
+	return count
+}
+
+func (ps *Broadcaster) publish(symbol string, symbolData SymbolPriceData) {
+	tick()
+	ps.lock.RLock()
+	receivers := []Subscription{}
 	for _, sub := range ps.subscribers {
 		if sub.tradeConfig.Symbol.String() != symbol {
 			continue
 		}
+		receivers = append(receivers, sub)
+	}
+	ps.lock.RUnlock()
+
+	synchronous := ps.streamer.State().Synchronous
+	for _, sub := range receivers {
+		if synchronous {
+			select {
+			case sub.channel <- symbolData:
+			case <-sub.done:
+				continue
+			}
+			select {
+			case <-sub.processed:
+			case <-sub.done:
+			}
+			continue
+		}
 		select {
 		case sub.channel <- symbolData:
 
@@ -0,0 +1,136 @@
+package stream
+
+import (
+	"sort"
+	"sync"
+	"time"
+	"trading/metrics"
+)
+
+type GapReason string
+
+const (
+	// the connection of the symbol failed or stopped sending prices
+	GapDisconnected GapReason = "disconnected"
+	// the connection is up but the symbol stopped ticking
+	GapStale GapReason = "stale"
+)
+
+// Gap is a time a symbol received no price from its socket. Prices polled
+// from the API while the socket is down are still published, they are just
+// too late to lock on. End is zero while the gap is open
+type Gap struct {
+	Symbol string
+	Reason GapReason
+	Start  time.Time
+	End    time.Time
+}
+
+func (g Gap) IsOpen() bool {
+	return g.End.IsZero()
+}
+
+var gaps = struct {
+	lock     sync.RWMutex
+	open     map[string]Gap
+	handlers map[int]func(Gap)
+	next     int
+}{open: map[string]Gap{}, handlers: map[int]func(Gap){}}
+
+func init() {
+	metrics.NewGaugeFunc("trading_stream_gaps", "symbols without prices from their socket", []string{"reason"},
+		func(emit func(value float64, labels ...string)) {
+			counts := map[GapReason]float64{GapDisconnected: 0, GapStale: 0}
+			for _, gap := range OpenGaps() {
+				counts[gap.Reason]++
+			}
+			for reason, count := range counts {
+				emit(count, string(reason))
+			}
+		})
+}
+
+// OnGap calls handler when a gap opens and again when it ends until remove
+// is called
+func OnGap(handler func(Gap)) (remove func()) {
+	gaps.lock.Lock()
+	defer gaps.lock.Unlock()
+	id := gaps.next
+	gaps.next++
+	gaps.handlers[id] = handler
+	return func() {
+		gaps.lock.Lock()
+		defer gaps.lock.Unlock()
+		delete(gaps.handlers, id)
+	}
+}
+
+// InGap is true while symbol has an open gap, traders do not lock its prices
+func InGap(symbol string) bool {
+	gaps.lock.RLock()
+	defer gaps.lock.RUnlock()
+	_, exist := gaps.open[symbol]
+	return exist
+}
+
+// OpenGaps returns the gaps that have not ended by symbol
+func OpenGaps() []Gap {
+	gaps.lock.RLock()
+	list := make([]Gap, 0, len(gaps.open))
+	for _, gap := range gaps.open {
+		list = append(list, gap)
+	}
+	gaps.lock.RUnlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].Symbol < list[j].Symbol })
+	return list
+}
+
+// openGaps opens a gap for the symbols that are not in one yet
+func openGaps(symbols []string, reason GapReason, start time.Time) {
+	opened := []Gap{}
+	gaps.lock.Lock()
+	for _, symbol := range symbols {
+		if _, exist := gaps.open[symbol]; exist {
+			continue
+		}
+		gap := Gap{Symbol: symbol, Reason: reason, Start: start}
+		gaps.open[symbol] = gap
+		opened = append(opened, gap)
+	}
+	gaps.lock.Unlock()
+	emitGaps(opened)
+}
+
+// closeGaps ends the open gaps of the symbols
+func closeGaps(symbols []string, end time.Time) {
+	closed := []Gap{}
+	gaps.lock.Lock()
+	for _, symbol := range symbols {
+		gap, exist := gaps.open[symbol]
+		if !exist {
+			continue
+		}
+		delete(gaps.open, symbol)
+		gap.End = end
+		closed = append(closed, gap)
+	}
+	gaps.lock.Unlock()
+	emitGaps(closed)
+}
+
+func emitGaps(list []Gap) {
+	if len(list) == 0 {
+		return
+	}
+	gaps.lock.RLock()
+	handlers := make([]func(Gap), 0, len(gaps.handlers))
+	for _, handler := range gaps.handlers {
+		handlers = append(handlers, handler)
+	}
+	gaps.lock.RUnlock()
+	for _, gap := range list {
+		for _, handler := range handlers {
+			handler(gap)
+		}
+	}
+}
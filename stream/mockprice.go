@@ -154,7 +154,7 @@ func readTesterDataDispatch(s *MockPrice) {
 
 			for readerId, Price := range symbols {
 				for _, bulkReader := range s.bulkReaders {
-					data := SymbolPriceData{Price, readerId}
+					data := SymbolPriceData{Price: Price, Symbol: readerId}
 					go bulkReader(s, data)
 				}
 			}
@@ -0,0 +1,258 @@
+package stream
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"trading/exchange"
+	"trading/names"
+)
+
+// events kept while the snapshot of a book is loading
+const maxBufferedDepth = 1000
+
+// OrderBook is the local order book of a symbol. The book ticker keeps its
+// best bid and ask, the depth stream and a snapshot keep every level
+type OrderBook struct {
+	Symbol       string
+	lock         sync.RWMutex
+	bids         map[float64]float64
+	asks         map[float64]float64
+	lastUpdateId int64
+	synced       bool
+	loading      bool
+	buffer       []exchange.DepthEvent
+	top          exchange.BookTicker
+}
+
+func NewOrderBook(symbol string) *OrderBook {
+	return &OrderBook{Symbol: symbol, bids: map[float64]float64{}, asks: map[float64]float64{}}
+}
+
+func setLevels(book map[float64]float64, levels []exchange.PriceLevel) {
+	for _, level := range levels {
+		if level.Quantity == 0 {
+			delete(book, level.Price)
+			continue
+		}
+		book[level.Price] = level.Quantity
+	}
+}
+
+// ApplySnapshot replaces the levels of the book and applies the depth
+// events received while the snapshot was loading
+func (b *OrderBook) ApplySnapshot(depth exchange.Depth) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.bids, b.asks = map[float64]float64{}, map[float64]float64{}
+	setLevels(b.bids, depth.Bids)
+	setLevels(b.asks, depth.Asks)
+	b.lastUpdateId = depth.LastUpdateId
+	b.synced, b.loading = true, false
+
+	buffer := b.buffer
+	b.buffer = nil
+	for _, event := range buffer {
+		if err := b.applyDepth(event); err != nil {
+			return
+		}
+	}
+}
+
+// ApplyDepth applies a depth event to the book. Events are kept until the
+// book has a snapshot, an error means updates were missed and the book
+// needs a new snapshot
+func (b *OrderBook) ApplyDepth(event exchange.DepthEvent) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.applyDepth(event)
+}
+
+func (b *OrderBook) applyDepth(event exchange.DepthEvent) error {
+	if !b.synced {
+		if len(b.buffer) >= maxBufferedDepth {
+			b.buffer = b.buffer[1:]
+		}
+		b.buffer = append(b.buffer, event)
+		return nil
+	}
+	if event.LastUpdateId <= b.lastUpdateId {
+		return nil
+	}
+	if event.FirstUpdateId > b.lastUpdateId+1 {
+		b.synced = false
+		b.buffer = []exchange.DepthEvent{event}
+		return fmt.Errorf("%s order book missed the updates %d to %d", b.Symbol, b.lastUpdateId+1, event.FirstUpdateId-1)
+	}
+	setLevels(b.bids, event.Bids)
+	setLevels(b.asks, event.Asks)
+	b.lastUpdateId = event.LastUpdateId
+	return nil
+}
+
+// UpdateTicker keeps the best bid and ask of the book
+func (b *OrderBook) UpdateTicker(ticker exchange.BookTicker) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if ticker.UpdateId >= b.top.UpdateId {
+		b.top = ticker
+	}
+}
+
+// Synced reports whether the book holds every level and not only the best bid and ask
+func (b *OrderBook) Synced() bool {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	return b.synced
+}
+
+// startLoading reports whether the caller should load the snapshot of the book
+func (b *OrderBook) startLoading() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.synced || b.loading {
+		return false
+	}
+	b.loading = true
+	return true
+}
+
+func (b *OrderBook) stopLoading() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.loading = false
+}
+
+func sortedLevels(book map[float64]float64, descending bool) []exchange.PriceLevel {
+	levels := make([]exchange.PriceLevel, 0, len(book))
+	for price, quantity := range book {
+		levels = append(levels, exchange.PriceLevel{Price: price, Quantity: quantity})
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		if descending {
+			return levels[i].Price > levels[j].Price
+		}
+		return levels[i].Price < levels[j].Price
+	})
+	return levels
+}
+
+// Bids of the book, the best first. Only the best bid is known until the book is synced
+func (b *OrderBook) Bids() []exchange.PriceLevel {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	if !b.synced {
+		if b.top.BidPrice <= 0 {
+			return []exchange.PriceLevel{}
+		}
+		return []exchange.PriceLevel{{Price: b.top.BidPrice, Quantity: b.top.BidQuantity}}
+	}
+	return sortedLevels(b.bids, true)
+}
+
+// Asks of the book, the best first. Only the best ask is known until the book is synced
+func (b *OrderBook) Asks() []exchange.PriceLevel {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	if !b.synced {
+		if b.top.AskPrice <= 0 {
+			return []exchange.PriceLevel{}
+		}
+		return []exchange.PriceLevel{{Price: b.top.AskPrice, Quantity: b.top.AskQuantity}}
+	}
+	return sortedLevels(b.asks, false)
+}
+
+// BestBid is the highest bid, zero when unknown
+func (b *OrderBook) BestBid() float64 {
+	if bids := b.Bids(); len(bids) > 0 {
+		return bids[0].Price
+	}
+	return 0
+}
+
+// BestAsk is the lowest ask, zero when unknown
+func (b *OrderBook) BestAsk() float64 {
+	if asks := b.Asks(); len(asks) > 0 {
+		return asks[0].Price
+	}
+	return 0
+}
+
+// Fill is the estimated execution of a market order against the book
+type Fill struct {
+	Side     names.TradeSide
+	Quantity float64
+	// the quantity the known levels can fill
+	Filled float64
+	// average price of the filled quantity
+	Price float64
+	// last level the order reaches
+	WorstPrice float64
+	Levels     int
+}
+
+// Complete reports whether the known levels fill the whole quantity
+func (f Fill) Complete() bool {
+	return f.Quantity > 0 && f.Filled >= f.Quantity
+}
+
+// Slippage of the fill from price in percent, positive when the fill is worse
+func (f Fill) Slippage(price float64) float64 {
+	if price <= 0 || f.Price <= 0 {
+		return 0
+	}
+	if f.Side.IsBuy() {
+		return (f.Price - price) / price * 100
+	}
+	return (price - f.Price) / price * 100
+}
+
+// EstimateFill walks the asks for a buy and the bids for a sell of quantity
+func (b *OrderBook) EstimateFill(side names.TradeSide, quantity float64) Fill {
+	levels := b.Bids()
+	if side.IsBuy() {
+		levels = b.Asks()
+	}
+	return estimateFill(side, quantity, levels)
+}
+
+func estimateFill(side names.TradeSide, quantity float64, levels []exchange.PriceLevel) Fill {
+	fill := Fill{Side: side, Quantity: quantity}
+	var quote float64
+	for _, level := range levels {
+		if fill.Filled >= quantity {
+			break
+		}
+		taken := level.Quantity
+		if fill.Filled+taken > quantity {
+			taken = quantity - fill.Filled
+		}
+		fill.Filled += taken
+		quote += taken * level.Price
+		fill.WorstPrice = level.Price
+		fill.Levels++
+	}
+	if fill.Filled > 0 {
+		fill.Price = quote / fill.Filled
+	}
+	return fill
+}
+
+var books sync.Map
+
+// GetOrderBook returns the local order book of symbol, an empty one is
+// created when symbol has none
+func GetOrderBook(symbol string) *OrderBook {
+	book, _ := books.LoadOrStore(symbol, NewOrderBook(symbol))
+	return book.(*OrderBook)
+}
+
+// FindOrderBook returns the local order book of symbol when one is kept
+func FindOrderBook(symbol string) (*OrderBook, bool) {
+	book, exist := books.Load(symbol)
+	if !exist {
+		return nil, false
+	}
+	return book.(*OrderBook), true
+}
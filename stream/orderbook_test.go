@@ -0,0 +1,79 @@
+package stream
+
+import (
+	"testing"
+	"trading/exchange"
+	"trading/names"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func levels(prices ...float64) []exchange.PriceLevel {
+	parsed := []exchange.PriceLevel{}
+	for i := 0; i < len(prices); i += 2 {
+		parsed = append(parsed, exchange.PriceLevel{Price: prices[i], Quantity: prices[i+1]})
+	}
+	return parsed
+}
+
+func TestOrderBookSync(t *testing.T) {
+	book := NewOrderBook("BTCUSDT")
+	// events before the snapshot wait for it, the first one is older than the snapshot
+	assert.Nil(t, book.ApplyDepth(exchange.DepthEvent{FirstUpdateId: 95, LastUpdateId: 99, Bids: levels(99, 9)}))
+	assert.Nil(t, book.ApplyDepth(exchange.DepthEvent{FirstUpdateId: 100, LastUpdateId: 102, Bids: levels(98, 0), Asks: levels(101, 4)}))
+	assert.False(t, book.Synced())
+
+	book.ApplySnapshot(exchange.Depth{LastUpdateId: 100, Bids: levels(99, 1, 98, 2, 97, 3), Asks: levels(101, 1, 102, 2)})
+	assert.True(t, book.Synced())
+	assert.Equal(t, levels(99, 1, 97, 3), book.Bids())
+	assert.Equal(t, levels(101, 4, 102, 2), book.Asks())
+
+	err := book.ApplyDepth(exchange.DepthEvent{FirstUpdateId: 105, LastUpdateId: 106, Asks: levels(101, 0)})
+	assert.NotNil(t, err, "updates 103 and 104 were missed")
+	assert.False(t, book.Synced())
+	assert.True(t, book.startLoading())
+	assert.False(t, book.startLoading(), "a single snapshot loads at once")
+
+	book.ApplySnapshot(exchange.Depth{LastUpdateId: 104, Bids: levels(99, 1), Asks: levels(101, 1, 103, 1)})
+	assert.Equal(t, 103.0, book.BestAsk(), "the missed event is applied after the snapshot")
+}
+
+func TestOrderBookTicker(t *testing.T) {
+	book := NewOrderBook("ETHUSDT")
+	assert.Equal(t, 0.0, book.BestBid())
+
+	book.UpdateTicker(exchange.BookTicker{UpdateId: 2, BidPrice: 10, BidQuantity: 3, AskPrice: 10.1, AskQuantity: 1})
+	book.UpdateTicker(exchange.BookTicker{UpdateId: 1, BidPrice: 9, AskPrice: 11})
+	assert.Equal(t, 10.0, book.BestBid(), "older tickers are ignored")
+	assert.Equal(t, 10.1, book.BestAsk())
+
+	fill := book.EstimateFill(names.TradeSideSell, 5)
+	assert.False(t, fill.Complete(), "only the best bid is known")
+	assert.Equal(t, 3.0, fill.Filled)
+}
+
+func TestEstimateFill(t *testing.T) {
+	book := NewOrderBook("DOGEUSDT")
+	book.ApplySnapshot(exchange.Depth{LastUpdateId: 1, Bids: levels(100, 1, 99, 1, 90, 10), Asks: levels(101, 2)})
+
+	fill := book.EstimateFill(names.TradeSideSell, 3)
+	assert.True(t, fill.Complete())
+	assert.Equal(t, 3, fill.Levels)
+	assert.Equal(t, 90.0, fill.WorstPrice)
+	assert.InDelta(t, (100+99+90)/3.0, fill.Price, 1e-9)
+	assert.InDelta(t, 3.667, fill.Slippage(100), 1e-3, "a thin bid side sells far below the last price")
+
+	fill = book.EstimateFill(names.TradeSideBuy, 1)
+	assert.Equal(t, 101.0, fill.Price)
+	assert.InDelta(t, 0.5, fill.Slippage(100.5), 1e-2)
+}
+
+func TestPriceDataWithBook(t *testing.T) {
+	data := SymbolPriceData{Price: 10, Symbol: "UNKNOWNUSDT"}.withBook()
+	assert.Equal(t, 0.0, data.BidPrice)
+
+	GetOrderBook("BOOKUSDT").UpdateTicker(exchange.BookTicker{BidPrice: 9.9, AskPrice: 10.1})
+	data = SymbolPriceData{Price: 10, Symbol: "BOOKUSDT"}.withBook()
+	assert.Equal(t, 9.9, data.BidPrice)
+	assert.Equal(t, 10.1, data.AskPrice)
+}
@@ -0,0 +1,106 @@
+package stream
+
+import (
+	"fmt"
+	"sync"
+	"trading/utils"
+)
+
+var StreamTypeReplay StreamType = "STREAM_REPLAY"
+
+// ReplayStream does not connect to an exchange, prices are pushed into it
+// through Publish. It is used to replay recorded prices to the traders in
+// the order they were recorded, every subscriber has processed a price
+// before Publish returns
+type ReplayStream struct {
+	symbols     []string
+	readers     map[string]ReaderFunc
+	bulkReaders map[string]ReaderFunc
+	closeStream bool
+	failHandler func(StreamInterface)
+	lock        sync.RWMutex
+}
+
+func NewReplayStream(symbols []string) *ReplayStream {
+	return &ReplayStream{
+		symbols:     symbols,
+		readers:     make(map[string]ReaderFunc),
+		bulkReaders: map[string]ReaderFunc{},
+		lock:        sync.RWMutex{},
+	}
+}
+
+// Publish sends this price to every registered reader and waits for them to return
+func (s *ReplayStream) Publish(data SymbolPriceData) {
+	if s.IsClosed() {
+		return
+	}
+	s.lock.RLock()
+	readers := []ReaderFunc{}
+	for readerId, reader := range s.readers {
+		if readerId == data.Symbol {
+			readers = append(readers, reader)
+		}
+	}
+	for _, reader := range s.bulkReaders {
+		readers = append(readers, reader)
+	}
+	s.lock.RUnlock()
+
+	for _, reader := range readers {
+		reader(s, data)
+	}
+}
+
+func (s *ReplayStream) Close() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.closeStream = true
+	return s.closeStream
+}
+
+func (s *ReplayStream) IsClosed() bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.closeStream
+}
+
+func (s *ReplayStream) CloseLog(message string) {
+	if s.Close() {
+		utils.LogInfo(fmt.Sprintf("<Replay Stream>: %s: Connection Closed", message))
+	}
+}
+
+func (s *ReplayStream) RegisterLegacyReader(symbol string, reader ReaderFunc) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.readers[symbol] = reader
+}
+
+func (s *ReplayStream) RegisterBroadcast(readerId string, reader ReaderFunc) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.bulkReaders[readerId] = reader
+}
+
+func (s *ReplayStream) UnregisterBroadcast(readerId string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, exist := s.bulkReaders[readerId]
+	delete(s.bulkReaders, readerId)
+	return exist
+}
+
+func (s *ReplayStream) State() streamState {
+	return streamState{
+		Readers:     s.readers,
+		Symbols:     s.symbols,
+		Type:        StreamTypeReplay,
+		BulkReader:  s.bulkReaders,
+		Synchronous: true,
+	}
+}
+
+func (s *ReplayStream) RegisterFailOver(fh func(failedStream StreamInterface)) {
+	s.failHandler = fh
+}
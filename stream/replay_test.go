@@ -0,0 +1,40 @@
+package stream
+
+import (
+	"testing"
+	"time"
+	"trading/names"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplayPublish(t *testing.T) {
+	previous := Streamer
+	defer UseStreamer(previous)
+	replay := NewReplayStream([]string{"BTCUSDT"})
+	UseStreamer(replay)
+
+	broadcast := NewBroadcast("replay")
+	subscription := broadcast.Subscribe(names.TradeConfig{Id: "btc", Symbol: "BTCUSDT"})
+	read := []float64{}
+	returned := make(chan struct{})
+	go func() {
+		subscription.Each(func(data SymbolPriceData) {
+			// a slow reader is waited for
+			time.Sleep(10 * time.Millisecond)
+			read = append(read, data.Price)
+		})
+		close(returned)
+	}()
+
+	replay.Publish(SymbolPriceData{Symbol: "BTCUSDT", Price: 1})
+	assert.Equal(t, []float64{1}, read, "the price was processed when Publish returns")
+	replay.Publish(SymbolPriceData{Symbol: "ETHUSDT", Price: 2})
+	replay.Publish(SymbolPriceData{Symbol: "BTCUSDT", Price: 3})
+	assert.Equal(t, []float64{1, 3}, read)
+
+	broadcast.TerminateBroadCast()
+	<-returned
+	replay.Publish(SymbolPriceData{Symbol: "BTCUSDT", Price: 4})
+	assert.Equal(t, []float64{1, 3}, read, "a terminated broadcast stops its readers")
+}
@@ -2,14 +2,16 @@ package stream
 
 import (
 	"fmt"
-	"strconv"
 	"sync"
 	"time"
+	"trading/exchange"
+	"trading/metrics"
 	"trading/utils"
-
-	"github.com/adshao/go-binance/v2"
 )
 
+// time from the exchange sending a price to the socket receiving it
+var tickLatency = metrics.NewSummary("trading_tick_latency_seconds", "time from a price event on the exchange to the socket stream", "symbol")
+
 type Socket struct {
 	readers         map[string]ReaderFunc
 	bulkReaders     map[string]ReaderFunc //Maybe we should change to array to avoid cuncurrency
@@ -17,9 +19,11 @@ type Socket struct {
 	symbols         []string
 	stopChannel     chan struct{}
 	doneChannel     chan struct{}
-	failHandler     func(StreamInterface)
-	streamIsClosed  bool
-	lock            sync.RWMutex
+	// stops the book streams that keep the order books of the symbols
+	bookStop       chan struct{}
+	failHandler    func(StreamInterface)
+	streamIsClosed bool
+	lock           sync.RWMutex
 }
 
 func NewSocketStream(symbols []string) StreamInterface {
@@ -71,9 +75,11 @@ func readSocketDataDispatch(s *Socket) {
 			s.CloseLog(err.Error())
 		}
 
-		messageHandler := func(event *binance.WsMarketStatEvent) {
-			price, _ := strconv.ParseFloat(event.LastPrice, 64)
-			data := SymbolPriceData{Price: price, Symbol: event.Symbol}
+		messageHandler := func(event exchange.PriceEvent) {
+			if event.Time > 0 {
+				tickLatency.Observe(utils.Now().Sub(time.UnixMilli(event.Time)).Seconds(), event.Symbol)
+			}
+			data := SymbolPriceData{Price: event.Price, Symbol: event.Symbol}.withBook()
 
 			go func(data SymbolPriceData) {
 				s.lock.RLock()
@@ -85,7 +91,7 @@ func readSocketDataDispatch(s *Socket) {
 
 		}
 
-		donChannel, stopChannel, err := binance.WsCombinedMarketStatServe(
+		donChannel, stopChannel, err := exchange.Get().StreamPrices(
 			s.symbols,
 			messageHandler,
 			errorHandler,
@@ -96,7 +102,15 @@ func readSocketDataDispatch(s *Socket) {
 
 		if err != nil {
 			errorHandler(err)
+			return
 		}
+
+		bookStop, err := StreamBooks(s.symbols)
+		if err != nil {
+			utils.LogWarn(fmt.Sprintf("<Socket Stream>: prices are published without the best bid and ask, %s", err.Error()))
+			return
+		}
+		s.bookStop = bookStop
 	}
 }
 
@@ -127,6 +141,10 @@ func (s *Socket) RegisterLegacyReader(symbol string, reader ReaderFunc) {
 }
 
 func (s *Socket) Close() bool {
+	if s.bookStop != nil {
+		close(s.bookStop)
+		s.bookStop = nil
+	}
 	s.stopChannel <- struct{}{}
 	// s.doneChannel <- struct{}{}
 	s.streamIsClosed = true
@@ -0,0 +1,81 @@
+package stream
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+	"trading/notify"
+	"trading/utils"
+)
+
+// when a price last reached a broadcaster in unix nanoseconds
+var lastTick int64
+
+func tick() {
+	atomic.StoreInt64(&lastTick, utils.Now().UnixNano())
+}
+
+// LastTick is when a price last reached a broadcaster, zero before the first one
+func LastTick() time.Time {
+	nano := atomic.LoadInt64(&lastTick)
+	if nano == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nano)
+}
+
+type stallWatch struct {
+	after   time.Duration
+	since   time.Time
+	stalled bool
+}
+
+// check notifies once when no price ticked for after, and logs when the
+// prices tick again
+func (w *stallWatch) check(now time.Time) {
+	last := LastTick()
+	if last.Before(w.since) {
+		last = w.since
+	}
+	silent := now.Sub(last)
+	if silent < w.after {
+		if w.stalled {
+			utils.LogInfo(fmt.Sprintf("<Stream>: prices tick again after %s", silent.Round(time.Second)))
+		}
+		w.stalled = false
+		return
+	}
+	if w.stalled {
+		return
+	}
+	w.stalled = true
+	utils.LogWarn(fmt.Sprintf("<Stream>: no price ticked for %s", silent.Round(time.Second)))
+	event := notify.NewEvent(notify.EventStall, "PRICES STOPPED TICKING",
+		"Silent For", silent.Round(time.Second).String(),
+		"Subscriptions", fmt.Sprintf("%d", ActiveSubscriptions()),
+	)
+	if !last.Equal(w.since) {
+		event.Fields = append(event.Fields, notify.Field{Name: "Last Tick", Value: last.Format(time.UnixDate)})
+	}
+	notify.Send(event)
+}
+
+// WatchStalls notifies when no price reaches a broadcaster for after until
+// stop is called
+func WatchStalls(after time.Duration) (stop func()) {
+	w := &stallWatch{after: after, since: utils.Now()}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(after / 4)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				w.check(utils.Now())
+			}
+		}
+	}()
+	return func() { close(done) }
+}
@@ -0,0 +1,46 @@
+package stream
+
+import (
+	"sync"
+	"testing"
+	"time"
+	"trading/notify"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type stalls struct {
+	lock   sync.Mutex
+	events []notify.Event
+}
+
+func (s *stalls) Name() string {
+	return "stalls"
+}
+
+func (s *stalls) Notify(e notify.Event) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.events = append(s.events, e)
+	return nil
+}
+
+func TestStallWatch(t *testing.T) {
+	received := &stalls{}
+	d := notify.New().Add(received, notify.EventStall)
+	defer notify.Use(notify.Use(d))
+
+	start := time.Now()
+	w := &stallWatch{after: time.Minute, since: start}
+
+	w.check(start.Add(30 * time.Second))
+	w.check(start.Add(61 * time.Second))
+	w.check(start.Add(2 * time.Minute))
+	d.Wait()
+	assert.Len(t, received.events, 1, "a stall is notified once")
+	assert.Equal(t, "1m1s", received.events[0].Fields[0].Value)
+
+	tick()
+	w.check(time.Now())
+	assert.False(t, w.stalled, "prices tick again")
+}
@@ -2,10 +2,14 @@ package stream
 
 import (
 	"fmt"
-	"math"
+	"os"
+	"strings"
+	"sync"
 
 	// "trading/constant"
+	"trading/metrics"
 	"trading/names"
+	"trading/notify"
 	"trading/utils"
 )
 
@@ -14,6 +18,9 @@ type ReaderFunc func(stream StreamInterface, data SymbolPriceData)
 type SymbolPriceData struct {
 	Price  float64
 	Symbol string
+	// best bid and ask of the local order book, zero when unknown
+	BidPrice float64
+	AskPrice float64
 }
 
 type StreamInterface interface {
@@ -37,16 +44,51 @@ type streamState struct {
 	Symbols    []string
 	Type       StreamType
 	BulkReader map[string]ReaderFunc
+	// Synchronous streams wait for every subscriber to receive a price
+	// instead of dropping it when the subscriber is busy
+	Synchronous bool
 }
 
-func GetPriceStreamer(symbols []string, useAPI bool) StreamInterface {
+func GetPriceStreamer(symbols []string, streamType StreamType) StreamInterface {
 	if utils.Env().IsMockStream() {
 		return NewMockStream(symbols)
 	}
-	if useAPI {
+	switch streamType {
+	case StreamTypeAPI:
 		return NewAPIStream(symbols)
+	case StreamTypeSocket:
+		return NewSocketStream(symbols)
+	}
+	return NewSupervisor(symbols)
+}
+
+// PriceStreamType is the stream picked by the env PRICE_STREAM, one of
+// supervised, socket or api, supervised when not set
+func PriceStreamType() StreamType {
+	switch value := strings.ToLower(os.Getenv("PRICE_STREAM")); value {
+	case "", "supervised":
+		return StreamTypeSupervised
+	case "socket":
+		return StreamTypeSocket
+	case "api":
+		return StreamTypeAPI
+	default:
+		utils.LogWarn(fmt.Sprintf("<Stream>: unknown PRICE_STREAM %s, using %s", value, StreamTypeSupervised))
+		return StreamTypeSupervised
+	}
+}
+
+var (
+	streamType      = metrics.NewGauge("trading_stream_type", "the price stream in use is 1", "type")
+	streamFailovers = metrics.NewCounter("trading_stream_failovers_total", "price streams replaced after they failed", "from", "to")
+)
+
+// useStreamType reports t as the price stream in use
+func useStreamType(t StreamType) {
+	for _, other := range []StreamType{StreamTypeAPI, StreamTypeSocket, StreamTypeSupervised} {
+		streamType.Set(0, string(other))
 	}
-	return NewSocketStream(symbols)
+	streamType.Set(1, string(t))
 }
 
 type StreamManager struct {
@@ -80,7 +122,16 @@ func (sm *StreamManager) SwitchStream(failedStream StreamInterface) {
 		sm.streamer = sm.copystream(NewAPIStream, sm.streamer)
 	default:
 		utils.LogWarn(fmt.Sprintf("There was problem switching the stream %s", state.Type))
+		return
 	}
+	next := sm.streamer.State().Type
+	streamFailovers.Inc(string(state.Type), string(next))
+	useStreamType(next)
+	notify.Send(notify.NewEvent(notify.EventFailover, "PRICE STREAM FAILED OVER",
+		"Failed Stream", string(state.Type),
+		"Next Stream", string(next),
+		"Symbols", fmt.Sprintf("%d", len(state.Symbols)),
+	))
 }
 
 func (sm *StreamManager) GetStream() StreamInterface {
@@ -89,20 +140,45 @@ func (sm *StreamManager) GetStream() StreamInterface {
 }
 
 func (sm *StreamManager) NewStream(symbols []string) StreamInterface {
-	sm.streamer = GetPriceStreamer(symbols, !true)
+	sm.streamer = GetPriceStreamer(symbols, PriceStreamType())
 	sm.streamer.RegisterFailOver(sm.SwitchStream)
+	useStreamType(sm.streamer.State().Type)
 	return sm.streamer
 }
 
 func (sm *StreamManager) StreamAll() StreamInterface {
 	// return sm.NewStream(constant.SymbolList)
 	v := names.GetNewInfo().SpotableSymbolInfo().List()
-	utils.LogWarn(fmt.Sprintf("Loaded %d, only 1090 will be streamed", len(v)))
-	return sm.NewStream(v[0:int(math.Min(float64(len(v)), 1090))])
+	if PriceStreamType() != StreamTypeSupervised && len(v) > 1090 {
+		// a single connection takes at most 1090 symbols, the supervisor
+		// shards them across connections instead
+		utils.LogWarn(fmt.Sprintf("Loaded %d, only 1090 will be streamed", len(v)))
+		v = v[0:1090]
+	}
+	return sm.NewStream(v)
 }
 
-var Streamer = func() StreamInterface {
-	s := StreamManager{}
-	// s := StreamManager{Symbols: names.GetSymbols().List()}
-	return s.StreamAll()
-}()
+var Streamer StreamInterface
+var streamerLock sync.Mutex
+
+// GetStreamer returns the streamer shared by every broadcaster. The first
+// call starts streaming all spotable symbols unless a streamer was provided
+// through UseStreamer
+func GetStreamer() StreamInterface {
+	streamerLock.Lock()
+	defer streamerLock.Unlock()
+	if Streamer == nil {
+		s := StreamManager{}
+		// s := StreamManager{Symbols: names.GetSymbols().List()}
+		Streamer = s.StreamAll()
+	}
+	return Streamer
+}
+
+// UseStreamer replaces the shared streamer, broadcasters created afterwards
+// will receive their prices from this streamer
+func UseStreamer(streamer StreamInterface) {
+	streamerLock.Lock()
+	defer streamerLock.Unlock()
+	Streamer = streamer
+}
@@ -0,0 +1,448 @@
+package stream
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+	"trading/exchange"
+	"trading/metrics"
+	"trading/notify"
+	"trading/utils"
+)
+
+var StreamTypeSupervised StreamType = "STREAM_SUPERVISED"
+
+const (
+	// Binance allows 1024 streams a connection, smaller shards keep the
+	// urls short and a failed connection loses fewer symbols
+	DefaultShardSize = 200
+	// a symbol without a price for this long is in a gap, a connection
+	// without any price for this long is opened again
+	DefaultStaleAfter = 30 * time.Second
+	// Binance closes a connection after 24 hours, it is replaced before
+	DefaultMaxConnectionAge = 23 * time.Hour
+	DefaultMinBackoff       = time.Second
+	DefaultMaxBackoff       = 2 * time.Minute
+)
+
+const (
+	reconnectFailed  = "failed"
+	reconnectSilent  = "silent"
+	reconnectRotated = "rotated"
+)
+
+var streamReconnects = metrics.NewCounter("trading_stream_reconnects_total", "connections of the supervised price stream opened again", "shard", "reason")
+
+type connection struct {
+	opened   time.Time
+	stop     chan struct{}
+	bookStop chan struct{}
+	// the exchange reported an error or the connection was given up
+	failed bool
+}
+
+// close stops the streams of the connection, the lock of the supervisor
+// must be held
+func (c *connection) close() {
+	if c.bookStop != nil {
+		close(c.bookStop)
+		c.bookStop = nil
+	}
+	if c.stop != nil {
+		close(c.stop)
+		c.stop = nil
+	}
+}
+
+// shard is a group of symbols streamed through one connection
+type shard struct {
+	index   int
+	symbols []string
+	conn    *connection
+	// failures since the shard last received a price, they grow the backoff
+	failures  int
+	retryAt   time.Time
+	downSince time.Time
+	// why the next connection is opened
+	reason string
+}
+
+// Supervisor streams prices through a socket connection for every shard of
+// its symbols. A connection that fails or stops sending prices is opened
+// again with an exponential backoff, the prices of its shard are polled
+// from the API meanwhile. Connections are replaced before the exchange
+// closes them and a symbol that stops ticking opens a gap
+type Supervisor struct {
+	symbols     []string
+	shards      []*shard
+	lastTick    map[string]time.Time
+	readers     map[string]ReaderFunc
+	bulkReaders map[string]ReaderFunc
+	shardSize   int
+	staleAfter  time.Duration
+	maxAge      time.Duration
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	// how often connections are checked and down shards are polled
+	every   time.Duration
+	started bool
+	closed  bool
+	done    chan struct{}
+	lock    sync.RWMutex
+}
+
+func NewSupervisor(symbols []string) *Supervisor {
+	return &Supervisor{
+		symbols:     symbols,
+		lastTick:    map[string]time.Time{},
+		readers:     map[string]ReaderFunc{},
+		bulkReaders: map[string]ReaderFunc{},
+		shardSize:   DefaultShardSize,
+		staleAfter:  DefaultStaleAfter,
+		maxAge:      DefaultMaxConnectionAge,
+		minBackoff:  DefaultMinBackoff,
+		maxBackoff:  DefaultMaxBackoff,
+		every:       time.Second,
+		done:        make(chan struct{}),
+	}
+}
+
+// UseShardSize streams at most size symbols through a connection
+func (s *Supervisor) UseShardSize(size int) *Supervisor {
+	if size > 0 {
+		s.shardSize = size
+	}
+	return s
+}
+
+// UseStaleAfter opens a gap for a symbol without a price for duration
+func (s *Supervisor) UseStaleAfter(duration time.Duration) *Supervisor {
+	s.staleAfter = duration
+	return s
+}
+
+// UseMaxConnectionAge replaces connections once they are open for duration
+func (s *Supervisor) UseMaxConnectionAge(duration time.Duration) *Supervisor {
+	s.maxAge = duration
+	return s
+}
+
+// UseBackoff waits min after the first failure of a connection, doubling
+// with every failure up to max
+func (s *Supervisor) UseBackoff(min, max time.Duration) *Supervisor {
+	s.minBackoff, s.maxBackoff = min, max
+	return s
+}
+
+func (s *Supervisor) backoff(failures int) time.Duration {
+	wait := float64(s.minBackoff) * math.Pow(2, float64(failures-1))
+	if wait > float64(s.maxBackoff) {
+		return s.maxBackoff
+	}
+	return time.Duration(wait)
+}
+
+// start connects every shard once and supervises them until the
+// supervisor is closed
+func (s *Supervisor) start() {
+	s.lock.Lock()
+	if s.started || s.closed {
+		s.lock.Unlock()
+		return
+	}
+	s.started = true
+	now := utils.Now()
+	for i := 0; i < len(s.symbols); i += s.shardSize {
+		end := int(math.Min(float64(i+s.shardSize), float64(len(s.symbols))))
+		s.shards = append(s.shards, &shard{index: len(s.shards), symbols: s.symbols[i:end]})
+	}
+	for _, symbol := range s.symbols {
+		s.lastTick[symbol] = now
+	}
+	shards := s.shards
+	s.lock.Unlock()
+
+	utils.LogInfo(fmt.Sprintf("<Stream Supervisor>: streaming %d symbols through %d connections", len(s.symbols), len(shards)))
+	for _, sh := range shards {
+		s.connect(sh)
+	}
+	go s.supervise()
+}
+
+func (s *Supervisor) supervise() {
+	ticker := time.NewTicker(s.every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.check(utils.Now())
+		}
+	}
+}
+
+// connect opens a connection for the shard, the one it replaces is closed
+// once the new one is up
+func (s *Supervisor) connect(sh *shard) {
+	c := &connection{opened: utils.Now()}
+	_, stop, err := exchange.Get().StreamPrices(sh.symbols, func(event exchange.PriceEvent) {
+		s.receive(sh, c, event)
+	}, func(err error) {
+		s.fail(sh, c, reconnectFailed, err)
+	})
+	if err != nil {
+		s.fail(sh, c, reconnectFailed, err)
+		return
+	}
+	bookStop, bookErr := StreamBooks(sh.symbols)
+	if bookErr != nil {
+		utils.LogWarn(fmt.Sprintf("<Stream Supervisor>: shard %d is published without the best bid and ask, %s", sh.index, bookErr.Error()))
+	}
+
+	s.lock.Lock()
+	c.stop, c.bookStop = stop, bookStop
+	if s.closed || c.failed {
+		// closed meanwhile or failed before it was up, a retry is planned
+		c.close()
+		s.lock.Unlock()
+		return
+	}
+	previous, reason := sh.conn, sh.reason
+	sh.conn, sh.reason = c, ""
+	if previous != nil {
+		previous.close()
+	}
+	s.lock.Unlock()
+
+	if reason != "" {
+		streamReconnects.Inc(strconv.Itoa(sh.index), reason)
+	}
+}
+
+// fail gives up the connection of the shard and plans the next one, the
+// symbols of the shard are in a gap until the next connection sends prices
+func (s *Supervisor) fail(sh *shard, c *connection, reason string, err error) {
+	s.lock.Lock()
+	if s.closed || c.failed {
+		s.lock.Unlock()
+		return
+	}
+	c.failed = true
+	if sh.conn != c && sh.conn != nil {
+		// a newer connection replaced this one or this one could not
+		// replace it, the replacement is tried again after a backoff
+		if sh.reason == reconnectRotated {
+			sh.reason = ""
+			sh.retryAt = utils.Now().Add(s.backoff(sh.failures + 1))
+		}
+		c.close()
+		s.lock.Unlock()
+		return
+	}
+	now := utils.Now()
+	sh.conn = nil
+	sh.failures++
+	wait := s.backoff(sh.failures)
+	sh.retryAt = now.Add(wait)
+	if sh.reason == "" {
+		sh.reason = reason
+	}
+	down := sh.downSince.IsZero()
+	if down {
+		sh.downSince = now
+	}
+	c.close()
+	s.lock.Unlock()
+
+	utils.LogWarn(fmt.Sprintf("<Stream Supervisor>: shard %d failed, %s, connecting again in %s", sh.index, err.Error(), wait))
+	if !down {
+		return
+	}
+	openGaps(sh.symbols, GapDisconnected, now)
+	notify.Send(notify.NewEvent(notify.EventFailover, fmt.Sprintf("PRICE STREAM SHARD %d DISCONNECTED", sh.index),
+		"Error", err.Error(),
+		"Symbols", fmt.Sprintf("%d", len(sh.symbols)),
+		"Polled Until", "the socket is back",
+	))
+}
+
+func (s *Supervisor) receive(sh *shard, c *connection, event exchange.PriceEvent) {
+	now := utils.Now()
+	if event.Time > 0 {
+		tickLatency.Observe(now.Sub(time.UnixMilli(event.Time)).Seconds(), event.Symbol)
+	}
+
+	s.lock.Lock()
+	s.lastTick[event.Symbol] = now
+	var outage time.Duration
+	if !sh.downSince.IsZero() && !c.failed {
+		outage = now.Sub(sh.downSince)
+		sh.downSince, sh.failures = time.Time{}, 0
+	}
+	s.lock.Unlock()
+
+	if outage > 0 {
+		utils.LogInfo(fmt.Sprintf("<Stream Supervisor>: shard %d is back after %s", sh.index, outage.Round(time.Second)))
+	}
+	if InGap(event.Symbol) {
+		closeGaps([]string{event.Symbol}, now)
+	}
+	s.publish(SymbolPriceData{Price: event.Price, Symbol: event.Symbol}.withBook())
+}
+
+// poll publishes the prices of a shard whose connection is down
+func (s *Supervisor) poll(sh *shard) {
+	prices, err := exchange.Get().Prices(sh.symbols)
+	if err != nil {
+		utils.LogWarn(fmt.Sprintf("<Stream Supervisor>: could not poll the prices of shard %d, %s", sh.index, err.Error()))
+		return
+	}
+	for symbol, price := range prices {
+		s.publish(SymbolPriceData{Price: price, Symbol: symbol}.withBook())
+	}
+}
+
+func (s *Supervisor) publish(data SymbolPriceData) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if reader, exist := s.readers[data.Symbol]; exist {
+		go reader(s, data)
+	}
+	for _, bulkReader := range s.bulkReaders {
+		go bulkReader(s, data)
+	}
+}
+
+// check connects the shards whose backoff is over and polls the others
+// that are down, it gives up silent connections, replaces old ones and
+// opens a gap for the symbols that stopped ticking
+func (s *Supervisor) check(now time.Time) {
+	type silentShard struct {
+		shard *shard
+		conn  *connection
+		since time.Time
+	}
+	connects, polls, silent := []*shard{}, []*shard{}, []silentShard{}
+	stale := []string{}
+
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return
+	}
+	for _, sh := range s.shards {
+		if sh.conn == nil {
+			if now.Before(sh.retryAt) {
+				polls = append(polls, sh)
+			} else {
+				connects = append(connects, sh)
+			}
+			continue
+		}
+		latest, shardStale := sh.conn.opened, []string{}
+		for _, symbol := range sh.symbols {
+			last := s.lastTick[symbol]
+			if last.Before(sh.conn.opened) {
+				last = sh.conn.opened
+			}
+			if last.After(latest) {
+				latest = last
+			}
+			if now.Sub(last) >= s.staleAfter {
+				shardStale = append(shardStale, symbol)
+			}
+		}
+		if now.Sub(latest) >= s.staleAfter {
+			silent = append(silent, silentShard{shard: sh, conn: sh.conn, since: latest})
+			continue
+		}
+		stale = append(stale, shardStale...)
+		if now.Sub(sh.conn.opened) >= s.maxAge && !now.Before(sh.retryAt) {
+			sh.reason = reconnectRotated
+			connects = append(connects, sh)
+		}
+	}
+	s.lock.Unlock()
+
+	for _, down := range silent {
+		s.fail(down.shard, down.conn, reconnectSilent, fmt.Errorf("no price for %s", now.Sub(down.since).Round(time.Second)))
+	}
+	openGaps(stale, GapStale, now)
+	for _, sh := range connects {
+		s.connect(sh)
+	}
+	for _, sh := range polls {
+		s.poll(sh)
+	}
+}
+
+func (s *Supervisor) RegisterBroadcast(readerId string, reader ReaderFunc) {
+	s.lock.Lock()
+	s.bulkReaders[readerId] = reader
+	s.lock.Unlock()
+	go s.start()
+}
+
+func (s *Supervisor) UnregisterBroadcast(readerId string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, exist := s.bulkReaders[readerId]
+	delete(s.bulkReaders, readerId)
+	return exist
+}
+
+func (s *Supervisor) RegisterLegacyReader(symbol string, reader ReaderFunc) {
+	s.lock.Lock()
+	s.readers[symbol] = reader
+	s.lock.Unlock()
+	go s.start()
+}
+
+// RegisterFailOver is kept for the stream interface, a supervisor opens its
+// failed connections again instead of failing over
+func (s *Supervisor) RegisterFailOver(func(failedStream StreamInterface)) {}
+
+func (s *Supervisor) Close() bool {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return true
+	}
+	s.closed = true
+	close(s.done)
+	for _, sh := range s.shards {
+		if sh.conn != nil {
+			sh.conn.close()
+			sh.conn = nil
+		}
+	}
+	s.lock.Unlock()
+
+	closeGaps(s.symbols, utils.Now())
+	return true
+}
+
+func (s *Supervisor) CloseLog(message string) {
+	s.Close()
+	utils.LogInfo(fmt.Sprintf("<Stream Supervisor>: %s: Connections Closed", message))
+}
+
+func (s *Supervisor) IsClosed() bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.closed
+}
+
+func (s *Supervisor) State() streamState {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return streamState{
+		Readers:    s.readers,
+		BulkReader: s.bulkReaders,
+		Symbols:    s.symbols,
+		Type:       StreamTypeSupervised,
+	}
+}
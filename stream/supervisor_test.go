@@ -0,0 +1,219 @@
+package stream
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+	"trading/exchange"
+	"trading/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeConnection struct {
+	symbols  []string
+	handler  func(exchange.PriceEvent)
+	errorsTo func(error)
+	stop     chan struct{}
+}
+
+func (c *fakeConnection) stopped() bool {
+	select {
+	case <-c.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+type socketExchange struct {
+	exchange.Exchange
+	lock        sync.Mutex
+	connections []*fakeConnection
+	refuse      bool
+	polled      [][]string
+	depths      []*fakeConnection
+}
+
+func (e *socketExchange) StreamPrices(symbols []string, handler func(exchange.PriceEvent), errHandler func(error)) (done, stop chan struct{}, err error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.refuse {
+		return nil, nil, errors.New("refused")
+	}
+	c := &fakeConnection{symbols: symbols, handler: handler, errorsTo: errHandler, stop: make(chan struct{})}
+	e.connections = append(e.connections, c)
+	return make(chan struct{}), c.stop, nil
+}
+
+func (e *socketExchange) StreamBookTickers(symbols []string, handler func(exchange.BookTicker), errHandler func(error)) (done, stop chan struct{}, err error) {
+	return make(chan struct{}), make(chan struct{}), nil
+}
+
+func (e *socketExchange) StreamDepth(symbols []string, handler func(exchange.DepthEvent), errHandler func(error)) (done, stop chan struct{}, err error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	c := &fakeConnection{symbols: symbols, errorsTo: errHandler, stop: make(chan struct{})}
+	e.depths = append(e.depths, c)
+	return make(chan struct{}), c.stop, nil
+}
+
+func (e *socketExchange) depth(i int) *fakeConnection {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.depths[i]
+}
+
+func (e *socketExchange) Prices(symbols []string) (map[string]float64, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.polled = append(e.polled, symbols)
+	prices := map[string]float64{}
+	for _, symbol := range symbols {
+		prices[symbol] = 1
+	}
+	return prices, nil
+}
+
+func (e *socketExchange) connection(i int) *fakeConnection {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.connections[i]
+}
+
+func (e *socketExchange) count() int {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return len(e.connections)
+}
+
+func TestSupervisorBackoff(t *testing.T) {
+	s := NewSupervisor(nil).UseBackoff(time.Second, 10*time.Second)
+	assert.Equal(t, time.Second, s.backoff(1))
+	assert.Equal(t, 2*time.Second, s.backoff(2))
+	assert.Equal(t, 8*time.Second, s.backoff(4))
+	assert.Equal(t, 10*time.Second, s.backoff(5), "the backoff is capped")
+}
+
+func TestSupervisor(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	utils.UseClock(func() time.Time { return now })
+	defer utils.UseClock(nil)
+	fake := &socketExchange{}
+	defer exchange.Use(exchange.Use(fake))
+
+	gapEvents := []Gap{}
+	var gapLock sync.Mutex
+	defer OnGap(func(g Gap) {
+		gapLock.Lock()
+		defer gapLock.Unlock()
+		gapEvents = append(gapEvents, g)
+	})()
+
+	s := NewSupervisor([]string{"AUSDT", "BUSDT", "CUSDT"}).
+		UseShardSize(2).
+		UseStaleAfter(30*time.Second).
+		UseBackoff(time.Second, time.Minute).
+		UseMaxConnectionAge(time.Hour)
+	s.every = time.Hour
+	defer s.Close()
+	s.start()
+	received := make(chan SymbolPriceData, 10)
+	s.RegisterBroadcast("test", func(_ StreamInterface, data SymbolPriceData) {
+		received <- data
+	})
+
+	assert.Equal(t, 2, fake.count(), "symbols are sharded across connections")
+	assert.Equal(t, []string{"AUSDT", "BUSDT"}, fake.connection(0).symbols)
+	assert.Equal(t, []string{"CUSDT"}, fake.connection(1).symbols)
+	assert.Equal(t, []string{"AUSDT", "BUSDT"}, fake.depth(0).symbols, "the depth of the shard symbols keeps their books")
+	assert.Equal(t, []string{"CUSDT"}, fake.depth(1).symbols)
+
+	fake.connection(0).handler(exchange.PriceEvent{Symbol: "AUSDT", Price: 10})
+	assert.Equal(t, 10.0, (<-received).Price)
+
+	// the first shard fails, its symbols are in a gap and are polled until
+	// its backoff is over
+	first := fake.connection(0)
+	first.errorsTo(errors.New("connection reset"))
+	assert.True(t, first.stopped())
+	assert.True(t, InGap("AUSDT"))
+	assert.True(t, InGap("BUSDT"))
+	assert.False(t, InGap("CUSDT"))
+
+	now = now.Add(500 * time.Millisecond)
+	fake.connection(1).handler(exchange.PriceEvent{Symbol: "CUSDT", Price: 3})
+	<-received
+	s.check(now)
+	assert.Equal(t, 2, fake.count(), "the backoff is not over")
+	assert.Equal(t, [][]string{{"AUSDT", "BUSDT"}}, fake.polled)
+	<-received
+	<-received
+	assert.True(t, InGap("AUSDT"), "polled prices do not end a gap")
+
+	fake.refuse = true
+	now = now.Add(time.Second)
+	s.check(now)
+	assert.Equal(t, 2*time.Second, s.shards[0].retryAt.Sub(now), "the backoff doubles")
+
+	fake.refuse = false
+	now = now.Add(2 * time.Second)
+	s.check(now)
+	assert.Equal(t, 3, fake.count())
+	fake.connection(2).handler(exchange.PriceEvent{Symbol: "AUSDT", Price: 11})
+	<-received
+	assert.False(t, InGap("AUSDT"), "a price of the socket ends the gap")
+	assert.True(t, InGap("BUSDT"))
+	assert.Equal(t, 0, s.shards[0].failures, "a price resets the backoff")
+
+	// the second shard stops sending prices
+	now = now.Add(30 * time.Second)
+	fake.connection(2).handler(exchange.PriceEvent{Symbol: "AUSDT", Price: 12})
+	<-received
+	s.check(now)
+	assert.True(t, fake.connection(1).stopped(), "a silent connection is given up")
+	assert.True(t, InGap("CUSDT"))
+
+	// connections are replaced before they get too old
+	now = now.Add(time.Hour)
+	fake.connection(2).handler(exchange.PriceEvent{Symbol: "AUSDT", Price: 13})
+	fake.connection(2).handler(exchange.PriceEvent{Symbol: "BUSDT", Price: 13})
+	<-received
+	<-received
+	s.check(now)
+	assert.Equal(t, 5, fake.count(), "the old connection rotates and the silent shard connects")
+	assert.True(t, fake.connection(2).stopped())
+	assert.Equal(t, []string{"AUSDT", "BUSDT"}, fake.connection(3).symbols)
+	assert.Equal(t, []string{"CUSDT"}, fake.connection(4).symbols)
+	fake.connection(4).handler(exchange.PriceEvent{Symbol: "CUSDT", Price: 3})
+	<-received
+	assert.Empty(t, OpenGaps())
+
+	// a symbol that stops ticking on a working connection is stale
+	now = now.Add(30 * time.Second)
+	fake.connection(3).handler(exchange.PriceEvent{Symbol: "AUSDT", Price: 14})
+	fake.connection(4).handler(exchange.PriceEvent{Symbol: "CUSDT", Price: 3})
+	<-received
+	<-received
+	s.check(now)
+	assert.Equal(t, []Gap{{Symbol: "BUSDT", Reason: GapStale, Start: now}}, OpenGaps())
+	assert.Equal(t, 5, fake.count())
+
+	s.Close()
+	assert.True(t, fake.connection(3).stopped())
+	assert.True(t, fake.connection(4).stopped())
+	assert.Eventually(t, fake.depth(4).stopped, time.Second, time.Millisecond, "closing stops the depth streams")
+	assert.Empty(t, OpenGaps(), "closing ends every gap")
+
+	gapLock.Lock()
+	defer gapLock.Unlock()
+	opened := 0
+	for _, g := range gapEvents {
+		if g.IsOpen() {
+			opened++
+		}
+	}
+	assert.Equal(t, 4, opened)
+	assert.Len(t, gapEvents, 8, "every gap that opened ended")
+}
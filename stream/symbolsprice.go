@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"sync"
 	"time"
-	"trading/binance"
+	"trading/exchange"
 	"trading/utils"
 )
 
@@ -27,7 +27,7 @@ func readApiDataDispatch(s *Stream) {
 			return
 		}
 
-		symbols, err := binance.GetSymbolPrices(s.symbols)
+		symbols, err := exchange.Get().Prices(s.symbols)
 
 		if err != nil && s.failHandler != nil {
 			s.CloseLog(fmt.Sprintf("<API Stream>: An Error happened will close and fail over: %s", err.Error()))
@@ -41,7 +41,7 @@ func readApiDataDispatch(s *Stream) {
 				continue
 			}
 			func(reader func(StreamInterface, SymbolPriceData), readerId string) {
-				data := SymbolPriceData{Price, readerId}
+				data := SymbolPriceData{Price: Price, Symbol: readerId}
 				reader(s, data)
 			}(reader, readerId)
 
@@ -49,7 +49,7 @@ func readApiDataDispatch(s *Stream) {
 		go func(symbols map[string]float64) {
 			for readerId, Price := range symbols {
 				for _, bulkReader := range s.bulkReaders {
-					data := SymbolPriceData{Price, readerId}
+					data := SymbolPriceData{Price: Price, Symbol: readerId}.withBook()
 					go bulkReader(s, data)
 				}
 			}
@@ -0,0 +1,224 @@
+// Package allocator shares the capital of the account between the configs
+// that trade it. A buy reserves its part of the quote asset before it is
+// executed and keeps it until the config sells, so configs sharing an asset
+// do not each assume they can spend the whole free balance
+package allocator
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"trading/names"
+	"trading/user"
+	"trading/utils"
+)
+
+// Reservation is the quote asset a config may spend on its buy, it is
+// filled once the buy executed and released when the config sells
+type Reservation struct {
+	ConfigId string       `json:"configId"`
+	Symbol   names.Symbol `json:"symbol"`
+	Asset    string       `json:"asset"`
+	Amount   float64      `json:"amount"`
+	Filled   bool         `json:"filled"`
+}
+
+type Allocator struct {
+	rule         Rule
+	minimum      float64
+	balance      func(asset string) float64
+	lock         sync.Mutex
+	reservations map[string]*Reservation
+	sources      map[string]func() []names.TradeConfig
+}
+
+func New(rule Rule) *Allocator {
+	return &Allocator{
+		rule: rule,
+		balance: func(asset string) float64 {
+			return user.CreateUser().GetAccount().GetBalance(asset).Free
+		},
+		reservations: map[string]*Reservation{},
+		sources:      map[string]func() []names.TradeConfig{},
+	}
+}
+
+func (a *Allocator) Rule() Rule {
+	return a.rule
+}
+
+// UseMinimum rejects reservations smaller than amount of the quote asset
+func (a *Allocator) UseMinimum(amount float64) *Allocator {
+	a.minimum = amount
+	return a
+}
+
+// UseBalance reads the free balance of an asset with balance instead of the account
+func (a *Allocator) UseBalance(balance func(asset string) float64) *Allocator {
+	a.balance = balance
+	return a
+}
+
+// Track shares the capital with the configs returned by configs, id
+// identifies them so that they can be untracked
+func (a *Allocator) Track(id string, configs func() []names.TradeConfig) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.sources[id] = configs
+}
+
+func (a *Allocator) Untrack(id string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	delete(a.sources, id)
+}
+
+func quoteAsset(config names.TradeConfig) string {
+	return config.Symbol.ParseTradingPair().Quote
+}
+
+// peers are the tracked configs buying with the quote asset of config, config included
+func (a *Allocator) peers(config names.TradeConfig) []names.TradeConfig {
+	asset := quoteAsset(config)
+	seen := map[string]bool{config.Id: true}
+	peers := []names.TradeConfig{config}
+	ids := make([]string, 0, len(a.sources))
+	for id := range a.sources {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		for _, peer := range a.sources[id]() {
+			if seen[peer.Id] || quoteAsset(peer) != asset {
+				continue
+			}
+			seen[peer.Id] = true
+			peers = append(peers, peer)
+		}
+	}
+	return peers
+}
+
+// funds of asset, the capital counts what filled reservations spent and
+// the available funds leave out what other reservations may still spend
+func (a *Allocator) funds(asset string) (capital, available float64) {
+	free := a.balance(asset)
+	capital, available = free, free
+	for _, r := range a.reservations {
+		if r.Asset != asset {
+			continue
+		}
+		if r.Filled {
+			capital += r.Amount
+		} else {
+			available -= r.Amount
+		}
+	}
+	return capital, available
+}
+
+// amount config may reserve at price, a config with a fixed quantity needs
+// all of it and the others take their share of the capital
+func (a *Allocator) amount(config names.TradeConfig, price float64) (float64, error) {
+	asset := quoteAsset(config)
+	capital, available := a.funds(asset)
+	if config.Buy.Quantity > 0 {
+		amount := config.Buy.Quantity * price
+		if amount > available {
+			return 0, fmt.Errorf("%s needs %f %s, only %f is not committed", config.Id, amount, asset, available)
+		}
+		return amount, nil
+	}
+
+	share := a.rule.Share(config, a.peers(config))
+	amount := capital * share
+	if amount > available {
+		amount = available
+	}
+	if amount <= 0 || amount < a.minimum {
+		return 0, fmt.Errorf("%s share of %f %s is committed, %f is left", config.Id, capital*share, asset, available)
+	}
+	return amount, nil
+}
+
+// Quote is the amount of the quote asset config would reserve at price, zero when it would be rejected
+func (a *Allocator) Quote(config names.TradeConfig, price float64) float64 {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if r, exist := a.reservations[config.Id]; exist {
+		return r.Amount
+	}
+	amount, err := a.amount(config, price)
+	if err != nil {
+		return 0
+	}
+	return amount
+}
+
+// Reserve sets aside the quote asset the buy of config spends at price. A
+// config holds a single reservation until it is released
+func (a *Allocator) Reserve(config names.TradeConfig, price float64) (Reservation, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if r, exist := a.reservations[config.Id]; exist {
+		return Reservation{}, fmt.Errorf("%s already holds %f %s", config.Id, r.Amount, r.Asset)
+	}
+	amount, err := a.amount(config, price)
+	if err != nil {
+		return Reservation{}, err
+	}
+	r := &Reservation{ConfigId: config.Id, Symbol: config.Symbol, Asset: quoteAsset(config), Amount: amount}
+	a.reservations[config.Id] = r
+	utils.LogInfo(fmt.Sprintf("<Allocator>: reserved %f %s for %s %s by %s", amount, r.Asset, config.Symbol, config.Id, a.rule.Name()))
+	return *r, nil
+}
+
+// Fill keeps the reservation of the config until it sells
+func (a *Allocator) Fill(configId string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if r, exist := a.reservations[configId]; exist {
+		r.Filled = true
+	}
+}
+
+// Release returns the reservation of the config to the capital
+func (a *Allocator) Release(configId string) bool {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	_, exist := a.reservations[configId]
+	delete(a.reservations, configId)
+	return exist
+}
+
+func (a *Allocator) Reservations() []Reservation {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	list := make([]Reservation, 0, len(a.reservations))
+	for _, r := range a.reservations {
+		list = append(list, *r)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ConfigId < list[j].ConfigId })
+	return list
+}
+
+var (
+	current *Allocator
+	lock    sync.RWMutex
+)
+
+// Get returns the allocator shared by every pool, nil when capital is not allocated
+func Get() *Allocator {
+	lock.RLock()
+	defer lock.RUnlock()
+	return current
+}
+
+// Use shares a between every pool and returns the allocator it replaced
+func Use(a *Allocator) *Allocator {
+	lock.Lock()
+	defer lock.Unlock()
+	previous := current
+	current = a
+	return previous
+}
@@ -0,0 +1,136 @@
+package allocator
+
+import (
+	"testing"
+	"trading/exchange"
+	"trading/names"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func useSymbols() {
+	info := exchange.ExchangeInfo{}
+	for _, s := range []string{"BTC", "ETH", "DOGE"} {
+		info.Symbols = append(info.Symbols, exchange.SymbolInfo{Symbol: s + "USDT", BaseAsset: s, QuoteAsset: "USDT"})
+	}
+	info.Symbols = append(info.Symbols, exchange.SymbolInfo{Symbol: "ETHBTC", BaseAsset: "ETH", QuoteAsset: "BTC"})
+	names.UseExchangeInfo(info)
+}
+
+func config(id, symbol string) names.TradeConfig {
+	return names.TradeConfig{Id: id, Symbol: names.Symbol(symbol), Side: names.TradeSideBuy, Buy: names.SideConfig{Quantity: -1}}
+}
+
+// an allocator of free USDT and BTC tracking configs
+func newAllocator(rule Rule, free map[string]float64, configs ...names.TradeConfig) *Allocator {
+	a := New(rule).UseBalance(func(asset string) float64 { return free[asset] })
+	a.Track("pool", func() []names.TradeConfig { return configs })
+	return a
+}
+
+func TestEqualWeight(t *testing.T) {
+	useSymbols()
+	free := map[string]float64{"USDT": 900, "BTC": 1}
+	btc, eth, doge := config("btc", "BTCUSDT"), config("eth", "ETHUSDT"), config("doge", "DOGEUSDT")
+	a := newAllocator(EqualWeight(), free, btc, eth, doge, config("ethbtc", "ETHBTC"))
+
+	assert.Equal(t, 300.0, a.Quote(btc, 100), "configs of other quote assets do not share USDT")
+	r, err := a.Reserve(btc, 100)
+	assert.Nil(t, err)
+	assert.Equal(t, "USDT", r.Asset)
+	assert.Equal(t, 300.0, r.Amount)
+	_, err = a.Reserve(btc, 100)
+	assert.NotNil(t, err, "a config holds a single reservation")
+
+	// the buy spent its reservation, the capital still counts it
+	a.Fill("btc")
+	free["USDT"] = 600
+	assert.Equal(t, 300.0, a.Quote(eth, 10))
+
+	_, err = a.Reserve(eth, 10)
+	assert.Nil(t, err)
+	_, err = a.Reserve(doge, 1)
+	assert.Nil(t, err)
+	free["USDT"] = 0
+	_, err = a.Reserve(config("late", "BTCUSDT"), 100)
+	assert.NotNil(t, err, "every fund is committed")
+
+	assert.True(t, a.Release("btc"))
+	assert.False(t, a.Release("btc"))
+	assert.Len(t, a.Reservations(), 2)
+}
+
+func TestUntrack(t *testing.T) {
+	useSymbols()
+	btc, eth := config("btc", "BTCUSDT"), config("eth", "ETHUSDT")
+	a := newAllocator(EqualWeight(), map[string]float64{"USDT": 100}, btc, eth)
+	assert.Equal(t, 50.0, a.Quote(btc, 1))
+	a.Untrack("pool")
+	assert.Equal(t, 100.0, a.Quote(btc, 1))
+}
+
+func TestFixedQuantity(t *testing.T) {
+	useSymbols()
+	free := map[string]float64{"USDT": 100}
+	fixed := config("fixed", "BTCUSDT")
+	fixed.Buy.Quantity = 0.6
+	a := newAllocator(FixedFraction(10), free, fixed)
+
+	r, err := a.Reserve(fixed, 150)
+	assert.Nil(t, err)
+	assert.Equal(t, 90.0, r.Amount, "a fixed quantity needs all of it")
+	_, err = a.Reserve(config("other", "ETHUSDT"), 1)
+	assert.Nil(t, err, "10% of the capital is still free")
+	_, err = a.Reserve(config("third", "DOGEUSDT"), 1)
+	assert.NotNil(t, err)
+
+	bigger := config("bigger", "ETHUSDT")
+	bigger.Buy.Quantity = 1
+	a.Release("other")
+	_, err = a.Reserve(bigger, 20)
+	assert.NotNil(t, err, "a fixed quantity is not cut down")
+}
+
+func TestMinimum(t *testing.T) {
+	useSymbols()
+	btc := config("btc", "BTCUSDT")
+	a := newAllocator(FixedFraction(5), map[string]float64{"USDT": 100}, btc).UseMinimum(10)
+	assert.Equal(t, 0.0, a.Quote(btc, 1))
+	_, err := a.Reserve(btc, 1)
+	assert.NotNil(t, err)
+}
+
+func TestVolatilityWeighted(t *testing.T) {
+	volatility := map[names.Symbol]float64{"BTCUSDT": 2, "ETHUSDT": 4}
+	rule := VolatilityWeighted(func(symbol names.Symbol) float64 { return volatility[symbol] })
+	btc, eth, doge := config("btc", "BTCUSDT"), config("eth", "ETHUSDT"), config("doge", "DOGEUSDT")
+	peers := []names.TradeConfig{btc, eth}
+
+	assert.InDelta(t, 2/3.0, rule.Share(btc, peers), 1e-9, "the calmer symbol gets twice the part")
+	assert.InDelta(t, 1/3.0, rule.Share(eth, peers), 1e-9)
+
+	peers = append(peers, doge)
+	// doge is weighted as the average of 1/2 and 1/4
+	assert.InDelta(t, 0.375/1.125, rule.Share(doge, peers), 1e-9)
+}
+
+func TestKelly(t *testing.T) {
+	stats := map[string]TradeStats{
+		"edge": {Trades: 20, WinRate: 0.6, Payoff: 1},
+		"none": {Trades: 20, WinRate: 0.4, Payoff: 1},
+		"new":  {Trades: 3, WinRate: 1, Payoff: 2},
+	}
+	rule := Kelly(0.5, 15, func(id string) TradeStats { return stats[id] })
+	peers := []names.TradeConfig{config("edge", "BTCUSDT"), config("none", "BTCUSDT"), config("new", "BTCUSDT")}
+
+	assert.InDelta(t, 0.1, rule.Share(peers[0], peers), 1e-9, "half of a 20% kelly fraction")
+	assert.Equal(t, 0.0, rule.Share(peers[1], peers), "no edge, no capital")
+	assert.Equal(t, 0.15, rule.Share(peers[2], peers), "too few trades take an equal part up to max")
+}
+
+func TestTradeStats(t *testing.T) {
+	stats := newTradeStats([]float64{0.1, 0.3}, []float64{0.1})
+	assert.Equal(t, 3, stats.Trades)
+	assert.InDelta(t, 2/3.0, stats.WinRate, 1e-9)
+	assert.InDelta(t, 2.0, stats.Payoff, 1e-9)
+}
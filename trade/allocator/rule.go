@@ -0,0 +1,202 @@
+package allocator
+
+import (
+	"math"
+	"trading/journal"
+	"trading/names"
+	"trading/trade/graph"
+)
+
+// Rule decides the part of the capital of an asset, from zero to one, a
+// config may use. peers are the configs sharing the asset, config included
+type Rule interface {
+	Name() string
+	Share(config names.TradeConfig, peers []names.TradeConfig) float64
+}
+
+type equalWeight struct{}
+
+// EqualWeight gives every config sharing the asset the same part
+func EqualWeight() Rule {
+	return equalWeight{}
+}
+
+func (equalWeight) Name() string {
+	return "equal"
+}
+
+func (equalWeight) Share(config names.TradeConfig, peers []names.TradeConfig) float64 {
+	return 1 / float64(len(peers))
+}
+
+type fixedFraction struct {
+	percent float64
+}
+
+// FixedFraction gives every config percent of the capital
+func FixedFraction(percent float64) Rule {
+	return fixedFraction{percent: percent}
+}
+
+func (fixedFraction) Name() string {
+	return "fixed"
+}
+
+func (f fixedFraction) Share(config names.TradeConfig, peers []names.TradeConfig) float64 {
+	return f.percent / 100
+}
+
+// VolatilityFunc is the volatility of symbol in percent, zero when unknown
+type VolatilityFunc func(symbol names.Symbol) float64
+
+// GraphVolatility is the average true range of the hourly candles of the last day
+func GraphVolatility(symbol names.Symbol) float64 {
+	set := graph.NewBinanceGraph(symbol.String(), "1h", 24).Indicators()
+	if !set.ATR.Ready() {
+		return 0
+	}
+	return set.ATR.Percent()
+}
+
+type volatilityWeighted struct {
+	volatility VolatilityFunc
+}
+
+// VolatilityWeighted gives calm symbols a bigger part than volatile ones so
+// that every config risks about the same. Configs of unknown volatility
+// are weighted as the average of the others
+func VolatilityWeighted(volatility VolatilityFunc) Rule {
+	if volatility == nil {
+		volatility = GraphVolatility
+	}
+	return volatilityWeighted{volatility: volatility}
+}
+
+func (volatilityWeighted) Name() string {
+	return "volatility"
+}
+
+func (v volatilityWeighted) Share(config names.TradeConfig, peers []names.TradeConfig) float64 {
+	weights := map[string]float64{}
+	var known, sum float64
+	for _, peer := range peers {
+		if volatility := v.volatility(peer.Symbol); volatility > 0 {
+			weights[peer.Id] = 1 / volatility
+			known++
+			sum += 1 / volatility
+		}
+	}
+	if known == 0 {
+		return 1 / float64(len(peers))
+	}
+	average := sum / known
+	total := sum + average*(float64(len(peers))-known)
+	weight, exist := weights[config.Id]
+	if !exist {
+		weight = average
+	}
+	return weight / total
+}
+
+// TradeStats are the closed trades of a config, Payoff is the average win
+// divided by the average loss
+type TradeStats struct {
+	Trades  int
+	WinRate float64
+	Payoff  float64
+}
+
+type StatsFunc func(configId string) TradeStats
+
+// JournalStats pairs every sell of the config in the journal with the buy before it
+func JournalStats(configId string) TradeStats {
+	records := journal.Default().ByConfig(configId)
+	var buy float64
+	var wins, losses []float64
+	for _, r := range records {
+		if r.Side.IsBuy() {
+			buy = r.FillPrice
+			continue
+		}
+		if buy <= 0 {
+			continue
+		}
+		change := (r.FillPrice - buy) / buy
+		if change > 0 {
+			wins = append(wins, change)
+		} else {
+			losses = append(losses, -change)
+		}
+		buy = 0
+	}
+	return newTradeStats(wins, losses)
+}
+
+func average(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func newTradeStats(wins, losses []float64) TradeStats {
+	stats := TradeStats{Trades: len(wins) + len(losses)}
+	if stats.Trades == 0 {
+		return stats
+	}
+	stats.WinRate = float64(len(wins)) / float64(stats.Trades)
+	switch {
+	case len(wins) == 0:
+		stats.Payoff = 0
+	case len(losses) == 0 || average(losses) == 0:
+		stats.Payoff = math.Inf(1)
+	default:
+		stats.Payoff = average(wins) / average(losses)
+	}
+	return stats
+}
+
+// trades a config needs before the kelly rule trusts its history
+const kellyMinTrades = 10
+
+type kelly struct {
+	multiplier float64
+	max        float64
+	stats      StatsFunc
+}
+
+// Kelly sizes a config by the edge of its closed trades, multiplier scales
+// the kelly fraction down (0.5 for half kelly) and max in percent caps it,
+// zero for either leaves it as is. Configs without enough trades get an equal part
+func Kelly(multiplier, max float64, stats StatsFunc) Rule {
+	if stats == nil {
+		stats = JournalStats
+	}
+	if multiplier <= 0 {
+		multiplier = 1
+	}
+	if max <= 0 {
+		max = 100
+	}
+	return kelly{multiplier: multiplier, max: max, stats: stats}
+}
+
+func (kelly) Name() string {
+	return "kelly"
+}
+
+func (k kelly) Share(config names.TradeConfig, peers []names.TradeConfig) float64 {
+	stats := k.stats(config.Id)
+	if stats.Trades < kellyMinTrades {
+		return math.Min(1/float64(len(peers)), k.max/100)
+	}
+	fraction := stats.WinRate
+	if !math.IsInf(stats.Payoff, 1) {
+		if stats.Payoff == 0 {
+			return 0
+		}
+		fraction = stats.WinRate - (1-stats.WinRate)/stats.Payoff
+	}
+	return math.Max(0, math.Min(fraction*k.multiplier, k.max/100))
+}
@@ -0,0 +1,92 @@
+package arbitrage
+
+import (
+	"testing"
+	"time"
+	"trading/exchange"
+	"trading/names"
+	"trading/stream"
+	"trading/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func symbolInfo(symbol, base, quote, stepSize string) exchange.SymbolInfo {
+	return exchange.SymbolInfo{
+		Symbol: symbol, BaseAsset: base, QuoteAsset: quote,
+		Filters: []map[string]interface{}{{"filterType": "LOT_SIZE", "stepSize": stepSize}},
+	}
+}
+
+func testGraph() *Graph {
+	symbols := []exchange.SymbolInfo{
+		symbolInfo("BTCUSDT", "BTC", "USDT", "0.00001000"),
+		symbolInfo("ETHUSDT", "ETH", "USDT", "0.00010000"),
+		symbolInfo("ETHBTC", "ETH", "BTC", "0.00010000"),
+		symbolInfo("DOGEUSDT", "DOGE", "USDT", "1.00000000"),
+	}
+	names.UseExchangeInfo(exchange.ExchangeInfo{Symbols: symbols})
+	return NewGraph(names.NewSymbolInfo(symbols), "USDT")
+}
+
+var testFees = map[string]float64{"BTCUSDT": 0.001, "ETHUSDT": 0.001, "ETHBTC": 0.001}
+
+func TestGraph(t *testing.T) {
+	g := testGraph()
+	cycles := []string{}
+	for _, cycle := range g.Cycles() {
+		cycles = append(cycles, cycle.String())
+	}
+	assert.ElementsMatch(t, []string{"USDT→BTC→ETH→USDT", "USDT→ETH→BTC→USDT"}, cycles)
+	assert.Equal(t, []string{"BTCUSDT", "ETHBTC", "ETHUSDT"}, g.Symbols(), "a pair without a cycle is not watched")
+	assert.Len(t, g.CyclesOf("ETHBTC"), 2)
+	assert.Empty(t, g.CyclesOf("DOGEUSDT"))
+
+	forward := g.CyclesOf("BTCUSDT")[0]
+	if forward.Legs[0].Side.IsSell() {
+		forward = g.CyclesOf("BTCUSDT")[1]
+	}
+	assert.Equal(t, names.TradeSideBuy, forward.Legs[0].Side, "USDT buys BTC")
+	assert.Equal(t, names.TradeSideBuy, forward.Legs[1].Side, "BTC buys ETH")
+	assert.Equal(t, names.TradeSideSell, forward.Legs[2].Side, "ETH sells for USDT")
+}
+
+func TestDetector(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	utils.UseClock(func() time.Time { return now })
+	defer utils.UseClock(nil)
+
+	reported := []Opportunity{}
+	d := NewDetector(testGraph()).
+		UseFees(testFees).
+		UseMinProfit(0.1).
+		UseAmount(100).
+		OnOpportunity(func(o Opportunity) { reported = append(reported, o) })
+
+	assert.Empty(t, d.Update(stream.SymbolPriceData{Symbol: "BTCUSDT", Price: 20000, BidPrice: 19990, AskPrice: 20000}))
+	assert.Empty(t, d.Update(stream.SymbolPriceData{Symbol: "ETHBTC", Price: 0.05, BidPrice: 0.0499, AskPrice: 0.05}))
+	assert.Empty(t, d.Update(stream.SymbolPriceData{Symbol: "ETHUSDT", Price: 1000, BidPrice: 999, AskPrice: 1001}), "the fees are not beaten")
+
+	found := d.Update(stream.SymbolPriceData{Symbol: "ETHUSDT", Price: 1010, BidPrice: 1010, AskPrice: 1011})
+	assert.Len(t, found, 1)
+	assert.Equal(t, "USDT→BTC→ETH→USDT", found[0].Cycle.String())
+	// 100 USDT buys 0.005 BTC that buys 0.1 ETH that sells for 101 USDT, three fees of 0.1%
+	assert.InDelta(t, (1.01*0.999*0.999*0.999-1)*100, found[0].Profit, 1e-9)
+	assert.Equal(t, found, reported)
+
+	assert.Empty(t, d.Update(stream.SymbolPriceData{Symbol: "ETHUSDT", Price: 1010, BidPrice: 1010, AskPrice: 1011}), "the cycle was reported within the cooldown")
+	now = now.Add(time.Minute)
+	assert.Len(t, d.Update(stream.SymbolPriceData{Symbol: "ETHUSDT", Price: 1010, BidPrice: 1010, AskPrice: 1011}), 1)
+
+	orders, err := d.orders(found[0], 100)
+	assert.Nil(t, err)
+	assert.Equal(t, 0.005, orders[0].config.Buy.Quantity)
+	assert.Equal(t, 20000.0, orders[0].price)
+	assert.Equal(t, 0.0999, orders[1].config.Buy.Quantity, "the 0.004995 BTC left after the fee of the first leg")
+	assert.Equal(t, names.TradeSideSell, orders[2].config.Side)
+	assert.Equal(t, 0.0998, orders[2].config.Sell.Quantity, "the 0.0998001 ETH left down to the lot size")
+	assert.InDelta(t, 0.0998*1010*0.999, orders[2].receives, 1e-9)
+
+	_, err = d.orders(found[0], 0.01)
+	assert.NotNil(t, err, "an amount below the lot size can not trade")
+}
@@ -0,0 +1,321 @@
+package arbitrage
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"trading/metrics"
+	"trading/names"
+	"trading/stream"
+	"trading/trade/executor"
+	"trading/utils"
+
+	"github.com/google/uuid"
+)
+
+var opportunities = metrics.NewCounter("trading_arbitrage_opportunities_total", "triangular cycles that beat their fees by result, logged, executed or failed", "start", "result")
+
+// Opportunity is a cycle whose legs trade back to more of its start asset
+// than they started with once the fees are paid
+type Opportunity struct {
+	Cycle Cycle `json:"cycle"`
+	// rate of every leg, what a unit of its From asset converts to
+	Rates [3]float64 `json:"rates"`
+	// percent gained around the cycle after fees
+	Profit float64   `json:"profit"`
+	Time   time.Time `json:"time"`
+}
+
+func (o Opportunity) String() string {
+	rates := []string{}
+	for i, leg := range o.Cycle.Legs {
+		rates = append(rates, fmt.Sprintf("%s %s %g", leg.Side, leg.Symbol, o.Rates[i]))
+	}
+	return fmt.Sprintf("%s %.4f%% (%s)", o.Cycle, o.Profit, strings.Join(rates, ", "))
+}
+
+// quote is the last price and best bid and ask of a symbol
+type quote struct {
+	price float64
+	bid   float64
+	ask   float64
+}
+
+// Detector follows the prices of every leg of the graph and reports the
+// cycles that beat their fees, it only logs them unless it executes
+type Detector struct {
+	id        string
+	graph     *Graph
+	fees      map[string]float64
+	quotes    map[names.Symbol]quote
+	minProfit float64
+	// amount of the start asset a cycle trades
+	amount      float64
+	execute     bool
+	maxSlippage float64
+	cooldown    time.Duration
+	seen        map[string]time.Time
+	handlers    []func(Opportunity)
+	executing   int32
+	streamer    stream.StreamInterface
+	lock        sync.Mutex
+}
+
+func NewDetector(graph *Graph) *Detector {
+	return &Detector{
+		id:       "arbitrage-" + uuid.New().String(),
+		graph:    graph,
+		quotes:   map[names.Symbol]quote{},
+		cooldown: time.Minute,
+		seen:     map[string]time.Time{},
+	}
+}
+
+// UseMinProfit sets the percent a cycle must gain after fees
+func (d *Detector) UseMinProfit(percent float64) *Detector {
+	d.minProfit = percent
+	return d
+}
+
+// UseAmount sets the amount of the start asset a cycle trades
+func (d *Detector) UseAmount(amount float64) *Detector {
+	d.amount = amount
+	return d
+}
+
+// UseExecute trades the three legs of the opportunities, they are only
+// logged when execute is false
+func (d *Detector) UseExecute(execute bool) *Detector {
+	d.execute = execute
+	return d
+}
+
+// UseMaxSlippage sets the percent a leg may fill away from its quote
+func (d *Detector) UseMaxSlippage(percent float64) *Detector {
+	d.maxSlippage = percent
+	return d
+}
+
+// UseCooldown sets how long the same cycle is not reported again
+func (d *Detector) UseCooldown(cooldown time.Duration) *Detector {
+	d.cooldown = cooldown
+	return d
+}
+
+// UseFees sets the taker fee rate of the symbols, they are loaded from the
+// exchange when the detector starts otherwise
+func (d *Detector) UseFees(fees map[string]float64) *Detector {
+	d.fees = fees
+	return d
+}
+
+// OnOpportunity calls handler with every opportunity the detector reports
+func (d *Detector) OnOpportunity(handler func(Opportunity)) *Detector {
+	d.handlers = append(d.handlers, handler)
+	return d
+}
+
+// Start listens to the prices of every leg of the graph
+func (d *Detector) Start() *Detector {
+	if d.fees == nil {
+		d.fees = map[string]float64{}
+		for symbol, fee := range names.GetTradeFees(d.graph.Symbols()) {
+			d.fees[symbol] = fee.TakerCommission
+		}
+	}
+	mode := "dry run"
+	if d.execute {
+		mode = fmt.Sprintf("trading %f of the start asset", d.amount)
+	}
+	utils.LogInfo(fmt.Sprintf("<Arbitrage>: watching %d cycles over %d symbols, %s", len(d.graph.Cycles()), len(d.graph.Symbols()), mode))
+
+	d.streamer = stream.GetStreamer()
+	d.streamer.RegisterBroadcast(d.id, func(_ stream.StreamInterface, data stream.SymbolPriceData) {
+		d.Update(data)
+	})
+	return d
+}
+
+func (d *Detector) Stop() {
+	if d.streamer != nil {
+		d.streamer.UnregisterBroadcast(d.id)
+	}
+}
+
+// rate is what a unit of the From asset of leg converts to after its fee,
+// zero when the symbol has no price yet
+func (d *Detector) rate(leg Leg) float64 {
+	q, known := d.quotes[leg.Symbol]
+	if !known {
+		return 0
+	}
+	fee := 1 - d.fees[leg.Symbol.String()]
+	if leg.Side.IsBuy() {
+		ask := q.ask
+		if ask <= 0 {
+			ask = q.price
+		}
+		if ask <= 0 {
+			return 0
+		}
+		return fee / ask
+	}
+	bid := q.bid
+	if bid <= 0 {
+		bid = q.price
+	}
+	return bid * fee
+}
+
+// evaluate is the opportunity of cycle, false when a leg has no price or the
+// cycle does not beat its fees
+func (d *Detector) evaluate(cycle Cycle) (Opportunity, bool) {
+	opportunity := Opportunity{Cycle: cycle, Time: utils.Now()}
+	product := 1.0
+	for i, leg := range cycle.Legs {
+		rate := d.rate(leg)
+		if rate <= 0 || stream.InGap(leg.Symbol.String()) {
+			return opportunity, false
+		}
+		opportunity.Rates[i] = rate
+		product *= rate
+	}
+	opportunity.Profit = (product - 1) * 100
+	return opportunity, opportunity.Profit > d.minProfit
+}
+
+// Update keeps the price of data and returns the opportunities of the
+// cycles through its symbol that were not reported within the cooldown
+func (d *Detector) Update(data stream.SymbolPriceData) []Opportunity {
+	symbol := names.Symbol(data.Symbol)
+	cycles := d.graph.CyclesOf(symbol)
+	if len(cycles) == 0 {
+		return nil
+	}
+
+	d.lock.Lock()
+	d.quotes[symbol] = quote{price: data.Price, bid: data.BidPrice, ask: data.AskPrice}
+	found := []Opportunity{}
+	for _, cycle := range cycles {
+		opportunity, ok := d.evaluate(cycle)
+		if !ok {
+			continue
+		}
+		key := cycle.String()
+		if last, exist := d.seen[key]; exist && opportunity.Time.Sub(last) < d.cooldown {
+			continue
+		}
+		d.seen[key] = opportunity.Time
+		found = append(found, opportunity)
+	}
+	d.lock.Unlock()
+
+	for _, opportunity := range found {
+		d.report(opportunity)
+	}
+	return found
+}
+
+func (d *Detector) report(opportunity Opportunity) {
+	for _, handler := range d.handlers {
+		handler(opportunity)
+	}
+	if !d.execute {
+		utils.LogInfo("<Arbitrage>: " + opportunity.String())
+		opportunities.Inc(opportunity.Cycle.Start(), "logged")
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&d.executing, 0, 1) {
+		utils.LogInfo(fmt.Sprintf("<Arbitrage>: %s skipped, another cycle is trading", opportunity.Cycle))
+		return
+	}
+	go func() {
+		defer atomic.StoreInt32(&d.executing, 0)
+		if d.Execute(opportunity) {
+			opportunities.Inc(opportunity.Cycle.Start(), "executed")
+		} else {
+			opportunities.Inc(opportunity.Cycle.Start(), "failed")
+		}
+	}()
+}
+
+// order is a leg sized to trade
+type order struct {
+	leg      Leg
+	config   names.TradeConfig
+	price    float64
+	receives float64
+}
+
+// orders sizes the legs of opportunity for amount of its start asset, every
+// leg trades what the last one is expected to receive
+func (d *Detector) orders(opportunity Opportunity, amount float64) ([]order, error) {
+	orders := []order{}
+	for i, leg := range opportunity.Cycle.Legs {
+		config := names.TradeConfig{
+			Id:     "arbitrage_" + strings.Join(opportunity.Cycle.Assets(), "_"),
+			Symbol: leg.Symbol,
+			Side:   leg.Side,
+		}
+		side := names.SideConfig{LimitType: names.RateFixed, MaxSlippage: d.maxSlippage}
+		var price, receives float64
+		if leg.Side.IsBuy() {
+			price = 1 / opportunity.Rates[i] * (1 - d.fees[leg.Symbol.String()])
+			side.Quantity = leg.Symbol.Quantity(amount / price)
+			receives = side.Quantity * (1 - d.fees[leg.Symbol.String()])
+			config.Buy = side
+		} else {
+			price = opportunity.Rates[i] / (1 - d.fees[leg.Symbol.String()])
+			side.Quantity = leg.Symbol.Quantity(amount)
+			receives = side.Quantity * opportunity.Rates[i]
+			config.Sell = side
+		}
+		if side.Quantity <= 0 {
+			return nil, fmt.Errorf("%f %s is below the lot size of %s", amount, leg.From, leg.Symbol)
+		}
+		if d.maxSlippage > 0 {
+			fill, known := stream.EstimateFill(leg.Symbol.String(), leg.Side, side.Quantity)
+			if known && (!fill.Complete() || fill.Slippage(price) > d.maxSlippage) {
+				return nil, fmt.Errorf("%s %s %f would fill %.3f%% from %g", leg.Side, leg.Symbol, side.Quantity, fill.Slippage(price), price)
+			}
+		}
+		orders = append(orders, order{leg: leg, config: config, price: price, receives: receives})
+		amount = receives
+	}
+	return orders, nil
+}
+
+// Execute trades the legs of opportunity one after the other as soon as the
+// last one filled, every leg is sized and checked against the order books
+// before the first is sent. A failed leg stops the cycle and what was
+// received by the last leg is left in its asset
+func (d *Detector) Execute(opportunity Opportunity) bool {
+	orders, err := d.orders(opportunity, d.amount)
+	if err != nil {
+		utils.LogWarn(fmt.Sprintf("<Arbitrage>: %s skipped, %s", opportunity.Cycle, err.Error()))
+		return false
+	}
+	for i, o := range orders {
+		var filled bool
+		if o.leg.Side.IsBuy() {
+			filled = executor.BuyExecutor(o.config, o.price, o.price).Execute()
+		} else {
+			filled = executor.SellExecutor(o.config, o.price, o.price).Execute()
+		}
+		if !filled {
+			if i > 0 {
+				utils.LogWarn(fmt.Sprintf("<Arbitrage>: %s stopped at leg %d, %f %s is left from %s",
+					opportunity.Cycle, i+1, orders[i-1].receives, o.leg.From, orders[i-1].leg.Symbol))
+			} else {
+				utils.LogWarn(fmt.Sprintf("<Arbitrage>: %s first leg %s was not filled", opportunity.Cycle, o.leg.Symbol))
+			}
+			return false
+		}
+	}
+	last := orders[len(orders)-1]
+	utils.LogInfo(fmt.Sprintf("<Arbitrage>: %s traded %f %s into %f, expected %.4f%%",
+		opportunity.Cycle, d.amount, opportunity.Cycle.Start(), last.receives, opportunity.Profit))
+	return true
+}
@@ -0,0 +1,116 @@
+// Package arbitrage finds triangular cycles across the spot pairs, like
+// USDT→BTC→ETH→USDT, whose rates multiply to more than the fees of their
+// three legs and optionally trades them
+package arbitrage
+
+import (
+	"sort"
+	"strings"
+	"trading/names"
+)
+
+// Leg converts From into To through Symbol, it buys Symbol when To is the
+// base asset of Symbol and sells it when From is
+type Leg struct {
+	Symbol names.Symbol    `json:"symbol"`
+	From   string          `json:"from"`
+	To     string          `json:"to"`
+	Side   names.TradeSide `json:"side"`
+}
+
+// Cycle is three legs from an asset back to it
+type Cycle struct {
+	Legs [3]Leg `json:"legs"`
+}
+
+// Start is the asset the cycle starts and ends in
+func (c Cycle) Start() string {
+	return c.Legs[0].From
+}
+
+// Assets are the assets the cycle goes through, the start asset at both ends
+func (c Cycle) Assets() []string {
+	assets := []string{c.Start()}
+	for _, leg := range c.Legs {
+		assets = append(assets, leg.To)
+	}
+	return assets
+}
+
+func (c Cycle) String() string {
+	return strings.Join(c.Assets(), "→")
+}
+
+// Graph is the currency graph of the spot pairs, an asset is connected to
+// every asset it has a pair with
+type Graph struct {
+	legs     map[string][]Leg
+	cycles   []Cycle
+	bySymbol map[names.Symbol][]int
+}
+
+// NewGraph builds the graph of every pair of info and finds the cycles
+// starting in assets
+func NewGraph(info names.SymbolInfo, assets ...string) *Graph {
+	g := &Graph{legs: map[string][]Leg{}, bySymbol: map[names.Symbol][]int{}}
+	for _, symbol := range info.List() {
+		pair := info.ToPair(symbol)
+		if pair.Base == "" || pair.Quote == "" {
+			continue
+		}
+		s := names.Symbol(symbol)
+		g.legs[pair.Quote] = append(g.legs[pair.Quote], Leg{Symbol: s, From: pair.Quote, To: pair.Base, Side: names.TradeSideBuy})
+		g.legs[pair.Base] = append(g.legs[pair.Base], Leg{Symbol: s, From: pair.Base, To: pair.Quote, Side: names.TradeSideSell})
+	}
+	for _, asset := range assets {
+		g.findCycles(asset)
+	}
+	return g
+}
+
+func (g *Graph) findCycles(start string) {
+	for _, first := range g.legs[start] {
+		for _, second := range g.legs[first.To] {
+			if second.To == start || second.Symbol == first.Symbol {
+				continue
+			}
+			for _, third := range g.legs[second.To] {
+				if third.To != start {
+					continue
+				}
+				g.add(Cycle{Legs: [3]Leg{first, second, third}})
+			}
+		}
+	}
+}
+
+func (g *Graph) add(cycle Cycle) {
+	index := len(g.cycles)
+	g.cycles = append(g.cycles, cycle)
+	for _, leg := range cycle.Legs {
+		g.bySymbol[leg.Symbol] = append(g.bySymbol[leg.Symbol], index)
+	}
+}
+
+func (g *Graph) Cycles() []Cycle {
+	return g.cycles
+}
+
+// CyclesOf are the cycles with a leg through symbol
+func (g *Graph) CyclesOf(symbol names.Symbol) []Cycle {
+	cycles := []Cycle{}
+	for _, index := range g.bySymbol[symbol] {
+		cycles = append(cycles, g.cycles[index])
+	}
+	return cycles
+}
+
+// Symbols are the symbols of every leg of the cycles
+func (g *Graph) Symbols() []string {
+	symbols := []string{}
+	for symbol := range g.bySymbol {
+		symbols = append(symbols, symbol.String())
+	}
+	sort.Strings(symbols)
+	return symbols
+}
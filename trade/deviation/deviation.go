@@ -8,11 +8,15 @@ package deviation
 import (
 	"fmt"
 	"trading/helper"
+	"trading/metrics"
 	"trading/names"
+	"trading/notify"
 	"trading/stream"
 	"trading/utils"
 )
 
+var deviationTriggers = metrics.NewCounter("trading_deviation_triggers_total", "configs re-watched after the price deviated from their lock", "symbol", "side")
+
 type DeviationManager struct {
 	trader      names.Trader
 	tradeLock   names.LockInterface
@@ -62,6 +66,7 @@ func (dev *DeviationManager) CheckDeviation(subscription *stream.Subscription) {
 	deviationPrice := GetDeviationTriggerPrice(pretradePrice, config)
 
 	if config.Side.IsBuy() && spotPrice >= deviationPrice {
+		deviationTriggers.Inc(config.Symbol.String(), config.Side.String())
 		if deviation.FlipSide {
 			config.Side = names.TradeSideSell
 			if config.Sell.StopLimit == 0 {
@@ -78,10 +83,12 @@ func (dev *DeviationManager) CheckDeviation(subscription *stream.Subscription) {
 				config = dev.postAddFunc(config)
 			}
 			dev.trader.AddConfig(config)
+			notifyRewatch(originalConfig, config, spotPrice, deviationPrice)
 		}
 	}
 
 	if config.Side.IsSell() && spotPrice <= deviationPrice {
+		deviationTriggers.Inc(config.Symbol.String(), config.Side.String())
 		if deviation.FlipSide {
 			config.Side = names.TradeSideBuy
 			if config.Buy.StopLimit == 0 {
@@ -97,10 +104,20 @@ func (dev *DeviationManager) CheckDeviation(subscription *stream.Subscription) {
 				config = dev.postAddFunc(config)
 			}
 			dev.trader.AddConfig(config)
+			notifyRewatch(originalConfig, config, spotPrice, deviationPrice)
 		}
 	}
 }
 
+func notifyRewatch(original, config names.TradeConfig, spotPrice, deviationPrice float64) {
+	notify.Send(notify.NewEvent(notify.EventDeviation, fmt.Sprintf("%s %s RE-WATCHED", original.Side.String(), original.Symbol.String()),
+		"Symbol", original.Symbol.String(),
+		"Ticker Price", original.Symbol.FormatQuotePrice(spotPrice),
+		"Deviation Price", original.Symbol.FormatQuotePrice(deviationPrice),
+		"Side", fmt.Sprintf("%s to %s", original.Side.String(), config.Side.String()),
+	))
+}
+
 func GetDeviationTriggerPrice(pretradePrice float64, config names.TradeConfig) float64 {
 	side := config.Side
 	deviationSpotLimit := 0.0
@@ -23,6 +23,7 @@ func BuyExecutor(
 		tradeStartPrice,
 		config,
 		helper.TradeFee{},
+		names.LockState{},
 	}
 }
 
@@ -45,9 +46,11 @@ func buy(buy *buyExecutor) bool {
 	account := user.CreateUser().GetAccount()
 
 	buyOrder, err := account.TradeBuyConfig(buy.config, buy.marketPrice)
-	if err != nil {
+	countOrder(buy.config, buy.config.Buy, buy.marketPrice, err)
+	if !traded(buy.config, err) {
 		return false
 	}
+	realized := journalOrder(buy.config, buy.config.Side, pretradePrice, buy.marketPrice, buy.fees, *buyOrder, buy.lockState)
 	summary(
 		buy.config,
 		buy.config.Side,
@@ -55,16 +58,24 @@ func buy(buy *buyExecutor) bool {
 		pretradePrice,
 		buy.tradeStartPrice,
 		buy.marketPrice,
-		buy.marketPrice-pretradePrice,
 		buy.fees,
 		buy.config.Buy.Quantity,
 		*buyOrder,
+		realized,
 	)
 	return true
 }
 
 func (exec *buyExecutor) Execute() bool {
+	if !hasLiquidity(exec.config, exec.config.Buy, exec.marketPrice) {
+		return false
+	}
 	exec.fees = helper.GetTradeFee(exec.config, exec.marketPrice)
 	bought := buy(exec)
 	return bought
 }
+
+func (exec *buyExecutor) UseLockState(lockState names.LockState) ExecutorInterface {
+	exec.lockState = lockState
+	return exec
+}
@@ -1,17 +1,63 @@
 package executor
 
 import (
+	"errors"
 	"fmt"
 	"time"
+	"trading/exchange"
 	"trading/helper"
+	"trading/journal"
+	"trading/ledger"
+	"trading/metrics"
 	"trading/names"
+	"trading/notify"
+	"trading/stream"
+	"trading/user"
 	"trading/utils"
-	"github.com/adshao/go-binance/v2"
 )
 
 type ExecutorInterface interface {
 	IsProfitable() bool
 	Execute() bool
+	// set the state of the config lock recorded with the executed order
+	UseLockState(lockState names.LockState) ExecutorInterface
+}
+
+var orders = metrics.NewCounter("trading_orders_total", "orders sent to the exchange by result, placed, partial or failed", "symbol", "side", "type", "result")
+
+// countOrder counts an order of side sent to the exchange and notifies
+// when it failed with err
+func countOrder(config names.TradeConfig, side names.SideConfig, marketPrice float64, err error) {
+	orderType := string(side.Order.Type)
+	if orderType == "" {
+		orderType = string(names.OrderTypeMarket)
+	}
+	result := "placed"
+	var partial *user.PartialFillError
+	if errors.As(err, &partial) {
+		result = "partial"
+	} else if err != nil {
+		result = "failed"
+		notify.Send(notify.NewEvent(notify.EventOrderError, fmt.Sprintf("%s %s ORDER FAILED", config.Side.String(), config.Symbol.String()),
+			"Symbol", config.Symbol.String(),
+			"Type", orderType,
+			"Ticker Price", config.Symbol.FormatQuotePrice(marketPrice),
+			"Quantity", fmt.Sprintf("%f", side.Quantity),
+			"Error", err.Error(),
+		))
+	}
+	orders.Inc(config.Symbol.String(), config.Side.String(), orderType, result)
+}
+
+// traded reports whether the order sent with err was filled, a partial fill
+// is kept as the trade of config with the quantity it executed
+func traded(config names.TradeConfig, err error) bool {
+	var partial *user.PartialFillError
+	if errors.As(err, &partial) {
+		utils.LogWarn(fmt.Sprintf("<Executor>: %s %s traded with %s", config.Symbol, config.Side, err.Error()))
+		return true
+	}
+	return err == nil
 }
 
 type executorType struct {
@@ -19,41 +65,126 @@ type executorType struct {
 	tradeStartPrice float64
 	config names.TradeConfig
 	fees   helper.TradeFee
+	lockState names.LockState
 }
 
 
-func summary(config names.TradeConfig, action names.TradeSide, symbol names.Symbol, marketPrice, tradeStartPrice, currentPrice, profit float64, fee helper.TradeFee, quantity float64, order binance.CreateOrderResponse) string {
-
-	sm := fmt.Sprintf(
-		`
-===== %s TRADE SUMMARY %s=====
-Symbol            : %s
-Last Trade Price  : %s
-Started Trade     : %s
-Traded Price      : %s
-Ticker Price      : %s
-Profit            : %s
-Calculated fee    : %s
-Quantity          : %s
-ID                : %d
-Status            : %s
-Time              : %s
-`,
-		action.String(),
-		config.Symbol.String(),
-		order.Symbol,
-		symbol.FormatQuotePrice(marketPrice),
-		symbol.FormatQuotePrice(tradeStartPrice),
-		order.Price,
-		symbol.FormatQuotePrice(currentPrice),
-		symbol.FormatBasePrice(profit),
-		fee.String,
-		order.ExecutedQuantity,
-		order.OrderID,
-		order.Status,
-		time.Now().Format(time.UnixDate),
+// the average price, quantity and commission of the order fills, the
+// market price and estimated fee are used when the order has no fills
+func orderFill(order exchange.Order, marketPrice float64, fee helper.TradeFee) (price, quantity, commission float64, commissionAsset string) {
+	var quoteQuantity float64
+	for _, fill := range order.Fills {
+		quantity += fill.Quantity
+		quoteQuantity += fill.Price * fill.Quantity
+		commission += fill.Commission
+		commissionAsset = fill.CommissionAsset
+	}
+	if quantity > 0 {
+		return quoteQuantity / quantity, quantity, commission, commissionAsset
+	}
+
+	price = order.Price
+	if price == 0 {
+		price = marketPrice
+	}
+	return price, order.ExecutedQuantity, fee.Value, ""
+}
+
+// the base quantity the side trades, the whole balance when it has none
+func orderQuantity(config names.TradeConfig, side names.SideConfig, marketPrice float64) float64 {
+	if side.Quantity > 0 {
+		return side.Quantity
+	}
+	pair := config.Symbol.ParseTradingPair()
+	account := user.CreateUser().GetAccount()
+	if config.Side.IsBuy() {
+		if marketPrice <= 0 {
+			return 0
+		}
+		return account.GetBalance(pair.Quote).Free / marketPrice
+	}
+	return account.GetBalance(pair.Base).Free
+}
+
+// hasLiquidity estimates the fill of a market order from the order book and
+// reports whether its slippage from the market price is within the max
+// slippage of the side. Limit orders never fill past their price
+func hasLiquidity(config names.TradeConfig, side names.SideConfig, marketPrice float64) bool {
+	if !side.Order.Type.IsMarket() {
+		return true
+	}
+	quantity := orderQuantity(config, side, marketPrice)
+	if quantity <= 0 {
+		return true
+	}
+	fill, known := stream.EstimateFill(config.Symbol.String(), config.Side, quantity)
+	if !known {
+		utils.LogInfo(fmt.Sprintf("<Executor>: no order book for %s, slippage is unknown", config.Symbol))
+		return true
+	}
+
+	slippage := fill.Slippage(marketPrice)
+	utils.LogInfo(fmt.Sprintf(
+		"<Executor>: %s %s %f estimated to fill at %s over %d levels, %.3f%% slippage",
+		config.Symbol, config.Side, quantity, config.Symbol.FormatQuotePrice(fill.Price), fill.Levels, slippage))
+	if side.MaxSlippage <= 0 {
+		return true
+	}
+	if !fill.Complete() {
+		utils.LogWarn(fmt.Sprintf("<Executor>: %s %s skipped, the order book only fills %f of %f",
+			config.Symbol, config.Side, fill.Filled, quantity))
+		return false
+	}
+	if slippage > side.MaxSlippage {
+		utils.LogWarn(fmt.Sprintf("<Executor>: %s %s skipped, %.3f%% slippage is above %.3f%%",
+			config.Symbol, config.Side, slippage, side.MaxSlippage))
+		return false
+	}
+	return true
+}
+
+// record the executed order in the trade journal and book it in the ledger,
+// the profit the order realized is returned
+func journalOrder(config names.TradeConfig, action names.TradeSide, pretradePrice, marketPrice float64, fee helper.TradeFee, order exchange.Order, lockState names.LockState) float64 {
+	price, quantity, commission, commissionAsset := orderFill(order, marketPrice, fee)
+	record, err := journal.Default().Record(journal.Record{
+		ConfigId:      config.Id,
+		Symbol:        config.Symbol,
+		Side:          action,
+		PretradePrice: pretradePrice,
+		FillPrice:     price,
+		Quantity:      quantity,
+		Fee:           commission,
+		FeeAsset:      commissionAsset,
+		OrderId:       order.OrderId,
+		Status:        string(order.Status),
+		Lock:          journal.NewLockSnapshot(lockState),
+	})
+	if err != nil {
+		utils.LogError(err, fmt.Sprintf("<Journal>: could not record %s order %d", config.Symbol, order.OrderId))
+	}
+	return ledger.Default().Record(ledger.FromRecord(record))
+}
+
+func summary(config names.TradeConfig, action names.TradeSide, symbol names.Symbol, marketPrice, tradeStartPrice, currentPrice float64, fee helper.TradeFee, quantity float64, order exchange.Order, realized float64) string {
+	event := notify.NewEvent(notify.EventTrade, fmt.Sprintf("%s TRADE SUMMARY %s", action.String(), config.Symbol.String()),
+		"Symbol", order.Symbol,
+		"Last Trade Price", symbol.FormatQuotePrice(marketPrice),
+		"Started Trade", symbol.FormatQuotePrice(tradeStartPrice),
+		"Traded Price", fmt.Sprintf("%f", order.Price),
+		"Ticker Price", symbol.FormatQuotePrice(currentPrice),
+		"Realized Profit", symbol.FormatQuotePrice(realized),
+		"Calculated fee", fee.String,
+		"Quantity", fmt.Sprintf("%f", order.ExecutedQuantity),
+		"ID", fmt.Sprintf("%d", order.OrderId),
+		"Status", string(order.Status),
+		"Time", utils.Now().Format(time.UnixDate),
 	)
+	sm := fmt.Sprintf("\n===== %s=====\n", event.Title)
+	for _, field := range event.Fields {
+		sm += fmt.Sprintf("%-17s : %s\n", field.Name, field.Value)
+	}
+	notify.Send(event)
 	utils.LogInfo(sm)
-	helper.WriteStringToFile("trades.txt",sm)
 	return sm
 }
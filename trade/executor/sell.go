@@ -1,10 +1,12 @@
 package executor
 
 import (
-
+	"fmt"
 	"trading/helper"
+	"trading/ledger"
 	"trading/names"
 	"trading/user"
+	"trading/utils"
 )
 
 type sellExecutor executorType
@@ -20,15 +22,24 @@ func SellExecutor(
 		tradeStartPrice,
 		config,
 		helper.TradeFee{},
+		names.LockState{},
 	}
 }
 
+// IsProfitable compares the price the sell gets net of its fee with the cost
+// of the position in the ledger, the start of the trade is used when the
+// ledger holds nothing of the symbol, assets transfered in always are
 func (exec *sellExecutor) IsProfitable() bool {
-	// We can look at the trade history and get the last trade
-	// for symbol if action == Sell last trade == symbol.lastBuy
-	// if does not exist then assume asset must have been transfered in
-	if !exec.config.Sell.MustProfit || exec.tradeStartPrice == 0 {
-		 return true
+	if !exec.config.Sell.MustProfit {
+		return true
+	}
+	l := ledger.Default()
+	l.ImportHistoryOnce(exec.config.Symbol)
+	if entry, held := l.EntryPrice(exec.config.Id, exec.config.Symbol); held {
+		return exec.marketPrice*(1-ledger.FeeRate) > entry
+	}
+	if exec.tradeStartPrice == 0 {
+		return true
 	}
 	return (exec.marketPrice - exec.tradeStartPrice) > exec.fees.Value*2
 }
@@ -37,10 +48,12 @@ func sell(sell *sellExecutor) bool {
 	pretradePrice := sell.tradeStartPrice
 	account := user.CreateUser().GetAccount()
 	sellOrder, err := account.TradeSellConfig(sell.config, sell.marketPrice)
+	countOrder(sell.config, sell.config.Sell, sell.marketPrice, err)
 	
-	if err != nil {
+	if !traded(sell.config, err) {
 		return false
 	}
+	realized := journalOrder(sell.config, sell.config.Side, pretradePrice, sell.marketPrice, sell.fees, *sellOrder, sell.lockState)
 
 	summary(
 		sell.config,
@@ -49,15 +62,29 @@ func sell(sell *sellExecutor) bool {
 		pretradePrice,
 		sell.tradeStartPrice,
 		sell.marketPrice,
-		sell.marketPrice-pretradePrice,
 		sell.fees,
 		sell.config.Sell.Quantity,
 		*sellOrder,
+		realized,
 	)
 	return true
 }
 
 func (exec *sellExecutor) Execute() bool {
+	if !hasLiquidity(exec.config, exec.config.Sell, exec.marketPrice) {
+		return false
+	}
+	// a stop loss exits regardless of MustProfit
+	if !exec.lockState.IsStopLossHit && !exec.IsProfitable() {
+		utils.LogInfo(fmt.Sprintf("<Executor>: %s sell skipped, %s does not cover the cost of the position",
+			exec.config.Symbol, exec.config.Symbol.FormatQuotePrice(exec.marketPrice)))
+		return false
+	}
 	sold := sell(exec)
 	return sold
 }
+
+func (exec *sellExecutor) UseLockState(lockState names.LockState) ExecutorInterface {
+	exec.lockState = lockState
+	return exec
+}
@@ -7,6 +7,7 @@ import (
 	"math"
 	"sort"
 	"trading/helper"
+	"trading/indicators"
 	"trading/kline"
 )
 
@@ -142,6 +143,11 @@ func (g *Graph) Kline() []kline.KlineData {
 	return g.getKLineData()
 }
 
+// Indicators of the candles of the graph with the default periods
+func (g *Graph) Indicators() *indicators.Set {
+	return indicators.NewKlineSet(g.getKLineData())
+}
+
 // Calculates the mean of the high and low prices of all the candles in a graph.
 // Generally price should be below this point to buy and above to sell
 func (g Graph) GetPriceMidpoint() float64 {
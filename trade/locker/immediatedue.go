@@ -14,6 +14,7 @@ type immediateDueLock struct {
 	pretradePrice             float64 // Starting price.
 	gainsAccrude              float64 // Current gains accrued.
 	tradeConfig               names.TradeConfig
+	stops                     stops
 	redemptionIsMature        bool
 	lockManager               names.LockManagerInterface
 	maturityCallback          func(names.LockInterface)
@@ -38,7 +39,12 @@ func (lock *immediateDueLock) SetVerbose(verbose bool) {
 
 // GetTradeLimit returns the stop loss limit for the lock.
 func (lock *immediateDueLock) GetTradeLimit() float64 {
-	return helper.CalculateTradePrice(lock.tradeConfig, lock.pretradePrice).Limit
+	return helper.CalculateTradePrice(lock.stops.apply(lock.tradeConfig), lock.pretradePrice).Limit
+}
+
+// UseStops replaces the stop limit and lock delta of the config in percent
+func (lock *immediateDueLock) UseStops(stopLimit, lockDelta float64) {
+	lock.stops = stops{stopLimit: stopLimit, lockDelta: lockDelta}
 }
 
 // GetTradeLimit returns the stop loss limit for the lock.
@@ -71,6 +77,8 @@ func (lock *immediateDueLock) GetLockState() names.LockState {
 		RedemptionCandidateCallback: lock.maturityCandidateCallback,
 		MinimumLockUnit:             lock.getMinimumLockUnit(),
 		AbsoluteGrowth:              lock.AbsoluteGrowthPercent(),
+		StopLossPrice:               lock.lockManager.StopLossPrice(lock.tradeConfig),
+		IsStopLossHit:               lock.IsStopLossHit(),
 	}
 }
 
@@ -95,10 +103,11 @@ func (lock immediateDueLock) RelativeGrowthPercent() float64 {
 func (lock *immediateDueLock) getMinimumLockUnit() float64 {
 	// LockUnit is derived as the product of stopLossLimit and the percentage
 	// represented by lockDelta
-	if lock.tradeConfig.Side.IsBuy() {
-		return lock.pretradePrice * (lock.tradeConfig.Buy.LockDelta / 100)
+	config := lock.stops.apply(lock.tradeConfig)
+	if config.Side.IsBuy() {
+		return lock.pretradePrice * (config.Buy.LockDelta / 100)
 	}
-	return lock.pretradePrice * (lock.tradeConfig.Sell.LockDelta / 100)
+	return lock.pretradePrice * (config.Sell.LockDelta / 100)
 }
 
 // TryLockPrice attempts to lock the price. A price will only lock if it is greater or less than minimum gain
@@ -128,6 +137,11 @@ func (lock *immediateDueLock) TryLockPrice(price float64) {
 		}
 	}
 
+	if lock.IsStopLossHit() {
+		// a stop loss exits whatever the gains, MustProfit included
+		lock.redemptionIsMature = true
+	}
+
 	if lock.verbose {
 		logLock(lock)
 	}
@@ -146,6 +160,11 @@ func (lock *immediateDueLock) IsRedemptionCandidate() bool {
 	return lock.IsRedemptionDue()
 }
 
+// the price has crossed the stop loss of the config
+func (lock *immediateDueLock) IsStopLossHit() bool {
+	return stopLossHit(lock.tradeConfig.Side, lock.lockManager.StopLossPrice(lock.tradeConfig), lock.price)
+}
+
 func (lock *immediateDueLock) IsRedemptionDue() bool {
 	return lock.redemptionIsMature
 }
@@ -2,7 +2,9 @@ package locker
 
 import (
 	"fmt"
+	"math"
 	"sync"
+	"sync/atomic"
 	"trading/helper"
 	"trading/names"
 	"trading/trade/deviation"
@@ -14,6 +16,14 @@ type LockManager struct {
 	locks       sync.Map
 	lockCreator names.LockCreatorFunc
 	prioritySide names.TradeSide
+	// records of locks to restore by config id
+	restored sync.Map
+	// cost of the positions held by selling configs, from the ledger
+	entryPrices names.EntryPriceFunc
+	// prices configs started watching their side at, stop losses are
+	// measured from them when the ledger holds no position of the config
+	entries sync.Map
+	exiting int32
 }
 
 // NewLockManager creates a new TradeLocker instance.
@@ -64,6 +74,7 @@ func (m *LockManager) RemoveLock(lock names.LockInterface) bool {
 // Remove All locks on this lock manager
 func (m *LockManager) RemoveLocks() bool {
 	m.locks = sync.Map{}
+	m.entries = sync.Map{}
 	return true
 }
 
@@ -76,9 +87,15 @@ func (m *LockManager) SetLockCreator(creator names.LockCreatorFunc) {
 func (m *LockManager) BestMatureLock() names.LockInterface {
 	var topSellDrift, topBuyDrift float64
 	var highestLockSell, highestLockBuy names.LockInterface
+	var stoppedLock names.LockInterface
 
 	m.locks.Range(func(key, value interface{}) bool {
 		lock := value.(names.LockInterface)
+		if lock.IsStopLossHit() {
+			// stop losses exit before any lock takes profit
+			stoppedLock = lock
+			return false
+		}
 		absoluteChange := lock.AbsoluteGrowthPercent()
 		side := lock.TradeSide()
 		if lock.IsRedemptionDue() {
@@ -93,6 +110,9 @@ func (m *LockManager) BestMatureLock() names.LockInterface {
 		return true
 	})
 
+	if stoppedLock != nil {
+		return stoppedLock
+	}
 	if !m.prioritySide.IsEmpty() {
 		if m.prioritySide.IsSell() && highestLockSell != nil {
 			return highestLockSell
@@ -140,11 +160,129 @@ func (l *LockManager) AddLock(config names.TradeConfig, initialPrice float64) na
 	validateLock(config, initialPrice)
 	validateConfig(config)
 
-	newLock := l.lockCreator(initialPrice, config, false, initialPrice, l, initialPrice)
+	newLock := l.restoreLock(config)
+	if newLock == nil {
+		newLock = l.lockCreator(initialPrice, config, false, initialPrice, l, initialPrice)
+	}
+	l.enter(config, newLock.GetLockState().PretradePrice)
 	l.locks.Store(config.Symbol, newLock)
 	return newLock
 }
 
+func entryKey(config names.TradeConfig, side names.TradeSide) string {
+	if config.Id == "" {
+		return fmt.Sprintf("%s/%s", config.Symbol, side)
+	}
+	return fmt.Sprintf("%s/%s", config.Id, side)
+}
+
+// enter keeps the first price the config was watched at on its side so that
+// watching it again on the same side does not move its stop loss
+func (l *LockManager) enter(config names.TradeConfig, price float64) {
+	if _, exist := l.entries.LoadOrStore(entryKey(config, config.Side), price); !exist {
+		// the config left its other side, it starts over when it returns
+		l.entries.Delete(entryKey(config, helper.SwitchTradeSide(config.Side)))
+	}
+}
+
+// UseEntryPrices sets where the cost of the position of a selling config is
+// read, without it stop losses are measured from the entries of the manager
+func (l *LockManager) UseEntryPrices(entryPrices names.EntryPriceFunc) {
+	l.entryPrices = entryPrices
+}
+
+// EntryPrice is the cost of the position a selling config holds in the
+// ledger, which outlives the lock manager a trader rebuilds after a trade,
+// or else the first price the config was watched at on its side
+func (l *LockManager) EntryPrice(config names.TradeConfig) float64 {
+	if config.Side.IsSell() && config.Id != "" && l.entryPrices != nil {
+		if entry, held := l.entryPrices(config.Id, config.Symbol); held {
+			return entry
+		}
+	}
+	price, exist := l.entries.Load(entryKey(config, config.Side))
+	if !exist {
+		return 0
+	}
+	return price.(float64)
+}
+
+func (l *LockManager) StopLossPrice(config names.TradeConfig) float64 {
+	if config.Side.IsSell() && atomic.LoadInt32(&l.exiting) == 1 {
+		return math.MaxFloat64
+	}
+	stopLoss := config.Buy.StopLoss
+	if config.Side.IsSell() {
+		stopLoss = config.Sell.StopLoss
+	}
+	return stopLoss.Price(config.Side, l.EntryPrice(config))
+}
+
+func (l *LockManager) ForceExit() {
+	if atomic.CompareAndSwapInt32(&l.exiting, 0, 1) {
+		utils.LogWarn("<LockManager>: forcing every sell lock to exit")
+	}
+}
+
+// stopLossHit reports if price has crossed the stop loss price of side
+func stopLossHit(side names.TradeSide, stopLossPrice, price float64) bool {
+	if stopLossPrice <= 0 {
+		return false
+	}
+	if side.IsBuy() {
+		return price >= stopLossPrice
+	}
+	return price <= stopLossPrice
+}
+
+// RestoreLocks keeps the records so that the next lock added for each config
+// continues from the prices of its record instead of the current price
+func (l *LockManager) RestoreLocks(records []names.LockRecord) {
+	for _, record := range records {
+		if record.ConfigId == "" {
+			continue
+		}
+		l.restored.Store(record.ConfigId, record)
+	}
+}
+
+func (l *LockManager) restoreLock(config names.TradeConfig) names.LockInterface {
+	value, exist := l.restored.Load(config.Id)
+	if !exist {
+		return nil
+	}
+	record := value.(names.LockRecord)
+	if record.Symbol != config.Symbol || record.Side != config.Side {
+		// the config has moved on since the record was taken
+		l.restored.Delete(config.Id)
+		return nil
+	}
+	l.restored.Delete(config.Id)
+	utils.LogInfo(fmt.Sprintf("<LockManager>: restored %s %s lock, pretrade price %f", config.Symbol, config.Side, record.PretradePrice))
+	return l.lockCreator(record.Price, config, false, record.PretradePrice, l, record.AccrudGains)
+}
+
+// stops set on a lock by UseStops, the config is used while they are zero
+type stops struct {
+	stopLimit float64
+	lockDelta float64
+}
+
+// apply replaces the stops of the side config is trading
+func (s stops) apply(config names.TradeConfig) names.TradeConfig {
+	side := &config.Sell
+	if config.Side.IsBuy() {
+		side = &config.Buy
+	}
+	if s.stopLimit > 0 {
+		side.StopLimit, side.LimitType = s.stopLimit, names.RatePercent
+	}
+	if s.lockDelta > 0 {
+		side.LockDelta = s.lockDelta
+	}
+	return config
+}
+
 func tradePricePercentChange(config names.TradeConfig, price, pretradePrice float64) float64 {
 	// Calculate price change
 	return helper.CalculatePercentageChange(price, pretradePrice)
@@ -196,5 +334,8 @@ func logLock(lock names.LockInterface) {
 	if deviationSpotLimit != 0 {
 		log = log + fmt.Sprintf("Deviation Trigger     : %s\n", symbol.FormatQuotePrice(deviationSpotLimit))
 	}
+	if state.StopLossPrice != 0 && state.StopLossPrice != math.MaxFloat64 {
+		log = log + fmt.Sprintf("Hard Stop Loss        : %s\n", symbol.FormatQuotePrice(state.StopLossPrice))
+	}
 	utils.LogInfo(log)
 }
@@ -0,0 +1,97 @@
+package locker
+
+import (
+	"testing"
+	"trading/ledger"
+	"trading/names"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func stopLossConfig() names.TradeConfig {
+	return names.TradeConfig{
+		Id:     "crash",
+		Symbol: "BTCUSDT",
+		Side:   names.TradeSideSell,
+		Sell: names.SideConfig{
+			StopLimit:  10,
+			LimitType:  names.RatePercent,
+			Quantity:   1,
+			LockDelta:  1,
+			MustProfit: true,
+			StopLoss:   names.StopLoss{Value: 5, Type: names.RatePercent},
+		},
+		Buy: names.SideConfig{
+			StopLimit: 10,
+			LimitType: names.RatePercent,
+			Quantity:  1,
+			LockDelta: 1,
+			StopLoss:  names.StopLoss{Value: 110, Type: names.RateFixed},
+		},
+	}
+}
+
+func TestStopLoss(t *testing.T) {
+	lockManager := NewLockManager(PeakHighLockCreator)
+	config := stopLossConfig()
+	lock := lockManager.AddLock(config, 100)
+	lock.SetVerbose(false)
+
+	assert.EqualValues(t, 95, lock.GetLockState().StopLossPrice)
+	lock.TryLockPrice(97)
+	assert.False(t, lock.IsRedemptionDue(), "a loss above the stop is held")
+	lock.TryLockPrice(94)
+	assert.True(t, lock.IsStopLossHit())
+	assert.True(t, lock.IsRedemptionDue(), "the stop loss sells below the pretrade price")
+	assert.Equal(t, lock, lockManager.BestMatureLock())
+
+	buy := config
+	buy.Side = names.TradeSideBuy
+	buyLock := NewLockManager(ImmediateDueLockCreator).AddLock(buy, 100)
+	buyLock.SetVerbose(false)
+	buyLock.TryLockPrice(111)
+	assert.True(t, buyLock.IsRedemptionDue(), "a buy stops above its fixed price")
+}
+
+func TestStopLossSurvivesDeviation(t *testing.T) {
+	lockManager := NewLockManager(PeakHighLockCreator)
+	config := stopLossConfig()
+	lockManager.AddLock(config, 100).RemoveFromManager()
+
+	// the deviation watched the config again at a lower price
+	lock := lockManager.AddLock(config, 96)
+	lock.SetVerbose(false)
+	assert.EqualValues(t, 100, lockManager.EntryPrice(config))
+	lock.TryLockPrice(94)
+	assert.True(t, lock.IsRedemptionDue(), "the stop loss is kept from the first entry")
+
+	// the config traded and came back to sell at a new price
+	buy := config
+	buy.Side = names.TradeSideBuy
+	lockManager.RemoveLock(lock)
+	lockManager.AddLock(buy, 90).RemoveFromManager()
+	lockManager.AddLock(config, 80)
+	assert.EqualValues(t, 80, lockManager.EntryPrice(config))
+}
+
+func TestForceExit(t *testing.T) {
+	lockManager := NewLockManager(PeakHighLockCreator)
+	lock := lockManager.AddLock(stopLossConfig(), 100)
+	lock.SetVerbose(false)
+	lockManager.ForceExit()
+	lock.TryLockPrice(120)
+	assert.True(t, lock.IsRedemptionDue(), "every sell exits")
+}
+
+func TestStopLossFromLedger(t *testing.T) {
+	positions := ledger.New(ledger.FIFO)
+	config := stopLossConfig()
+	positions.Add(ledger.Fill{OrderId: 1, ConfigId: config.Id, Symbol: config.Symbol, Side: names.TradeSideBuy, Price: 100, Quantity: 1})
+
+	// the trader rebuilt its lock manager after an other config traded
+	lockManager := NewLockManager(PeakHighLockCreator)
+	lockManager.UseEntryPrices(positions.EntryPrice)
+	lock := lockManager.AddLock(config, 90)
+	lock.SetVerbose(false)
+	assert.EqualValues(t, 95, lock.GetLockState().StopLossPrice, "the stop loss is measured from the cost of the position")
+}
@@ -15,6 +15,7 @@ type peakHigh struct {
 	pretradePrice             float64 // Starting price.
 	gainsAccrude              float64 // Current gains accrued.
 	tradeConfig               names.TradeConfig
+	stops                     stops
 	redemptionIsMature        bool
 	lockManager               names.LockManagerInterface
 	maturityCallback          func(names.LockInterface)
@@ -45,7 +46,12 @@ func (lock *peakHigh) SetVerbose(verbose bool) {
 
 // GetTradeLimit returns the stop loss limit for the lock.
 func (lock *peakHigh) GetTradeLimit() float64 {
-	return helper.CalculateTradePrice(lock.tradeConfig, lock.pretradePrice).Limit
+	return helper.CalculateTradePrice(lock.stops.apply(lock.tradeConfig), lock.pretradePrice).Limit
+}
+
+// UseStops replaces the stop limit and lock delta of the config in percent
+func (lock *peakHigh) UseStops(stopLimit, lockDelta float64) {
+	lock.stops = stops{stopLimit: stopLimit, lockDelta: lockDelta}
 }
 
 // PretradePrice returns the pre-trade price for the lock.
@@ -73,6 +79,8 @@ func (lock *peakHigh) GetLockState() names.LockState {
 		RedemptionCandidateCallback: lock.maturityCandidateCallback,
 		MinimumLockUnit:             lock.getMinimumLockUnit(),
 		AbsoluteGrowth:              lock.AbsoluteGrowthPercent(),
+		StopLossPrice:               lock.lockManager.StopLossPrice(lock.tradeConfig),
+		IsStopLossHit:               lock.IsStopLossHit(),
 	}
 }
 
@@ -98,10 +106,11 @@ func (lock peakHigh) RelativeGrowthPercent() float64 {
 func (lock *peakHigh) getMinimumLockUnit() float64 {
 	// LockUnit is derived as the product of stopLossLimit and the percentage
 	// represented by lockDelta
-	if lock.tradeConfig.Side.IsBuy() {
-		return lock.pretradePrice * (lock.tradeConfig.Buy.LockDelta / 100)
+	config := lock.stops.apply(lock.tradeConfig)
+	if config.Side.IsBuy() {
+		return lock.pretradePrice * (config.Buy.LockDelta / 100)
 	}
-	return lock.pretradePrice * (lock.tradeConfig.Sell.LockDelta / 100)
+	return lock.pretradePrice * (config.Sell.LockDelta / 100)
 }
 
 // TryLockPrice attempts to lock the price. A price will only lock if it is greater or less than minimum gain
@@ -132,6 +141,11 @@ func (lock *peakHigh) TryLockPrice(price float64) {
 		}
 	}
 
+	if lock.IsStopLossHit() {
+		// a stop loss exits whatever the gains, MustProfit included
+		lock.redemptionIsMature = true
+	}
+
 	if lock.verbose {
 		logLock(lock)
 	}
@@ -153,6 +167,11 @@ func (lock *peakHigh) IsRedemptionCandidate() bool {
 	return same //2
 }
 
+// the price has crossed the stop loss of the config
+func (lock *peakHigh) IsStopLossHit() bool {
+	return stopLossHit(lock.tradeConfig.Side, lock.lockManager.StopLossPrice(lock.tradeConfig), lock.price)
+}
+
 // Checks if this lock is mature on it own
 func (lock *peakHigh) IsRedemptionDue() bool {
 	isDue := lock.redemptionIsMature
@@ -0,0 +1,299 @@
+package locker
+
+import (
+	"math"
+	"time"
+	"trading/helper"
+	"trading/indicators"
+	"trading/kline"
+	"trading/names"
+	"trading/utils"
+)
+
+// trailing lock follows the best price of its side at a distance measured in
+// volatilities of the symbol instead of a fixed LockDelta. A sell lock is
+// due when the price falls back to its trail, a buy lock when the price
+// rebounds to it. The distance tightens as the gains grow and once the gains
+// reach the breakeven move the trail never falls behind the pretrade price
+
+type VolatilitySource string
+
+const (
+	// average true range of the candles of the symbol
+	VolatilityATR VolatilitySource = "atr"
+	// standard deviation of the prices the lock receives
+	VolatilityStdDev VolatilitySource = "stddev"
+)
+
+type TrailingOptions struct {
+	Source VolatilitySource
+	// candles of the atr or prices of the standard deviation
+	Period int
+	// interval of the candles of the atr
+	Interval string
+	// distance of the trail in volatilities, it tightens from Multiplier to
+	// MinMultiplier as the gains grow to TightenPercent
+	Multiplier     float64
+	MinMultiplier  float64
+	TightenPercent float64
+	// percent gain after which the trail is kept at or beyond the pretrade
+	// price, the trail never moves to breakeven when zero
+	BreakevenPercent float64
+}
+
+var DefaultTrailing = TrailingOptions{
+	Source:           VolatilityATR,
+	Period:           14,
+	Interval:         "15m",
+	Multiplier:       3,
+	MinMultiplier:    1,
+	TightenPercent:   5,
+	BreakevenPercent: 1,
+}
+
+// candles the atr of a lock is measured on, the shared kline store unless a test replaces it
+var loadCandles = func(symbol, interval string, n int) []kline.KlineData {
+	return kline.Default().Last(symbol, interval, n)
+}
+
+// volatility is how far the price of a symbol usually moves, zero until it is known
+type volatility interface {
+	add(price float64)
+	value() float64
+}
+
+type tickDeviation struct {
+	bands *indicators.Bollinger
+}
+
+func (d *tickDeviation) add(price float64) {
+	d.bands.Update(price)
+}
+
+func (d *tickDeviation) value() float64 {
+	if !d.bands.Ready() {
+		return 0
+	}
+	// bands one deviation wide
+	return d.bands.Upper() - d.bands.Middle()
+}
+
+// candleRange measures the atr again once a candle of interval has closed
+type candleRange struct {
+	symbol    string
+	interval  string
+	period    int
+	atr       float64
+	refreshed time.Time
+}
+
+func (r *candleRange) add(price float64) {}
+
+func (r *candleRange) value() float64 {
+	now := utils.Now()
+	if !r.refreshed.IsZero() && now.Sub(r.refreshed) < kline.IntervalDuration(r.interval) {
+		return r.atr
+	}
+	r.refreshed = now
+	atr := indicators.NewATR(r.period)
+	for _, candle := range indicators.FromKlines(loadCandles(r.symbol, r.interval, r.period*2)) {
+		atr.Add(candle)
+	}
+	r.atr = atr.Value()
+	return r.atr
+}
+
+type trailing struct {
+	price                     float64 // Last price.
+	pretradePrice             float64 // Starting price.
+	gainsAccrude              float64 // Best price of the side since the lock started.
+	tradeConfig               names.TradeConfig
+	options                   TrailingOptions
+	volatility                volatility
+	redemptionIsMature        bool
+	lockManager               names.LockManagerInterface
+	maturityCallback          func(names.LockInterface)
+	maturityCandidateCallback func(names.LockInterface)
+	verbose                   bool
+}
+
+// NewTrailingLockCreator creates trailing locks with the options
+func NewTrailingLockCreator(options TrailingOptions) names.LockCreatorFunc {
+	return func(price float64, tradeConfig names.TradeConfig, redemptionIsMature bool, pretradePrice float64, lockManager names.LockManagerInterface, gainsAccrude float64) names.LockInterface {
+		var v volatility = &tickDeviation{bands: indicators.NewBollinger(options.Period, 1)}
+		if options.Source == VolatilityATR {
+			v = &candleRange{symbol: tradeConfig.Symbol.String(), interval: options.Interval, period: options.Period}
+		}
+		return &trailing{
+			price:              price,
+			tradeConfig:        tradeConfig,
+			options:            options,
+			volatility:         v,
+			redemptionIsMature: redemptionIsMature,
+			pretradePrice:      pretradePrice,
+			lockManager:        lockManager,
+			gainsAccrude:       gainsAccrude,
+			verbose:            true,
+		}
+	}
+}
+
+// TrailingLockCreator creates trailing locks with the default options
+var TrailingLockCreator = NewTrailingLockCreator(DefaultTrailing)
+
+func (lock *trailing) RemoveFromManager() bool {
+	return lock.lockManager.RemoveLock(lock)
+}
+
+func (lock *trailing) SetVerbose(verbose bool) {
+	lock.verbose = verbose
+}
+
+// GetTradeLimit returns the stop loss limit for the lock.
+func (lock *trailing) GetTradeLimit() float64 {
+	return helper.CalculateTradePrice(lock.tradeConfig, lock.pretradePrice).Limit
+}
+
+// GetLockState returns the current state of the lock.
+func (lock *trailing) GetLockState() names.LockState {
+	return names.LockState{
+		StopLimit:                   lock.GetTradeLimit(),
+		LockOwner:                   lock.lockManager,
+		AccrudGains:                 lock.gainsAccrude,
+		TradeConfig:                 lock.tradeConfig,
+		PretradePrice:               lock.pretradePrice,
+		Price:                       lock.price,
+		IsRedemptionIsDue:           lock.IsRedemptionDue(),
+		IsRedemptionCandidate:       lock.IsRedemptionCandidate(),
+		RedemptionDueCallback:       lock.maturityCallback,
+		RedemptionCandidateCallback: lock.maturityCandidateCallback,
+		MinimumLockUnit:             lock.distance(),
+		AbsoluteGrowth:              lock.AbsoluteGrowthPercent(),
+		StopLossPrice:               lock.lockManager.StopLossPrice(lock.tradeConfig),
+		IsStopLossHit:               lock.IsStopLossHit(),
+		TrailingStop:                lock.trail(),
+	}
+}
+
+// GetLockManager returns the owner of the lock.
+func (lock *trailing) GetLockManager() names.LockManagerInterface {
+	return lock.lockManager
+}
+
+// AbsoluteGrowthPercent calculates the percentage by which the current price has deviated from the pre-trade price.
+func (lock trailing) AbsoluteGrowthPercent() float64 {
+	return math.Abs(tradePricePercentChange(lock.tradeConfig, lock.price, lock.pretradePrice))
+}
+
+func (lock trailing) RelativeGrowthPercent() float64 {
+	return tradePricePercentChange(lock.tradeConfig, lock.price, lock.pretradePrice)
+}
+
+// gainPercent is how far the best price has moved in favour of the side
+func (lock *trailing) gainPercent() float64 {
+	if lock.pretradePrice == 0 {
+		return 0
+	}
+	gain := (lock.gainsAccrude - lock.pretradePrice) / lock.pretradePrice * 100
+	if lock.tradeConfig.Side.IsBuy() {
+		return -gain
+	}
+	return gain
+}
+
+// multiplier tightens from Multiplier to MinMultiplier as the gains grow
+func (lock *trailing) multiplier() float64 {
+	options := lock.options
+	if options.TightenPercent <= 0 || options.MinMultiplier >= options.Multiplier {
+		return options.Multiplier
+	}
+	progress := math.Min(1, math.Max(0, lock.gainPercent()/options.TightenPercent))
+	return options.Multiplier - (options.Multiplier-options.MinMultiplier)*progress
+}
+
+// distance of the trail from the best price, the LockDelta of the config
+// until the volatility is known
+func (lock *trailing) distance() float64 {
+	if v := lock.volatility.value(); v > 0 {
+		return v * lock.multiplier()
+	}
+	if lock.tradeConfig.Side.IsBuy() {
+		return lock.pretradePrice * (lock.tradeConfig.Buy.LockDelta / 100)
+	}
+	return lock.pretradePrice * (lock.tradeConfig.Sell.LockDelta / 100)
+}
+
+// trail is the price the lock is due at
+func (lock *trailing) trail() float64 {
+	breakeven := lock.options.BreakevenPercent > 0 && lock.gainPercent() >= lock.options.BreakevenPercent
+	if lock.tradeConfig.Side.IsBuy() {
+		trail := lock.gainsAccrude + lock.distance()
+		if breakeven {
+			trail = math.Min(trail, lock.pretradePrice)
+		}
+		return trail
+	}
+	trail := lock.gainsAccrude - lock.distance()
+	if breakeven {
+		trail = math.Max(trail, lock.pretradePrice)
+	}
+	return trail
+}
+
+// TryLockPrice moves the trail with the best price and matures the lock
+// when the price crosses the trail beyond the limit of the config
+func (lock *trailing) TryLockPrice(price float64) {
+	lock.price = price
+	lock.volatility.add(price)
+	limit := lock.GetTradeLimit()
+
+	if lock.tradeConfig.Side.IsSell() {
+		lock.gainsAccrude = math.Max(lock.gainsAccrude, price)
+		lock.redemptionIsMature = price <= lock.trail() && price > limit
+	} else if lock.tradeConfig.Side.IsBuy() {
+		lock.gainsAccrude = math.Min(lock.gainsAccrude, price)
+		lock.redemptionIsMature = price >= lock.trail() && price < limit
+	}
+
+	if lock.IsStopLossHit() {
+		// a stop loss exits whatever the gains, MustProfit included
+		lock.redemptionIsMature = true
+	}
+
+	if lock.verbose {
+		logLock(lock)
+	}
+
+	if lock.maturityCallback != nil && lock.IsRedemptionDue() {
+		lock.maturityCallback(lock)
+	}
+
+	if lock.maturityCandidateCallback != nil && lock.IsRedemptionCandidate() {
+		lock.maturityCandidateCallback(lock)
+	}
+}
+
+// determines if it is the most profitable lock from other locks of similar action
+func (lock *trailing) IsRedemptionCandidate() bool {
+	return lock.GetLockManager().BestMatureLock() == lock
+}
+
+func (lock *trailing) IsStopLossHit() bool {
+	return stopLossHit(lock.tradeConfig.Side, lock.lockManager.StopLossPrice(lock.tradeConfig), lock.price)
+}
+
+func (lock *trailing) IsRedemptionDue() bool {
+	return lock.redemptionIsMature
+}
+
+func (lock *trailing) SetRedemptionDueCallback(cb func(lock names.LockInterface)) {
+	lock.maturityCallback = cb
+}
+
+func (lock *trailing) SetRedemptionCandidateCallback(cb func(lock names.LockInterface)) {
+	lock.maturityCandidateCallback = cb
+}
+
+func (lock trailing) TradeSide() names.TradeSide {
+	return lock.tradeConfig.Side
+}
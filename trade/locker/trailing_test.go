@@ -0,0 +1,140 @@
+package locker
+
+import (
+	"testing"
+	"trading/kline"
+	"trading/names"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// candles whose true range is always 4
+func useCandles(t *testing.T, candles int) {
+	previous := loadCandles
+	loadCandles = func(symbol, interval string, n int) []kline.KlineData {
+		data := []kline.KlineData{}
+		for i := 0; i < candles; i++ {
+			data = append(data, kline.KlineData{Open: 100, High: 102, Low: 98, Close: 100})
+		}
+		return data
+	}
+	t.Cleanup(func() { loadCandles = previous })
+}
+
+var trailingTest = TrailingOptions{
+	Source:           VolatilityATR,
+	Period:           3,
+	Interval:         "15m",
+	Multiplier:       2,
+	MinMultiplier:    1,
+	TightenPercent:   20,
+	BreakevenPercent: 3,
+}
+
+func TestTrailingSell(t *testing.T) {
+	useCandles(t, 6)
+	manager := NewLockManager(NewTrailingLockCreator(trailingTest))
+	config := names.TradeConfig{
+		Symbol: "BTCUSDT",
+		Side:   names.TradeSideSell,
+		Sell:   names.SideConfig{StopLimit: 90, LimitType: names.RateFixed, LockDelta: 1},
+	}
+	lock := manager.AddLock(config, 100).(*trailing)
+	lock.SetVerbose(false)
+
+	lock.TryLockPrice(100)
+	assert.Equal(t, 8.0, lock.GetLockState().MinimumLockUnit, "two atrs before any gain")
+	assert.Equal(t, 92.0, lock.GetLockState().TrailingStop)
+
+	lock.TryLockPrice(102)
+	assert.InDelta(t, 7.6, lock.distance(), 1e-9, "the trail tightens as the gains grow")
+	assert.False(t, lock.IsRedemptionDue())
+
+	lock.TryLockPrice(104)
+	assert.Equal(t, 100.0, lock.trail(), "the trail moves to breakeven after a 3% gain")
+	lock.TryLockPrice(101)
+	assert.False(t, lock.IsRedemptionDue())
+	assert.Equal(t, 104.0, lock.GetLockState().AccrudGains, "the best price is kept")
+	lock.TryLockPrice(99.5)
+	assert.True(t, lock.IsRedemptionDue(), "the price fell back to the trail")
+
+	lock.TryLockPrice(112)
+	assert.InDelta(t, 106.4, lock.trail(), 1e-9)
+	assert.False(t, lock.IsRedemptionDue(), "a new best price moves the trail up")
+	lock.TryLockPrice(106)
+	assert.True(t, lock.IsRedemptionDue())
+	assert.True(t, lock.IsRedemptionCandidate())
+
+	lock.TryLockPrice(80)
+	assert.False(t, lock.IsRedemptionDue(), "not below the stop limit of the config")
+}
+
+func TestTrailingBuy(t *testing.T) {
+	useCandles(t, 6)
+	options := trailingTest
+	options.BreakevenPercent = 0
+	manager := NewLockManager(NewTrailingLockCreator(options))
+	config := names.TradeConfig{
+		Symbol: "BTCUSDT",
+		Side:   names.TradeSideBuy,
+		Buy:    names.SideConfig{StopLimit: 110, LimitType: names.RateFixed, LockDelta: 1},
+	}
+	lock := manager.AddLock(config, 100).(*trailing)
+	lock.SetVerbose(false)
+
+	lock.TryLockPrice(96)
+	assert.InDelta(t, 103.2, lock.trail(), 1e-9, "the trail follows the lowest price")
+	assert.False(t, lock.IsRedemptionDue())
+	lock.TryLockPrice(104)
+	assert.True(t, lock.IsRedemptionDue(), "the price rebounded to the trail")
+}
+
+func TestTrailingVolatility(t *testing.T) {
+	useCandles(t, 0)
+	config := names.TradeConfig{
+		Symbol: "BTCUSDT",
+		Side:   names.TradeSideSell,
+		Sell:   names.SideConfig{StopLimit: 90, LimitType: names.RateFixed, LockDelta: 1},
+	}
+	lock := NewLockManager(TrailingLockCreator).AddLock(config, 100).(*trailing)
+	lock.SetVerbose(false)
+	assert.Equal(t, 1.0, lock.distance(), "the lock delta until the atr is known")
+
+	options := trailingTest
+	options.Source = VolatilityStdDev
+	lock = NewLockManager(NewTrailingLockCreator(options)).AddLock(config, 100).(*trailing)
+	lock.SetVerbose(false)
+	lock.TryLockPrice(100)
+	lock.TryLockPrice(102)
+	assert.Equal(t, 1.0, lock.distance(), "the lock delta until enough prices are seen")
+	lock.TryLockPrice(104)
+	// deviation of 100, 102 and 104 is 1.633, two of them tightened by a 4% gain
+	assert.InDelta(t, 1.633*1.8, lock.distance(), 1e-3)
+
+	restored := NewTrailingLockCreator(options)(101, config, false, 100, lock.GetLockManager(), 110)
+	assert.Equal(t, 110.0, restored.GetLockState().AccrudGains, "a restored lock continues from its best price")
+}
+
+func TestTrailingRanking(t *testing.T) {
+	useCandles(t, 6)
+	manager := NewLockManager(NewTrailingLockCreator(trailingTest))
+	sell := func(symbol names.Symbol) names.TradeConfig {
+		return names.TradeConfig{
+			Symbol: symbol,
+			Side:   names.TradeSideSell,
+			Sell:   names.SideConfig{StopLimit: 90, LimitType: names.RateFixed, LockDelta: 1},
+		}
+	}
+	small := manager.AddLock(sell("ETHUSDT"), 100).(*trailing)
+	large := manager.AddLock(sell("BTCUSDT"), 100).(*trailing)
+	small.SetVerbose(false)
+	large.SetVerbose(false)
+
+	small.TryLockPrice(104)
+	small.TryLockPrice(99)
+	large.TryLockPrice(120)
+	large.TryLockPrice(110)
+	assert.True(t, small.IsRedemptionDue())
+	assert.True(t, large.IsRedemptionDue())
+	assert.True(t, large == manager.BestMatureLock(), "the lock with the larger growth is redeemed first")
+}
@@ -0,0 +1,149 @@
+package manager
+
+import (
+	"fmt"
+	"sync"
+	"time"
+	"trading/ledger"
+	"trading/notify"
+	"trading/utils"
+)
+
+// how often a pool with a max drawdown checks its open positions, the
+// drawdown is also checked before every buy
+var DrawdownInterval = 5 * time.Second
+
+// drawdown follows the profit of a pool in the quote asset from the ledger,
+// what it realized and what its open positions are worth. The drawdown is the
+// fall of the profit from its peak in percent of the most the pool has had
+// invested at once, so each position weighs by its value
+type drawdown struct {
+	max      float64
+	exit     bool
+	realized float64
+	equity   float64
+	peak     float64
+	capital  float64
+	breached bool
+	// the peak starts at the first profit seen
+	started bool
+	watched bool
+	lock    sync.Mutex
+}
+
+// DrawdownStatus is in the quote asset, but for Max and Drawdown in percent
+type DrawdownStatus struct {
+	Max      float64 `json:"max"`
+	Realized float64 `json:"realized"`
+	Equity   float64 `json:"equity"`
+	Peak     float64 `json:"peak"`
+	Capital  float64 `json:"capital"`
+	Drawdown float64 `json:"drawdown"`
+	Breached bool    `json:"breached"`
+}
+
+func newDrawdown(max float64, exit bool, resume *DrawdownStatus) *drawdown {
+	d := &drawdown{max: max, exit: exit}
+	if resume != nil {
+		d.realized, d.equity, d.peak, d.capital, d.breached = resume.Realized, resume.Equity, resume.Peak, resume.Capital, resume.Breached
+		d.started = true
+	}
+	return d
+}
+
+func (d *drawdown) percent() float64 {
+	if d.capital <= 0 {
+		return 0
+	}
+	return (d.peak - d.equity) / d.capital * 100
+}
+
+// update sets the profit of the pool and reports if the max drawdown has
+// been breached and if this update breached it, a breached pool stays breached
+func (d *drawdown) update(pnl ledger.PnL) (breached, crossed bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.realized, d.equity = pnl.Realized, pnl.Total()
+	if pnl.Cost > d.capital {
+		d.capital = pnl.Cost
+	}
+	if !d.started || d.equity > d.peak {
+		d.peak, d.started = d.equity, true
+	}
+	if !d.breached && d.max > 0 && d.percent() >= d.max {
+		d.breached, crossed = true, true
+		utils.LogWarn(fmt.Sprintf("<Drawdown>: %.2f%% from the peak of %f on %f invested reached the max of %.2f%%", d.percent(), d.peak, d.capital, d.max))
+	}
+	return d.breached, crossed
+}
+
+func (d *drawdown) status() DrawdownStatus {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return DrawdownStatus{
+		Max:      d.max,
+		Realized: d.realized,
+		Equity:   d.equity,
+		Peak:     d.peak,
+		Capital:  d.capital,
+		Drawdown: d.percent(),
+		Breached: d.breached,
+	}
+}
+
+// UseMaxDrawdown stops the manager from buying once its profit falls percent
+// of what it invested below its peak, with exit every position is also sold
+func (tm *TradeManager) UseMaxDrawdown(percent float64, exit bool) *TradeManager {
+	tm.maxDrawdown = percent
+	tm.drawdownExit = exit
+	return tm
+}
+
+// checkDrawdown updates the drawdown of the pool and reports if the
+// pool must stop buying, positions are sold when the pool exits
+func (p *Pool) checkDrawdown() bool {
+	breached, crossed := p.drawdown.update(p.PnL())
+	if !breached {
+		return false
+	}
+	if crossed {
+		status := p.drawdown.status()
+		notify.Send(notify.NewEvent(notify.EventDrawdown, fmt.Sprintf("POOL %s REACHED ITS MAX DRAWDOWN", p.id),
+			"Drawdown", fmt.Sprintf("%.2f%%", status.Drawdown),
+			"Peak", fmt.Sprintf("%f", status.Peak),
+			"Capital", fmt.Sprintf("%f", status.Capital),
+			"Max", fmt.Sprintf("%.2f%%", status.Max),
+			"Exit", fmt.Sprintf("%t", p.drawdown.exit),
+		))
+	}
+	if tm := p.Manager(); p.drawdown.exit && tm != nil && tm.lockManager != nil {
+		tm.lockManager.ForceExit()
+	}
+	return true
+}
+
+func (p *Pool) Drawdown() DrawdownStatus {
+	return p.drawdown.status()
+}
+
+// watchDrawdown checks the drawdown of the pool until the pool is stopped
+func (p *Pool) watchDrawdown() {
+	p.drawdown.lock.Lock()
+	if p.drawdown.watched || p.drawdown.max <= 0 {
+		p.drawdown.lock.Unlock()
+		return
+	}
+	p.drawdown.watched = true
+	p.drawdown.lock.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(DrawdownInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if !p.IsRunning() {
+				return
+			}
+			p.checkDrawdown()
+		}
+	}()
+}
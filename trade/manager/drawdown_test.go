@@ -0,0 +1,69 @@
+package manager
+
+import (
+	"fmt"
+	"testing"
+	"time"
+	"trading/exchange"
+	"trading/ledger"
+	"trading/names"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxDrawdown(t *testing.T) {
+	names.UseExchangeInfo(exchange.ExchangeInfo{Symbols: []exchange.SymbolInfo{
+		{Symbol: "BTCUSDT", BaseAsset: "BTC", QuoteAsset: "USDT"},
+		{Symbol: "ETHUSDT", BaseAsset: "ETH", QuoteAsset: "USDT"},
+	}})
+	prices := map[string]float64{"ETHUSDT": 100, "BTCUSDT": 100}
+	positions := ledger.New(ledger.FIFO).UsePrices(func(symbol string) (float64, error) {
+		if price, exist := prices[symbol]; exist {
+			return price, nil
+		}
+		return 0, fmt.Errorf("no price for %s", symbol)
+	})
+
+	config := names.TradeConfig{
+		Id:     "drawdown",
+		Symbol: "ETHUSDT",
+		Side:   names.TradeSideSell,
+		Sell:   names.SideConfig{LimitType: names.RatePercent, StopLimit: 1, LockDelta: 1},
+	}
+	small := names.TradeConfig{
+		Id:     "small",
+		Symbol: "BTCUSDT",
+		Side:   names.TradeSideSell,
+		Sell:   names.SideConfig{LimitType: names.RatePercent, StopLimit: 1, LockDelta: 1},
+	}
+	positions.Add(
+		ledger.Fill{OrderId: 1, ConfigId: "drawdown", Symbol: "ETHUSDT", Side: names.TradeSideBuy, Price: 100, Quantity: 1, Time: time.Unix(1, 0)},
+		ledger.Fill{OrderId: 2, ConfigId: "small", Symbol: "BTCUSDT", Side: names.TradeSideBuy, Price: 100, Quantity: 0.1, Time: time.Unix(2, 0)},
+	)
+	tm := NewTradeManager(&configTrader{configs: []names.TradeConfig{config, small}, price: 100}).
+		UseMaxDrawdown(5, true).
+		UseLedger(positions).
+		DoTrade()
+	lock := tm.lockManager.RetrieveLock(config)
+	lock.SetVerbose(false)
+
+	prices["ETHUSDT"] = 104
+	assert.False(t, tm.Pool().checkDrawdown())
+	prices["BTCUSDT"] = 50
+	assert.False(t, tm.Pool().checkDrawdown(), "the small position losing half only weighs its value")
+	assert.InDelta(t, 5/110.0*100, tm.Pool().Drawdown().Drawdown, 1e-9)
+
+	prices["ETHUSDT"] = 98
+	assert.True(t, tm.Pool().checkDrawdown(), "11 of the 110 invested was lost from the peak")
+	assert.InDelta(t, 10, tm.Pool().Drawdown().Drawdown, 1e-9)
+
+	executed := false
+	buy := config
+	buy.Side = names.TradeSideBuy
+	tm.Execute(buy, 98, 100, func() { executed = true })
+	assert.False(t, executed, "buys are halted")
+
+	lock.TryLockPrice(99)
+	assert.True(t, lock.IsRedemptionDue(), "positions exit")
+	assert.True(t, tm.Pool().Status().Drawdown.Breached)
+}
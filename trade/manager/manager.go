@@ -3,7 +3,9 @@ package manager
 import (
 	"fmt"
 	"trading/helper"
+	"trading/ledger"
 	"trading/names"
+	"trading/trade/allocator"
 	"trading/trade/executor"
 	"trading/trade/locker"
 	"trading/utils"
@@ -13,6 +15,14 @@ type TradeManager struct {
 	trader       names.Trader
 	prioritySide names.TradeSide
 	lockCreator  names.LockCreatorFunc
+	lockManager  names.LockManagerInterface
+	pool         *Pool
+	snapshotFile string
+	resume       *Snapshot
+	maxDrawdown  float64
+	drawdownExit bool
+	// positions of the configs, ledger.Default when nil
+	ledger *ledger.Ledger
 }
 
 func NewTradeManager(trader names.Trader) *TradeManager {
@@ -36,6 +46,21 @@ func (tm *TradeManager) UseLockCreator(lockCreator names.LockCreatorFunc) *Trade
 	return tm
 }
 
+// set the ledger the positions and profit of the configs are read from,
+// default ledger.Default
+func (tm *TradeManager) UseLedger(l *ledger.Ledger) *TradeManager {
+	tm.ledger = l
+	return tm
+}
+
+// Ledger the positions and profit of the configs are read from
+func (tm *TradeManager) Ledger() *ledger.Ledger {
+	if tm.ledger == nil {
+		return ledger.Default()
+	}
+	return tm.ledger
+}
+
 func (tm *TradeManager) DoTrade() *TradeManager {
 
 	if tm.trader == nil {
@@ -44,6 +69,14 @@ func (tm *TradeManager) DoTrade() *TradeManager {
 	}
 
 	lockManager := locker.NewLockManager(tm.lockCreator)
+	// the ledger is only opened when a stop loss asks for a position
+	lockManager.UseEntryPrices(func(configId string, symbol names.Symbol) (float64, bool) {
+		return tm.Ledger().EntryPrice(configId, symbol)
+	})
+	tm.lockManager = lockManager
+	if tm.resume != nil {
+		lockManager.RestoreLocks(tm.resume.Locks)
+	}
 	if tm.prioritySide != "" {
 		if !helper.SideIsValid(tm.prioritySide) {
 			utils.LogError(fmt.Errorf("invalid priority side"), string(tm.prioritySide))
@@ -55,6 +88,21 @@ func (tm *TradeManager) DoTrade() *TradeManager {
 			"Priority Side     :%s\n",
 		tm.prioritySide,
 	))
+	if tm.pool == nil {
+		tm.pool = newPool(tm)
+	}
+	tm.pool.attach(tm)
+	if a := allocator.Get(); a != nil {
+		a.Track(tm.pool.id, tm.pool.Configs)
+	}
+	if tm.snapshotFile != "" {
+		tm.pool.keepSnapshot(tm.snapshotFile)
+	}
+	if status := tm.pool.Drawdown(); status.Breached && tm.pool.drawdown.exit {
+		// the pool was exiting before its trader rebuilt itself
+		lockManager.ForceExit()
+	}
+	tm.pool.watchDrawdown()
 	tm.trader.
 		SetLockManager(lockManager).
 		SetExecutor(tm.Execute).
@@ -62,6 +110,12 @@ func (tm *TradeManager) DoTrade() *TradeManager {
 	return tm
 }
 
+// budgetTrader sizes its own buys within budgets of its own, like the dca
+// trader, so they are not reserved from the allocator
+type budgetTrader interface {
+	Budgeted() bool
+}
+
 func (tm *TradeManager) Execute(
 	config names.TradeConfig,
 	spot float64,
@@ -69,13 +123,66 @@ func (tm *TradeManager) Execute(
 	done func()) {
 	var sold bool
 
+	if tm.pool != nil {
+		if !tm.pool.IsRunning() {
+			return
+		}
+		tm.pool.work.Add(1)
+		defer tm.pool.work.Done()
+	}
+
+	var lockState names.LockState
+	if tm.lockManager != nil {
+		if lock := tm.lockManager.RetrieveLock(config); lock != nil {
+			lockState = lock.GetLockState()
+		}
+	}
+
+	if config.Side.IsBuy() && tm.pool != nil && tm.pool.checkDrawdown() {
+		utils.LogWarn(fmt.Sprintf("<TradeManager>: pool %s reached its max drawdown, %s buy is halted", tm.pool.id, config.Symbol))
+		return
+	}
+
+	capital := allocator.Get()
+	if trader, ok := tm.trader.(budgetTrader); ok && trader.Budgeted() {
+		capital = nil
+	}
+	if config.Side.IsBuy() && capital != nil {
+		reservation, err := capital.Reserve(config, spot)
+		if err != nil {
+			utils.LogWarn(fmt.Sprintf("<Allocator>: %s buy rejected, %s", config.Symbol, err.Error()))
+			return
+		}
+		config.Buy.Quantity = config.Symbol.Quantity(reservation.Amount / config.Buy.Order.Prices(names.TradeSideBuy, spot).Highest())
+	}
+
 	if config.Side.IsBuy() {
-		sold = executor.BuyExecutor(config, spot, basePrice).Execute()
+		sold = executor.BuyExecutor(config, spot, basePrice).UseLockState(lockState).Execute()
 	} else {
-		sold = executor.SellExecutor(config, spot, basePrice).Execute()
+		sold = executor.SellExecutor(config, spot, basePrice).UseLockState(lockState).Execute()
+	}
+	if capital != nil {
+		switch {
+		case config.Side.IsBuy() && sold:
+			capital.Fill(config.Id)
+		case config.Side.IsBuy() || sold:
+			// the buy failed or the position was sold
+			capital.Release(config.Id)
+		}
 	}
 	if !sold {
 		return
 	}
+	if tm.pool != nil {
+		tm.pool.tradeCompleted()
+	}
 	done()
+	if tm.pool != nil {
+		tm.pool.saveSnapshot()
+	}
+}
+
+// Pool returns the pool the manager runs in, nil until the manager is started
+func (tm *TradeManager) Pool() *Pool {
+	return tm.pool
 }
@@ -0,0 +1,92 @@
+package manager
+
+import (
+	"sort"
+	"trading/metrics"
+	"trading/user"
+)
+
+// gauges read from the running pools when the metrics are scraped
+func init() {
+	metrics.NewGaugeFunc("trading_lock_state", "locks that are due or candidates for redemption are 1", []string{"pool", "symbol", "side", "state"}, collectLockStates)
+	metrics.NewGaugeFunc("trading_lock_absolute_growth_percent", "price growth of a lock from its pretrade price", []string{"pool", "symbol", "side"}, collectLockGrowth)
+	metrics.NewGaugeFunc("trading_pnl", "realized and unrealized profit and fees of a pool in the quote asset", []string{"pool", "kind"}, collectPnL)
+	metrics.NewGaugeFunc("trading_balance", "free and locked balance of the assets the pools trade", []string{"asset", "kind"}, collectBalances)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func eachLock(emit func(pool, symbol, side string, lock lockReading)) {
+	for _, p := range Pools() {
+		tm := p.Manager()
+		if tm == nil || tm.lockManager == nil {
+			continue
+		}
+		for symbol, lock := range tm.lockManager.RetrieveLocks() {
+			state := lock.GetLockState()
+			emit(p.id, symbol.String(), lock.TradeSide().String(), lockReading{
+				due:       lock.IsRedemptionDue(),
+				candidate: state.IsRedemptionCandidate,
+				growth:    lock.AbsoluteGrowthPercent(),
+			})
+		}
+	}
+}
+
+type lockReading struct {
+	due, candidate bool
+	growth         float64
+}
+
+func collectLockStates(emit func(value float64, labels ...string)) {
+	eachLock(func(pool, symbol, side string, lock lockReading) {
+		emit(boolValue(lock.due), pool, symbol, side, "due")
+		emit(boolValue(lock.candidate), pool, symbol, side, "candidate")
+	})
+}
+
+func collectLockGrowth(emit func(value float64, labels ...string)) {
+	eachLock(func(pool, symbol, side string, lock lockReading) {
+		emit(lock.growth, pool, symbol, side)
+	})
+}
+
+func collectPnL(emit func(value float64, labels ...string)) {
+	for _, p := range Pools() {
+		pnl := p.PnL()
+		emit(pnl.Realized, p.id, "realized")
+		emit(pnl.Unrealized, p.id, "unrealized")
+		emit(pnl.Fees, p.id, "fees")
+	}
+}
+
+func collectBalances(emit func(value float64, labels ...string)) {
+	assets := map[string]bool{}
+	for _, p := range Pools() {
+		for _, config := range p.Configs() {
+			pair := config.Symbol.ParseTradingPair()
+			assets[pair.Base], assets[pair.Quote] = true, true
+		}
+	}
+	delete(assets, "")
+	if len(assets) == 0 {
+		return
+	}
+	list := make([]string, 0, len(assets))
+	for asset := range assets {
+		list = append(list, asset)
+	}
+	sort.Strings(list)
+
+	account := user.CreateUser().GetAccount()
+	for _, asset := range list {
+		balance := account.GetBalance(asset)
+		emit(balance.Free, asset, "free")
+		emit(balance.Locked, asset, "locked")
+	}
+}
@@ -0,0 +1,299 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+	"trading/ledger"
+	"trading/names"
+	"trading/trade/allocator"
+	"trading/utils"
+
+	"github.com/google/uuid"
+)
+
+// Pool is a running trade manager and every manager that continues it
+// when its trader rebuilds itself after a trade
+type Pool struct {
+	id              string
+	started         time.Time
+	stopped         time.Time
+	tradesCompleted int64
+	running         bool
+	manager         *TradeManager
+	snapshotFile    string
+	// stops saving the snapshot and waits for a save in progress
+	stopSnapshots func()
+	// held while the snapshot file is written or removed
+	snapshotLock sync.Mutex
+	drawdown     *drawdown
+	// trades being executed and traders being restarted in the pool
+	work sync.WaitGroup
+	lock sync.RWMutex
+}
+
+type PoolStatus struct {
+	Id              string              `json:"id"`
+	Started         time.Time           `json:"started"`
+	RunningTime     string              `json:"runningTime"`
+	TradesCompleted int64               `json:"tradesCompleted"`
+	IsRunning       bool                `json:"isRunning"`
+	Configs         []names.TradeConfig `json:"configs"`
+	Drawdown        DrawdownStatus      `json:"drawdown"`
+}
+
+// pools by id
+var pools sync.Map
+
+// pools by the trader currently running them
+var traderPools sync.Map
+
+func newPool(tm *TradeManager) *Pool {
+	pool := &Pool{
+		id:      uuid.New().String(),
+		started: utils.Now(),
+		running: true,
+		manager: tm,
+	}
+	var resumeDrawdown *DrawdownStatus
+	if tm.resume != nil && tm.resume.PoolId != "" {
+		pool.id = tm.resume.PoolId
+		pool.started = tm.resume.Started
+		pool.tradesCompleted = tm.resume.TradesCompleted
+		resumeDrawdown = tm.resume.Drawdown
+	}
+	pool.drawdown = newDrawdown(tm.maxDrawdown, tm.drawdownExit, resumeDrawdown)
+	pools.Store(pool.id, pool)
+	return pool
+}
+
+// Pools returns every registered pool, oldest first
+func Pools() []*Pool {
+	list := []*Pool{}
+	pools.Range(func(key, value interface{}) bool {
+		list = append(list, value.(*Pool))
+		return true
+	})
+	sort.Slice(list, func(a, b int) bool {
+		return list[a].started.Before(list[b].started)
+	})
+	return list
+}
+
+func GetPool(id string) (*Pool, bool) {
+	pool, ok := pools.Load(id)
+	if !ok {
+		return nil, false
+	}
+	return pool.(*Pool), true
+}
+
+// Continue starts next in the pool of the previous trader so that a trader that
+// rebuilds itself after a trade keeps its pool, lock creator, priority and drawdown.
+// next is not started when the pool has been stopped
+func Continue(previous names.Trader, next *TradeManager) *TradeManager {
+	value, ok := traderPools.LoadAndDelete(previous)
+	if !ok {
+		return next.DoTrade()
+	}
+	pool := value.(*Pool)
+	if current := pool.Manager(); current != nil {
+		next.UseLockCreator(current.lockCreator).
+			UsePriority(current.prioritySide).
+			UseMaxDrawdown(current.maxDrawdown, current.drawdownExit).
+			UseLedger(current.ledger)
+	}
+	next.pool = pool
+	if !pool.IsRunning() {
+		utils.LogInfo(fmt.Sprintf("<Pool>: %s is stopped, trader will not restart", pool.id))
+		return next
+	}
+	return next.DoTrade()
+}
+
+// Restart continues the pool of previous with next in the background like a
+// go Continue, the pool waits for next to start in Wait
+func Restart(previous names.Trader, next *TradeManager) {
+	value, ok := traderPools.Load(previous)
+	if !ok {
+		go Continue(previous, next)
+		return
+	}
+	pool := value.(*Pool)
+	pool.work.Add(1)
+	go func() {
+		defer pool.work.Done()
+		Continue(previous, next)
+	}()
+}
+
+// Wait blocks until the trades and restarts in flight in the pool are done
+func (p *Pool) Wait() {
+	p.work.Wait()
+}
+
+// attach makes tm the manager currently running the pool
+func (p *Pool) attach(tm *TradeManager) {
+	p.lock.Lock()
+	p.manager = tm
+	p.lock.Unlock()
+	traderPools.Store(tm.trader, p)
+}
+
+func (p *Pool) Id() string {
+	return p.id
+}
+
+func (p *Pool) Manager() *TradeManager {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.manager
+}
+
+func (p *Pool) IsRunning() bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.running
+}
+
+func (p *Pool) TradesCompleted() int64 {
+	return atomic.LoadInt64(&p.tradesCompleted)
+}
+
+func (p *Pool) tradeCompleted() {
+	atomic.AddInt64(&p.tradesCompleted, 1)
+}
+
+// lockfreeTrader is a trader with configs no lock watches, like the cells
+// of the grid trader, it lists them itself
+type lockfreeTrader interface {
+	Configs() []names.TradeConfig
+}
+
+// Configs returns the configs the pool is currently watching
+func (p *Pool) Configs() []names.TradeConfig {
+	configs := []names.TradeConfig{}
+	tm := p.Manager()
+	if tm == nil || tm.lockManager == nil {
+		return configs
+	}
+	if trader, ok := tm.trader.(lockfreeTrader); ok {
+		configs = trader.Configs()
+	}
+	for _, lock := range tm.lockManager.RetrieveLocks() {
+		configs = append(configs, lock.GetLockState().TradeConfig)
+	}
+	sort.Slice(configs, func(a, b int) bool {
+		return configs[a].Id < configs[b].Id
+	})
+	return configs
+}
+
+// PnL of the configs in the pool, from the ledger
+func (p *Pool) PnL() ledger.PnL {
+	ids := []string{}
+	for _, config := range p.Configs() {
+		ids = append(ids, config.Id)
+	}
+	if tm := p.Manager(); tm != nil {
+		return tm.Ledger().PnL(ids...)
+	}
+	return ledger.Default().PnL(ids...)
+}
+
+func (p *Pool) FindConfig(configId string) (names.TradeConfig, bool) {
+	for _, config := range p.Configs() {
+		if config.Id == configId {
+			return config, true
+		}
+	}
+	return names.TradeConfig{}, false
+}
+
+func (p *Pool) Status() PoolStatus {
+	p.lock.RLock()
+	end := utils.Now()
+	if !p.running {
+		end = p.stopped
+	}
+	status := PoolStatus{
+		Id:          p.id,
+		Started:     p.started,
+		RunningTime: end.Sub(p.started).Round(time.Second).String(),
+		IsRunning:   p.running,
+	}
+	p.lock.RUnlock()
+
+	status.TradesCompleted = p.TradesCompleted()
+	status.Configs = p.Configs()
+	status.Drawdown = p.Drawdown()
+	return status
+}
+
+// AddConfig starts watching a new config in the running pool
+func (p *Pool) AddConfig(config names.TradeConfig) error {
+	if !p.IsRunning() {
+		return fmt.Errorf("pool %s is stopped", p.id)
+	}
+	if _, exist := p.FindConfig(config.Id); exist {
+		return fmt.Errorf("config %s already exists in pool %s", config.Id, p.id)
+	}
+	p.Manager().trader.AddConfig(config)
+	utils.LogInfo(fmt.Sprintf("<Pool>: added config %s %s to %s", config.Id, config.Symbol, p.id))
+	return nil
+}
+
+// RemoveConfig stops watching the config with configId
+func (p *Pool) RemoveConfig(configId string) (names.TradeConfig, error) {
+	config, exist := p.FindConfig(configId)
+	if !exist {
+		return names.TradeConfig{}, fmt.Errorf("config %s not found in pool %s", configId, p.id)
+	}
+	if !p.Manager().trader.RemoveConfig(config) {
+		return config, fmt.Errorf("config %s of pool %s could not be removed", configId, p.id)
+	}
+	utils.LogInfo(fmt.Sprintf("<Pool>: removed config %s %s from %s", config.Id, config.Symbol, p.id))
+	return config, nil
+}
+
+// Stop removes every config of the pool, no trade is executed by the
+// pool after it is stopped and its snapshot is removed so it is not resumed
+func (p *Pool) Stop() PoolStatus {
+	p.lock.Lock()
+	if p.running {
+		p.running = false
+		p.stopped = utils.Now()
+	}
+	snapshotFile := p.snapshotFile
+	stopSnapshots := p.stopSnapshots
+	p.stopSnapshots = nil
+	p.lock.Unlock()
+
+	// the snapshot is not saved again once it is removed
+	if stopSnapshots != nil {
+		stopSnapshots()
+	}
+	if snapshotFile != "" {
+		p.snapshotLock.Lock()
+		os.Remove(snapshotFile)
+		p.snapshotLock.Unlock()
+	}
+
+	capital := allocator.Get()
+	if capital != nil {
+		capital.Untrack(p.id)
+	}
+	if tm := p.Manager(); tm != nil {
+		for _, config := range p.Configs() {
+			tm.trader.RemoveConfig(config)
+			if capital != nil {
+				capital.Release(config.Id)
+			}
+		}
+	}
+	utils.LogInfo(fmt.Sprintf("<Pool>: stopped %s", p.id))
+	return p.Status()
+}
@@ -0,0 +1,167 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+	"trading/names"
+	"trading/utils"
+)
+
+// how often a running pool saves its snapshot, a snapshot is also
+// saved after every completed trade
+var SnapshotInterval = 10 * time.Second
+
+// Snapshot is the state of a pool that is needed to resume it after a restart
+type Snapshot struct {
+	PoolId          string             `json:"poolId"`
+	Started         time.Time          `json:"started"`
+	Saved           time.Time          `json:"saved"`
+	TradesCompleted int64              `json:"tradesCompleted"`
+	PrioritySide    names.TradeSide    `json:"prioritySide"`
+	Locks           []names.LockRecord `json:"locks"`
+	Drawdown        *DrawdownStatus    `json:"drawdown,omitempty"`
+	// state of the trader, only set for traders that can be resumed
+	Trader json.RawMessage `json:"trader,omitempty"`
+}
+
+// snapshotTrader is a trader that can save its state to be resumed later
+type snapshotTrader interface {
+	Snapshot() any
+}
+
+// TraderState decodes the saved trader state into state
+func (s Snapshot) TraderState(state any) error {
+	if len(s.Trader) == 0 {
+		return fmt.Errorf("snapshot of pool %s has no trader state", s.PoolId)
+	}
+	return json.Unmarshal(s.Trader, state)
+}
+
+func LoadSnapshot(filename string) (Snapshot, error) {
+	var snapshot Snapshot
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return snapshot, err
+	}
+	if err := json.Unmarshal(content, &snapshot); err != nil {
+		return snapshot, fmt.Errorf("could not read snapshot %s: %w", filename, err)
+	}
+	return snapshot, nil
+}
+
+// UseSnapshotFile saves the state of the manager pool to filename while it runs
+func (tm *TradeManager) UseSnapshotFile(filename string) *TradeManager {
+	tm.snapshotFile = filename
+	return tm
+}
+
+// Resume continues the pool saved in snapshot, the locks of the trader
+// start from their saved prices instead of the current price
+func (tm *TradeManager) Resume(snapshot Snapshot) *TradeManager {
+	tm.resume = &snapshot
+	if snapshot.PrioritySide != "" {
+		tm.prioritySide = snapshot.PrioritySide
+	}
+	return tm
+}
+
+func (p *Pool) Snapshot() (Snapshot, error) {
+	snapshot := Snapshot{
+		PoolId:          p.id,
+		Started:         p.started,
+		Saved:           utils.Now(),
+		TradesCompleted: p.TradesCompleted(),
+		Locks:           []names.LockRecord{},
+	}
+	if drawdown := p.Drawdown(); drawdown.Max > 0 {
+		snapshot.Drawdown = &drawdown
+	}
+	tm := p.Manager()
+	if tm == nil {
+		return snapshot, nil
+	}
+	snapshot.PrioritySide = tm.prioritySide
+	if tm.lockManager != nil {
+		for _, lock := range tm.lockManager.RetrieveLocks() {
+			snapshot.Locks = append(snapshot.Locks, names.NewLockRecord(lock.GetLockState()))
+		}
+	}
+	if trader, ok := tm.trader.(snapshotTrader); ok {
+		state, err := json.Marshal(trader.Snapshot())
+		if err != nil {
+			return snapshot, err
+		}
+		snapshot.Trader = state
+	}
+	return snapshot, nil
+}
+
+// saveSnapshot writes the snapshot to a temporary file first so a crash
+// while saving does not corrupt the previous snapshot
+func (p *Pool) saveSnapshot() {
+	p.snapshotLock.Lock()
+	defer p.snapshotLock.Unlock()
+	p.lock.RLock()
+	filename := p.snapshotFile
+	p.lock.RUnlock()
+	if filename == "" || !p.IsRunning() {
+		return
+	}
+
+	snapshot, err := p.Snapshot()
+	if err != nil {
+		utils.LogError(err, fmt.Sprintf("<Pool>: could not snapshot %s", p.id))
+		return
+	}
+	content, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		utils.LogError(err, fmt.Sprintf("<Pool>: could not snapshot %s", p.id))
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		utils.LogError(err, fmt.Sprintf("<Pool>: could not save snapshot %s", filename))
+		return
+	}
+	temporary := filename + ".tmp"
+	if err := ioutil.WriteFile(temporary, content, 0644); err != nil {
+		utils.LogError(err, fmt.Sprintf("<Pool>: could not save snapshot %s", filename))
+		return
+	}
+	if err := os.Rename(temporary, filename); err != nil {
+		utils.LogError(err, fmt.Sprintf("<Pool>: could not save snapshot %s", filename))
+	}
+}
+
+// keepSnapshot saves the pool to filename until the pool is stopped
+func (p *Pool) keepSnapshot(filename string) {
+	p.lock.Lock()
+	if p.snapshotFile != "" {
+		p.lock.Unlock()
+		return
+	}
+	p.snapshotFile = filename
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	p.stopSnapshots = func() {
+		close(stop)
+		<-stopped
+	}
+	p.lock.Unlock()
+
+	ticker := time.NewTicker(SnapshotInterval)
+	go func() {
+		defer close(stopped)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.saveSnapshot()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,110 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"trading/ledger"
+	"trading/names"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// configTrader adds a lock for each config at price when it runs
+type configTrader struct {
+	configs     []names.TradeConfig
+	price       float64
+	lockManager names.LockManagerInterface
+}
+
+func (t *configTrader) Run() {
+	for _, config := range t.configs {
+		t.lockManager.AddLock(config, t.price)
+	}
+}
+func (t *configTrader) Done(config names.TradeConfig, lock names.LockInterface) {}
+func (t *configTrader) SetExecutor(executor names.ExecutorFunc) names.Trader {
+	return t
+}
+func (t *configTrader) SetLockManager(lockManager names.LockManagerInterface) names.Trader {
+	t.lockManager = lockManager
+	return t
+}
+func (t *configTrader) AddConfig(config names.TradeConfig) {}
+func (t *configTrader) RemoveConfig(config names.TradeConfig) bool {
+	return false
+}
+func (t *configTrader) Snapshot() any {
+	return t.configs
+}
+
+func TestSnapshotResume(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "pool.json")
+	config := names.TradeConfig{
+		Id:     "cyclic",
+		Symbol: "BTCUSDT",
+		Side:   names.TradeSideSell,
+		Sell:   names.SideConfig{LimitType: names.RatePercent, StopLimit: 1, LockDelta: 1, MustProfit: true},
+	}
+
+	tm := NewTradeManager(&configTrader{configs: []names.TradeConfig{config}, price: 100}).
+		UseLedger(ledger.New(ledger.FIFO)).
+		UsePriority(names.TradeSideBuy).
+		UseSnapshotFile(filename).
+		DoTrade()
+	tm.lockManager.RetrieveLock(config).TryLockPrice(104)
+	tm.Pool().tradeCompleted()
+	tm.Pool().saveSnapshot()
+
+	snapshot, err := LoadSnapshot(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, tm.Pool().Id(), snapshot.PoolId)
+	assert.EqualValues(t, 1, snapshot.TradesCompleted)
+	assert.Equal(t, names.TradeSideBuy, snapshot.PrioritySide)
+	assert.Equal(t, []names.LockRecord{{
+		ConfigId:      "cyclic",
+		Symbol:        "BTCUSDT",
+		Side:          names.TradeSideSell,
+		Price:         104,
+		PretradePrice: 100,
+		AccrudGains:   104,
+	}}, snapshot.Locks)
+
+	var configs []names.TradeConfig
+	assert.Nil(t, snapshot.TraderState(&configs))
+	assert.Equal(t, []names.TradeConfig{config}, configs)
+
+	// the process restarted and the price is now 90
+	resumed := NewTradeManager(&configTrader{configs: configs, price: 90}).UseLedger(ledger.New(ledger.FIFO)).Resume(snapshot).DoTrade()
+	state := resumed.lockManager.RetrieveLock(config).GetLockState()
+	assert.EqualValues(t, 100, state.PretradePrice, "the lock keeps the price it started from")
+	assert.EqualValues(t, 104, state.AccrudGains)
+	assert.Equal(t, snapshot.PoolId, resumed.Pool().Id())
+	assert.EqualValues(t, 1, resumed.Pool().TradesCompleted())
+	assert.Equal(t, names.TradeSideBuy, resumed.prioritySide)
+
+	moved := config
+	moved.Side = names.TradeSideBuy
+	fresh := NewTradeManager(&configTrader{configs: []names.TradeConfig{moved}, price: 90}).UseLedger(ledger.New(ledger.FIFO)).Resume(snapshot).DoTrade()
+	assert.EqualValues(t, 90, fresh.lockManager.RetrieveLock(moved).GetLockState().PretradePrice, "a record of another side is not restored")
+}
+
+func TestSnapshotRemovedOnStop(t *testing.T) {
+	previous := SnapshotInterval
+	SnapshotInterval = time.Millisecond
+	defer func() { SnapshotInterval = previous }()
+
+	filename := filepath.Join(t.TempDir(), "pool.json")
+	config := names.TradeConfig{Id: "stop", Symbol: "BTCUSDT", Side: names.TradeSideSell}
+	tm := NewTradeManager(&configTrader{configs: []names.TradeConfig{config}, price: 100}).
+		UseLedger(ledger.New(ledger.FIFO)).
+		UseSnapshotFile(filename).
+		DoTrade()
+	time.Sleep(10 * time.Millisecond)
+	tm.Pool().Stop()
+	time.Sleep(10 * time.Millisecond)
+
+	_, err := os.Stat(filename)
+	assert.True(t, os.IsNotExist(err), "the ticker does not write the snapshot back after stop")
+}
@@ -0,0 +1,100 @@
+package screener
+
+import (
+	"trading/names"
+	"trading/trade/graph"
+)
+
+type Filter struct {
+	Name string
+	Pass func(c *Candidate) bool
+}
+
+// within reports whether value is between min and max, a max of zero has no limit
+func within(value, min, max float64) bool {
+	return value >= min && (max == 0 || value <= max)
+}
+
+func QuoteAsset(asset string) Filter {
+	return Filter{Name: "quoteAsset", Pass: func(c *Candidate) bool {
+		return c.Symbol.ParseTradingPair().Quote == asset
+	}}
+}
+
+// PriceChange keeps symbols whose 24 hour change is strictly between min and max percent
+func PriceChange(min, max float64) Filter {
+	return Filter{Name: "priceChange", Pass: func(c *Candidate) bool {
+		change := c.Ticker.PriceChangePercent
+		return change > min && change < max
+	}}
+}
+
+func MinQuoteVolume(volume float64) Filter {
+	return Filter{Name: "quoteVolume", Pass: func(c *Candidate) bool {
+		return c.Ticker.QuoteVolume >= volume
+	}}
+}
+
+func MinTrades(count int64) Filter {
+	return Filter{Name: "trades", Pass: func(c *Candidate) bool {
+		return c.Ticker.Count >= count
+	}}
+}
+
+// MaxSpread keeps symbols whose spread is known and at most percent
+func MaxSpread(percent float64) Filter {
+	return Filter{Name: "spread", Pass: func(c *Candidate) bool {
+		spread := c.Spread()
+		return spread >= 0 && spread <= percent
+	}}
+}
+
+// Volatility keeps symbols whose 24 hour range is between min and max percent
+func Volatility(min, max float64) Filter {
+	return Filter{Name: "volatility", Pass: func(c *Candidate) bool {
+		return within(c.Volatility(), min, max)
+	}}
+}
+
+// RSI keeps symbols whose index on the graph candles is between min and max
+func RSI(min, max float64) Filter {
+	return Filter{Name: "rsi", Pass: func(c *Candidate) bool {
+		return within(c.Indicators().RSI.Value(), min, max)
+	}}
+}
+
+// Trend keeps symbols whose graph is in one of trends
+func Trend(trends ...graph.TrendType) Filter {
+	return Filter{Name: "trend", Pass: func(c *Candidate) bool {
+		trend := c.Graph().DetermineTrend()
+		for _, t := range trends {
+			if t == trend {
+				return true
+			}
+		}
+		return false
+	}}
+}
+
+func symbolSet(symbols []names.Symbol) map[names.Symbol]bool {
+	set := map[names.Symbol]bool{}
+	for _, s := range symbols {
+		set[s] = true
+	}
+	return set
+}
+
+func Blacklist(symbols ...names.Symbol) Filter {
+	set := symbolSet(symbols)
+	return Filter{Name: "blacklist", Pass: func(c *Candidate) bool {
+		return !set[c.Symbol]
+	}}
+}
+
+// Whitelist only keeps symbols
+func Whitelist(symbols ...names.Symbol) Filter {
+	set := symbolSet(symbols)
+	return Filter{Name: "whitelist", Pass: func(c *Candidate) bool {
+		return set[c.Symbol]
+	}}
+}
@@ -0,0 +1,44 @@
+package screener
+
+import "math"
+
+func ByPriceChange(c *Candidate) float64 {
+	return c.Ticker.PriceChangePercent
+}
+
+func ByQuoteVolume(c *Candidate) float64 {
+	return c.Ticker.QuoteVolume
+}
+
+func ByTrades(c *Candidate) float64 {
+	return float64(c.Ticker.Count)
+}
+
+func ByVolatility(c *Candidate) float64 {
+	return c.Volatility()
+}
+
+// ByOversold favours the lowest RSI of the graph candles
+func ByOversold(c *Candidate) float64 {
+	return 100 - c.Indicators().RSI.Value()
+}
+
+// ByLiquidity favours volume and penalises the spread, symbols without a
+// known spread rank last
+func ByLiquidity(c *Candidate) float64 {
+	spread := c.Spread()
+	if spread < 0 {
+		return math.Inf(-1)
+	}
+	return math.Log10(1+c.Ticker.QuoteVolume) - spread
+}
+
+// Scores by the name a config refers to them
+var Scores = map[string]Score{
+	"change":     ByPriceChange,
+	"volume":     ByQuoteVolume,
+	"trades":     ByTrades,
+	"volatility": ByVolatility,
+	"oversold":   ByOversold,
+	"liquidity":  ByLiquidity,
+}
@@ -0,0 +1,172 @@
+// Package screener picks the symbols the stable traders trade. The tickers of
+// the exchange pass a chain of filters and the symbols left are ranked by a score
+package screener
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"trading/exchange"
+	"trading/indicators"
+	"trading/names"
+	"trading/trade/graph"
+	"trading/utils"
+)
+
+// Candidate is a symbol being screened, the graph and indicators are only
+// fetched when a filter or the score asks for them
+type Candidate struct {
+	Symbol     names.Symbol
+	Ticker     exchange.Ticker
+	interval   string
+	datapoints int
+	graph      *graph.Graph
+	indicators *indicators.Set
+}
+
+// Spread between the best bid and ask in percent of their middle, -1 when unknown
+func (c *Candidate) Spread() float64 {
+	bid, ask := c.Ticker.BidPrice, c.Ticker.AskPrice
+	if bid <= 0 || ask <= 0 {
+		return -1
+	}
+	return (ask - bid) / ((ask + bid) / 2) * 100
+}
+
+// Volatility is the 24 hour range in percent of the low, -1 when unknown
+func (c *Candidate) Volatility() float64 {
+	if c.Ticker.LowPrice <= 0 {
+		return -1
+	}
+	return (c.Ticker.HighPrice - c.Ticker.LowPrice) / c.Ticker.LowPrice * 100
+}
+
+func (c *Candidate) Graph() *graph.Graph {
+	if c.graph == nil {
+		c.graph = graph.NewBinanceGraph(c.Symbol.String(), c.interval, c.datapoints)
+	}
+	return c.graph
+}
+
+func (c *Candidate) Indicators() *indicators.Set {
+	if c.indicators == nil {
+		c.indicators = c.Graph().Indicators()
+	}
+	return c.indicators
+}
+
+// Score ranks the candidates that passed the filters, the highest first
+type Score func(c *Candidate) float64
+
+type Result struct {
+	Symbol names.Symbol
+	Score  float64
+}
+
+type Screener struct {
+	name       string
+	filters    []Filter
+	score      Score
+	limit      int
+	interval   string
+	datapoints int
+}
+
+func New(name string) *Screener {
+	return &Screener{name: name, score: ByPriceChange, interval: "15m", datapoints: 30}
+}
+
+// Default is the screener of a stable trader without a named screener,
+// symbols of quoteAsset whose 24 hour change is within minChange and maxChange
+func Default(quoteAsset string, minChange, maxChange float64) *Screener {
+	return New("default").UseFilters(QuoteAsset(quoteAsset), PriceChange(minChange, maxChange))
+}
+
+func (s *Screener) Name() string {
+	return s.name
+}
+
+// UseFilters adds filters to the screener, they run in order so cheap
+// filters should come before the ones that need the graph
+func (s *Screener) UseFilters(filters ...Filter) *Screener {
+	s.filters = append(s.filters, filters...)
+	return s
+}
+
+func (s *Screener) UseScore(score Score) *Screener {
+	s.score = score
+	return s
+}
+
+// UseLimit keeps the best limit symbols, every symbol is kept when zero
+func (s *Screener) UseLimit(limit int) *Screener {
+	s.limit = limit
+	return s
+}
+
+// UseGraph sets the candles the graph filters and scores look at
+func (s *Screener) UseGraph(interval string, datapoints int) *Screener {
+	s.interval, s.datapoints = interval, datapoints
+	return s
+}
+
+func (s *Screener) passes(c *Candidate) bool {
+	for _, filter := range s.filters {
+		if !filter.Pass(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// Screen ranks the tickers that pass every filter, the limit is not applied
+func (s *Screener) Screen(tickers []exchange.Ticker) []Result {
+	results := []Result{}
+	for _, ticker := range tickers {
+		c := &Candidate{Symbol: names.Symbol(ticker.Symbol), Ticker: ticker, interval: s.interval, datapoints: s.datapoints}
+		if s.passes(c) {
+			results = append(results, Result{Symbol: c.Symbol, Score: s.score(c)})
+		}
+	}
+	sort.SliceStable(results, func(a, b int) bool { return results[a].Score > results[b].Score })
+	return results
+}
+
+// Run screens the tickers of the exchange and returns the best symbols that can be spot traded
+func (s *Screener) Run() []names.Symbol {
+	tickers, err := exchange.Get().Tickers()
+	if err != nil {
+		utils.LogError(err, fmt.Sprintf("<Screener>: %s could not get the tickers", s.name))
+		return []names.Symbol{}
+	}
+
+	symbols := []names.Symbol{}
+	for _, result := range s.Screen(tickers) {
+		symbols = append(symbols, result.Symbol)
+	}
+	symbols = names.GetNewInfo().FilterSpotable(symbols)
+	if s.limit > 0 && len(symbols) > s.limit {
+		symbols = symbols[:s.limit]
+	}
+	utils.LogInfo(fmt.Sprintf("<Screener>: %s picked %v", s.name, symbols))
+	return symbols
+}
+
+var (
+	screeners = map[string]*Screener{}
+	lock      sync.RWMutex
+)
+
+// Register makes the screener available to stable traders by its name
+func Register(s *Screener) {
+	lock.Lock()
+	defer lock.Unlock()
+	screeners[s.name] = s
+}
+
+func Get(name string) (*Screener, bool) {
+	lock.RLock()
+	defer lock.RUnlock()
+	s, exist := screeners[name]
+	return s, exist
+}
@@ -0,0 +1,78 @@
+package screener
+
+import (
+	"testing"
+	"trading/exchange"
+	"trading/names"
+	"trading/trade/graph"
+	"trading/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func useSymbols() {
+	info := exchange.ExchangeInfo{}
+	for _, s := range []string{"BTC", "ETH", "DOGE", "SHIB"} {
+		info.Symbols = append(info.Symbols, exchange.SymbolInfo{Symbol: s + "USDT", BaseAsset: s, QuoteAsset: "USDT"})
+	}
+	info.Symbols = append(info.Symbols, exchange.SymbolInfo{Symbol: "ETHBTC", BaseAsset: "ETH", QuoteAsset: "BTC"})
+	names.UseExchangeInfo(info)
+}
+
+var tickers = []exchange.Ticker{
+	{Symbol: "BTCUSDT", PriceChangePercent: 5, QuoteVolume: 900e6, Count: 900000, BidPrice: 99.99, AskPrice: 100.01, HighPrice: 104, LowPrice: 96},
+	{Symbol: "ETHUSDT", PriceChangePercent: 8, QuoteVolume: 400e6, Count: 500000, BidPrice: 99.95, AskPrice: 100.05, HighPrice: 110, LowPrice: 95},
+	{Symbol: "DOGEUSDT", PriceChangePercent: 25, QuoteVolume: 50e6, Count: 90000, BidPrice: 99.5, AskPrice: 100.5, HighPrice: 140, LowPrice: 90},
+	{Symbol: "SHIBUSDT", PriceChangePercent: 12, QuoteVolume: 1e6, Count: 2000},
+	{Symbol: "ETHBTC", PriceChangePercent: 9, QuoteVolume: 80e6, Count: 100000, BidPrice: 0.05, AskPrice: 0.0501},
+}
+
+func symbolsOf(results []Result) []names.Symbol {
+	symbols := []names.Symbol{}
+	for _, r := range results {
+		symbols = append(symbols, r.Symbol)
+	}
+	return symbols
+}
+
+func TestDefaultScreener(t *testing.T) {
+	useSymbols()
+	results := Default("USDT", 6, 30).Screen(tickers)
+	assert.Equal(t, []names.Symbol{"DOGEUSDT", "SHIBUSDT", "ETHUSDT"}, symbolsOf(results), "ranked by the price change")
+}
+
+func TestFilters(t *testing.T) {
+	useSymbols()
+	s := New("liquid").UseFilters(QuoteAsset("USDT"), MinQuoteVolume(10e6), MinTrades(50000), MaxSpread(0.5))
+	assert.Equal(t, []names.Symbol{"ETHUSDT", "BTCUSDT"}, symbolsOf(s.Screen(tickers)), "DOGEUSDT spread is 1%")
+
+	s = New("calm").UseFilters(Volatility(0, 20), Blacklist("ETHUSDT")).UseScore(ByQuoteVolume)
+	assert.Equal(t, []names.Symbol{"BTCUSDT"}, symbolsOf(s.Screen(tickers)), "symbols without a range have no volatility")
+
+	s = New("picked").UseFilters(Whitelist("SHIBUSDT", "ETHBTC")).UseScore(ByTrades)
+	assert.Equal(t, []names.Symbol{"ETHBTC", "SHIBUSDT"}, symbolsOf(s.Screen(tickers)))
+
+	s = New("liquidity").UseFilters(QuoteAsset("USDT")).UseScore(ByLiquidity)
+	assert.Equal(t, []names.Symbol{"BTCUSDT", "ETHUSDT", "DOGEUSDT", "SHIBUSDT"}, symbolsOf(s.Screen(tickers)))
+}
+
+func TestGraphFilters(t *testing.T) {
+	utils.Env().SetModeMock()
+	useSymbols()
+	// the mock candles are flat
+	s := New("range").UseFilters(Whitelist("BTCUSDT"), Trend(graph.Range), RSI(40, 60))
+	assert.Equal(t, []names.Symbol{"BTCUSDT"}, symbolsOf(s.Screen(tickers)))
+
+	s = New("uptrend").UseFilters(Whitelist("BTCUSDT"), Trend(graph.Uptrend, graph.Breakout))
+	assert.Empty(t, s.Screen(tickers))
+}
+
+func TestRegister(t *testing.T) {
+	Register(New("registered").UseLimit(1))
+	s, exist := Get("registered")
+	assert.True(t, exist)
+	assert.Equal(t, "registered", s.Name())
+
+	_, exist = Get("missing")
+	assert.False(t, exist)
+}
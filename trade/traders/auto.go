@@ -3,7 +3,7 @@ package traders
 //Always force trades with percentage
 import (
 	"fmt"
-	"trading/binance"
+	"trading/exchange"
 	"trading/helper"
 	"trading/names"
 	"trading/stream"
@@ -106,7 +106,7 @@ func (tm *autoTrader) Done(config names.TradeConfig, locker names.LockInterface)
 
 			// destroy other configs so they can get new price
 			// recreate the completed config with sides switched
-			go NewAutoTrade(tm.tradeConfigs, tm.datapoints, tm.interval).DoTrade()
+			manager.Restart(tm, NewAutoTrade(tm.tradeConfigs, tm.datapoints, tm.interval))
 			return
 		}
 
@@ -148,7 +148,7 @@ func (trader *autoTrader) Watch(config names.TradeConfig) {
 	lockManager := trader.tradeLockManager
 
 	subscription := trader.broadcast.Subscribe(config)
-	pretradePrice := binance.GetPriceLatest(config.Symbol.String())
+	pretradePrice := exchange.GetPriceLatest(config.Symbol.String())
 	configLocker := lockManager.AddLock(config, pretradePrice) //we mayy not need stop for sell
 
 	configLocker.SetRedemptionCandidateCallback(func(l names.LockInterface) {
@@ -164,10 +164,12 @@ func (trader *autoTrader) Watch(config names.TradeConfig) {
 	})
 
 	deviationManager := deviation.NewDeviationManager(trader, configLocker)
-	for sub := range subscription.GetChannel() {
+	stops := newLiveStops(config.Symbol, trader.interval, trader.datapoints)
+	subscription.Each(func(sub stream.SymbolPriceData) {
 		go deviationManager.CheckDeviation(&subscription)
-		configLocker.TryLockPrice(sub.Price)
-	}
+		stops.follow(configLocker, sub)
+		tryLockPrice(configLocker, sub)
+	})
 }
 
 func NewAutoTrade(configs []names.TradeConfig, datapoints int, interval string) *manager.TradeManager {
@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"sync"
 	"time"
-	"trading/binance"
+	"trading/exchange"
 	"trading/helper"
 	"trading/names"
 	"trading/stream"
@@ -102,8 +102,10 @@ func (tm *autoStable) UstradeTrend(trend graph.TrendType) *autoStable {
 	panic("Unsupported action")
 }
 
+// the contention time of a resumed config is kept so that its
+// contention does not start over after a restart
 func (tm *autoStable) setConfigContentionTime(config names.TradeConfig) {
-	tm.contentionTime.Store(config.Id, time.Now())
+	tm.contentionTime.LoadOrStore(config.Id, utils.Now())
 }
 
 // renit this contention if the time is elapsed a contention can only run for
@@ -236,7 +238,7 @@ func (tm *autoStable) Watch(config names.TradeConfig) {
 	tm.setConfigContentionTime(config)
 	executor := tm.executorFunc
 	subscription := tm.broadcast.Subscribe(config)
-	pretradePrice := binance.GetPriceLatest(config.Symbol.String())
+	pretradePrice := exchange.GetPriceLatest(config.Symbol.String())
 	configLocker := tm.tradeLockManager.AddLock(config, pretradePrice)
 
 	configLocker.SetRedemptionCandidateCallback(func(l names.LockInterface) {
@@ -272,10 +274,10 @@ func (tm *autoStable) Watch(config names.TradeConfig) {
 		return renitTradeConfig(config, tm.initParams)
 	})
 
-	for sub := range subscription.GetChannel() {
+	subscription.Each(func(sub stream.SymbolPriceData) {
 
 		if tm.isContentionTimeUp(config) {
-			continue
+			return
 		}
 
 		if tm.status == StatusFullfilment && tm.fullfillId != config.Id {
@@ -286,7 +288,7 @@ func (tm *autoStable) Watch(config names.TradeConfig) {
 			deviation.CheckDeviation(&subscription)
 		}
 
-		configLocker.TryLockPrice(sub.Price)
+		tryLockPrice(configLocker, sub)
 
 		if tm.status == StatusFullfilment && tm.fullfillId != subscription.State().TradingConfig.Id {
 			configLocker.SetVerbose(false)
@@ -294,7 +296,51 @@ func (tm *autoStable) Watch(config names.TradeConfig) {
 			configLocker.SetVerbose(true)
 		}
 
+	})
+}
+
+// AutoStableSnapshot is the state needed to resume an autoStable trader
+type AutoStableSnapshot struct {
+	InitParams     StableTradeParam     `json:"initParams"`
+	Configs        []names.TradeConfig  `json:"configs"`
+	BestSide       names.TradeSide      `json:"bestSide"`
+	Status         status               `json:"status"`
+	FullfillId     string               `json:"fullfillId"`
+	ContentionTime map[string]time.Time `json:"contentionTime"`
+}
+
+func (tm *autoStable) Snapshot() any {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	snapshot := AutoStableSnapshot{
+		InitParams:     tm.initParams,
+		Configs:        append([]names.TradeConfig{}, tm.tradingConfigs...),
+		BestSide:       tm.bestSide,
+		Status:         tm.status,
+		FullfillId:     tm.fullfillId,
+		ContentionTime: map[string]time.Time{},
+	}
+	tm.contentionTime.Range(func(key, value interface{}) bool {
+		snapshot.ContentionTime[key.(string)] = value.(time.Time)
+		return true
+	})
+	return snapshot
+}
+
+// ResumeAutoStableTrader rebuilds the trader saved in snapshot with its status,
+// fullfilment config and contention times
+func ResumeAutoStableTrader(snapshot manager.Snapshot) (*manager.TradeManager, error) {
+	var state AutoStableSnapshot
+	if err := snapshot.TraderState(&state); err != nil {
+		return nil, err
+	}
+	trader := createAutoStable(state.InitParams, state.Configs, state.Status, state.BestSide)
+	trader.fullfillId = state.FullfillId
+	for id, contentionTime := range state.ContentionTime {
+		trader.contentionTime.Store(id, contentionTime)
 	}
+	return manager.NewTradeManager(trader).Resume(snapshot), nil
 }
 
 // CuncurrentTrades
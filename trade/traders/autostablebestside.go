@@ -3,7 +3,7 @@ package traders
 import (
 	"fmt"
 	"sync"
-	"trading/binance"
+	"trading/exchange"
 	"trading/names"
 	"trading/stream"
 	"trading/trade/deviation"
@@ -146,21 +146,21 @@ func (tm *stableBestSide_v1) Done(tradedConfig names.TradeConfig, locker names.L
 		}
 
 		fullfilConfig.Side = tm.bestSide
-		tm.stableTrader(
+		manager.Continue(tm, tm.stableTrader(
 			tm.initConfigs,
 			tm.bestSide,
 			nextStatus,
 			fullfilConfig,
-		).DoTrade()
+		))
 		return
 	} else {
 		//Run operation to choose the best side
-		tm.stableTrader(
+		manager.Continue(tm, tm.stableTrader(
 			tm.initConfigs,
 			tm.bestSide,
 			nextStatus,
 			names.TradeConfig{},
-		).DoTrade()
+		))
 	}
 }
 
@@ -173,7 +173,7 @@ func (trader *stableBestSide_v1) Watch(config names.TradeConfig) {
 	executor := trader.executorFunc
 	subscription := trader.broadcast.Subscribe(config)
 
-	pretradePrice := binance.GetPriceLatest(config.Symbol.String())
+	pretradePrice := exchange.GetPriceLatest(config.Symbol.String())
 	configLocker := trader.tradeLockManager.AddLock(config, pretradePrice)
 
 	trader.lock.Lock()
@@ -208,7 +208,7 @@ func (trader *stableBestSide_v1) Watch(config names.TradeConfig) {
 		return stableConfig[0]
 	})
 
-	for sub := range subscription.GetChannel() {
+	subscription.Each(func(sub stream.SymbolPriceData) {
 		if trader.status == StatusContention {
 
 			// Deviation is executed selectively, specifically when the status is in contention.
@@ -216,8 +216,8 @@ func (trader *stableBestSide_v1) Watch(config names.TradeConfig) {
 			// TODO provide configuration to either enable or disable this behaviour
 			go deviation.CheckDeviation(&subscription)
 		}
-		configLocker.TryLockPrice(sub.Price)
-	}
+		tryLockPrice(configLocker, sub)
+	})
 }
 
 // bestSide the side that the contention will fall to after the parallel side finds a candidate
@@ -3,7 +3,7 @@ package traders
 import (
 	"fmt"
 	"sync"
-	"trading/binance"
+	"trading/exchange"
 	"trading/names"
 	"trading/stream"
 	"trading/trade/deviation"
@@ -143,7 +143,7 @@ func (tm *autoStableBestSide) Done(tradedConfig names.TradeConfig, locker names.
 			nextStatus,
 			fullfilConfig,
 		)
-		trader.DoTrade()
+		manager.Continue(tm, trader)
 		return
 	} else {
 		//Run operation to choose the best side
@@ -155,7 +155,7 @@ func (tm *autoStableBestSide) Done(tradedConfig names.TradeConfig, locker names.
 			nextStatus,
 			names.TradeConfig{},
 		)
-		trader.DoTrade()
+		manager.Continue(tm, trader)
 	}
 }
 
@@ -168,7 +168,7 @@ func (trader *autoStableBestSide) Watch(config names.TradeConfig) {
 	executor := trader.executorFunc
 	subscription := trader.broadcast.Subscribe(config)
 
-	pretradePrice := binance.GetPriceLatest(config.Symbol.String())
+	pretradePrice := exchange.GetPriceLatest(config.Symbol.String())
 	configLocker := trader.tradeLockManager.AddLock(config, pretradePrice)
 
 	trader.lock.Lock()
@@ -203,7 +203,7 @@ func (trader *autoStableBestSide) Watch(config names.TradeConfig) {
 		return stableConfig[0]
 	})
 
-	for sub := range subscription.GetChannel() {
+	subscription.Each(func(sub stream.SymbolPriceData) {
 		// if trader.status == StatusContention {
 
 		// Deviation is executed selectively, specifically when the status is in contention.
@@ -211,8 +211,8 @@ func (trader *autoStableBestSide) Watch(config names.TradeConfig) {
 		// TODO provide configuration to either enable or disable this behaviour
 		go deviation.CheckDeviation(&subscription)
 		// }
-		configLocker.TryLockPrice(sub.Price)
-	}
+		tryLockPrice(configLocker, sub)
+	})
 }
 
 // bestSide the side that the contention will fall to after the parallel side finds a candidate
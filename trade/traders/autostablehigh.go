@@ -3,12 +3,14 @@ package traders
 import (
 	"sync"
 	"time"
-	"trading/binance"
+	"trading/exchange"
 	"trading/names"
 	"trading/stream"
 	"trading/trade/deviation"
 	"trading/trade/graph"
 	"trading/trade/manager"
+	"trading/utils"
+
 	"github.com/google/uuid"
 )
 
@@ -92,7 +94,7 @@ func (tm *autoStableBuyHigh) UstradeTrend(trend graph.TrendType) *autoStableBuyH
 }
 
 func (tm *autoStableBuyHigh) setConfigContentionTime(config names.TradeConfig) {
-	tm.contentionTime.Store(config.Id, time.Now())
+	tm.contentionTime.Store(config.Id, utils.Now())
 }
 
 // renit this contention if the time is elapsed a contention can only run for
@@ -199,7 +201,7 @@ func (tm *autoStableBuyHigh) Watch(config names.TradeConfig) {
 	tm.setConfigContentionTime(config)
 	executor := tm.executorFunc
 	subscription := tm.broadcast.Subscribe(config)
-	pretradePrice := binance.GetPriceLatest(config.Symbol.String())
+	pretradePrice := exchange.GetPriceLatest(config.Symbol.String())
 	configLocker := tm.tradeLockManager.AddLock(config, pretradePrice)
 
 	configLocker.SetRedemptionCandidateCallback(func(l names.LockInterface) {
@@ -244,15 +246,15 @@ func (tm *autoStableBuyHigh) Watch(config names.TradeConfig) {
 		return cfg
 	})
 
-	for sub := range subscription.GetChannel() {
+	subscription.Each(func(sub stream.SymbolPriceData) {
 
 		if tm.isContentionTimeUp(config) {
-			continue
+			return
 		}
 
 		deviation.CheckDeviation(&subscription)
-		configLocker.TryLockPrice(sub.Price)
-	}
+		tryLockPrice(configLocker, sub)
+	})
 }
 
 func NewAutoStableBuyHighTrader(initParams StableTradeParam) *manager.TradeManager {
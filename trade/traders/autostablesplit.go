@@ -3,7 +3,7 @@ package traders
 import (
 	"fmt"
 	"sync"
-	"trading/binance"
+	"trading/exchange"
 	"trading/helper"
 	"trading/names"
 	"trading/stream"
@@ -154,7 +154,7 @@ func (trader *autoStableSplit) Watch(config names.TradeConfig) {
 	executor := trader.executorFunc
 	subscription := trader.broadcast.Subscribe(config)
 
-	pretradePrice := binance.GetPriceLatest(config.Symbol.String())
+	pretradePrice := exchange.GetPriceLatest(config.Symbol.String())
 	configLocker := trader.tradeLockManager.AddLock(config, pretradePrice)
 
 	trader.lock.Lock()
@@ -192,7 +192,7 @@ func (trader *autoStableSplit) Watch(config names.TradeConfig) {
 		return stableConfig[0]
 	})
 
-	for sub := range subscription.GetChannel() {
+	subscription.Each(func(sub stream.SymbolPriceData) {
 		// if trader.status == StatusContention {
 
 		// Deviation is executed selectively, specifically when the status is in contention.
@@ -200,8 +200,8 @@ func (trader *autoStableSplit) Watch(config names.TradeConfig) {
 		// TODO provide configuration to either enable or disable this behaviour
 		go deviation.CheckDeviation(&subscription)
 		// }
-		configLocker.TryLockPrice(sub.Price)
-	}
+		tryLockPrice(configLocker, sub)
+	})
 }
 
 // CuncurrentTrades
@@ -3,7 +3,7 @@ package traders
 import (
 	"fmt"
 	"sync"
-	"trading/binance"
+	"trading/exchange"
 	"trading/helper"
 	"trading/names"
 	"trading/stream"
@@ -123,25 +123,25 @@ func (tm *bestSideTrader) Done(bestConfig names.TradeConfig, locker names.LockIn
 		// the other list of configurations
 		bestSideConfig := bestConfig
 		bestSideConfig.Side = tm.bestSide
-		NewBestSideTrade(
+		manager.Continue(tm, NewBestSideTrade(
 			tm.tradeConfigs,
 			tm.datapoints,
 			tm.interval,
 			tm.bestSide,
 			nextStatus,
 			bestSideConfig,
-		).DoTrade()
+		))
 		return
 	} else {
 		//Run operation to choose the best side
-		NewBestSideTrade(
+		manager.Continue(tm, NewBestSideTrade(
 			tm.tradeConfigs,
 			tm.datapoints,
 			tm.interval,
 			tm.bestSide,
 			nextStatus,
 			names.TradeConfig{},
-		).DoTrade()
+		))
 	}
 }
 
@@ -154,7 +154,7 @@ func (trader *bestSideTrader) Watch(config names.TradeConfig) {
 	executor := trader.executorFunc
 	subscription := trader.broadcast.Subscribe(config)
 
-	pretradePrice := binance.GetPriceLatest(config.Symbol.String())
+	pretradePrice := exchange.GetPriceLatest(config.Symbol.String())
 	configLocker := trader.tradeLockManager.AddLock(config, pretradePrice)
 
 	trader.lock.Lock()
@@ -174,16 +174,18 @@ func (trader *bestSideTrader) Watch(config names.TradeConfig) {
 	})
 
 	deviation := deviation.NewDeviationManager(trader, configLocker)
+	stops := newLiveStops(config.Symbol, trader.interval, trader.datapoints)
 
-	for sub := range subscription.GetChannel() {
+	subscription.Each(func(sub stream.SymbolPriceData) {
 		if trader.status == StatusContention {
 
 			//We only want to run deviation when the status is in contention
 			// to avoid loosing gains while fulliling our contention
 			go deviation.CheckDeviation(&subscription)
 		}
-		configLocker.TryLockPrice(sub.Price)
-	}
+		stops.follow(configLocker, sub)
+		tryLockPrice(configLocker, sub)
+	})
 }
 
 type AutoBestBestSideConfig struct {
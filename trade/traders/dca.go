@@ -0,0 +1,394 @@
+package traders
+
+import (
+	"fmt"
+	"sync"
+	"trading/ledger"
+	"trading/names"
+	"trading/stream"
+	"trading/trade/manager"
+	"trading/utils"
+
+	"github.com/google/uuid"
+)
+
+// DCATradeParam buys a base order of every symbol and averages down with
+// safety orders as the price drops, the whole position is sold once the
+// price trails back from TakeProfit percent above its average entry
+type DCATradeParam struct {
+	// prefix of the config ids of the symbols, a new id when empty
+	Id      string         `json:"id" yaml:"id"`
+	Symbols []names.Symbol `json:"symbols" yaml:"symbols"`
+	// quote amount of the first buy of a symbol
+	BaseOrder float64 `json:"baseOrder" yaml:"baseOrder"`
+	// quote amount of the first safety order, every next one is VolumeScale
+	// times the last
+	SafetyOrder  float64 `json:"safetyOrder" yaml:"safetyOrder"`
+	SafetyOrders int     `json:"safetyOrders" yaml:"safetyOrders"`
+	VolumeScale  float64 `json:"volumeScale" yaml:"volumeScale"`
+	// percent below the base order the first safety order buys at, every
+	// next step is StepScale times the last
+	StepPercent float64 `json:"stepPercent" yaml:"stepPercent"`
+	StepScale   float64 `json:"stepScale" yaml:"stepScale"`
+	// percent above the average entry the exit lock starts trailing at, it
+	// sells once the price falls LockDelta percent from its peak
+	TakeProfit float64 `json:"takeProfit" yaml:"takeProfit"`
+	LockDelta  float64 `json:"lockDelta" yaml:"lockDelta"`
+	// quote amount a symbol may have invested at most, MaxInvestments sets
+	// it by symbol. No cap when zero
+	MaxInvestment  float64                  `json:"maxInvestment" yaml:"maxInvestment"`
+	MaxInvestments map[names.Symbol]float64 `json:"maxInvestments" yaml:"maxInvestments"`
+	// a sold symbol starts over with a new base order
+	Cyclic    bool              `json:"cyclic" yaml:"cyclic"`
+	BuyOrder  names.OrderConfig `json:"buyOrder" yaml:"buyOrder"`
+	SellOrder names.OrderConfig `json:"sellOrder" yaml:"sellOrder"`
+}
+
+// SafetyOrderAt is the percent below the base order the nth safety order,
+// from 1, buys at and the quote amount it buys
+func (p DCATradeParam) SafetyOrderAt(n int) (deviation, amount float64) {
+	step, volume := p.StepPercent, p.SafetyOrder
+	for i := 1; i <= n; i++ {
+		deviation += step
+		amount = volume
+		step *= scale(p.StepScale)
+		volume *= scale(p.VolumeScale)
+	}
+	return deviation, amount
+}
+
+// Cap is the quote amount symbol may have invested, zero when it has no cap
+func (p DCATradeParam) Cap(symbol names.Symbol) float64 {
+	if cap, exist := p.MaxInvestments[symbol]; exist {
+		return cap
+	}
+	return p.MaxInvestment
+}
+
+func scale(value float64) float64 {
+	if value <= 0 {
+		return 1
+	}
+	return value
+}
+
+// DCAPosition is what the trader holds of a symbol
+type DCAPosition struct {
+	ConfigId string       `json:"configId"`
+	Symbol   names.Symbol `json:"symbol"`
+	// price of the base order, the safety orders are measured from it
+	BasePrice float64 `json:"basePrice"`
+	// safety orders filled since the base order
+	SafetyOrders int     `json:"safetyOrders"`
+	Invested     float64 `json:"invested"`
+	Quantity     float64 `json:"quantity"`
+	// weighted average entry, fees included once the ledger has the fills
+	Average float64 `json:"average"`
+	// price the next safety order buys at, zero when none is left
+	NextSafety float64 `json:"nextSafety"`
+	// positions sold since the trader started
+	Exits int `json:"exits"`
+	// price the pending order was placed at
+	price   float64
+	pending bool
+	stopped bool
+}
+
+func (p DCAPosition) open() bool {
+	return p.Quantity > 0
+}
+
+type DCAReport struct {
+	Positions []DCAPosition `json:"positions"`
+	Invested  float64       `json:"invested"`
+	// realized profit of the positions net of fees, from the ledger
+	Profit float64 `json:"profit"`
+}
+
+type dcaTrader struct {
+	param            DCATradeParam
+	positions        []*DCAPosition
+	executorFunc     names.ExecutorFunc
+	tradeLockManager names.LockManagerInterface
+	broadcast        *stream.Broadcaster
+	lock             sync.Mutex
+}
+
+func getDCATrader(param DCATradeParam) *dcaTrader {
+	if param.Id == "" {
+		param.Id = uuid.New().String()
+	}
+	trader := &dcaTrader{
+		param:     param,
+		broadcast: stream.NewBroadcast(uuid.New().String()),
+	}
+	for _, symbol := range param.Symbols {
+		trader.positions = append(trader.positions, &DCAPosition{
+			ConfigId: fmt.Sprintf("%s_dca_%s", param.Id, symbol),
+			Symbol:   symbol,
+		})
+	}
+	return trader
+}
+
+// config is the trade config of the next order of position, the buy side
+// is the next buy and the sell side the exit of the whole position
+func (t *dcaTrader) config(position *DCAPosition) names.TradeConfig {
+	config := names.TradeConfig{
+		Id:     position.ConfigId,
+		Symbol: position.Symbol,
+		Side:   names.TradeSideBuy,
+		Buy: names.SideConfig{
+			LimitType: names.RatePercent,
+			StopLimit: t.param.StepPercent,
+			Order:     t.param.BuyOrder,
+		},
+		Sell: names.SideConfig{
+			LimitType:  names.RatePercent,
+			StopLimit:  t.param.TakeProfit,
+			LockDelta:  t.param.LockDelta,
+			Quantity:   position.Quantity,
+			MustProfit: true,
+			Order:      t.param.SellOrder,
+		},
+	}
+	if position.open() {
+		config.Side = names.TradeSideSell
+	}
+	return config
+}
+
+func (t *dcaTrader) position(configId string) *DCAPosition {
+	for _, position := range t.positions {
+		if position.ConfigId == configId {
+			return position
+		}
+	}
+	return nil
+}
+
+func (t *dcaTrader) Run() {
+	for _, position := range t.positions {
+		t.Watch(position)
+	}
+}
+
+// Watch subscribes to the prices of position, they are read in the background
+func (t *dcaTrader) Watch(position *DCAPosition) {
+	subscription := t.broadcast.Subscribe(names.TradeConfig{Id: position.ConfigId, Symbol: position.Symbol})
+	go subscription.Each(func(data stream.SymbolPriceData) {
+		if stream.InGap(data.Symbol) {
+			return
+		}
+		t.TryPrice(position, data.Price)
+	})
+}
+
+// TryPrice buys the base order of a flat position or its next safety order
+// once the price drops to it, the exit lock of an open position follows the
+// price until it sells
+func (t *dcaTrader) TryPrice(position *DCAPosition, price float64) {
+	t.lock.Lock()
+	if position.pending || position.stopped {
+		t.lock.Unlock()
+		return
+	}
+	amount := t.nextBuy(position, price)
+	if amount > 0 {
+		position.pending = true
+	}
+	if position.BasePrice == 0 {
+		position.BasePrice = price
+	}
+	position.price = price
+	config := t.config(position)
+	t.lock.Unlock()
+
+	if amount > 0 {
+		config.Side = names.TradeSideBuy
+		config.Buy.Quantity = position.Symbol.Quantity(amount / price)
+		t.executorFunc(config, price, price, func() {
+			t.Done(config, nil)
+		})
+		t.lock.Lock()
+		position.pending = false
+		if !position.open() {
+			// the base order failed, the next one is measured from the next price
+			position.BasePrice = 0
+		}
+		t.lock.Unlock()
+		return
+	}
+
+	if lock := t.tradeLockManager.RetrieveLock(config); lock != nil {
+		lock.TryLockPrice(price)
+	}
+}
+
+// nextBuy is the quote amount the position buys at price, zero when it
+// does not buy
+func (t *dcaTrader) nextBuy(position *DCAPosition, price float64) float64 {
+	amount := t.param.BaseOrder
+	if position.open() {
+		if position.SafetyOrders >= t.param.SafetyOrders || price > position.NextSafety {
+			return 0
+		}
+		_, amount = t.param.SafetyOrderAt(position.SafetyOrders + 1)
+	}
+	if cap := t.param.Cap(position.Symbol); cap > 0 && position.Invested+amount > cap {
+		if position.open() {
+			return 0
+		}
+		utils.LogWarn(fmt.Sprintf("<DCA>: %s base order of %f is above its max investment %f", position.Symbol, amount, cap))
+		position.stopped = true
+		return 0
+	}
+	return amount
+}
+
+// exit places the lock that sells the position once the price trails back
+// from the take profit above its average entry, it replaces the lock placed
+// at the last average
+func (t *dcaTrader) exit(position *DCAPosition) {
+	lock := t.tradeLockManager.AddLock(t.config(position), position.Average)
+	lock.SetRedemptionCandidateCallback(func(l names.LockInterface) {
+		state := l.GetLockState()
+		t.executorFunc(state.TradeConfig, state.Price, state.PretradePrice, func() {
+			t.Done(state.TradeConfig, l)
+		})
+	})
+}
+
+func (t *dcaTrader) SetExecutor(executorFunc names.ExecutorFunc) names.Trader {
+	t.executorFunc = executorFunc
+	return t
+}
+
+func (t *dcaTrader) SetLockManager(tl names.LockManagerInterface) names.Trader {
+	t.tradeLockManager = tl
+	return t
+}
+
+// Budgeted keeps the allocator from resizing the orders, the positions are
+// capped by their max investment
+func (t *dcaTrader) Budgeted() bool {
+	return true
+}
+
+// Done fills the buy of a position or closes it after its exit sold
+func (t *dcaTrader) Done(config names.TradeConfig, locker names.LockInterface) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	position := t.position(config.Id)
+	if position == nil {
+		return
+	}
+
+	if config.Side.IsSell() {
+		if locker != nil {
+			locker.RemoveFromManager()
+		}
+		utils.LogInfo(fmt.Sprintf("<DCA>: %s sold %f after %d safety orders, profit %f",
+			position.Symbol, position.Quantity, position.SafetyOrders, ledger.Default().ConfigPnL(position.ConfigId).Realized))
+		*position = DCAPosition{ConfigId: position.ConfigId, Symbol: position.Symbol, Exits: position.Exits + 1}
+		if !t.param.Cyclic {
+			position.stopped = true
+			t.broadcast.Unsubscribe(names.TradeConfig{Id: position.ConfigId, Symbol: position.Symbol})
+		}
+		return
+	}
+
+	price, quantity := position.price, config.Buy.Quantity
+	if position.open() {
+		position.SafetyOrders++
+	}
+	position.Average = (position.Average*position.Quantity + price*quantity) / (position.Quantity + quantity)
+	position.Quantity += quantity
+	position.Invested += price * quantity
+	if pnl := ledger.Default().ConfigPnL(position.ConfigId); pnl.Quantity > 0 {
+		// what the ledger holds, fees included
+		position.Quantity = pnl.Quantity
+		position.Average = pnl.Cost / pnl.Quantity
+	}
+
+	position.NextSafety = 0
+	if position.SafetyOrders < t.param.SafetyOrders {
+		deviation, _ := t.param.SafetyOrderAt(position.SafetyOrders + 1)
+		position.NextSafety = position.BasePrice * (1 - deviation/100)
+	}
+	t.exit(position)
+}
+
+// RemoveConfig stops trading the symbol of config, its exit lock is removed
+// with it so what it holds is kept
+func (t *dcaTrader) RemoveConfig(config names.TradeConfig) bool {
+	t.lock.Lock()
+	positions := []*DCAPosition{}
+	var removed *DCAPosition
+	for _, position := range t.positions {
+		if position.ConfigId == config.Id {
+			removed = position
+		} else {
+			positions = append(positions, position)
+		}
+	}
+	t.positions = positions
+	empty := len(positions) == 0
+	t.lock.Unlock()
+
+	if removed == nil {
+		return false
+	}
+	t.broadcast.Unsubscribe(names.TradeConfig{Id: removed.ConfigId, Symbol: removed.Symbol})
+	if lock := t.tradeLockManager.RetrieveLock(config); lock != nil && lock.GetLockState().TradeConfig.Id == config.Id {
+		lock.RemoveFromManager()
+	}
+	if empty {
+		t.broadcast.TerminateBroadCast()
+	}
+	return true
+}
+
+// AddConfig starts averaging into the symbol of config
+func (t *dcaTrader) AddConfig(config names.TradeConfig) {
+	t.lock.Lock()
+	position := &DCAPosition{ConfigId: config.Id, Symbol: config.Symbol}
+	t.positions = append(t.positions, position)
+	t.lock.Unlock()
+	t.Watch(position)
+}
+
+// Configs are the configs of the flat positions, the open ones are watched
+// by their exit locks
+func (t *dcaTrader) Configs() []names.TradeConfig {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	configs := []names.TradeConfig{}
+	for _, position := range t.positions {
+		if !position.open() {
+			configs = append(configs, t.config(position))
+		}
+	}
+	return configs
+}
+
+// Report is every position and the profit of the trader
+func (t *dcaTrader) Report() DCAReport {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	report := DCAReport{}
+	ids := []string{}
+	for _, position := range t.positions {
+		report.Positions = append(report.Positions, *position)
+		report.Invested += position.Invested
+		ids = append(ids, position.ConfigId)
+	}
+	report.Profit = ledger.Default().PnL(ids...).Realized
+	return report
+}
+
+// NewDCATrade averages into the symbols of param, every symbol starts with
+// its base order at the first price it receives
+func NewDCATrade(param DCATradeParam) *manager.TradeManager {
+	return manager.NewTradeManager(getDCATrader(param))
+}
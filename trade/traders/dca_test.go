@@ -0,0 +1,122 @@
+package traders
+
+import (
+	"testing"
+	"trading/exchange"
+	"trading/ledger"
+	"trading/names"
+	"trading/trade/locker"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var dcaTest = DCATradeParam{
+	Id:             "dca",
+	Symbols:        []names.Symbol{"ETHUSDT"},
+	BaseOrder:      100,
+	SafetyOrder:    100,
+	SafetyOrders:   3,
+	VolumeScale:    2,
+	StepPercent:    10,
+	StepScale:      1,
+	TakeProfit:     5,
+	LockDelta:      1,
+	MaxInvestment:  700,
+	MaxInvestments: map[names.Symbol]float64{"BTCUSDT": 50},
+}
+
+func TestDCASafetyOrders(t *testing.T) {
+	deviation, amount := dcaTest.SafetyOrderAt(1)
+	assert.Equal(t, 10.0, deviation)
+	assert.Equal(t, 100.0, amount)
+	deviation, amount = dcaTest.SafetyOrderAt(3)
+	assert.Equal(t, 30.0, deviation)
+	assert.Equal(t, 400.0, amount, "every safety order is twice the last")
+
+	param := dcaTest
+	param.StepScale = 2
+	deviation, _ = param.SafetyOrderAt(3)
+	assert.Equal(t, 70.0, deviation, "steps of 10, 20 and 40")
+
+	assert.Equal(t, 700.0, dcaTest.Cap("ETHUSDT"))
+	assert.Equal(t, 50.0, dcaTest.Cap("BTCUSDT"))
+}
+
+func TestDCATrader(t *testing.T) {
+	names.UseExchangeInfo(exchange.ExchangeInfo{Symbols: []exchange.SymbolInfo{{
+		Symbol: "ETHUSDT", BaseAsset: "ETH", QuoteAsset: "USDT",
+		Filters: []map[string]interface{}{{"filterType": "LOT_SIZE", "stepSize": "0.00100000"}},
+	}}})
+	book := ledger.New(ledger.FIFO).UsePrices(func(symbol string) (float64, error) { return 100, nil })
+	defer ledger.Use(ledger.Use(book))
+
+	executed := []names.TradeConfig{}
+	dca := getDCATrader(dcaTest)
+	lockManager := locker.NewLockManager(locker.PeakHighLockCreator)
+	dca.SetLockManager(lockManager)
+	dca.SetExecutor(func(config names.TradeConfig, price, basePrice float64, done func()) {
+		executed = append(executed, config)
+		quantity := config.Buy.Quantity
+		if config.Side.IsSell() {
+			quantity = config.Sell.Quantity
+		}
+		book.Add(ledger.Fill{
+			OrderId:  int64(len(executed)),
+			ConfigId: config.Id,
+			Symbol:   config.Symbol,
+			Side:     config.Side,
+			Price:    price,
+			Quantity: quantity,
+		})
+		done()
+	})
+	position := dca.positions[0]
+	assert.Len(t, dca.Configs(), 1, "the flat position is listed by the trader")
+
+	dca.TryPrice(position, 100)
+	assert.Len(t, executed, 1)
+	assert.Equal(t, names.TradeSideBuy, executed[0].Side)
+	assert.Equal(t, 1.0, executed[0].Buy.Quantity, "the base order of 100 USDT")
+	assert.Equal(t, 90.0, position.NextSafety)
+	assert.Empty(t, dca.Configs(), "the open position is watched by its exit lock")
+
+	dca.TryPrice(position, 95)
+	assert.Len(t, executed, 1, "not down to the first safety order")
+	dca.TryPrice(position, 90)
+	assert.Len(t, executed, 2)
+	assert.InDelta(t, 1.111, executed[1].Buy.Quantity, 1e-9)
+	assert.InDelta(t, (100+1.111*90)/2.111, position.Average, 1e-9, "the average of both buys")
+	assert.InDelta(t, 80.0, position.NextSafety, 1e-9)
+
+	dca.TryPrice(position, 80)
+	assert.Len(t, executed, 3)
+	assert.Equal(t, 2, position.SafetyOrders)
+	assert.InDelta(t, 400, position.Invested, 1e-2)
+	dca.TryPrice(position, 70)
+	assert.Len(t, executed, 3, "the third safety order of 400 is above the max investment")
+
+	lock := lockManager.RetrieveLock(names.TradeConfig{Symbol: "ETHUSDT"})
+	lock.SetVerbose(false)
+	exit := lock.GetLockState()
+	assert.Equal(t, names.TradeSideSell, exit.TradeConfig.Side)
+	assert.InDelta(t, position.Quantity, exit.TradeConfig.Sell.Quantity, 1e-9, "the exit sells the whole position")
+	assert.InDelta(t, position.Average*1.05, exit.StopLimit, 1e-9, "the take profit is above the average entry")
+
+	for _, price := range []float64{84, 88, 92, 95, 98} {
+		dca.TryPrice(position, price)
+	}
+	assert.Len(t, executed, 3, "the exit trails the price up")
+	dca.TryPrice(position, 96)
+	assert.Len(t, executed, 4)
+	assert.Equal(t, names.TradeSideSell, executed[3].Side)
+
+	report := dca.Report()
+	assert.Equal(t, 1, report.Positions[0].Exits)
+	assert.Equal(t, 0.0, report.Positions[0].Quantity)
+	assert.InDelta(t, 96*4.611-(100+1.111*90+2.5*80), report.Profit, 1e-9)
+	assert.Greater(t, report.Profit, 0.0)
+	assert.Nil(t, lockManager.RetrieveLock(names.TradeConfig{Symbol: "ETHUSDT"}))
+
+	dca.TryPrice(position, 96)
+	assert.Len(t, executed, 4, "a position that is not cyclic does not start over")
+}
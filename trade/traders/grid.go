@@ -0,0 +1,315 @@
+package traders
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"trading/ledger"
+	"trading/names"
+	"trading/stream"
+	"trading/trade/manager"
+	"trading/utils"
+
+	"github.com/google/uuid"
+)
+
+type GridSpacing string
+
+const (
+	// levels the same price apart
+	GridArithmetic GridSpacing = "arithmetic"
+	// levels the same percent apart
+	GridGeometric GridSpacing = "geometric"
+)
+
+// GridTradeParam is a ladder of Levels prices from Lower to Upper. Every
+// cell between two neighbouring levels buys Quantity when the price crosses
+// down through its lower level and sells it when the price crosses up
+// through its upper level
+type GridTradeParam struct {
+	// prefix of the config ids of the cells, a new id when empty
+	Id       string       `json:"id" yaml:"id"`
+	Symbol   names.Symbol `json:"symbol" yaml:"symbol"`
+	Lower    float64      `json:"lower" yaml:"lower"`
+	Upper    float64      `json:"upper" yaml:"upper"`
+	Levels   int          `json:"levels" yaml:"levels"`
+	Spacing  GridSpacing  `json:"spacing" yaml:"spacing"`
+	Quantity float64      `json:"quantity" yaml:"quantity"`
+	// orders of the cells, market orders when not set
+	BuyOrder  names.OrderConfig `json:"buyOrder" yaml:"buyOrder"`
+	SellOrder names.OrderConfig `json:"sellOrder" yaml:"sellOrder"`
+}
+
+// GridLevels are the prices of the ladder from the lowest
+func GridLevels(lower, upper float64, levels int, spacing GridSpacing) []float64 {
+	if levels < 2 || lower <= 0 || upper <= lower {
+		return nil
+	}
+	prices := make([]float64, levels)
+	steps := float64(levels - 1)
+	for i := range prices {
+		if spacing == GridGeometric {
+			prices[i] = lower * math.Pow(upper/lower, float64(i)/steps)
+		} else {
+			prices[i] = lower + (upper-lower)*float64(i)/steps
+		}
+	}
+	return prices
+}
+
+// GridCell is the inventory of the grid between two levels
+type GridCell struct {
+	ConfigId string  `json:"configId"`
+	Buy      float64 `json:"buy"`
+	Sell     float64 `json:"sell"`
+	// base quantity the cell holds since it bought at BoughtAt
+	Held     float64 `json:"held"`
+	BoughtAt float64 `json:"boughtAt"`
+	// round trips completed by the cell
+	Trades int `json:"trades"`
+	// an order of the cell is being executed
+	pending bool
+}
+
+type GridReport struct {
+	Symbol names.Symbol `json:"symbol"`
+	Cells  []GridCell   `json:"cells"`
+	// base quantity held by every cell
+	Inventory float64 `json:"inventory"`
+	Trades    int     `json:"trades"`
+	// realized profit of the cells net of fees, from the ledger
+	Profit float64 `json:"profit"`
+}
+
+type gridTrader struct {
+	param            GridTradeParam
+	cells            []*GridCell
+	last             float64
+	executorFunc     names.ExecutorFunc
+	tradeLockManager names.LockManagerInterface
+	broadcast        *stream.Broadcaster
+	lock             sync.Mutex
+}
+
+func getGridTrader(param GridTradeParam) *gridTrader {
+	if param.Id == "" {
+		param.Id = uuid.New().String()
+	}
+	trader := &gridTrader{
+		param:     param,
+		broadcast: stream.NewBroadcast(uuid.New().String()),
+	}
+	levels := GridLevels(param.Lower, param.Upper, param.Levels, param.Spacing)
+	for i := 0; i+1 < len(levels); i++ {
+		trader.cells = append(trader.cells, &GridCell{
+			ConfigId: fmt.Sprintf("%s_grid_%d", param.Id, i),
+			Buy:      levels[i],
+			Sell:     levels[i+1],
+		})
+	}
+	return trader
+}
+
+// watchConfig is the config the grid subscribes to the prices of its symbol with
+func (t *gridTrader) watchConfig() names.TradeConfig {
+	return names.TradeConfig{Id: t.param.Id, Symbol: t.param.Symbol}
+}
+
+// config is the trade config of the next order of cell
+func (t *gridTrader) config(cell *GridCell) names.TradeConfig {
+	config := names.TradeConfig{
+		Id:     cell.ConfigId,
+		Symbol: t.param.Symbol,
+		Side:   names.TradeSideBuy,
+		Buy: names.SideConfig{
+			LimitType: names.RateFixed,
+			StopLimit: cell.Buy,
+			Quantity:  t.param.Quantity,
+			Order:     t.param.BuyOrder,
+		},
+		Sell: names.SideConfig{
+			LimitType: names.RateFixed,
+			StopLimit: cell.Sell,
+			Quantity:  t.param.Quantity,
+			// a sell that slipped below the cost of the cell waits for the next cross
+			MustProfit: true,
+			Order:      t.param.SellOrder,
+		},
+	}
+	if cell.Held > 0 {
+		config.Side = names.TradeSideSell
+		config.Sell.Quantity = cell.Held
+	}
+	return config
+}
+
+func (t *gridTrader) cell(configId string) *GridCell {
+	for _, cell := range t.cells {
+		if cell.ConfigId == configId {
+			return cell
+		}
+	}
+	return nil
+}
+
+func (t *gridTrader) Run() {
+	if len(t.cells) == 0 {
+		utils.LogError(fmt.Errorf("invalid grid %s", t.param.Symbol), fmt.Sprintf("<Grid>: needs at least 2 levels between %f and %f", t.param.Lower, t.param.Upper))
+		return
+	}
+	subscription := t.broadcast.Subscribe(t.watchConfig())
+	go func() {
+		subscription.Each(func(data stream.SymbolPriceData) {
+			if stream.InGap(data.Symbol) {
+				return
+			}
+			t.TryPrice(data.Price)
+		})
+	}()
+}
+
+// TryPrice executes the orders of the cells whose level price crossed since
+// the last price, a cell that bought arms its sell and a cell that sold
+// arms its buy again
+func (t *gridTrader) TryPrice(price float64) {
+	t.lock.Lock()
+	last := t.last
+	t.last = price
+	due := []*GridCell{}
+	for _, cell := range t.cells {
+		if last == 0 || cell.pending {
+			continue
+		}
+		crossedDown := last > cell.Buy && price <= cell.Buy
+		crossedUp := last < cell.Sell && price >= cell.Sell
+		if (cell.Held == 0 && crossedDown) || (cell.Held > 0 && crossedUp) {
+			cell.pending = true
+			due = append(due, cell)
+		}
+	}
+	configs := []names.TradeConfig{}
+	for _, cell := range due {
+		configs = append(configs, t.config(cell))
+	}
+	t.lock.Unlock()
+
+	for i, config := range configs {
+		basePrice := due[i].Buy
+		if config.Side.IsSell() {
+			basePrice = due[i].BoughtAt
+		}
+		t.executorFunc(config, price, basePrice, func() {
+			t.Done(config, nil)
+		})
+		t.lock.Lock()
+		due[i].pending = false
+		t.lock.Unlock()
+	}
+}
+
+func (t *gridTrader) SetExecutor(executorFunc names.ExecutorFunc) names.Trader {
+	t.executorFunc = executorFunc
+	return t
+}
+
+// SetLockManager keeps the lock manager of the trade manager, the grid
+// trades its levels without locks
+func (t *gridTrader) SetLockManager(tl names.LockManagerInterface) names.Trader {
+	t.tradeLockManager = tl
+	return t
+}
+
+// Done fills the order of the cell of config
+func (t *gridTrader) Done(config names.TradeConfig, locker names.LockInterface) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	cell := t.cell(config.Id)
+	if cell == nil {
+		return
+	}
+	if config.Side.IsBuy() {
+		cell.Held = config.Buy.Quantity
+		if held := ledger.Default().ConfigPnL(cell.ConfigId).Quantity; held > 0 {
+			// what is left once a fee paid in the base asset is taken
+			cell.Held = held
+		}
+		cell.BoughtAt = t.last
+		return
+	}
+	cell.Held, cell.BoughtAt = 0, 0
+	cell.Trades++
+	utils.LogInfo(fmt.Sprintf("<Grid>: %s sold %s at %s, grid profit %f after %d trades",
+		t.param.Symbol, config.Id, t.param.Symbol.FormatQuotePrice(cell.Sell), t.profit(), t.trades()))
+}
+
+// AddConfig is not supported, the cells of a grid are set by its levels
+func (t *gridTrader) AddConfig(config names.TradeConfig) {
+	utils.LogWarn(fmt.Sprintf("<Grid>: %s can not be added, the cells of a grid are set by its levels", config.Id))
+}
+
+// RemoveConfig stops trading the cell of config, the grid stops once it
+// has no cell left
+func (t *gridTrader) RemoveConfig(config names.TradeConfig) bool {
+	t.lock.Lock()
+	cells := []*GridCell{}
+	for _, cell := range t.cells {
+		if cell.ConfigId != config.Id {
+			cells = append(cells, cell)
+		}
+	}
+	removed := len(cells) < len(t.cells)
+	t.cells = cells
+	empty := len(cells) == 0
+	t.lock.Unlock()
+
+	if removed && empty {
+		t.broadcast.Unsubscribe(t.watchConfig())
+		t.broadcast.TerminateBroadCast()
+	}
+	return removed
+}
+
+// Configs are the configs of the next order of every cell
+func (t *gridTrader) Configs() []names.TradeConfig {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	configs := []names.TradeConfig{}
+	for _, cell := range t.cells {
+		configs = append(configs, t.config(cell))
+	}
+	return configs
+}
+
+func (t *gridTrader) trades() int {
+	trades := 0
+	for _, cell := range t.cells {
+		trades += cell.Trades
+	}
+	return trades
+}
+
+func (t *gridTrader) profit() float64 {
+	ids := []string{}
+	for _, cell := range t.cells {
+		ids = append(ids, cell.ConfigId)
+	}
+	return ledger.Default().PnL(ids...).Realized
+}
+
+// Report is the inventory of every cell and the profit of the grid
+func (t *gridTrader) Report() GridReport {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	report := GridReport{Symbol: t.param.Symbol, Trades: t.trades(), Profit: t.profit()}
+	for _, cell := range t.cells {
+		report.Cells = append(report.Cells, *cell)
+		report.Inventory += cell.Held
+	}
+	return report
+}
+
+// NewGridTrade trades the grid of param, a cell only trades once the price
+// crosses one of its levels so a grid starts without inventory
+func NewGridTrade(param GridTradeParam) *manager.TradeManager {
+	return manager.NewTradeManager(getGridTrader(param))
+}
@@ -0,0 +1,82 @@
+package traders
+
+import (
+	"testing"
+	"trading/ledger"
+	"trading/names"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGridLevels(t *testing.T) {
+	assert.Equal(t, []float64{100, 110, 120, 130, 140}, GridLevels(100, 140, 5, GridArithmetic))
+
+	geometric := GridLevels(100, 400, 3, GridGeometric)
+	assert.Len(t, geometric, 3)
+	assert.InDelta(t, 200, geometric[1], 1e-9, "every level is the same percent above the last")
+	assert.InDelta(t, 400, geometric[2], 1e-9)
+
+	assert.Nil(t, GridLevels(100, 140, 1, GridArithmetic))
+	assert.Nil(t, GridLevels(140, 100, 5, GridArithmetic))
+}
+
+func TestGridTrader(t *testing.T) {
+	book := ledger.New(ledger.FIFO).UsePrices(func(symbol string) (float64, error) { return 120, nil })
+	defer ledger.Use(ledger.Use(book))
+
+	executed := []names.TradeConfig{}
+	grid := getGridTrader(GridTradeParam{Id: "eth", Symbol: "ETHUSDT", Lower: 100, Upper: 130, Levels: 4, Quantity: 2})
+	grid.SetExecutor(func(config names.TradeConfig, price, basePrice float64, done func()) {
+		executed = append(executed, config)
+		quantity := config.Buy.Quantity
+		if config.Side.IsSell() {
+			quantity = config.Sell.Quantity
+		}
+		book.Add(ledger.Fill{
+			OrderId:  int64(len(executed)),
+			ConfigId: config.Id,
+			Symbol:   config.Symbol,
+			Side:     config.Side,
+			Price:    price,
+			Quantity: quantity,
+		})
+		done()
+	})
+	assert.Len(t, grid.Configs(), 3, "a cell between every two levels")
+
+	grid.TryPrice(115)
+	assert.Empty(t, executed, "no level is crossed by the first price")
+
+	grid.TryPrice(109)
+	assert.Len(t, executed, 1, "the price fell through the level of 110")
+	assert.Equal(t, "eth_grid_1", executed[0].Id)
+	assert.Equal(t, names.TradeSideBuy, executed[0].Side)
+	assert.Equal(t, 2.0, grid.Report().Inventory)
+
+	grid.TryPrice(99)
+	assert.Len(t, executed, 2)
+	assert.Equal(t, "eth_grid_0", executed[1].Id)
+	grid.TryPrice(95)
+	assert.Len(t, executed, 2, "a cell holding its inventory does not buy again")
+
+	grid.TryPrice(121)
+	assert.Len(t, executed, 4, "both cells sell at their upper level")
+	sells := map[string]bool{}
+	for _, config := range executed[2:] {
+		assert.Equal(t, names.TradeSideSell, config.Side)
+		assert.Equal(t, 2.0, config.Sell.Quantity)
+		assert.True(t, config.Sell.MustProfit)
+		sells[config.Id] = true
+	}
+	assert.Equal(t, map[string]bool{"eth_grid_0": true, "eth_grid_1": true}, sells)
+
+	report := grid.Report()
+	assert.Equal(t, 0.0, report.Inventory)
+	assert.Equal(t, 2, report.Trades)
+	// 2 bought at 109 and 2 at 99 sold at 121
+	assert.InDelta(t, 68, report.Profit, 1e-9)
+
+	assert.True(t, grid.RemoveConfig(names.TradeConfig{Id: "eth_grid_2"}))
+	assert.False(t, grid.RemoveConfig(names.TradeConfig{Id: "eth_grid_2"}))
+	assert.Len(t, grid.Configs(), 2)
+}
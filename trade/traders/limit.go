@@ -2,7 +2,7 @@ package traders
 
 import (
 	"fmt"
-	"trading/binance"
+	"trading/exchange"
 	"trading/helper"
 	"trading/names"
 	"trading/stream"
@@ -61,7 +61,7 @@ func (t *limitTrader) Run() {
 				continue
 			}
 		}
-		go t.Watch(tc)
+		t.Watch(tc)
 	}
 }
 
@@ -75,7 +75,7 @@ func (t *limitTrader) SetExecutor(executorFunc names.ExecutorFunc) names.Trader
 // them will also be removed
 func (t *limitTrader) AddConfig(config names.TradeConfig) {
 	t.tradeConfigs = append(t.tradeConfigs, config)
-	go t.Watch(config)
+	t.Watch(config)
 }
 
 // Remove a config and it associated registeredLocks (subscription and lock)
@@ -110,7 +110,7 @@ func (tm *limitTrader) Done(config names.TradeConfig, locker names.LockInterface
 			// destroy other configs so they can get new price
 			// recreate the completed config with sides switched
 			tm.broadcast.TerminateBroadCast()
-			go NewLimitTrade(tm.tradeConfigs).DoTrade()
+			manager.Restart(tm, NewLimitTrade(tm.tradeConfigs))
 			return
 		}
 
@@ -146,10 +146,12 @@ func (trader *limitTrader) SetLockManager(tl names.LockManagerInterface) names.T
 	return trader
 }
 
+// Watch locks the prices of config, the lock is in place when Watch
+// returns and the prices are read in the background
 func (trader *limitTrader) Watch(config names.TradeConfig) {
 	executor := trader.executorFunc
 	subscription := trader.broadcast.Subscribe(config)
-	pretradePrice := binance.GetPriceLatest(config.Symbol.String())
+	pretradePrice := exchange.GetPriceLatest(config.Symbol.String())
 	configLocker := trader.tradeLockManager.AddLock(config, pretradePrice) //we mayy not need stop for sell
 
 	configLocker.SetRedemptionCandidateCallback(func(l names.LockInterface) {
@@ -166,10 +168,29 @@ func (trader *limitTrader) Watch(config names.TradeConfig) {
 
 	deviationManager := deviation.NewDeviationManager(trader, configLocker)
 
-	for sub := range subscription.GetChannel() {
+	go subscription.Each(func(sub stream.SymbolPriceData) {
 		go deviationManager.CheckDeviation(&subscription)
-		configLocker.TryLockPrice(sub.Price)
+		tryLockPrice(configLocker, sub)
+	})
+}
+
+// LimitTradeSnapshot is the state needed to resume a limit trader
+type LimitTradeSnapshot struct {
+	Configs []names.TradeConfig `json:"configs"`
+}
+
+func (trader *limitTrader) Snapshot() any {
+	return LimitTradeSnapshot{Configs: append([]names.TradeConfig{}, trader.tradeConfigs...)}
+}
+
+// ResumeLimitTrade rebuilds the limit trader saved in snapshot, the side of
+// every cyclic config is the side it was trading when the snapshot was taken
+func ResumeLimitTrade(snapshot manager.Snapshot) (*manager.TradeManager, error) {
+	var state LimitTradeSnapshot
+	if err := snapshot.TraderState(&state); err != nil {
+		return nil, err
 	}
+	return NewLimitTrade(state.Configs).Resume(snapshot), nil
 }
 
 func NewLimitTrade(configs []names.TradeConfig) *manager.TradeManager {
@@ -0,0 +1,445 @@
+package traders
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+	"trading/helper"
+	"trading/kline"
+	"trading/names"
+	"trading/stream"
+	"trading/trade/manager"
+	"trading/user"
+	"trading/utils"
+
+	"github.com/google/uuid"
+)
+
+type PairState string
+
+const (
+	PairFlat PairState = "flat"
+	// first bought and second sold, the spread was below its mean
+	PairLongFirst PairState = "longFirst"
+	// first sold and second bought, the spread was above its mean
+	PairShortFirst PairState = "shortFirst"
+)
+
+// PairsTradeParam trades the spread between two correlated symbols of the
+// same quote asset. The log price of First is regressed on the log price of
+// Second over the last Lookback candles, when the spread is EntryZ standard
+// deviations from its mean the cheap symbol is bought and held inventory of
+// the rich one is sold, both legs are closed once the spread is back within
+// ExitZ
+type PairsTradeParam struct {
+	// prefix of the config ids of the legs, a new id when empty
+	Id       string       `json:"id" yaml:"id"`
+	First    names.Symbol `json:"first" yaml:"first"`
+	Second   names.Symbol `json:"second" yaml:"second"`
+	Interval string       `json:"interval" yaml:"interval"`
+	Lookback int          `json:"lookback" yaml:"lookback"`
+	EntryZ   float64      `json:"entryZ" yaml:"entryZ"`
+	ExitZ    float64      `json:"exitZ" yaml:"exitZ"`
+	// quote amount the bought leg spends, the sold leg sells the hedge ratio
+	// of it from the inventory held
+	Amount float64 `json:"amount" yaml:"amount"`
+	// orders of the legs, market orders when not set
+	BuyOrder  names.OrderConfig `json:"buyOrder" yaml:"buyOrder"`
+	SellOrder names.OrderConfig `json:"sellOrder" yaml:"sellOrder"`
+}
+
+// PairModel is the regression of the log prices of a pair, the spread is
+// log(first) - Beta*log(second) and has Mean and StdDev over the lookback
+type PairModel struct {
+	Beta   float64 `json:"beta"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stdDev"`
+}
+
+// FitPair regresses the log of first on the log of second, both oldest
+// first. It is false when the prices do not pair up or do not move
+func FitPair(first, second []float64) (PairModel, bool) {
+	n := len(first)
+	if n < 2 || n != len(second) {
+		return PairModel{}, false
+	}
+	y, x := make([]float64, n), make([]float64, n)
+	var meanX, meanY float64
+	for i := range first {
+		if first[i] <= 0 || second[i] <= 0 {
+			return PairModel{}, false
+		}
+		y[i], x[i] = math.Log(first[i]), math.Log(second[i])
+		meanX += x[i] / float64(n)
+		meanY += y[i] / float64(n)
+	}
+	var cov, variance float64
+	for i := range x {
+		cov += (x[i] - meanX) * (y[i] - meanY)
+		variance += (x[i] - meanX) * (x[i] - meanX)
+	}
+	if variance == 0 {
+		return PairModel{}, false
+	}
+	model := PairModel{Beta: cov / variance}
+	spreads := make([]float64, n)
+	for i := range x {
+		spreads[i] = y[i] - model.Beta*x[i]
+		model.Mean += spreads[i] / float64(n)
+	}
+	for _, s := range spreads {
+		model.StdDev += (s - model.Mean) * (s - model.Mean) / float64(n)
+	}
+	model.StdDev = math.Sqrt(model.StdDev)
+	return model, model.StdDev > 0
+}
+
+// ZScore is how many standard deviations the spread of the prices is from its mean
+func (m PairModel) ZScore(first, second float64) float64 {
+	if m.StdDev == 0 || first <= 0 || second <= 0 {
+		return 0
+	}
+	return (math.Log(first) - m.Beta*math.Log(second) - m.Mean) / m.StdDev
+}
+
+// PairLeg is one symbol of the pair and what it holds or sold
+type PairLeg struct {
+	ConfigId string       `json:"configId"`
+	Symbol   names.Symbol `json:"symbol"`
+	// side the leg was opened with, empty when it is closed
+	Side     names.TradeSide `json:"side"`
+	Quantity float64         `json:"quantity"`
+	Price    float64         `json:"price"`
+}
+
+type PairsReport struct {
+	State  PairState  `json:"state"`
+	Model  PairModel  `json:"model"`
+	ZScore float64    `json:"zScore"`
+	Legs   [2]PairLeg `json:"legs"`
+	// round trips of both legs and their profit before fees
+	Trades int     `json:"trades"`
+	Profit float64 `json:"profit"`
+}
+
+// candles the spread is fitted on, the shared kline store unless a test replaces it
+var pairCandles = func(symbol, interval string, n int) []kline.KlineData {
+	return kline.Default().Last(symbol, interval, n)
+}
+
+// free base quantity the short leg can sell, the account balance unless a test replaces it
+var pairInventory = func(asset string) float64 {
+	return user.GetAccount().GetBalance(asset).Free
+}
+
+type pairsTrader struct {
+	param            PairsTradeParam
+	legs             [2]*PairLeg
+	prices           [2]float64
+	state            PairState
+	model            PairModel
+	fitted           bool
+	nextFit          time.Time
+	trades           int
+	profit           float64
+	pending          bool
+	executorFunc     names.ExecutorFunc
+	tradeLockManager names.LockManagerInterface
+	broadcast        *stream.Broadcaster
+	lock             sync.Mutex
+}
+
+func getPairsTrader(param PairsTradeParam) *pairsTrader {
+	if param.Id == "" {
+		param.Id = uuid.New().String()
+	}
+	if param.Interval == "" {
+		param.Interval = "1h"
+	}
+	if param.Lookback == 0 {
+		param.Lookback = 100
+	}
+	trader := &pairsTrader{
+		param:     param,
+		state:     PairFlat,
+		broadcast: stream.NewBroadcast(uuid.New().String()),
+	}
+	for i, symbol := range []names.Symbol{param.First, param.Second} {
+		trader.legs[i] = &PairLeg{ConfigId: fmt.Sprintf("%s_pair_%s", param.Id, symbol), Symbol: symbol}
+	}
+	return trader
+}
+
+func (t *pairsTrader) Run() {
+	for i := range t.legs {
+		t.watch(i)
+	}
+}
+
+// watch subscribes to the prices of leg i, they are read in the background
+func (t *pairsTrader) watch(i int) {
+	subscription := t.broadcast.Subscribe(names.TradeConfig{Id: t.legs[i].ConfigId, Symbol: t.legs[i].Symbol})
+	go subscription.Each(func(data stream.SymbolPriceData) {
+		if stream.InGap(data.Symbol) {
+			return
+		}
+		t.TryPrice(t.legs[i].Symbol, data.Price)
+	})
+}
+
+// fit regresses the pair again once a candle of the interval closed since
+// the last fit
+func (t *pairsTrader) fit() {
+	now := utils.Now()
+	if t.fitted && now.Before(t.nextFit) {
+		return
+	}
+	t.nextFit = now.Add(kline.IntervalDuration(t.param.Interval))
+	first := pairCandles(t.param.First.String(), t.param.Interval, t.param.Lookback)
+	second := pairCandles(t.param.Second.String(), t.param.Interval, t.param.Lookback)
+	closes := func(candles []kline.KlineData, n int) []float64 {
+		prices := []float64{}
+		for _, candle := range candles[len(candles)-n:] {
+			prices = append(prices, candle.Close)
+		}
+		return prices
+	}
+	n := len(first)
+	if len(second) < n {
+		n = len(second)
+	}
+	model, ok := FitPair(closes(first, n), closes(second, n))
+	if !ok {
+		utils.LogWarn(fmt.Sprintf("<Pairs>: %s and %s can not be fitted on %d candles of %s", t.param.First, t.param.Second, n, t.param.Interval))
+		return
+	}
+	t.model, t.fitted = model, true
+}
+
+// TryPrice keeps the price of symbol and opens both legs when the spread is
+// EntryZ from its mean or closes them once it is back within ExitZ
+func (t *pairsTrader) TryPrice(symbol names.Symbol, price float64) {
+	t.lock.Lock()
+	for i, leg := range t.legs {
+		if leg.Symbol == symbol {
+			t.prices[i] = price
+		}
+	}
+	if t.pending || t.prices[0] == 0 || t.prices[1] == 0 {
+		t.lock.Unlock()
+		return
+	}
+	t.fit()
+	if !t.fitted {
+		t.lock.Unlock()
+		return
+	}
+	z := t.model.ZScore(t.prices[0], t.prices[1])
+	var orders []names.TradeConfig
+	switch {
+	case t.state == PairFlat && z <= -t.param.EntryZ:
+		orders = t.open(PairLongFirst, z)
+	case t.state == PairFlat && z >= t.param.EntryZ:
+		orders = t.open(PairShortFirst, z)
+	case t.state == PairLongFirst && z >= -t.param.ExitZ,
+		t.state == PairShortFirst && z <= t.param.ExitZ:
+		orders = t.close()
+	}
+	t.pending = len(orders) > 0
+	prices := t.prices
+	t.lock.Unlock()
+
+	for _, config := range orders {
+		price := prices[0]
+		if config.Symbol == t.param.Second {
+			price = prices[1]
+		}
+		filled := false
+		t.executorFunc(config, price, price, func() {
+			filled = true
+			t.Done(config, nil)
+		})
+		if !filled {
+			utils.LogWarn(fmt.Sprintf("<Pairs>: %s %s was not filled, the pair goes on with the legs that are open", config.Side, config.Symbol))
+			break
+		}
+	}
+	t.lock.Lock()
+	if t.legs[0].Side == "" && t.legs[1].Side == "" {
+		// the first leg of an entry was not filled
+		t.state = PairFlat
+	}
+	t.pending = false
+	t.lock.Unlock()
+}
+
+// open is the orders that open the pair in state, the sold leg first as it
+// needs inventory. None when there is no inventory to sell
+func (t *pairsTrader) open(state PairState, z float64) []names.TradeConfig {
+	long, short := 0, 1
+	if state == PairShortFirst {
+		long, short = 1, 0
+	}
+	if t.model.Beta <= 0 {
+		return nil
+	}
+	// the sold leg is worth the hedge ratio of the bought one
+	shortAmount := t.param.Amount * t.model.Beta
+	if long == 1 {
+		shortAmount = t.param.Amount / t.model.Beta
+	}
+	shortSymbol := t.legs[short].Symbol
+	quantity := shortAmount / t.prices[short]
+	buyAmount := t.param.Amount
+	// the bought leg stays hedged by the part of the sold leg there is inventory for
+	if held := pairInventory(shortSymbol.ParseTradingPair().Base); held < quantity {
+		buyAmount *= held / quantity
+		quantity = held
+	}
+	quantity = shortSymbol.Quantity(quantity)
+	buy := t.legs[long].Symbol.Quantity(buyAmount / t.prices[long])
+	if quantity <= 0 || buy <= 0 {
+		utils.LogWarn(fmt.Sprintf("<Pairs>: z score %.2f but there is no %s to sell", z, shortSymbol))
+		return nil
+	}
+	utils.LogInfo(fmt.Sprintf("<Pairs>: z score %.2f, buying %s and selling %s", z, t.legs[long].Symbol, shortSymbol))
+	t.state = state
+	return []names.TradeConfig{
+		t.config(t.legs[short], names.TradeSideSell, quantity, t.prices[short]),
+		t.config(t.legs[long], names.TradeSideBuy, buy, t.prices[long]),
+	}
+}
+
+// close is the orders that close the open legs, the bought leg is sold
+// first so its quote buys back the sold one
+func (t *pairsTrader) close() []names.TradeConfig {
+	orders := []names.TradeConfig{}
+	for _, side := range []names.TradeSide{names.TradeSideBuy, names.TradeSideSell} {
+		for i, leg := range t.legs {
+			if leg.Side != side || leg.Quantity <= 0 {
+				continue
+			}
+			orders = append(orders, t.config(leg, helper.SwitchTradeSide(leg.Side), leg.Quantity, t.prices[i]))
+		}
+	}
+	return orders
+}
+
+func (t *pairsTrader) config(leg *PairLeg, side names.TradeSide, quantity, price float64) names.TradeConfig {
+	config := names.TradeConfig{Id: leg.ConfigId, Symbol: leg.Symbol, Side: side}
+	order := names.SideConfig{LimitType: names.RateFixed, StopLimit: price, Quantity: quantity}
+	if side.IsBuy() {
+		order.Order = t.param.BuyOrder
+		config.Buy = order
+	} else {
+		order.Order = t.param.SellOrder
+		config.Sell = order
+	}
+	return config
+}
+
+func (t *pairsTrader) SetExecutor(executorFunc names.ExecutorFunc) names.Trader {
+	t.executorFunc = executorFunc
+	return t
+}
+
+// SetLockManager keeps the lock manager of the trade manager, the pair
+// trades its spread without locks
+func (t *pairsTrader) SetLockManager(tl names.LockManagerInterface) names.Trader {
+	t.tradeLockManager = tl
+	return t
+}
+
+// Budgeted reports that the bought leg is sized by the amount of the pair
+// so the capital is not reserved for it
+func (t *pairsTrader) Budgeted() bool {
+	return true
+}
+
+// Done fills the order of the leg of config, the pair is flat again once
+// both legs are closed
+func (t *pairsTrader) Done(config names.TradeConfig, locker names.LockInterface) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	var leg *PairLeg
+	var price float64
+	for i := range t.legs {
+		if t.legs[i].ConfigId == config.Id {
+			leg, price = t.legs[i], t.prices[i]
+		}
+	}
+	if leg == nil {
+		return
+	}
+	quantity := config.Buy.Quantity
+	if config.Side.IsSell() {
+		quantity = config.Sell.Quantity
+	}
+	if leg.Side == "" {
+		leg.Side, leg.Quantity, leg.Price = config.Side, quantity, price
+		return
+	}
+	gain := (price - leg.Price) * quantity
+	if leg.Side.IsSell() {
+		gain = -gain
+	}
+	t.profit += gain
+	leg.Side, leg.Quantity, leg.Price = "", 0, 0
+	if t.legs[0].Side == "" && t.legs[1].Side == "" {
+		t.state = PairFlat
+		t.trades++
+		utils.LogInfo(fmt.Sprintf("<Pairs>: %s and %s closed, profit %f after %d trades", t.param.First, t.param.Second, t.profit, t.trades))
+	}
+}
+
+// AddConfig is not supported, the legs of a pair are set by its symbols
+func (t *pairsTrader) AddConfig(config names.TradeConfig) {
+	utils.LogWarn(fmt.Sprintf("<Pairs>: %s can not be added, the legs of a pair are set by its symbols", config.Id))
+}
+
+// RemoveConfig stops the pair when config is one of its legs, open legs
+// are left as they are
+func (t *pairsTrader) RemoveConfig(config names.TradeConfig) bool {
+	for _, leg := range t.legs {
+		if leg.ConfigId == config.Id {
+			for _, l := range t.legs {
+				t.broadcast.Unsubscribe(names.TradeConfig{Id: l.ConfigId, Symbol: l.Symbol})
+			}
+			t.broadcast.TerminateBroadCast()
+			return true
+		}
+	}
+	return false
+}
+
+// Configs are the configs of both legs
+func (t *pairsTrader) Configs() []names.TradeConfig {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	configs := []names.TradeConfig{}
+	for _, leg := range t.legs {
+		configs = append(configs, names.TradeConfig{Id: leg.ConfigId, Symbol: leg.Symbol, Side: leg.Side})
+	}
+	return configs
+}
+
+// Report is the state of the pair and the profit of its round trips
+func (t *pairsTrader) Report() PairsReport {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return PairsReport{
+		State:  t.state,
+		Model:  t.model,
+		ZScore: t.model.ZScore(t.prices[0], t.prices[1]),
+		Legs:   [2]PairLeg{*t.legs[0], *t.legs[1]},
+		Trades: t.trades,
+		Profit: t.profit,
+	}
+}
+
+// NewPairsTrade trades the spread of the pair of param, it starts flat and
+// only sells inventory the account already holds
+func NewPairsTrade(param PairsTradeParam) *manager.TradeManager {
+	return manager.NewTradeManager(getPairsTrader(param))
+}
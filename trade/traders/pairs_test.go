@@ -0,0 +1,109 @@
+package traders
+
+import (
+	"math"
+	"testing"
+	"trading/exchange"
+	"trading/kline"
+	"trading/names"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// prices of a pair whose spread swings around first = 2 * second
+func pairPrices(n int) (first, second []float64) {
+	for i := 0; i < n; i++ {
+		s := 100 * (1 + 0.05*math.Sin(float64(i)/5))
+		second = append(second, s)
+		first = append(first, 2*s*(1+0.01*math.Cos(float64(i)*1.7)))
+	}
+	return first, second
+}
+
+func TestFitPair(t *testing.T) {
+	first, second := pairPrices(100)
+	model, ok := FitPair(first, second)
+	assert.True(t, ok)
+	assert.InDelta(t, 1, model.Beta, 0.05, "first moves with second")
+	assert.InDelta(t, math.Log(2), model.Mean, 0.2)
+	assert.Greater(t, model.StdDev, 0.0)
+	assert.InDelta(t, 0, model.ZScore(math.Exp(model.Mean)*math.Pow(100, model.Beta), 100), 1e-9, "the spread at its mean")
+
+	_, ok = FitPair(second, second)
+	assert.False(t, ok, "a spread that does not move")
+	_, ok = FitPair(first, second[1:])
+	assert.False(t, ok)
+}
+
+func TestPairsTrader(t *testing.T) {
+	names.UseExchangeInfo(exchange.ExchangeInfo{Symbols: []exchange.SymbolInfo{
+		{Symbol: "ETHUSDT", BaseAsset: "ETH", QuoteAsset: "USDT", Filters: []map[string]interface{}{{"filterType": "LOT_SIZE", "stepSize": "0.00100000"}}},
+		{Symbol: "LTCUSDT", BaseAsset: "LTC", QuoteAsset: "USDT", Filters: []map[string]interface{}{{"filterType": "LOT_SIZE", "stepSize": "0.00100000"}}},
+	}})
+	first, second := pairPrices(100)
+	candles := map[string][]float64{"ETHUSDT": first, "LTCUSDT": second}
+	defer func(load func(string, string, int) []kline.KlineData) { pairCandles = load }(pairCandles)
+	pairCandles = func(symbol, interval string, n int) []kline.KlineData {
+		data := []kline.KlineData{}
+		for _, price := range candles[symbol] {
+			data = append(data, kline.KlineData{Close: price})
+		}
+		return data
+	}
+	defer func(inventory func(string) float64) { pairInventory = inventory }(pairInventory)
+	inventory := map[string]float64{"ETH": 10}
+	pairInventory = func(asset string) float64 { return inventory[asset] }
+
+	model, _ := FitPair(first, second)
+	// price of first whose spread is z from the mean when second is 100
+	at := func(z float64) float64 {
+		return math.Exp(z*model.StdDev + model.Mean + model.Beta*math.Log(100))
+	}
+
+	executed := []names.TradeConfig{}
+	pairs := getPairsTrader(PairsTradeParam{Id: "pair", First: "ETHUSDT", Second: "LTCUSDT", EntryZ: 2, ExitZ: 0.5, Amount: 100})
+	pairs.SetExecutor(func(config names.TradeConfig, price, basePrice float64, done func()) {
+		executed = append(executed, config)
+		done()
+	})
+
+	pairs.TryPrice("LTCUSDT", 100)
+	pairs.TryPrice("ETHUSDT", at(1))
+	assert.Empty(t, executed)
+	assert.InDelta(t, 1, pairs.Report().ZScore, 1e-9)
+
+	pairs.TryPrice("ETHUSDT", at(3))
+	assert.Len(t, executed, 2)
+	assert.Equal(t, PairShortFirst, pairs.Report().State)
+	assert.Equal(t, names.TradeSideSell, executed[0].Side, "the held inventory of the rich leg is sold first")
+	assert.Equal(t, names.Symbol("ETHUSDT"), executed[0].Symbol)
+	assert.InDelta(t, names.Symbol("ETHUSDT").Quantity(100/model.Beta/at(3)), executed[0].Sell.Quantity, 1e-9, "the hedge ratio of the amount")
+	assert.Equal(t, names.TradeSideBuy, executed[1].Side)
+	assert.Equal(t, 1.0, executed[1].Buy.Quantity, "the amount of the cheap leg")
+
+	pairs.TryPrice("ETHUSDT", at(1))
+	assert.Len(t, executed, 2, "not back within the exit z score")
+	pairs.TryPrice("ETHUSDT", at(0.2))
+	assert.Len(t, executed, 4)
+	assert.Equal(t, names.TradeSideSell, executed[2].Side, "the bought leg is sold first")
+	assert.Equal(t, names.Symbol("LTCUSDT"), executed[2].Symbol)
+	assert.Equal(t, names.TradeSideBuy, executed[3].Side)
+	assert.Equal(t, executed[0].Sell.Quantity, executed[3].Buy.Quantity, "the sold leg is bought back")
+
+	report := pairs.Report()
+	assert.Equal(t, PairFlat, report.State)
+	assert.Equal(t, 1, report.Trades)
+	assert.InDelta(t, (at(3)-at(0.2))*executed[0].Sell.Quantity, report.Profit, 1e-9)
+
+	pairs.TryPrice("ETHUSDT", at(-3))
+	assert.Len(t, executed, 4, "there is no LTC to sell")
+	assert.Equal(t, PairFlat, pairs.Report().State)
+
+	inventory["LTC"] = 0.5
+	pairs.TryPrice("ETHUSDT", at(-3))
+	assert.Len(t, executed, 6)
+	assert.Equal(t, names.Symbol("LTCUSDT"), executed[4].Symbol)
+	assert.Equal(t, 0.5, executed[4].Sell.Quantity, "the sold leg is capped by the inventory")
+	assert.InDelta(t, names.Symbol("ETHUSDT").Quantity(100*0.5/(100*model.Beta/100)/at(-3)), executed[5].Buy.Quantity, 1e-9,
+		"the bought leg is scaled to the sold quantity")
+}
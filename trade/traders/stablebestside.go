@@ -3,7 +3,7 @@ package traders
 import (
 	"fmt"
 	"sync"
-	"trading/binance"
+	"trading/exchange"
 	"trading/names"
 	"trading/stream"
 	"trading/trade/deviation"
@@ -138,21 +138,21 @@ func (tm *stableBestSide) Done(tradedConfig names.TradeConfig, locker names.Lock
 
 		// change awarded config side from contention to bestside
 		fullfilConfig.Side = tm.bestSide
-		NewStableBestSide(
+		manager.Continue(tm, NewStableBestSide(
 			tm.initConfigs,
 			tm.bestSide,
 			nextStatus,
 			fullfilConfig,
-		).DoTrade()
+		))
 		return
 	} else {
 		//Run operation to choose the best side
-		NewStableBestSide(
+		manager.Continue(tm, NewStableBestSide(
 			tm.initConfigs,
 			tm.bestSide,
 			nextStatus,
 			names.TradeConfig{},
-		).DoTrade()
+		))
 	}
 }
 
@@ -165,7 +165,7 @@ func (trader *stableBestSide) Watch(config names.TradeConfig) {
 	executor := trader.executorFunc
 	subscription := trader.broadcast.Subscribe(config)
 
-	pretradePrice := binance.GetPriceLatest(config.Symbol.String())
+	pretradePrice := exchange.GetPriceLatest(config.Symbol.String())
 	configLocker := trader.tradeLockManager.AddLock(config, pretradePrice)
 
 	trader.lock.Lock()
@@ -200,15 +200,15 @@ func (trader *stableBestSide) Watch(config names.TradeConfig) {
 		return stableConfig[0]
 	})
 
-	for sub := range subscription.GetChannel() {
+	subscription.Each(func(sub stream.SymbolPriceData) {
 		if trader.status == StatusContention {
 			// Deviation is executed selectively, specifically when the status is in contention.
 			// This approach is adopted to prevent potential loss of gains while fulfilling.
 			// TODO provide configuration to either enable or disable this behaviour
 			go deviation.CheckDeviation(&subscription)
 		}
-		configLocker.TryLockPrice(sub.Price)
-	}
+		tryLockPrice(configLocker, sub)
+	})
 }
 
 // bestSide the side that the contention will fall to after the parallel side finds a candidate
@@ -6,9 +6,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
-	"trading/binance"
+	"trading/exchange"
 	"trading/helper"
 	"trading/names"
+	"trading/trade/allocator"
+	"trading/trade/screener"
 	"trading/user"
 	"trading/utils"
 )
@@ -82,6 +84,10 @@ func (stable *stableutil) QuantityInBaseValue(account user.AccountInterface) flo
 		quoteAsset := symbol.ParseTradingPair().Quote
 		if quantity < 0 {
 			quantity = account.GetBalance(quoteAsset).Free
+			if capital := allocator.Get(); capital != nil {
+				// the config only spends its part of the balance
+				quantity = capital.Quote(stable.config, stable.spotPrice)
+			}
 		}
 		quantity = (quantity / stable.spotPrice)
 	}
@@ -298,7 +304,7 @@ func getStableTradeConfigs(configs []names.TradeConfig) []names.TradeConfig {
 
 	// convertDeltaStop to percentage implementation, remove old implementation
 
-	spotPrices, err := binance.GetSymbolPrices(symbolList)
+	spotPrices, err := exchange.Get().Prices(symbolList)
 	if err != nil {
 		panic("ERROR>>>>" )
 	}
@@ -307,18 +313,48 @@ func getStableTradeConfigs(configs []names.TradeConfig) []names.TradeConfig {
 }
 
 type StableTradeParam struct {
-	QuoteAsset         string          `json:"quoteAsset"`
-	SellDeviationDelta float64         `json:"sellDeviationDelta"`
-	SellStopLimit      float64         `json:"sellStopLimit"`
-	SellLockDelta      float64         `json:"sellLockDelta"`
-	BuyDeviationDelta  float64         `json:"buyDeviationDelta"`
-	BuyStopLimit       float64         `json:"buyStopLimit"`
-	BuyLockDelta       float64         `json:"buyLockDelta"`
-	BestSide           names.TradeSide `json:"bestSide"`
-	Status             status          `json:"status"`
-	MinPriceChange     float64         `json:"minPriceChange"`
-	MaxPriceChange     float64         `json:"maxPriceChange"`
-	Side               names.TradeSide `json:"side"`
+	QuoteAsset         string          `json:"quoteAsset" yaml:"quoteAsset"`
+	SellDeviationDelta float64         `json:"sellDeviationDelta" yaml:"sellDeviationDelta"`
+	SellStopLimit      float64         `json:"sellStopLimit" yaml:"sellStopLimit"`
+	SellLockDelta      float64         `json:"sellLockDelta" yaml:"sellLockDelta"`
+	BuyDeviationDelta  float64         `json:"buyDeviationDelta" yaml:"buyDeviationDelta"`
+	BuyStopLimit       float64         `json:"buyStopLimit" yaml:"buyStopLimit"`
+	BuyLockDelta       float64         `json:"buyLockDelta" yaml:"buyLockDelta"`
+	BestSide           names.TradeSide `json:"bestSide" yaml:"bestSide"`
+	Status             status          `json:"status" yaml:"status"`
+	MinPriceChange     float64         `json:"minPriceChange" yaml:"minPriceChange"`
+	MaxPriceChange     float64         `json:"maxPriceChange" yaml:"maxPriceChange"`
+	Side               names.TradeSide `json:"side" yaml:"side"`
+	// orders the generated configs trade with, market orders when not set
+	BuyOrder  names.OrderConfig `json:"buyOrder" yaml:"buyOrder"`
+	SellOrder names.OrderConfig `json:"sellOrder" yaml:"sellOrder"`
+	// exits the generated configs when the price moves against them
+	BuyStopLoss  names.StopLoss `json:"buyStopLoss" yaml:"buyStopLoss"`
+	SellStopLoss names.StopLoss `json:"sellStopLoss" yaml:"sellStopLoss"`
+	// name of the screener picking the assets, MinPriceChange and
+	// MaxPriceChange pick them when not set
+	Screener string `json:"screener" yaml:"screener"`
+}
+
+// the named screener of params, assets with at least 20% and at most 31%
+// increase when there is none. This group of increase always indicates an
+// entry bull that has not peaked yet meaning there is still room for growth
+func stableScreener(params StableTradeParam) *screener.Screener {
+	if params.Screener != "" {
+		if s, exist := screener.Get(params.Screener); exist {
+			return s
+		}
+		utils.LogWarn(fmt.Sprintf("<Stable>: unknown screener %q, using the price change of the params", params.Screener))
+	}
+
+	minimumPrice, maximumPrice := 20.0, 31.0
+	if params.MaxPriceChange != 0 {
+		maximumPrice = params.MaxPriceChange
+	}
+	if params.MinPriceChange != 0 {
+		minimumPrice = params.MinPriceChange
+	}
+	return screener.Default(params.QuoteAsset, minimumPrice, maximumPrice)
 }
 
 // Fetch a list of assets and decorate them
@@ -329,34 +365,7 @@ func GenerateStableTradeConfigs(params StableTradeParam) []names.TradeConfig {
 	if utils.Env().IsMock() {
 		symbols = []names.Symbol{"BTCUSDT", "BNBUSDT"}
 	} else {
-		stats := binance.GetSymbolStats()
-		// We select asset with at most 2% and increase, this
-		// group of increase always indicate entry bull and have not peaked yet
-		// meaning there is still room for growth
-
-		minimumPrice, maximumPrice := 20.0, 31.0
-
-		if params.MaxPriceChange != 0 {
-			maximumPrice = params.MaxPriceChange
-		}
-		if params.MinPriceChange != 0 {
-			minimumPrice = params.MinPriceChange
-		}
-
-		if len(stats) == 0 {
-			return tradingConfigs
-		}
-
-		for _, s := range stats {
-			symbol := names.Symbol(s.Symbol)
-			change := s.PriceChangePercent
-			if symbol.ParseTradingPair().Quote == params.QuoteAsset && change > minimumPrice && change < maximumPrice {
-				symbols = append(symbols, symbol)
-			}
-		}
-
-		info := names.GetNewInfo()
-		symbols = info.FilterSpotable(symbols)
+		symbols = stableScreener(params).Run()
 
 		for _, s := range symbols {
 			fmt.Println(s)
@@ -389,6 +398,8 @@ func initConfig(symbol names.Symbol, params StableTradeParam) names.TradeConfig
 			DeviationSync: names.DeviationSync{
 				Delta: params.BuyDeviationDelta,
 			},
+			Order:    params.BuyOrder,
+			StopLoss: params.BuyStopLoss,
 		},
 		Sell: names.SideConfig{
 			MustProfit: true,
@@ -399,6 +410,8 @@ func initConfig(symbol names.Symbol, params StableTradeParam) names.TradeConfig
 			DeviationSync: names.DeviationSync{
 				Delta: params.SellDeviationDelta,
 			},
+			Order:    params.SellOrder,
+			StopLoss: params.SellStopLoss,
 		} ,
 	}
 	return config
@@ -3,10 +3,14 @@ package traders
 import (
 	"math"
 	"sync"
+	"time"
 	"trading/helper"
+	"trading/indicators"
 	"trading/kline"
 	"trading/names"
+	"trading/stream"
 	"trading/trade/graph"
+	"trading/utils"
 )
 
 
@@ -15,6 +19,108 @@ type status string
 var StatusContention status = "CONTENTION"
 var StatusFullfilment status = "FULLFILMENT"
 
+// tryLockPrice locks the price unless the symbol is in a gap of the price
+// stream, a price polled while its socket is down is too late to lock on
+func tryLockPrice(lock names.LockInterface, data stream.SymbolPriceData) {
+	if stream.InGap(data.Symbol) {
+		return
+	}
+	lock.TryLockPrice(data.Price)
+}
+
+
+// candles the indicators of the stops are measured on, the shared kline store
+// unless a test replaces it
+var loadCandles = func(symbol, interval string, n int) []kline.KlineData {
+	return kline.Default().Last(symbol, interval, n)
+}
+
+// indicatorCandles is enough candles for the indicators of the stops to be
+// ready, and at least the datapoints of the graph
+func indicatorCandles(datapoints int) int {
+	periods := indicators.DefaultPeriods
+	candles := datapoints
+	for _, period := range []int{periods.ATR, periods.Bollinger} {
+		if period > candles {
+			candles = period
+		}
+	}
+	return candles
+}
+
+func indicatorSet(symbol names.Symbol, interval string, datapoints int) *indicators.Set {
+	set := indicators.NewSet(indicators.DefaultPeriods)
+	for _, candle := range indicators.FromKlines(loadCandles(symbol.String(), interval, indicatorCandles(datapoints))) {
+		set.Add(candle)
+	}
+	return set
+}
+
+// liveStops moves the StopLimit and LockDelta of a lock with the indicators
+// of its symbol. The ticks of an interval are added to the indicators as one
+// candle once the interval is over so that they keep the scale of the klines
+type liveStops struct {
+	set      *indicators.Set
+	interval time.Duration
+	candle   indicators.Candle
+	started  time.Time
+}
+
+func newLiveStops(symbol names.Symbol, interval string, datapoints int) *liveStops {
+	return &liveStops{
+		set:      indicatorSet(symbol, interval, datapoints),
+		interval: kline.IntervalDuration(interval),
+		started:  utils.Now(),
+	}
+}
+
+// add the price of a tick and report if it closed the candle of the interval
+func (s *liveStops) add(price float64) bool {
+	if s.candle.Open == 0 {
+		s.candle = indicators.Candle{Open: price, High: price, Low: price}
+	}
+	s.candle.High = math.Max(s.candle.High, price)
+	s.candle.Low = math.Min(s.candle.Low, price)
+	s.candle.Close = price
+
+	now := utils.Now()
+	if s.interval <= 0 || now.Sub(s.started) < s.interval {
+		return false
+	}
+	s.candle.Time = now.UnixMilli()
+	s.set.Add(s.candle)
+	s.candle, s.started = indicators.Candle{}, now
+	return true
+}
+
+// stops are the StopLimit from the middle bollinger band to the upper band or
+// an average true range above the middle, and the LockDelta of an average true
+// range, both in percent. False until the indicators are ready
+func (s *liveStops) stops() (stopLimit, lockDelta float64, ok bool) {
+	set := s.set
+	if !set.ATR.Ready() || !set.Bollinger.Ready() || set.Last().Close <= 0 {
+		return 0, 0, false
+	}
+	atr, midpoint := set.ATR.Value(), set.Bollinger.Middle()
+	lockDelta = helper.CalculatePercentageOfValue(set.Last().Close, atr)
+	stopLimit = helper.CalculatePercentageChange(math.Max(midpoint+atr, set.Bollinger.Upper()), midpoint)
+	return stopLimit, lockDelta, true
+}
+
+// follow adds the tick to the indicators and moves the stops of lock when
+// a candle closed
+func (s *liveStops) follow(lock names.LockInterface, data stream.SymbolPriceData) {
+	if stream.InGap(data.Symbol) || !s.add(data.Price) {
+		return
+	}
+	stopsLock, ok := lock.(names.StopsLock)
+	if !ok {
+		return
+	}
+	if stopLimit, lockDelta, ready := s.stops(); ready {
+		stopsLock.UseStops(stopLimit, lockDelta)
+	}
+}
 
 func alignStopWithGraph(configs []names.TradeConfig, interval string, datapoints int) []names.TradeConfig {
 	//TODO dont change configurations that are already defined
@@ -25,11 +131,21 @@ func alignStopWithGraph(configs []names.TradeConfig, interval string, datapoints
 		entryPoints := graph.FindAverageEntryPoints()
 		sell := cfg.Sell
 
+		// the true range includes the gaps between candles, prefer it once there are enough candles
+		set := indicatorSet(cfg.Symbol, interval, datapoints)
+		if set.ATR.Ready() {
+			priceAvgMovement = set.ATR.Value()
+		}
+
 		// will lock profit everytime the price increases or decreases by priceAvgMovement
 
 		sell.LockDelta = helper.CalculatePercentageOfValue(currentPrice, priceAvgMovement)
 		//price from midpoint of the trend to the highes reported gain price by graph
 		sellLimit := math.Max(entryPoints.GainHighPrice, (midpoint + priceAvgMovement))
+		if set.Bollinger.Ready() {
+			// the upper band is where the price is stretched
+			sellLimit = math.Max(sellLimit, set.Bollinger.Upper())
+		}
 
 		percentFromMidPointToHighestGain := helper.CalculatePercentageChange(sellLimit, midpoint)
 
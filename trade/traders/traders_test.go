@@ -0,0 +1,59 @@
+package traders
+
+import (
+	"testing"
+	"time"
+	"trading/kline"
+	"trading/names"
+	"trading/stream"
+	"trading/trade/locker"
+	"trading/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLiveStops(t *testing.T) {
+	requested := 0
+	previous := loadCandles
+	loadCandles = func(symbol, interval string, n int) []kline.KlineData {
+		requested = n
+		data := []kline.KlineData{}
+		for i := 0; i < n; i++ {
+			data = append(data, kline.KlineData{Open: 100, High: 102, Low: 98, Close: 100})
+		}
+		return data
+	}
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	utils.UseClock(func() time.Time { return now })
+	t.Cleanup(func() {
+		loadCandles = previous
+		utils.UseClock(nil)
+	})
+
+	stops := newLiveStops("BTCUSDT", "1m", 12)
+	assert.Equal(t, 20, requested, "the bollinger bands need more candles than the graph")
+	stopLimit, lockDelta, ready := stops.stops()
+	assert.True(t, ready)
+	assert.InDelta(t, 4, stopLimit, 1e-9, "an average true range above the middle band")
+	assert.InDelta(t, 4, lockDelta, 1e-9)
+
+	config := names.TradeConfig{
+		Id:     "live",
+		Symbol: "BTCUSDT",
+		Side:   names.TradeSideSell,
+		Sell:   names.SideConfig{LimitType: names.RatePercent, StopLimit: 10, LockDelta: 1},
+	}
+	lock := locker.NewLockManager(locker.PeakHighLockCreator).AddLock(config, 100)
+	lock.SetVerbose(false)
+	stops.follow(lock, stream.SymbolPriceData{Symbol: "BTCUSDT", Price: 105})
+	assert.InDelta(t, 1, lock.GetLockState().MinimumLockUnit, 1e-9, "the stops move once the candle of the ticks closes")
+
+	now = now.Add(time.Minute)
+	stops.follow(lock, stream.SymbolPriceData{Symbol: "BTCUSDT", Price: 110})
+	stopLimit, lockDelta, _ = stops.stops()
+	assert.InDelta(t, 62/14.0/110*100, lockDelta, 1e-9, "the candle of the ticks ranged 10")
+	state := lock.GetLockState()
+	assert.InDelta(t, lockDelta, state.MinimumLockUnit, 1e-9)
+	assert.InDelta(t, 100*(1+stopLimit/100), state.StopLimit, 1e-9)
+	assert.Equal(t, config, state.TradeConfig, "the config of the lock is kept")
+}
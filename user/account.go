@@ -2,49 +2,46 @@ package user
 
 import (
 	"fmt"
-	"strconv"
-	"trading/binance"
+	"trading/exchange"
 	"trading/names"
 	"trading/utils"
-
-	binLib "github.com/adshao/go-binance/v2"
 )
 
 type AccountInterface interface {
 	GetBalance(asset string) Balance
-	Account() *binLib.Account
 	Trade(quantity, spot float64, symbol names.Symbol, side names.TradeSide) (error, bool)
 	UpdateLockBalance(asset string, quantity float64)
 	UpdateFreeBalance(asset string, quantity float64)
-	TradeBuyConfig(config names.TradeConfig, spot float64) (*binLib.CreateOrderResponse, error)
-	TradeSellConfig(config names.TradeConfig, spot float64) (*binLib.CreateOrderResponse, error)
+	TradeBuyConfig(config names.TradeConfig, spot float64) (*exchange.Order, error)
+	TradeSellConfig(config names.TradeConfig, spot float64) (*exchange.Order, error)
 }
 
 type Account struct {
 	balances map[string]Balance
-	account  *binLib.Account
 }
 
 func GetAccount() AccountInterface {
+	if utils.Env().IsPaperAccount() {
+		return PaperExchange
+	}
 	if utils.Env().IsMockAccount() {
 		return MockAccount
 	}
 
 	bals := map[string]Balance{}
-	account := binance.GetBinanceAccount()
-	for _, b := range account.Balances {
-		Locked, _ := strconv.ParseFloat(b.Locked, 64)
-		Free, _ := strconv.ParseFloat(b.Free, 64)
-		Asset := b.Asset
+	balances, err := exchange.Get().Balances()
+	if err != nil {
+		utils.LogError(err, "GetAccount()")
+	}
+	for _, b := range balances {
 		bals[b.Asset] = Balance{
-			Locked: Locked,
-			Free:   Free,
-			Asset:  Asset,
+			Locked: b.Locked,
+			Free:   b.Free,
+			Asset:  b.Asset,
 		}
 	}
 	return &Account{
 		balances: bals,
-		account:  account,
 	}
 }
 
@@ -52,10 +49,6 @@ func (account *Account) GetBalance(asset string) Balance {
 	return account.balances[asset]
 }
 
-func (account *Account) Account() *binLib.Account {
-	return account.account
-}
-
 func (account *Account) UpdateLockBalance(asset string, quantity float64) {
 	if b, exists := account.balances[asset]; !exists {
 		account.balances[asset] = Balance{
@@ -90,18 +83,27 @@ func (account *Account) Trade(quantity, spot float64, symbol names.Symbol, side
 	panic("Not implemented for production account")
 }
 
-func (account *Account) TradeBuyConfig(config names.TradeConfig, spot float64) (*binLib.CreateOrderResponse, error) {
+func (account *Account) TradeBuyConfig(config names.TradeConfig, spot float64) (*exchange.Order, error) {
 	symbol := config.Symbol
 	quoteBalance := account.GetBalance(symbol.ParseTradingPair().Quote)
 	quantity := config.Buy.Quantity
 
-	if quantity <= 0 {
-		quantity = symbol.Quantity(quoteBalance.Free / spot)
+	var buyOrder *exchange.Order
+	var err error
+	if config.Buy.Order.Type.IsMarket() {
+		if quantity <= 0 {
+			quantity = symbol.Quantity(quoteBalance.Free / spot)
+		}
+		buyOrder, err = marketOrder(symbol, names.TradeSideBuy, quantity)
+	} else {
+		if quantity <= 0 {
+			// the order may fill above the spot price
+			quantity = symbol.Quantity(quoteBalance.Free / config.Buy.Order.Prices(names.TradeSideBuy, spot).Highest())
+		}
+		buyOrder, err = placeOrder(symbol, names.TradeSideBuy, quantity, spot, config.Buy.Order)
 	}
-	buyOrder, err := binance.CreateBuyMarketOrder(symbol.String(), quantity)
 
 	if err != nil {
-		utils.TextToSpeach("Buy error")
 		utils.LogError(err, fmt.Sprintf(
 			"Error  Buying %s,\n Supplied Qty=%f\n Calculated Qty=%f\n Quote Balance=%f", config.Symbol, config.Buy.Quantity, quantity, quoteBalance.Free))
 	}
@@ -109,7 +111,7 @@ func (account *Account) TradeBuyConfig(config names.TradeConfig, spot float64) (
 	return buyOrder, err
 }
 
-func (account *Account) TradeSellConfig(config names.TradeConfig, spot float64) (*binLib.CreateOrderResponse, error) {
+func (account *Account) TradeSellConfig(config names.TradeConfig, spot float64) (*exchange.Order, error) {
 	symbol := config.Symbol
 	baseBalance := account.GetBalance(symbol.ParseTradingPair().Base)
 	quantity := config.Sell.Quantity
@@ -118,10 +120,15 @@ func (account *Account) TradeSellConfig(config names.TradeConfig, spot float64)
 		quantity = config.Symbol.Quantity(baseBalance.Free)
 	}
 
-	sellOrder, err := binance.CreateSellMarketOrder(symbol.String(), quantity)
+	var sellOrder *exchange.Order
+	var err error
+	if config.Sell.Order.Type.IsMarket() {
+		sellOrder, err = marketOrder(symbol, names.TradeSideSell, quantity)
+	} else {
+		sellOrder, err = placeOrder(symbol, names.TradeSideSell, quantity, spot, config.Sell.Order)
+	}
 
 	if err != nil {
-		utils.TextToSpeach("sell error")
 		utils.LogError(err, fmt.Sprintf("Error Selling %s, Qty=%f Balance=%f", symbol, quantity, baseBalance.Free))
 	}
 
@@ -2,11 +2,10 @@ package user
 
 import (
 	"fmt"
-	"time"
+	"trading/exchange"
 	"trading/names"
 	"trading/utils"
 
-	binLib "github.com/adshao/go-binance/v2"
 	"github.com/joho/godotenv"
 )
 
@@ -16,7 +15,6 @@ func init() {
 
 type AccountMock struct {
 	balances map[string]Balance
-	account  *binLib.Account
 }
 
 func getEnvBalance() map[string]float64 {
@@ -44,7 +42,6 @@ func CreateMockBalance(balance map[string]float64) AccountMock {
 func CreateMockAccount(mock AccountMock) AccountInterface {
 	return &AccountMock{
 		balances: mock.balances,
-		account:  mock.account,
 	}
 }
 
@@ -63,10 +60,6 @@ func (mock *AccountMock) UpdateLockBalance(asset string, locked float64) {
 	}
 }
 
-func (mock *AccountMock) Account() *binLib.Account {
-	return mock.account
-}
-
 func (mock *AccountMock) GetBalance(asset string) Balance {
 	return mock.balances[asset]
 }
@@ -127,7 +120,7 @@ func (mock *AccountMock) Trade(quantity, spot float64, symbol names.Symbol, side
 	return err, debited
 }
 
-func (mock *AccountMock) TradeBuyConfig(config names.TradeConfig, spot float64) (*binLib.CreateOrderResponse, error) {
+func (mock *AccountMock) TradeBuyConfig(config names.TradeConfig, spot float64) (*exchange.Order, error) {
 	symbol := config.Symbol
 	quoteBalance := mock.GetBalance(symbol.ParseTradingPair().Quote)
 
@@ -136,27 +129,27 @@ func (mock *AccountMock) TradeBuyConfig(config names.TradeConfig, spot float64)
 		quantity = symbol.Quantity(quoteBalance.Free / spot)
 	}
 	if err, _ := mock.Trade(quantity, spot, symbol, names.TradeSideBuy); err != nil {
-		utils.TextToSpeach("Buy error")
 		utils.LogError(err, fmt.Sprintf(
 			"Error  Buying %s,\n Supplied Qty=%f\n Calculated Qty=%f\n Quote Balance=%f", config.Symbol, config.Buy.Quantity, quantity, quoteBalance.Free))
-		return &binLib.CreateOrderResponse{}, err
+		return &exchange.Order{}, err
 	}
 
-	buyOrder := &binLib.CreateOrderResponse{
-		Price:            fmt.Sprintf("%f", spot),
-		OrigQuantity:     fmt.Sprintf("%f", quantity),
-		ExecutedQuantity: fmt.Sprintf("%f", quantity),
-		Type:             binLib.OrderTypeMarket,
-		Status:           "FILLED",
-		TransactTime:     time.Now().Unix(),
+	buyOrder := &exchange.Order{
+		Price:            spot,
+		OrigQuantity:     quantity,
+		ExecutedQuantity: quantity,
+		QuoteQuantity:    quantity * spot,
+		Type:             string(names.OrderTypeMarket),
+		Status:           exchange.OrderStatusFilled,
+		Time:             utils.Now().UnixMilli(),
 		Symbol:           symbol.String(),
-		Side:             binLib.SideTypeBuy,
-		OrderID:          123,
+		Side:             names.TradeSideBuy.String(),
+		OrderId:          123,
 	}
 	return buyOrder, nil
 }
 
-func (mock *AccountMock) TradeSellConfig(config names.TradeConfig, spot float64) (*binLib.CreateOrderResponse, error) {
+func (mock *AccountMock) TradeSellConfig(config names.TradeConfig, spot float64) (*exchange.Order, error) {
 	symbol := config.Symbol
 	baseBalance := mock.GetBalance(symbol.ParseTradingPair().Base)
 	quantity := config.Sell.Quantity
@@ -166,21 +159,21 @@ func (mock *AccountMock) TradeSellConfig(config names.TradeConfig, spot float64)
 	}
 
 	if err, _ := mock.Trade(quantity, spot, symbol, names.TradeSideSell); err != nil {
-		utils.TextToSpeach("sell error")
 		utils.LogError(err, fmt.Sprintf("Error Selling %s, Qty=%f Balance=%f", symbol, quantity, baseBalance.Free))
-		return &binLib.CreateOrderResponse{}, err
+		return &exchange.Order{}, err
 	}
 
-	sellOrder := &binLib.CreateOrderResponse{
-		Price:            fmt.Sprintf("%f", spot),
-		OrigQuantity:     fmt.Sprintf("%f", quantity),
-		ExecutedQuantity: fmt.Sprintf("%f", quantity),
-		Type:             binLib.OrderTypeMarket,
-		Status:           "FILLED",
-		TransactTime:     time.Now().Unix(),
+	sellOrder := &exchange.Order{
+		Price:            spot,
+		OrigQuantity:     quantity,
+		ExecutedQuantity: quantity,
+		QuoteQuantity:    quantity * spot,
+		Type:             string(names.OrderTypeMarket),
+		Status:           exchange.OrderStatusFilled,
+		Time:             utils.Now().UnixMilli(),
 		Symbol:           symbol.String(),
-		Side:             binLib.SideTypeSell,
-		OrderID:          123,
+		Side:             names.TradeSideSell.String(),
+		OrderId:          123,
 	}
 	return sellOrder, nil
 }
@@ -0,0 +1,580 @@
+package user
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+	"trading/exchange"
+	"trading/names"
+	"trading/utils"
+)
+
+// PaperBook is the synthetic order book market orders are matched against.
+// The first level is HalfSpread away from the spot price, every other level
+// is LevelSpread further away and each level holds LevelLiquidity worth of
+// the quote asset. Orders larger than the book are partially filled
+type PaperBook struct {
+	HalfSpread     float64
+	LevelSpread    float64
+	LevelLiquidity float64
+	Depth          int
+}
+
+var DefaultPaperBook = PaperBook{
+	HalfSpread:     0.0005,
+	LevelSpread:    0.0005,
+	LevelLiquidity: 5000,
+	Depth:          20,
+}
+
+type paperFee struct {
+	maker float64
+	taker float64
+}
+
+type paperFill struct {
+	price    float64
+	quantity float64
+	// rested on the book before it was filled
+	maker bool
+}
+
+// PaperAccount simulates the exchange for paper trading. Orders must pass the
+// LOT_SIZE and MIN_NOTIONAL filters of the symbol and are filled against a
+// synthetic order book paying the taker fee of the symbol. A limit order
+// takes what its price crosses and the rest rests at the limit price until a
+// later price crosses it, it is then filled paying the maker fee. Stop and
+// OCO orders wait for the price to reach their stop
+type PaperAccount struct {
+	balances map[string]Balance
+	book     PaperBook
+	fees     map[names.Symbol]paperFee
+	// latest price of a symbol the open orders are matched against
+	prices  func(symbol string) (float64, error)
+	orderId int64
+	tradeId int64
+	orders  []exchange.Order
+	lock    sync.Mutex
+}
+
+func NewPaperAccount(balances map[string]float64) *PaperAccount {
+	b := make(map[string]Balance)
+	for asset, free := range balances {
+		b[asset] = Balance{Free: free, Asset: asset}
+	}
+	return &PaperAccount{
+		balances: b,
+		book:     DefaultPaperBook,
+		fees:     make(map[names.Symbol]paperFee),
+		prices: func(symbol string) (float64, error) {
+			return exchange.Get().PriceLatest(symbol)
+		},
+		// ids stay unique across restarts of a paper run
+		orderId: utils.Now().UnixMilli(),
+	}
+}
+
+func (p *PaperAccount) UseBook(book PaperBook) *PaperAccount {
+	p.book = book
+	return p
+}
+
+// UsePrices sets where the open orders get the latest price of their symbol
+func (p *PaperAccount) UsePrices(prices func(symbol string) (float64, error)) *PaperAccount {
+	p.prices = prices
+	return p
+}
+
+// UseFee sets the fee of symbol instead of the fee of the exchange
+func (p *PaperAccount) UseFee(symbol names.Symbol, maker, taker float64) *PaperAccount {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.fees[symbol] = paperFee{maker: maker, taker: taker}
+	return p
+}
+
+// fee of symbol, the fees of the exchange are fetched without holding the lock
+func (p *PaperAccount) fee(symbol names.Symbol) paperFee {
+	p.lock.Lock()
+	fee, exist := p.fees[symbol]
+	p.lock.Unlock()
+	if exist {
+		return fee
+	}
+
+	fee = paperFee{maker: 0.001, taker: 0.001}
+	if details, exist := names.GetTradeFees([]string{symbol.String()})[symbol.String()]; exist {
+		fee = paperFee{maker: details.MakerCommission, taker: details.TakerCommission}
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if cached, exist := p.fees[symbol]; exist {
+		// set by UseFee while the fees were fetched
+		return cached
+	}
+	p.fees[symbol] = fee
+	return fee
+}
+
+func (f paperFee) rate(fill paperFill) float64 {
+	if fill.maker {
+		return f.maker
+	}
+	return f.taker
+}
+
+func (p *PaperAccount) GetBalance(asset string) Balance {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.balances[asset]
+}
+
+func (p *PaperAccount) UpdateLockBalance(asset string, quantity float64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	b := p.balances[asset]
+	b.Asset, b.Locked = asset, quantity
+	p.balances[asset] = b
+}
+
+func (p *PaperAccount) UpdateFreeBalance(asset string, quantity float64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	b := p.balances[asset]
+	b.Asset, b.Free = asset, quantity
+	p.balances[asset] = b
+}
+
+// Orders returns every order of the account once it is complete
+func (p *PaperAccount) Orders() []exchange.Order {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return append([]exchange.Order{}, p.orders...)
+}
+
+func (p *PaperAccount) levelPrice(level int, spot float64, side names.TradeSide) float64 {
+	offset := p.book.HalfSpread + float64(level)*p.book.LevelSpread
+	if side.IsBuy() {
+		return spot * (1 + offset)
+	}
+	return spot * (1 - offset)
+}
+
+// walk matches quantity against the levels of the book on the opposite side
+// of the order up to limit, quantity that is left when the book runs out or
+// reaches limit is not filled. A limit of zero takes every level
+func (p *PaperAccount) walk(quantity, spot, limit float64, side names.TradeSide) []paperFill {
+	fills := []paperFill{}
+	for level := 0; level < p.book.Depth && quantity > 0; level++ {
+		price := p.levelPrice(level, spot, side)
+		if limit > 0 && (side.IsBuy() && price > limit || side.IsSell() && price < limit) {
+			break
+		}
+		filled := math.Min(quantity, p.book.LevelLiquidity/price)
+		fills = append(fills, paperFill{price: price, quantity: filled})
+		quantity -= filled
+	}
+	return fills
+}
+
+// affordable is the quantity that budget of the quote asset buys from the book
+func (p *PaperAccount) affordable(budget, spot float64) float64 {
+	quantity := 0.0
+	for level := 0; level < p.book.Depth && budget > 0; level++ {
+		price := p.levelPrice(level, spot, names.TradeSideBuy)
+		spent := math.Min(budget, p.book.LevelLiquidity)
+		quantity += spent / price
+		budget -= spent
+	}
+	return quantity
+}
+
+// rounds quantity down to a multiple of step
+func stepQuantity(quantity, step float64) float64 {
+	if step <= 0 {
+		return quantity
+	}
+	steps := math.Floor(quantity/step + 1e-9)
+	decimals := math.Max(0, math.Ceil(-math.Log10(step)))
+	power := math.Pow(10, decimals)
+	return math.Round(steps*step*power) / power
+}
+
+// order fills a market order, a quantity that is not above zero uses the whole
+// balance of the asset that is spent
+func (p *PaperAccount) order(quantity, spot float64, symbol names.Symbol, side names.TradeSide) (*exchange.Order, error) {
+	order, _, err := p.place(quantity, spot, symbol, side, names.OrderConfig{})
+	return order, err
+}
+
+// place places the order described by orderConfig. A market order is filled
+// right away, any other order is returned with its paperOrder that stays
+// open until it is matched or canceled
+func (p *PaperAccount) place(quantity, spot float64, symbol names.Symbol, side names.TradeSide, orderConfig names.OrderConfig) (*exchange.Order, *paperOrder, error) {
+	fee := p.fee(symbol)
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	info := symbol.Info()
+	if info.Symbol == "" {
+		return nil, nil, fmt.Errorf("unknown symbol %s", symbol)
+	}
+	if !side.IsBuy() && !side.IsSell() {
+		return nil, nil, fmt.Errorf("invalid trade side, must be BUY or SELL")
+	}
+	if spot <= 0 {
+		return nil, nil, fmt.Errorf("invalid %s price %f", symbol, spot)
+	}
+	// a buy that is not a market order reserves the most a unit can cost
+	reserve := 0.0
+	if !orderConfig.Type.IsMarket() && side.IsBuy() {
+		reserve = symbol.Price(orderConfig.Prices(side, spot).Highest())
+	}
+	base, quote := p.balances[info.BaseAsset], p.balances[info.QuoteAsset]
+	minQty, maxQty, step := symbol.Filter().LotSize()
+
+	if quantity <= 0 {
+		if reserve > 0 {
+			quantity = quote.Free / reserve
+		} else if side.IsBuy() {
+			quantity = p.affordable(quote.Free, spot)
+		} else {
+			quantity = base.Free
+		}
+	}
+	quantity = stepQuantity(quantity, step)
+
+	if quantity <= 0 || quantity < minQty {
+		return nil, nil, fmt.Errorf("LOT_SIZE: %s quantity %f is below the minimum %f", symbol, quantity, minQty)
+	}
+	if maxQty > 0 && quantity > maxQty {
+		return nil, nil, fmt.Errorf("LOT_SIZE: %s quantity %f is above the maximum %f", symbol, quantity, maxQty)
+	}
+	if minNotional := symbol.Filter().MinNotional(); quantity*spot < minNotional {
+		return nil, nil, fmt.Errorf("MIN_NOTIONAL: %s order value %f is below the minimum %f", symbol, quantity*spot, minNotional)
+	}
+	if orderConfig.Type.IsMarket() {
+		order, err := p.fillMarket(quantity, spot, symbol, side, fee)
+		return order, nil, err
+	}
+	open, err := p.open(quantity, spot, symbol, side, orderConfig, fee, reserve)
+	if err != nil {
+		return nil, nil, err
+	}
+	order := open.order
+	return &order, open, nil
+}
+
+// fillMarket walks the book for quantity, what the book does not hold is not filled
+func (p *PaperAccount) fillMarket(quantity, spot float64, symbol names.Symbol, side names.TradeSide, fee paperFee) (*exchange.Order, error) {
+	info := symbol.Info()
+	base, quote := p.balances[info.BaseAsset], p.balances[info.QuoteAsset]
+	fills := p.walk(quantity, spot, 0, side)
+	var executed, value float64
+	for _, f := range fills {
+		executed += f.quantity
+		value += f.quantity * f.price
+	}
+	if executed == 0 {
+		return nil, fmt.Errorf("%s order of %f found no liquidity", symbol, quantity)
+	}
+
+	if side.IsBuy() {
+		if value > quote.Free {
+			return nil, fmt.Errorf("%s cost %f, or balance %f, error", info.QuoteAsset, value, quote.Free)
+		}
+		quote.Free -= value
+		// buy commission is paid from the asset received
+		for _, f := range fills {
+			base.Free += f.quantity * (1 - fee.rate(f))
+		}
+	} else {
+		if quantity > base.Free {
+			return nil, fmt.Errorf("%s cost %f, or balance %f, error", info.BaseAsset, quantity, base.Free)
+		}
+		base.Free -= executed
+		for _, f := range fills {
+			quote.Free += f.quantity * f.price * (1 - fee.rate(f))
+		}
+	}
+	base.Asset, quote.Asset = info.BaseAsset, info.QuoteAsset
+	p.balances[info.BaseAsset], p.balances[info.QuoteAsset] = base, quote
+
+	order := p.newOrder(symbol, side, names.OrderTypeMarket, quantity)
+	order.Status = exchange.OrderStatusFilled
+	if executed < quantity-1e-12 {
+		// the book did not hold enough liquidity for the whole order
+		order.Status = exchange.OrderStatusPartiallyFilled
+	}
+	p.record(&order, side, fills, fee)
+	p.orders = append(p.orders, order)
+	return &order, nil
+}
+
+func (p *PaperAccount) newOrder(symbol names.Symbol, side names.TradeSide, orderType names.OrderType, quantity float64) exchange.Order {
+	p.orderId++
+	return exchange.Order{
+		Symbol:        symbol.String(),
+		OrderId:       p.orderId,
+		ClientOrderId: fmt.Sprintf("paper-%d", p.orderId),
+		Time:          utils.Now().UnixMilli(),
+		OrigQuantity:  quantity,
+		Status:        exchange.OrderStatusNew,
+		Type:          string(orderType),
+		Side:          side.String(),
+	}
+}
+
+// record adds fills to order with their commission
+func (p *PaperAccount) record(order *exchange.Order, side names.TradeSide, fills []paperFill, fee paperFee) {
+	info := names.Symbol(order.Symbol).Info()
+	for _, f := range fills {
+		p.tradeId++
+		commission, commissionAsset := f.quantity*fee.rate(f), info.BaseAsset
+		if side.IsSell() {
+			commission, commissionAsset = f.quantity*f.price*fee.rate(f), info.QuoteAsset
+		}
+		order.Fills = append(order.Fills, exchange.Fill{
+			TradeId:         p.tradeId,
+			Price:           f.price,
+			Quantity:        f.quantity,
+			Commission:      commission,
+			CommissionAsset: commissionAsset,
+		})
+		order.ExecutedQuantity += f.quantity
+		order.QuoteQuantity += f.quantity * f.price
+	}
+	if order.ExecutedQuantity > 0 {
+		order.Price = order.QuoteQuantity / order.ExecutedQuantity
+	}
+}
+
+// paperOrder is a limit, stop or OCO order of the account until it is
+// complete. Its limit takes the book when it is placed or triggered and
+// then rests there, its stop waits for the price to reach it
+type paperOrder struct {
+	order       exchange.Order
+	side        names.TradeSide
+	timeInForce names.TimeInForce
+	fee         paperFee
+	// price the order rests at, zero until a stop order is triggered
+	limit float64
+	// price that triggers the stop, zero without a stop or once triggered
+	stop float64
+	// the stop is reached by a price rising to it
+	stopAbove bool
+	// limit of the order once its stop is triggered
+	stopLimit float64
+	// the limit was just placed and takes what it crosses before it rests
+	taking bool
+	// quote locked per unit of a buy until the order completes
+	reserve float64
+}
+
+// open places an order that is not a market order and locks the funds it
+// needs, it is matched right away against spot
+func (p *PaperAccount) open(quantity, spot float64, symbol names.Symbol, side names.TradeSide, orderConfig names.OrderConfig, fee paperFee, reserve float64) (*paperOrder, error) {
+	info := symbol.Info()
+	prices := orderConfig.Prices(side, spot)
+	po := &paperOrder{side: side, timeInForce: orderConfig.GetTimeInForce(), fee: fee, reserve: reserve, taking: true}
+	switch orderConfig.Type {
+	case names.OrderTypeLimit:
+		po.limit = symbol.Price(prices.Price)
+	case names.OrderTypeStopLossLimit:
+		// a stop loss is triggered by a move against the trade
+		po.stop, po.stopLimit, po.stopAbove = symbol.Price(prices.StopPrice), symbol.Price(prices.Price), side.IsBuy()
+	case names.OrderTypeTakeProfitLimit:
+		po.stop, po.stopLimit, po.stopAbove = symbol.Price(prices.StopPrice), symbol.Price(prices.Price), side.IsSell()
+	case names.OrderTypeOCO:
+		// the limit leg rests until the stop leg is triggered and replaces it
+		po.limit = symbol.Price(prices.Price)
+		po.stop, po.stopLimit, po.stopAbove = symbol.Price(prices.StopPrice), symbol.Price(prices.StopLimitPrice), side.IsBuy()
+	default:
+		return nil, fmt.Errorf("unsupported %s order type %s", symbol, orderConfig.Type)
+	}
+	if po.timeInForce == names.TimeInForceFOK && po.limit > 0 {
+		filled := 0.0
+		for _, f := range p.walk(quantity, spot, po.limit, side) {
+			filled += f.quantity
+		}
+		if filled < quantity-1e-12 {
+			return nil, fmt.Errorf("FOK: %s order of %f could not be filled at %f", symbol, quantity, po.limit)
+		}
+	}
+
+	base, quote := p.balances[info.BaseAsset], p.balances[info.QuoteAsset]
+	if side.IsBuy() {
+		if cost := quantity * reserve; cost > quote.Free {
+			return nil, fmt.Errorf("%s cost %f, or balance %f, error", info.QuoteAsset, cost, quote.Free)
+		}
+		quote.Free -= quantity * reserve
+		quote.Locked += quantity * reserve
+	} else {
+		if quantity > base.Free {
+			return nil, fmt.Errorf("%s cost %f, or balance %f, error", info.BaseAsset, quantity, base.Free)
+		}
+		base.Free -= quantity
+		base.Locked += quantity
+	}
+	base.Asset, quote.Asset = info.BaseAsset, info.QuoteAsset
+	p.balances[info.BaseAsset], p.balances[info.QuoteAsset] = base, quote
+
+	po.order = p.newOrder(symbol, side, orderConfig.Type, quantity)
+	p.match(po, spot)
+	return po, nil
+}
+
+// match fills the open order at the price spot. A stop reached by spot
+// places its limit, a limit that was just placed takes the levels of the
+// book it crosses and a resting limit crossed by spot is filled at its price
+func (p *PaperAccount) match(po *paperOrder, spot float64) {
+	if po.order.Status.IsComplete() {
+		return
+	}
+	if po.stop > 0 && (po.stopAbove && spot >= po.stop || !po.stopAbove && spot <= po.stop) {
+		// the limit leg of an OCO order is canceled by its stop
+		po.limit, po.stop, po.taking = po.stopLimit, 0, true
+	}
+	if po.limit <= 0 {
+		return
+	}
+	rest := po.order.OrigQuantity - po.order.ExecutedQuantity
+	if po.taking {
+		po.taking = false
+		fills := p.walk(rest, spot, po.limit, po.side)
+		filled := 0.0
+		for _, f := range fills {
+			filled += f.quantity
+		}
+		if po.timeInForce == names.TimeInForceFOK && filled < rest-1e-12 {
+			p.end(po, exchange.OrderStatusExpired)
+			return
+		}
+		p.fill(po, fills)
+		if po.timeInForce == names.TimeInForceIOC && !po.order.Status.IsComplete() {
+			// what an IOC order did not take is not left on the book
+			p.end(po, exchange.OrderStatusExpired)
+		}
+		return
+	}
+	if po.side.IsBuy() && spot <= po.limit || po.side.IsSell() && spot >= po.limit {
+		p.fill(po, []paperFill{{price: po.limit, quantity: rest, maker: true}})
+	}
+}
+
+// fill books fills of the open order from the funds it locked
+func (p *PaperAccount) fill(po *paperOrder, fills []paperFill) {
+	if len(fills) == 0 {
+		return
+	}
+	info := names.Symbol(po.order.Symbol).Info()
+	base, quote := p.balances[info.BaseAsset], p.balances[info.QuoteAsset]
+	for _, f := range fills {
+		if po.side.IsBuy() {
+			quote.Locked -= f.quantity * po.reserve
+			// a fill below the reserved price gives the difference back
+			quote.Free += f.quantity * (po.reserve - f.price)
+			base.Free += f.quantity * (1 - po.fee.rate(f))
+		} else {
+			base.Locked -= f.quantity
+			quote.Free += f.quantity * f.price * (1 - po.fee.rate(f))
+		}
+	}
+	p.balances[info.BaseAsset], p.balances[info.QuoteAsset] = base, quote
+
+	p.record(&po.order, po.side, fills, po.fee)
+	po.order.Status = exchange.OrderStatusPartiallyFilled
+	if po.order.ExecutedQuantity >= po.order.OrigQuantity-1e-12 {
+		po.order.Status = exchange.OrderStatusFilled
+		p.orders = append(p.orders, po.order)
+	}
+}
+
+// end completes the open order with status and unlocks the funds of what
+// was not filled
+func (p *PaperAccount) end(po *paperOrder, status exchange.OrderStatus) {
+	info := names.Symbol(po.order.Symbol).Info()
+	rest := po.order.OrigQuantity - po.order.ExecutedQuantity
+	if po.side.IsBuy() {
+		quote := p.balances[info.QuoteAsset]
+		quote.Locked -= rest * po.reserve
+		quote.Free += rest * po.reserve
+		p.balances[info.QuoteAsset] = quote
+	} else {
+		base := p.balances[info.BaseAsset]
+		base.Locked -= rest
+		base.Free += rest
+		p.balances[info.BaseAsset] = base
+	}
+	po.order.Status = status
+	p.orders = append(p.orders, po.order)
+}
+
+// wait matches the open order against the latest prices of its symbol until
+// it is complete, what is not filled after timeout is canceled
+func (p *PaperAccount) wait(po *paperOrder, timeout time.Duration) exchange.Order {
+	started := utils.Now()
+	for {
+		p.lock.Lock()
+		if !po.order.Status.IsComplete() && utils.Now().Sub(started) >= timeout {
+			p.end(po, exchange.OrderStatusCanceled)
+		}
+		if po.order.Status.IsComplete() {
+			order := po.order
+			p.lock.Unlock()
+			return order
+		}
+		p.lock.Unlock()
+
+		time.Sleep(OrderPollInterval)
+		price, err := p.prices(po.order.Symbol)
+		if err != nil {
+			utils.LogWarn(fmt.Sprintf("<Paper>: %s order %d is not matched, %s", po.order.Symbol, po.order.OrderId, err.Error()))
+			continue
+		}
+		p.lock.Lock()
+		p.match(po, price)
+		p.lock.Unlock()
+	}
+}
+
+func (p *PaperAccount) Trade(quantity, spot float64, symbol names.Symbol, side names.TradeSide) (error, bool) {
+	_, err := p.order(quantity, spot, symbol, side)
+	return err, err == nil
+}
+
+// trade places the order of sideConfig and waits for it like an order of
+// the exchange, a partial fill is returned with a PartialFillError
+func (p *PaperAccount) trade(symbol names.Symbol, side names.TradeSide, sideConfig names.SideConfig, spot float64) (*exchange.Order, error) {
+	order, open, err := p.place(sideConfig.Quantity, spot, symbol, side, sideConfig.Order)
+	if err != nil || open == nil {
+		return order, err
+	}
+	final := p.wait(open, sideConfig.Order.GetTimeout())
+	return orderResult(symbol, &final, final.ExecutedQuantity, final.OrigQuantity)
+}
+
+func (p *PaperAccount) TradeBuyConfig(config names.TradeConfig, spot float64) (*exchange.Order, error) {
+	order, err := p.trade(config.Symbol, names.TradeSideBuy, config.Buy, spot)
+	if err != nil {
+		utils.LogError(err, fmt.Sprintf("<Paper>: Error Buying %s, Qty=%f", config.Symbol, config.Buy.Quantity))
+	}
+	if order == nil {
+		return &exchange.Order{}, err
+	}
+	return order, err
+}
+
+func (p *PaperAccount) TradeSellConfig(config names.TradeConfig, spot float64) (*exchange.Order, error) {
+	order, err := p.trade(config.Symbol, names.TradeSideSell, config.Sell, spot)
+	if err != nil {
+		utils.LogError(err, fmt.Sprintf("<Paper>: Error Selling %s, Qty=%f", config.Symbol, config.Sell.Quantity))
+	}
+	if order == nil {
+		return &exchange.Order{}, err
+	}
+	return order, err
+}
+
+var PaperExchange = NewPaperAccount(getEnvBalance())
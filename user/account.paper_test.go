@@ -0,0 +1,231 @@
+package user
+
+import (
+	"testing"
+	"time"
+	"trading/exchange"
+	"trading/names"
+	"trading/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func usePaperSymbol() names.Symbol {
+	names.UseExchangeInfo(exchange.ExchangeInfo{
+		Symbols: []exchange.SymbolInfo{{
+			Symbol:     "BTCUSDT",
+			BaseAsset:  "BTC",
+			QuoteAsset: "USDT",
+			Filters: []map[string]interface{}{
+				{"filterType": "LOT_SIZE", "minQty": "0.001", "maxQty": "100", "stepSize": "0.001"},
+				{"filterType": "MIN_NOTIONAL", "minNotional": "10"},
+			},
+		}},
+	})
+	return names.Symbol("BTCUSDT")
+}
+
+func TestPaperAccountFill(t *testing.T) {
+	utils.Env().SetModeMock()
+	symbol := usePaperSymbol()
+	account := NewPaperAccount(map[string]float64{"USDT": 100000}).
+		UseBook(PaperBook{HalfSpread: 0.001, LevelSpread: 0.001, LevelLiquidity: 1000, Depth: 3}).
+		UseFee(symbol, 0.001, 0.002)
+
+	order, err := account.order(0.01, 1000, symbol, names.TradeSideBuy)
+	assert.Nil(t, err)
+	assert.Equal(t, exchange.OrderStatusFilled, order.Status)
+	assert.Len(t, order.Fills, 1)
+	assert.InDelta(t, 1001, order.Fills[0].Price, 1e-9, "buy is filled above the spot price")
+	assert.InDelta(t, 0.01*0.002, order.Fills[0].Commission, 1e-12, "market orders pay the taker fee")
+	assert.Equal(t, "BTC", order.Fills[0].CommissionAsset)
+	assert.InDelta(t, 100000-10.01, account.GetBalance("USDT").Free, 1e-9)
+	assert.InDelta(t, 0.01*(1-0.002), account.GetBalance("BTC").Free, 1e-12)
+
+	// 1.5 BTC takes the first level and part of the second
+	order, err = account.order(1.5, 1000, symbol, names.TradeSideBuy)
+	assert.Nil(t, err)
+	assert.Len(t, order.Fills, 2)
+	assert.Greater(t, order.Price, 1001.0, "larger orders slip further into the book")
+
+	sell, err := account.order(0.5, 1000, symbol, names.TradeSideSell)
+	assert.Nil(t, err)
+	assert.InDelta(t, 999, sell.Fills[0].Price, 1e-9, "sell is filled below the spot price")
+	assert.Equal(t, "USDT", sell.Fills[0].CommissionAsset)
+	assert.NotEqual(t, order.OrderId, sell.OrderId)
+	assert.Len(t, account.Orders(), 3)
+}
+
+func TestPaperAccountPartialFill(t *testing.T) {
+	utils.Env().SetModeMock()
+	symbol := usePaperSymbol()
+	account := NewPaperAccount(map[string]float64{"USDT": 100000}).
+		UseBook(PaperBook{HalfSpread: 0, LevelSpread: 0, LevelLiquidity: 1000, Depth: 2}).
+		UseFee(symbol, 0, 0)
+
+	order, err := account.order(5, 1000, symbol, names.TradeSideBuy)
+	assert.Nil(t, err)
+	assert.Equal(t, exchange.OrderStatusPartiallyFilled, order.Status)
+	assert.Equal(t, 5.0, order.OrigQuantity)
+	assert.InDelta(t, 2, order.ExecutedQuantity, 1e-9, "the book only holds 2000 USDT")
+	assert.InDelta(t, 98000, account.GetBalance("USDT").Free, 1e-9)
+}
+
+func TestPaperAccountFilters(t *testing.T) {
+	utils.Env().SetModeMock()
+	symbol := usePaperSymbol()
+	account := NewPaperAccount(map[string]float64{"USDT": 100000, "BTC": 1000}).UseFee(symbol, 0, 0)
+
+	_, err := account.order(0.0009, 1000, symbol, names.TradeSideBuy)
+	assert.ErrorContains(t, err, "LOT_SIZE")
+	_, err = account.order(101, 1000, symbol, names.TradeSideSell)
+	assert.ErrorContains(t, err, "LOT_SIZE")
+	_, err = account.order(0.005, 1000, symbol, names.TradeSideBuy)
+	assert.ErrorContains(t, err, "MIN_NOTIONAL")
+
+	order, err := account.order(0.0129, 1000, symbol, names.TradeSideSell)
+	assert.Nil(t, err)
+	assert.Equal(t, 0.012, order.OrigQuantity, "quantity is rounded down to the step size")
+
+	_, err = account.order(1, 1000, names.Symbol("ETHUSDT"), names.TradeSideBuy)
+	assert.ErrorContains(t, err, "unknown symbol")
+	assert.Len(t, account.Orders(), 1, "rejected orders are not filled")
+}
+
+func TestPaperAccountMaxBuy(t *testing.T) {
+	utils.Env().SetModeMock()
+	symbol := usePaperSymbol()
+	account := NewPaperAccount(map[string]float64{"USDT": 1500}).UseFee(symbol, 0, 0)
+
+	config := names.TradeConfig{Symbol: symbol, Buy: names.SideConfig{Quantity: -1}}
+	order, err := account.TradeBuyConfig(config, 1000)
+	assert.Nil(t, err)
+	assert.Equal(t, exchange.OrderStatusFilled, order.Status)
+	assert.LessOrEqual(t, order.QuoteQuantity, 1500.0, "max buy is limited by the quote balance")
+	assert.Greater(t, account.GetBalance("BTC").Free, 1.49)
+}
+
+// replayPrices returns prices one after the other, the last one is repeated
+func replayPrices(prices ...float64) func(string) (float64, error) {
+	return func(symbol string) (float64, error) {
+		price := prices[0]
+		if len(prices) > 1 {
+			prices = prices[1:]
+		}
+		return price, nil
+	}
+}
+
+func TestPaperAccountLimit(t *testing.T) {
+	utils.Env().SetModeMock()
+	symbol := usePaperSymbol()
+	interval := OrderPollInterval
+	OrderPollInterval = 0
+	defer func() { OrderPollInterval = interval }()
+	account := NewPaperAccount(map[string]float64{"USDT": 100000}).
+		UseBook(PaperBook{HalfSpread: 0.001, LevelSpread: 0.001, LevelLiquidity: 1000, Depth: 3}).
+		UseFee(symbol, 0.001, 0.002)
+
+	// the limit is below the book so the whole order rests
+	limit := names.OrderConfig{Type: names.OrderTypeLimit, LimitOffset: 1}
+	order, open, err := account.place(1, 1000, symbol, names.TradeSideBuy, limit)
+	assert.Nil(t, err)
+	assert.Equal(t, exchange.OrderStatusNew, order.Status, "a limit buy below spot stays open")
+	assert.Equal(t, 0.0, order.ExecutedQuantity)
+	assert.Equal(t, Balance{Asset: "USDT", Free: 100000 - 990, Locked: 990}, account.GetBalance("USDT"), "the cost of the order is locked")
+	account.match(open, 995)
+	assert.Equal(t, exchange.OrderStatusNew, open.order.Status, "a price above the limit does not fill it")
+	account.match(open, 989)
+	assert.Equal(t, exchange.OrderStatusFilled, open.order.Status)
+	assert.InDelta(t, 990, open.order.Price, 1e-9, "a resting order is filled at its limit price")
+	assert.InDelta(t, 0.001, open.order.Fills[0].Commission, 1e-12, "resting fills pay the maker fee")
+	assert.InDelta(t, 1-0.001, account.GetBalance("BTC").Free, 1e-12)
+	assert.InDelta(t, 0, account.GetBalance("USDT").Locked, 1e-9)
+
+	resting := names.TradeConfig{Symbol: symbol, Buy: names.SideConfig{Quantity: 1, Order: limit}}
+	account.UsePrices(replayPrices(1000, 992, 985))
+	order, err = account.TradeBuyConfig(resting, 1000)
+	assert.Nil(t, err)
+	assert.Equal(t, exchange.OrderStatusFilled, order.Status, "filled once a later price crosses the limit")
+
+	// the price never comes down to the limit
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	utils.UseClock(func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	})
+	defer utils.UseClock(nil)
+	account.UsePrices(replayPrices(1000))
+	resting.Buy.Order.Timeout = 60
+	free := account.GetBalance("USDT").Free
+	order, err = account.TradeBuyConfig(resting, 1000)
+	assert.ErrorContains(t, err, "ended CANCELED without a fill")
+	assert.Equal(t, exchange.OrderStatusCanceled, order.Status)
+	assert.InDelta(t, free, account.GetBalance("USDT").Free, 1e-9, "a canceled order unlocks its funds")
+
+	// the limit crosses the first level, the rest of the order rests at the limit
+	crossing := names.OrderConfig{Type: names.OrderTypeLimit, LimitOffset: -0.15}
+	order, open, err = account.place(2, 1000, symbol, names.TradeSideBuy, crossing)
+	assert.Nil(t, err)
+	assert.Equal(t, exchange.OrderStatusPartiallyFilled, order.Status)
+	assert.InDelta(t, 1000/1001.0*0.002, order.Fills[0].Commission, 1e-12, "what crosses the book pays the taker fee")
+	account.match(open, 1001)
+	assert.Equal(t, exchange.OrderStatusFilled, open.order.Status)
+	assert.InDelta(t, (2-1000/1001.0)*0.001, open.order.Fills[1].Commission, 1e-12)
+
+	crossing.TimeInForce = names.TimeInForceIOC
+	order, _, err = account.place(2, 1000, symbol, names.TradeSideBuy, crossing)
+	assert.Nil(t, err)
+	assert.Equal(t, exchange.OrderStatusExpired, order.Status, "an IOC order does not rest")
+	assert.InDelta(t, 1000/1001.0, order.ExecutedQuantity, 1e-9)
+
+	resting.Buy.Order.TimeInForce = names.TimeInForceFOK
+	_, err = account.TradeBuyConfig(resting, 1000)
+	assert.ErrorContains(t, err, "FOK")
+}
+
+func TestPaperAccountStops(t *testing.T) {
+	utils.Env().SetModeMock()
+	symbol := usePaperSymbol()
+	account := NewPaperAccount(map[string]float64{"BTC": 10}).
+		UseBook(PaperBook{HalfSpread: 0, LevelSpread: 0.001, LevelLiquidity: 100000, Depth: 3}).
+		UseFee(symbol, 0, 0)
+
+	// triggered at 980 and then sold down to 970
+	stopLoss := names.OrderConfig{Type: names.OrderTypeStopLossLimit, StopOffset: 2, StopLimitOffset: 1.0204}
+	order, open, err := account.place(1, 1000, symbol, names.TradeSideSell, stopLoss)
+	assert.Nil(t, err)
+	assert.Equal(t, exchange.OrderStatusNew, order.Status, "a stop loss waits for its stop")
+	account.match(open, 1050)
+	account.match(open, 990)
+	assert.Equal(t, exchange.OrderStatusNew, open.order.Status)
+	account.match(open, 975)
+	assert.Equal(t, exchange.OrderStatusFilled, open.order.Status, "the triggered limit takes the book")
+	assert.InDelta(t, 975, open.order.Price, 1e-9)
+
+	takeProfit := names.OrderConfig{Type: names.OrderTypeTakeProfitLimit, StopOffset: 2}
+	_, open, err = account.place(1, 1000, symbol, names.TradeSideSell, takeProfit)
+	assert.Nil(t, err)
+	account.match(open, 990)
+	assert.Equal(t, exchange.OrderStatusNew, open.order.Status, "a take profit waits for the price to rise")
+	account.match(open, 1025)
+	assert.Equal(t, exchange.OrderStatusFilled, open.order.Status)
+
+	oco := names.OrderConfig{Type: names.OrderTypeOCO, LimitOffset: 3, StopOffset: 2, StopLimitOffset: 1}
+	_, open, err = account.place(1, 1000, symbol, names.TradeSideSell, oco)
+	assert.Nil(t, err)
+	account.match(open, 1010)
+	assert.Equal(t, exchange.OrderStatusNew, open.order.Status, "neither leg is reached")
+	account.match(open, 1031)
+	assert.Equal(t, exchange.OrderStatusFilled, open.order.Status, "the limit leg rests at its price")
+	assert.InDelta(t, 1030, open.order.Price, 1e-9)
+
+	_, open, err = account.place(1, 1000, symbol, names.TradeSideSell, oco)
+	assert.Nil(t, err)
+	account.match(open, 979)
+	account.match(open, 1031)
+	assert.Equal(t, exchange.OrderStatusFilled, open.order.Status)
+	assert.Less(t, open.order.Price, 1000.0, "the stop leg canceled the limit leg")
+	assert.InDelta(t, 6, account.GetBalance("BTC").Free, 1e-9)
+	assert.InDelta(t, 0, account.GetBalance("BTC").Locked, 1e-9)
+}
@@ -0,0 +1,237 @@
+package user
+
+import (
+	"fmt"
+	"time"
+	"trading/exchange"
+	"trading/names"
+	"trading/utils"
+)
+
+// how often a placed order is checked until it completes
+var OrderPollInterval = 2 * time.Second
+
+// consecutive failed checks of an order before it is no longer followed
+var OrderQueryRetries = 10
+
+type OrderStatusChange struct {
+	OrderId int64
+	Status  exchange.OrderStatus
+	Time    time.Time
+}
+
+// OrderLifecycle follows the orders placed for a trade until they are
+// filled, canceled, rejected or expired. An OCO trade has two orders
+type OrderLifecycle struct {
+	Symbol   string
+	OrderIds []int64
+	Changes  []OrderStatusChange
+	// last state of the order that completed the trade
+	Order *exchange.Order
+	// last state of every order that could be checked
+	last []*exchange.Order
+}
+
+// PartialFillError is returned with an order that ended before its whole
+// quantity was filled, the executed quantity was traded
+type PartialFillError struct {
+	Symbol   string
+	OrderId  int64
+	Executed float64
+	Quantity float64
+}
+
+func (e *PartialFillError) Error() string {
+	return fmt.Sprintf("%s order %d only filled %f of %f", e.Symbol, e.OrderId, e.Executed, e.Quantity)
+}
+
+// Status of the order with orderId, empty when it has not been seen
+func (l *OrderLifecycle) Status(orderId int64) exchange.OrderStatus {
+	for i := len(l.Changes) - 1; i >= 0; i-- {
+		if l.Changes[i].OrderId == orderId {
+			return l.Changes[i].Status
+		}
+	}
+	return ""
+}
+
+func (l *OrderLifecycle) update(order *exchange.Order) {
+	if l.Status(order.OrderId) == order.Status {
+		return
+	}
+	l.Changes = append(l.Changes, OrderStatusChange{OrderId: order.OrderId, Status: order.Status, Time: utils.Now()})
+	utils.LogInfo(fmt.Sprintf("<Order>: %s %s order %d is %s, executed %f of %f",
+		order.Symbol, order.Type, order.OrderId, order.Status, order.ExecutedQuantity, order.OrigQuantity))
+}
+
+// settle records the orders and reports whether the trade is complete, a trade
+// is complete when one of its orders is filled or every order is complete
+func (l *OrderLifecycle) settle(orders []*exchange.Order) bool {
+	complete := true
+	for _, order := range orders {
+		l.update(order)
+		complete = complete && order.Status.IsComplete()
+	}
+	for _, order := range orders {
+		if order.Status == exchange.OrderStatusFilled {
+			l.Order = order
+			return true
+		}
+	}
+	if !complete {
+		return false
+	}
+	// none was filled, keep the order that executed the most before it ended
+	l.Order = orders[0]
+	for _, order := range orders[1:] {
+		if order.ExecutedQuantity > l.Order.ExecutedQuantity {
+			l.Order = order
+		}
+	}
+	return true
+}
+
+// check reads every order, false when one of them could not be read
+func (l *OrderLifecycle) check() ([]*exchange.Order, bool) {
+	orders := []*exchange.Order{}
+	for _, id := range l.OrderIds {
+		order, err := exchange.Get().GetOrder(l.Symbol, id)
+		if err != nil {
+			utils.LogWarn(fmt.Sprintf("<Order>: could not check %s order %d, %s", l.Symbol, id, err.Error()))
+			return orders, false
+		}
+		orders = append(orders, order)
+	}
+	l.last = orders
+	return orders, true
+}
+
+// cancel the orders that are not known to be complete
+func (l *OrderLifecycle) cancel(reason string) {
+	for _, id := range l.OrderIds {
+		if l.Status(id).IsComplete() {
+			continue
+		}
+		utils.LogInfo(fmt.Sprintf("<Order>: canceling %s order %d %s", l.Symbol, id, reason))
+		if err := exchange.Get().CancelOrder(l.Symbol, id); err != nil {
+			utils.LogError(err, fmt.Sprintf("<Order>: could not cancel %s order %d", l.Symbol, id))
+		}
+	}
+}
+
+// mostExecuted is the order last seen that filled the most
+func (l *OrderLifecycle) mostExecuted() *exchange.Order {
+	order := &exchange.Order{Symbol: l.Symbol}
+	for _, last := range l.last {
+		if last.ExecutedQuantity > order.ExecutedQuantity {
+			order = last
+		}
+	}
+	return order
+}
+
+// Track checks the orders until the trade is complete and returns the
+// quantity it executed, orders still open after timeout are canceled. Orders
+// that can not be checked are canceled and checked one last time
+func (l *OrderLifecycle) Track(timeout time.Duration) (float64, error) {
+	started := utils.Now()
+	canceled, failures := false, 0
+	for {
+		if orders, ok := l.check(); ok {
+			failures = 0
+			if l.settle(orders) {
+				return l.Order.ExecutedQuantity, nil
+			}
+		} else if failures++; failures >= OrderQueryRetries {
+			l.cancel(fmt.Sprintf("after %d failed checks", failures))
+			if orders, ok := l.check(); ok && l.settle(orders) {
+				return l.Order.ExecutedQuantity, nil
+			}
+			return l.mostExecuted().ExecutedQuantity, fmt.Errorf("%s orders %v could not be checked after %d attempts", l.Symbol, l.OrderIds, failures)
+		}
+
+		if !canceled && utils.Now().Sub(started) >= timeout {
+			canceled = true
+			l.cancel(fmt.Sprintf("after %s", timeout))
+			continue
+		}
+		time.Sleep(OrderPollInterval)
+	}
+}
+
+// Response is the order that completed the trade, or the order last seen
+// that filled the most when the trade could not be followed to its end
+func (l *OrderLifecycle) Response() *exchange.Order {
+	if l.Order == nil {
+		return l.mostExecuted()
+	}
+	return l.Order
+}
+
+// marketOrder fills quantity at the market price
+func marketOrder(symbol names.Symbol, side names.TradeSide, quantity float64) (*exchange.Order, error) {
+	return exchange.Get().PlaceOrder(exchange.OrderRequest{
+		Symbol:   symbol.String(),
+		Side:     side.String(),
+		Type:     string(names.OrderTypeMarket),
+		Quantity: quantity,
+	})
+}
+
+// placeOrder places the order described by orderConfig and waits for it to complete,
+// an error is returned when nothing was filled and a PartialFillError with the
+// order when only part of quantity was
+func placeOrder(symbol names.Symbol, side names.TradeSide, quantity, spot float64, orderConfig names.OrderConfig) (*exchange.Order, error) {
+	prices := orderConfig.Prices(side, spot)
+	request := exchange.OrderRequest{
+		Symbol:         symbol.String(),
+		Side:           side.String(),
+		Type:           string(orderConfig.Type),
+		TimeInForce:    string(orderConfig.GetTimeInForce()),
+		Quantity:       quantity,
+		Price:          symbol.Price(prices.Price),
+		StopPrice:      symbol.Price(prices.StopPrice),
+		StopLimitPrice: symbol.Price(prices.StopLimitPrice),
+	}
+
+	lifecycle := &OrderLifecycle{Symbol: symbol.String()}
+	if orderConfig.Type == names.OrderTypeOCO {
+		orders, err := exchange.Get().PlaceOCOOrder(request)
+		if err != nil {
+			return nil, err
+		}
+		for _, order := range orders {
+			lifecycle.OrderIds = append(lifecycle.OrderIds, order.OrderId)
+		}
+	} else {
+		order, err := exchange.Get().PlaceOrder(request)
+		if err != nil {
+			return nil, err
+		}
+		lifecycle.OrderIds = []int64{order.OrderId}
+	}
+	utils.LogInfo(fmt.Sprintf("<Order>: placed %s %s %s Qty=%f Price=%f Stop=%f StopLimit=%f",
+		orderConfig.Type, side, symbol, request.Quantity, request.Price, request.StopPrice, request.StopLimitPrice))
+
+	executed, err := lifecycle.Track(orderConfig.GetTimeout())
+	if err != nil && executed == 0 {
+		return nil, err
+	}
+	if err != nil {
+		// the orders were lost after part of them was filled
+		utils.LogError(err, fmt.Sprintf("<Order>: %s %s kept the last fill seen", side, symbol))
+	}
+	return orderResult(symbol, lifecycle.Response(), executed, request.Quantity)
+}
+
+// orderResult returns order that ended after executing executed of quantity
+// with an error when nothing was filled and a PartialFillError when part was
+func orderResult(symbol names.Symbol, order *exchange.Order, executed, quantity float64) (*exchange.Order, error) {
+	if executed == 0 {
+		return order, fmt.Errorf("%s order %d ended %s without a fill", symbol, order.OrderId, order.Status)
+	}
+	if executed < quantity-1e-12 {
+		return order, &PartialFillError{Symbol: symbol.String(), OrderId: order.OrderId, Executed: executed, Quantity: quantity}
+	}
+	return order, nil
+}
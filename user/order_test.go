@@ -0,0 +1,178 @@
+package user
+
+import (
+	"fmt"
+	"testing"
+	"time"
+	"trading/exchange"
+	"trading/names"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeOrders answers every check of an order with its next state, the
+// last state is repeated once all were returned
+type fakeOrders struct {
+	exchange.Exchange
+	states   map[int64][]exchange.Order
+	canceled []int64
+	// checks that fail before the orders can be checked again
+	failures int
+}
+
+func (f *fakeOrders) PlaceOrder(request exchange.OrderRequest) (*exchange.Order, error) {
+	return &exchange.Order{Symbol: request.Symbol, OrderId: 1, Status: exchange.OrderStatusNew}, nil
+}
+
+func (f *fakeOrders) GetOrder(symbol string, orderId int64) (*exchange.Order, error) {
+	if f.failures > 0 {
+		f.failures--
+		return nil, fmt.Errorf("order %d timed out", orderId)
+	}
+	states, exist := f.states[orderId]
+	if !exist {
+		return nil, fmt.Errorf("unknown order %d", orderId)
+	}
+	order := states[0]
+	if len(states) > 1 {
+		f.states[orderId] = states[1:]
+	}
+	return &order, nil
+}
+
+func (f *fakeOrders) CancelOrder(symbol string, orderId int64) error {
+	f.canceled = append(f.canceled, orderId)
+	states, exist := f.states[orderId]
+	if !exist {
+		return fmt.Errorf("unknown order %d", orderId)
+	}
+	last := states[len(states)-1]
+	last.Status = exchange.OrderStatusCanceled
+	f.states[orderId] = []exchange.Order{last}
+	return nil
+}
+
+func useFakeOrders(t *testing.T, states map[int64][]exchange.Order) *fakeOrders {
+	fake := &fakeOrders{states: states}
+	interval := OrderPollInterval
+	OrderPollInterval = time.Millisecond
+	previous := exchange.Use(fake)
+	t.Cleanup(func() {
+		OrderPollInterval = interval
+		exchange.Use(previous)
+	})
+	return fake
+}
+
+func orderState(id int64, status exchange.OrderStatus, executed, price float64) exchange.Order {
+	return exchange.Order{Symbol: "BTCUSDT", OrderId: id, Status: status, OrigQuantity: 2, ExecutedQuantity: executed, Price: price, QuoteQuantity: executed * price}
+}
+
+func TestOrderPrices(t *testing.T) {
+	oco := names.OrderConfig{Type: names.OrderTypeOCO, LimitOffset: 2, StopOffset: 1, StopLimitOffset: 1}
+	prices := oco.Prices(names.TradeSideSell, 100)
+	assert.InDelta(t, 102, prices.Price, 1e-9, "sell limit leg is above the market")
+	assert.InDelta(t, 99, prices.StopPrice, 1e-9, "sell stop is below the market")
+	assert.InDelta(t, 98.01, prices.StopLimitPrice, 1e-9)
+
+	prices = names.OrderConfig{Type: names.OrderTypeLimit, LimitOffset: 1}.Prices(names.TradeSideBuy, 100)
+	assert.InDelta(t, 99, prices.Price, 1e-9, "buy limit is below the market")
+
+	prices = names.OrderConfig{Type: names.OrderTypeTakeProfitLimit, StopOffset: 5, StopLimitOffset: 1}.Prices(names.TradeSideSell, 100)
+	assert.InDelta(t, 105, prices.StopPrice, 1e-9, "sell take profit is above the market")
+	assert.InDelta(t, 103.95, prices.Price, 1e-9)
+}
+
+func TestOrderLifecycle(t *testing.T) {
+	useFakeOrders(t, map[int64][]exchange.Order{
+		1: {
+			orderState(1, exchange.OrderStatusNew, 0, 0),
+			orderState(1, exchange.OrderStatusPartiallyFilled, 1, 100),
+			orderState(1, exchange.OrderStatusPartiallyFilled, 1, 100),
+			orderState(1, exchange.OrderStatusFilled, 2, 105),
+		},
+	})
+	lifecycle := &OrderLifecycle{Symbol: "BTCUSDT", OrderIds: []int64{1}}
+	executed, err := lifecycle.Track(time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, 2.0, executed)
+
+	statuses := []exchange.OrderStatus{}
+	for _, change := range lifecycle.Changes {
+		statuses = append(statuses, change.Status)
+	}
+	assert.Equal(t, []exchange.OrderStatus{
+		exchange.OrderStatusNew, exchange.OrderStatusPartiallyFilled, exchange.OrderStatusFilled,
+	}, statuses)
+
+	response := lifecycle.Response()
+	assert.Equal(t, exchange.OrderStatusFilled, response.Status)
+	assert.Equal(t, 105.0, response.Price)
+}
+
+func TestOrderLifecycleOCO(t *testing.T) {
+	useFakeOrders(t, map[int64][]exchange.Order{
+		1: {orderState(1, exchange.OrderStatusNew, 0, 0), orderState(1, exchange.OrderStatusCanceled, 0, 0)},
+		2: {orderState(2, exchange.OrderStatusNew, 0, 0), orderState(2, exchange.OrderStatusFilled, 2, 100)},
+	})
+	lifecycle := &OrderLifecycle{Symbol: "BTCUSDT", OrderIds: []int64{1, 2}}
+	_, err := lifecycle.Track(time.Minute)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 2, lifecycle.Response().OrderId, "the filled leg completes the trade")
+}
+
+func TestOrderLifecycleTimeout(t *testing.T) {
+	fake := useFakeOrders(t, map[int64][]exchange.Order{
+		1: {orderState(1, exchange.OrderStatusPartiallyFilled, 0.5, 100)},
+	})
+	lifecycle := &OrderLifecycle{Symbol: "BTCUSDT", OrderIds: []int64{1}}
+	executed, err := lifecycle.Track(5 * time.Millisecond)
+	assert.Nil(t, err)
+	assert.Equal(t, 0.5, executed, "the caller decides what to do with a partial fill")
+	assert.Equal(t, []int64{1}, fake.canceled)
+
+	response := lifecycle.Response()
+	assert.Equal(t, exchange.OrderStatusCanceled, response.Status)
+	assert.Equal(t, 0.5, response.ExecutedQuantity, "the filled part is kept")
+}
+
+func TestOrderLifecycleUnknown(t *testing.T) {
+	useFakeOrders(t, map[int64][]exchange.Order{})
+	retries := OrderQueryRetries
+	OrderQueryRetries = 3
+	defer func() { OrderQueryRetries = retries }()
+
+	lifecycle := &OrderLifecycle{Symbol: "BTCUSDT", OrderIds: []int64{9}}
+	_, err := lifecycle.Track(time.Minute)
+	assert.NotNil(t, err)
+}
+
+func TestOrderLifecycleLost(t *testing.T) {
+	fake := useFakeOrders(t, map[int64][]exchange.Order{
+		1: {orderState(1, exchange.OrderStatusPartiallyFilled, 0.5, 100)},
+	})
+	fake.failures = 3
+	retries := OrderQueryRetries
+	OrderQueryRetries = 3
+	defer func() { OrderQueryRetries = retries }()
+
+	lifecycle := &OrderLifecycle{Symbol: "BTCUSDT", OrderIds: []int64{1}}
+	executed, err := lifecycle.Track(time.Minute)
+	assert.Nil(t, err, "the order is checked one last time after it is canceled")
+	assert.Equal(t, []int64{1}, fake.canceled)
+	assert.Equal(t, 0.5, executed)
+	assert.Equal(t, exchange.OrderStatusCanceled, lifecycle.Response().Status)
+}
+
+func TestPlaceOrderPartialFill(t *testing.T) {
+	symbol := usePaperSymbol()
+	useFakeOrders(t, map[int64][]exchange.Order{
+		1: {orderState(1, exchange.OrderStatusPartiallyFilled, 0.5, 100)},
+	})
+	order, err := placeOrder(symbol, names.TradeSideBuy, 2, 100, names.OrderConfig{Type: names.OrderTypeLimit, Timeout: 1})
+	var partial *PartialFillError
+	assert.ErrorAs(t, err, &partial)
+	assert.Equal(t, 0.5, partial.Executed)
+	assert.Equal(t, 2.0, partial.Quantity)
+	assert.Equal(t, 0.5, order.ExecutedQuantity, "the order is returned with the error")
+}
@@ -4,9 +4,10 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strconv"
+	"sync/atomic"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -17,6 +18,27 @@ func init() {
 
 type env string
 
+// clock holds the func() time.Time set by UseClock, it is swapped while
+// other goroutines read it so it is never assigned directly
+var clock atomic.Value
+
+// Now returns the time of the clock in use. This is the wall clock
+// unless it has been replaced by UseClock
+func Now() time.Time {
+	if now, ok := clock.Load().(func() time.Time); ok {
+		return now()
+	}
+	return time.Now()
+}
+
+// UseClock replaces the clock returned by Now, a nil clock restores the wall clock
+func UseClock(now func() time.Time) {
+	if now == nil {
+		now = time.Now
+	}
+	clock.Store(now)
+}
+
 func (e *env) IsMock() bool {
 	return os.Getenv("ENV") == "mock"
 }
@@ -29,6 +51,11 @@ func (e *env) SetMockAccount() {
 	os.Setenv("MOCK_ACCOUNT","true")
 }
 
+// paper accounts simulate the exchange, taking precedence over the mock account
+func (e *env) IsPaperAccount() bool {
+	return os.Getenv("PAPER_ACCOUNT") == "true"
+}
+
 func (e *env) IsMockStream() bool {
 	return os.Getenv("MOCK_STREAM") == "true"
 }
@@ -37,6 +64,10 @@ func (e *env) IsMockFees() bool {
 	return os.Getenv("MOCK_FEES") == "true"
 }
 
+func (e *env) SetMockFees() {
+	os.Setenv("MOCK_FEES", "true")
+}
+
 func (e *env) IsPreventTrade() bool {
 	return os.Getenv("PREVENT_TRADE") == "true"
 }
@@ -119,21 +150,6 @@ func RandomNumber(min, max float64) float64 {
 	return min + rand.Float64()*(max-min)
 }
 
-func TextToSpeach(text string) {
-
-	cmd := exec.Command("say", text)
-
-	// Redirect the 'say' command's output to the standard audio output
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	// Execute the command
-	if err := cmd.Run(); err != nil {
-		panic(err)
-	}
-
-}
-
 func LoadMyEnvFile() {
 	baseDir, _ := os.Getwd() // Get the current working directory
 	envFilePath := filepath.Join(baseDir, ".env")